@@ -17,7 +17,8 @@ import {
   userUpdate,
   chainUpdate,
   ChainHeaders,
-  refreshToken,
+  AuthTokens,
+  authSet,
 } from "./api";
 import dayjs from "./dayjs";
 import { OverlayContainsState, OverlayState } from "./utils/overlay_open";
@@ -25,6 +26,7 @@ import { OverlayContainsState, OverlayState } from "./utils/overlay_open";
 interface StorageAuth {
   user_uid: string;
   token: string;
+  refresh_token?: string;
 }
 
 export enum IsAuthenticated {
@@ -134,7 +136,7 @@ export function StoreProvider({
     await logout().catch((err) => {
       console.warn(err);
     });
-    window.axios.defaults.auth = undefined;
+    authSet(null);
 
     await storage.set("auth", "");
     await storage.set("chain_uid", "");
@@ -153,16 +155,24 @@ export function StoreProvider({
   async function login(email: string, token: string) {
     let emailBase64 = btoa(email);
     const res = await loginValidate(emailBase64, token);
-    window.axios.defaults.auth = "Bearer " + res.data.token;
-    await storage.set("auth", {
-      user_uid: res.data.user.uid,
-      token: res.data.token,
-    } as StorageAuth);
+    const storeTokens = authTokensStore(res.data.user.uid);
+    authSet(res.data, storeTokens);
+    await storeTokens(res.data);
     setAuthUser(res.data.user);
     setIsAuthenticated(IsAuthenticated.LoggedIn);
     refresh("settings", res.data.user);
   }
 
+  // The refresh token is single use, the tokens are stored each time they are refreshed
+  function authTokensStore(userUID: UID) {
+    return (tokens: AuthTokens) =>
+      storage.set("auth", {
+        user_uid: userUID,
+        token: tokens.token,
+        refresh_token: tokens.refresh_token,
+      } as StorageAuth);
+  }
+
   // Will set the isAuthenticated value and directly return it as well (no need to run setIsAuthenticated)
   async function authenticate(): Promise<IsAuthenticated> {
     console.log("run authenticate");
@@ -173,7 +183,11 @@ export function StoreProvider({
     let _isChainAdmin: typeof isChainAdmin = false;
     try {
       if (auth && auth.user_uid) {
-        await refreshToken();
+        // an expired access token is refreshed by the first request
+        authSet(
+          { token: auth.token, refresh_token: auth.refresh_token || "" },
+          authTokensStore(auth.user_uid),
+        );
         _authUser = (await userGetByUID(undefined, auth.user_uid)).data;
 
         _isAuthenticated = IsAuthenticated.LoggedIn;
@@ -181,7 +195,7 @@ export function StoreProvider({
         // logout without clearing empty token
         console.log("logout without clearing empty token");
         _isAuthenticated = IsAuthenticated.LoggedOut;
-        window.axios.defaults.auth = undefined;
+        authSet(null);
         setIsAuthenticated(_isAuthenticated);
         return _isAuthenticated;
       }
@@ -123,11 +123,75 @@ export const bagColors = [
   "#3C3C3B",
 ];
 
+export interface AuthTokens {
+  token: string;
+  refresh_token: string;
+}
+
 window.axios = redaxios.create({
   baseURL: import.meta.env.VITE_API_BASE_URL || "",
   withCredentials: false,
 });
 
+// The access token is short lived, on a 401 the refresh token is exchanged
+// for new tokens and the request is retried once.
+let authRefreshToken = "";
+let authOnRefresh: ((tokens: AuthTokens) => void) | undefined;
+let authRefreshing: Promise<void> | null = null;
+
+const requestPost = window.axios.post;
+
+// Sets the tokens used by every request, onRefresh is called with the new tokens to store them
+export function authSet(
+  tokens: AuthTokens | null,
+  onRefresh?: (tokens: AuthTokens) => void,
+) {
+  window.axios.defaults.auth = tokens ? "Bearer " + tokens.token : undefined;
+  authRefreshToken = tokens?.refresh_token || "";
+  authOnRefresh = onRefresh;
+}
+
+// Concurrent requests wait for the same refresh, a refresh token can only be used once
+function authRefresh(): Promise<void> {
+  if (!authRefreshing) {
+    authRefreshing = requestPost<AuthTokens>(
+      "/v2/refresh-token",
+      { refresh_token: authRefreshToken },
+      { auth: undefined },
+    )
+      .then((res) => {
+        authSet(res.data, authOnRefresh);
+        authOnRefresh?.(res.data);
+      })
+      .finally(() => {
+        authRefreshing = null;
+      });
+  }
+  return authRefreshing;
+}
+
+function withAuthRefresh<T extends (...args: any[]) => Promise<any>>(
+  request: T,
+): T {
+  return (async (...args: any[]) => {
+    try {
+      return await request(...args);
+    } catch (err: any) {
+      if (err?.status !== 401 || !authRefreshToken) throw err;
+      await authRefresh().catch(() => {
+        throw err;
+      });
+      return await request(...args);
+    }
+  }) as T;
+}
+
+window.axios.get = withAuthRefresh(window.axios.get);
+window.axios.post = withAuthRefresh(window.axios.post);
+window.axios.put = withAuthRefresh(window.axios.put);
+window.axios.patch = withAuthRefresh(window.axios.patch);
+window.axios.delete = withAuthRefresh(window.axios.delete);
+
 export function loginEmail(email: string) {
   return window.axios.post<unknown>(
     "/v2/login/email",
@@ -137,7 +201,7 @@ export function loginEmail(email: string) {
 }
 
 export function loginValidate(u: string, apiKey: string) {
-  return window.axios.get<{ user: User; token: string }>("/v2/login/validate", {
+  return window.axios.get<{ user: User } & AuthTokens>("/v2/login/validate", {
     auth: undefined,
     withCredentials: false,
     params: { apiKey, u },
@@ -148,10 +212,6 @@ export function logout() {
   return window.axios.delete<never>("/v2/logout");
 }
 
-export function refreshToken() {
-  return window.axios.post<never>("/v2/refresh-token");
-}
-
 export function userGetByUID(chainUID: string | undefined, userUID: string) {
   let params: { user_uid: string; chain_uid?: string } = { user_uid: userUID };
   if (chainUID) params.chain_uid = chainUID;
@@ -1,4 +1,13 @@
-import type { Chain, UID } from "./types";
+import type {
+  Chain,
+  ChainHostInvitation,
+  ChainInvite,
+  ChainQuestion,
+  ChainWaitlist,
+  RequestChainQuestionAnswer,
+  UID,
+  UserChainRole,
+} from "./types";
 import type { RequestRegisterChain } from "./login";
 import axios from "./index";
 
@@ -40,6 +49,63 @@ export function chainGetNear(params?: RequestChainGetNearParams) {
   return axios.get<Chain[]>("/v2/chain/near", { params });
 }
 
+export interface RequestChainSearchParams {
+  q?: string;
+  latitude?: number;
+  longitude?: number;
+  // in km, requires latitude and longitude
+  radius?: number;
+  country_code?: string;
+  filter_sizes?: string[];
+  filter_genders?: string[];
+  sort?: "name" | "distance" | "newest";
+  cursor?: string;
+  limit?: number;
+}
+export interface ChainSearchResponse {
+  chains: (Chain & { distance?: number })[];
+  total: number;
+  next_cursor: string | null;
+}
+
+export function chainSearch(params: RequestChainSearchParams) {
+  return axios.get<ChainSearchResponse>("/v2/chain/search", { params });
+}
+
+export interface ChainGeoJSONClusterProperties {
+  cluster: true;
+  point_count: number;
+  point_count_open: number;
+}
+export interface ChainGeoJSON {
+  type: "FeatureCollection";
+  features: {
+    type: "Feature";
+    geometry: { type: "Point"; coordinates: [number, number] };
+    properties: Chain | ChainGeoJSONClusterProperties;
+  }[];
+}
+
+interface RequestChainGetGeoJSONFilters {
+  filter_sizes?: string[];
+  filter_genders?: string[];
+}
+
+// bbox is [min_longitude, min_latitude, max_longitude, max_latitude]
+export function chainGetGeoJSON(
+  zoom: number,
+  bbox?: number[],
+  filters?: RequestChainGetGeoJSONFilters,
+) {
+  const params: Record<string, string | number> = { zoom: Math.floor(zoom) };
+  if (bbox) params.bbox = bbox.join(",");
+  if (filters?.filter_sizes?.length)
+    params.filter_sizes = filters.filter_sizes.join(",");
+  if (filters?.filter_genders?.length)
+    params.filter_genders = filters.filter_genders.join(",");
+  return axios.get<ChainGeoJSON>("/v2/chain/geojson", { params });
+}
+
 export function chainCreate(chain: RequestRegisterChain) {
   return axios.post<never>("/v2/chain", chain);
 }
@@ -54,22 +120,256 @@ export function chainDelete(chainUID: UID) {
   return axios.delete<never>(`/v2/chain?chain_uid=${chainUID}`);
 }
 
+export function chainConfirmStillActive(chainUID: UID) {
+  return axios.post<never>("/v2/chain/still-active", { chain_uid: chainUID });
+}
+
+export interface ChainAuditLog {
+  action:
+    | "chain_update"
+    | "user_approve"
+    | "user_deny"
+    | "user_remove"
+    | "role_change"
+    | "route_order"
+    | "user_transfer";
+  actor_user_uid: UID;
+  actor_name: string;
+  target_user_uid: UID;
+  target_name: string;
+  changes: Record<string, { old: unknown; new: unknown }> | null;
+  created_at: string;
+}
+
+export function chainGetAuditLog(chainUID: UID) {
+  return axios.get<ChainAuditLog[]>("/v2/chain/audit-log", {
+    params: { chain_uid: chainUID },
+  });
+}
+
+export type ChainDeleted = Chain & { deleted_at: string };
+
+export function chainGetAllDeleted() {
+  return axios.get<ChainDeleted[]>("/v2/chain/deleted");
+}
+
+export function chainRestore(chainUID: UID) {
+  return axios.post<never>("/v2/chain/restore", { chain_uid: chainUID });
+}
+
+export interface ChainMergeResult {
+  source_chain_uid: UID;
+  target_chain_uid: UID;
+  members_moved: number;
+  members_deduplicated: number;
+  bags_moved: number;
+  bulky_items_moved: number;
+  total_members: number;
+  dry_run: boolean;
+}
+
+// Moves all members of the source loop into the target loop and removes the source loop
+export function chainMerge(
+  sourceChainUID: UID,
+  targetChainUID: UID,
+  dryRun: boolean,
+) {
+  return axios.post<ChainMergeResult>("/v2/chain/merge", {
+    source_chain_uid: sourceChainUID,
+    target_chain_uid: targetChainUID,
+    dry_run: dryRun,
+  });
+}
+
+export interface ChainSplitGroup {
+  latitude: number;
+  longitude: number;
+  user_uids: UID[];
+}
+
+// Suggests two groups of members, hosts can adjust them before splitting
+export function chainSplitPreview(chainUID: UID) {
+  return axios.get<[ChainSplitGroup, ChainSplitGroup]>(
+    "/v2/chain/split/preview",
+    { params: { chain_uid: chainUID } },
+  );
+}
+
+// Moves the given members to a new loop named name
+export function chainSplit(chainUID: UID, name: string, userUIDs: UID[]) {
+  return axios.post<{ chain_uid: UID }>("/v2/chain/split", {
+    chain_uid: chainUID,
+    name,
+    user_uids: userUIDs,
+  });
+}
+
 export function chainAddUser(
   chainUID: UID,
   userUID: UID,
   isChainAdmin: boolean,
+  answers?: RequestChainQuestionAnswer[],
 ) {
   return axios.post<never>("/v2/chain/add-user", {
     user_uid: userUID,
     chain_uid: chainUID,
     is_chain_admin: isChainAdmin,
+    answers,
+  });
+}
+
+export function chainQuestionGetAll(chainUID: UID) {
+  return axios.get<ChainQuestion[]>("/v2/chain/questions", {
+    params: { chain_uid: chainUID },
   });
 }
 
-export function chainRemoveUser(chainUID: UID, userUID: UID) {
+export function chainQuestionSetAll(
+  chainUID: UID,
+  questions: Pick<
+    ChainQuestion,
+    "uid" | "type" | "question" | "options" | "is_required"
+  >[],
+) {
+  return axios.put<ChainQuestion[]>("/v2/chain/questions", {
+    chain_uid: chainUID,
+    questions,
+  });
+}
+
+export function chainSetUserRole(
+  chainUID: UID,
+  userUID: UID,
+  role: UserChainRole,
+) {
+  return axios.post<never>("/v2/chain/add-user", {
+    user_uid: userUID,
+    chain_uid: chainUID,
+    role,
+  });
+}
+
+// Inviting by email also works for people that have not registered yet
+export function chainHostInvitationCreate(
+  chainUID: UID,
+  invitee: { user_uid: UID } | { email: string },
+) {
+  return axios.post<ChainHostInvitation>("/v2/chain/host-invitation", {
+    chain_uid: chainUID,
+    ...invitee,
+  });
+}
+
+export function chainHostInvitationGetAll(chainUID: UID) {
+  return axios.get<ChainHostInvitation[]>("/v2/chain/host-invitation/all", {
+    params: { chain_uid: chainUID },
+  });
+}
+
+export function chainHostInvitationRevoke(chainUID: UID, uid: UID) {
+  return axios.delete<never>(`/v2/chain/host-invitation/${uid}`, {
+    params: { chain_uid: chainUID },
+  });
+}
+
+export function chainInviteCreate(
+  chainUID: UID,
+  options: {
+    expires_in_days?: number;
+    max_uses?: number;
+    is_auto_approved: boolean;
+  },
+) {
+  return axios.post<ChainInvite>("/v2/chain/invite", {
+    chain_uid: chainUID,
+    ...options,
+  });
+}
+
+export function chainInviteGetAll(chainUID: UID) {
+  return axios.get<ChainInvite[]>("/v2/chain/invite/all", {
+    params: { chain_uid: chainUID },
+  });
+}
+
+export function chainInviteRevoke(chainUID: UID, uid: UID) {
+  return axios.delete<never>(`/v2/chain/invite/${uid}`, {
+    params: { chain_uid: chainUID },
+  });
+}
+
+// The invite is the uid of an invite link or an invite code
+export function chainInviteGet(invite: string) {
+  return axios.get<
+    Pick<
+      ChainInvite,
+      "chain_uid" | "chain_name" | "is_auto_approved" | "expires_at"
+    >
+  >("/v2/chain/invite", { params: { invite } });
+}
+
+export function chainInviteAccept(
+  invite: string,
+  answers?: RequestChainQuestionAnswer[],
+) {
+  return axios.post<{ chain_uid: UID; is_approved: boolean }>(
+    "/v2/chain/invite/accept",
+    { invite, answers },
+  );
+}
+
+// Only for loops that are closed to new members
+export function chainWaitlistJoin(chainUID: UID) {
+  return axios.post<ChainWaitlist>("/v2/chain/waitlist", {
+    chain_uid: chainUID,
+  });
+}
+
+// Without a userUID the authenticated user leaves the waitlist
+export function chainWaitlistLeave(chainUID: UID, userUID?: UID) {
+  return axios.delete<never>("/v2/chain/waitlist", {
+    params: { chain_uid: chainUID, user_uid: userUID },
+  });
+}
+
+export function chainWaitlistGetAll(chainUID: UID) {
+  return axios.get<ChainWaitlist[]>("/v2/chain/waitlist/all", {
+    params: { chain_uid: chainUID },
+  });
+}
+
+export function chainWaitlistSetOrder(chainUID: UID, userUIDs: UID[]) {
+  return axios.post<never>("/v2/chain/waitlist/order", {
+    chain_uid: chainUID,
+    user_uids: userUIDs,
+  });
+}
+
+// the successor is invited to become host when the last host leaves
+export function chainRemoveUser(
+  chainUID: UID,
+  userUID: UID,
+  successorUserUID?: UID,
+) {
   return axios.post<never>("/v2/chain/remove-user", {
     user_uid: userUID,
     chain_uid: chainUID,
+    successor_user_uid: successorUserUID,
+  });
+}
+
+export interface ChainOrphaned extends Chain {
+  orphaned_at: string;
+}
+
+export function chainGetAllOrphaned() {
+  return axios.get<ChainOrphaned[]>("/v2/chain/orphaned/all");
+}
+
+export function chainBecomeHost(chainUID: UID) {
+  return axios.post<never>("/v2/chain/become-host", {
+    chain_uid: chainUID,
+    allow_toh: true,
   });
 }
 
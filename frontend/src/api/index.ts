@@ -3,4 +3,43 @@ import redaxios from "redaxios";
 const axios = redaxios.create({
   baseURL: "/api",
 });
+
+// The access token cookie is short lived, on a 401 the refresh token cookie
+// is exchanged for new tokens and the request is retried once.
+let authRefreshing: Promise<unknown> | null = null;
+
+const requestPost = axios.post;
+
+// Concurrent requests wait for the same refresh, a refresh token can only be used once
+function authRefresh() {
+  if (!authRefreshing) {
+    authRefreshing = requestPost<never>("/v2/refresh-token").finally(() => {
+      authRefreshing = null;
+    });
+  }
+  return authRefreshing;
+}
+
+function withAuthRefresh<T extends (...args: any[]) => Promise<any>>(
+  request: T,
+): T {
+  return (async (url: string, ...args: any[]) => {
+    try {
+      return await request(url, ...args);
+    } catch (err: any) {
+      if (err?.status !== 401 || url === "/v2/refresh-token") throw err;
+      await authRefresh().catch(() => {
+        throw err;
+      });
+      return await request(url, ...args);
+    }
+  }) as T;
+}
+
+axios.get = withAuthRefresh(axios.get);
+axios.post = withAuthRefresh(axios.post);
+axios.put = withAuthRefresh(axios.put);
+axios.patch = withAuthRefresh(axios.patch);
+axios.delete = withAuthRefresh(axios.delete);
+
 export default axios;
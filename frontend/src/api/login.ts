@@ -1,5 +1,5 @@
 import { Sizes } from "./enums";
-import type { UID, User } from "./types";
+import type { RequestChainQuestionAnswer, UID, User } from "./types";
 import axios from "./index";
 
 export interface RequestRegisterUser {
@@ -34,10 +34,18 @@ export function registerChainAdmin(
   return axios.post<never>("/v2/register/chain-admin", { user, chain });
 }
 
-export function registerBasicUser(user: RequestRegisterUser, chainUID: UID) {
+// The invite is the uid of an invite link or an invite code
+export function registerBasicUser(
+  user: RequestRegisterUser,
+  chainUID: UID,
+  answers?: RequestChainQuestionAnswer[],
+  invite?: string,
+) {
   return axios.post<never>("/v2/register/basic-user", {
     user,
     chain_uid: chainUID,
+    answers,
+    invite,
   });
 }
 
@@ -58,16 +66,45 @@ export function loginEmailAndAddToChain(email: string, chainUID: UID) {
   });
 }
 
+export interface LoginValidateResponse {
+  user: User;
+  chain_uid: UID;
+  // the loop of chain_uid has questions to answer before joining
+  join_form?: boolean;
+}
+
 export function loginValidate(u: string, apiKey: string, chainUID: UID) {
   let params: Record<string, string> = { u, apiKey };
   if (chainUID) {
     params["c"] = chainUID;
   }
-  return axios.get<{ user: User }>(`/v2/login/validate`, {
+  return axios.get<LoginValidateResponse>(`/v2/login/validate`, {
     params,
   });
 }
 
+export function loginValidateMagicLink(t: string) {
+  return axios.get<LoginValidateResponse>(`/v2/login/validate`, {
+    params: { t },
+  });
+}
+
+export interface OidcProvider {
+  id: string;
+  name: string;
+}
+
+export function loginOidcProviders() {
+  return axios.get<OidcProvider[]>("/v2/login/oidc");
+}
+
+// Navigated to directly, the server redirects to the identity provider
+export function loginOidcURL(providerID: string, chainUID?: UID) {
+  let url = `/api/v2/login/oidc/${providerID}`;
+  if (chainUID) url += `?c=${chainUID}`;
+  return url;
+}
+
 export function logout() {
   return axios.delete<never>("/v2/logout");
 }
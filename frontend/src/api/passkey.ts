@@ -0,0 +1,82 @@
+import type { UID, User } from "./types";
+import axios from "./index";
+
+export interface Passkey {
+  uid: UID;
+  name: string;
+  created_at: string;
+  last_used_at: string | null;
+}
+
+function decode(s: string): ArrayBuffer {
+  const b = atob(s.replace(/-/g, "+").replace(/_/g, "/"));
+  return Uint8Array.from(b, (c) => c.charCodeAt(0)).buffer;
+}
+
+function encode(b: ArrayBuffer): string {
+  return btoa(String.fromCharCode(...new Uint8Array(b)))
+    .replace(/\+/g, "-")
+    .replace(/\//g, "_")
+    .replace(/=+$/, "");
+}
+
+export function passkeyIsSupported() {
+  return !!globalThis.window?.PublicKeyCredential;
+}
+
+export function passkeyGetAll() {
+  return axios.get<Passkey[]>("/v2/user/passkeys");
+}
+
+export function passkeyDelete(uid: UID) {
+  return axios.delete<never>(`/v2/user/passkeys/${uid}`);
+}
+
+export async function passkeyRegister(name: string) {
+  const options = (await axios.post<any>("/v2/user/passkeys/begin")).data;
+  const credential = (await navigator.credentials.create({
+    publicKey: {
+      ...options,
+      challenge: decode(options.challenge),
+      user: { ...options.user, id: decode(options.user.id) },
+      excludeCredentials: (options.excludeCredentials || []).map((c: any) => ({
+        ...c,
+        id: decode(c.id),
+      })),
+    },
+  })) as PublicKeyCredential;
+  const response = credential.response as AuthenticatorAttestationResponse;
+
+  return axios.post<Passkey>("/v2/user/passkeys", {
+    name,
+    credential: {
+      id: credential.id,
+      rawId: encode(credential.rawId),
+      type: credential.type,
+      response: {
+        clientDataJSON: encode(response.clientDataJSON),
+        attestationObject: encode(response.attestationObject),
+      },
+    },
+  });
+}
+
+export async function loginPasskey() {
+  const options = (await axios.post<any>("/v2/login/passkey/begin")).data;
+  const credential = (await navigator.credentials.get({
+    publicKey: { ...options, challenge: decode(options.challenge) },
+  })) as PublicKeyCredential;
+  const response = credential.response as AuthenticatorAssertionResponse;
+
+  return axios.post<{ user: User; chain_uid: UID }>("/v2/login/passkey", {
+    id: credential.id,
+    rawId: encode(credential.rawId),
+    type: credential.type,
+    response: {
+      clientDataJSON: encode(response.clientDataJSON),
+      authenticatorData: encode(response.authenticatorData),
+      signature: encode(response.signature),
+      userHandle: response.userHandle ? encode(response.userHandle) : undefined,
+    },
+  });
+}
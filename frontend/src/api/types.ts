@@ -28,12 +28,84 @@ export interface Bag {
   updated_at: string;
 }
 
+// A participant has no role
+export type UserChainRole =
+  | ""
+  | "host"
+  | "bag_manager"
+  | "route_manager"
+  | "moderator";
+
 export interface UserChain {
   user_uid: UID;
   chain_uid: UID;
   is_chain_admin: boolean;
+  role: UserChainRole;
   is_approved: boolean;
   created_at: string;
+  // Only set for users that are not yet approved
+  join_answers?: ChainQuestionAnswer[];
+}
+
+export type ChainQuestionType = "text" | "single_choice" | "yes_no";
+
+export interface ChainQuestion {
+  uid: UID;
+  type: ChainQuestionType;
+  question: string;
+  // Only used by single choice questions
+  options: string[] | null;
+  is_required: boolean;
+  created_at: string;
+}
+
+export interface ChainQuestionAnswer {
+  question_uid: UID;
+  question: string;
+  answer: string;
+  created_at: string;
+}
+
+// yes_no questions are answered with "yes" or "no"
+export interface RequestChainQuestionAnswer {
+  question_uid: UID;
+  answer: string;
+}
+
+// The email is set for invitations to people that have not registered yet
+export interface ChainHostInvitation {
+  uid: UID;
+  chain_uid: UID;
+  chain_name: string;
+  invited_by_name: string;
+  user_uid: UID;
+  email: string;
+  expires_at: string;
+  created_at: string;
+}
+
+// Lets users join a loop that is closed to new members
+export interface ChainInvite {
+  uid: UID;
+  code: string;
+  chain_uid: UID;
+  chain_name: string;
+  created_by_name: string;
+  // null when the invite can be used an unlimited amount of times
+  max_uses: number | null;
+  uses: number;
+  is_auto_approved: boolean;
+  expires_at: string;
+  created_at: string;
+}
+
+export interface ChainWaitlist {
+  user_uid: UID;
+  user_name: string;
+  user_email: string;
+  position: number;
+  created_at: string;
+  invited_at: string | null;
 }
 
 export interface Chain {
@@ -48,6 +120,8 @@ export interface Chain {
   sizes: string[] | null;
   published: boolean;
   open_to_new_members: boolean;
+  // 0 when the Loop has no member limit
+  max_members: number;
   total_members?: number;
   total_hosts?: number;
   route_privacy?: number;
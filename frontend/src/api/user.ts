@@ -1,4 +1,4 @@
-import type { UID, User } from "./types";
+import type { ChainHostInvitation, UID, User } from "./types";
 import axios from "./index";
 
 export function userGetByUID(
@@ -50,10 +50,16 @@ export function userAddAsChainAdmin(chainUID: string, userUID: string) {
   });
 }
 
-export function userPurge(userUID: string) {
-  return axios.delete<never>("v2/user/purge", {
-    params: { user_uid: userUID },
-  });
+// successors maps the uid of a loop to the uid of the member invited to become host
+export function userPurge(
+  userUID: string,
+  successors: Record<UID, UID> = {},
+) {
+  const params: Record<string, string> = { user_uid: userUID };
+  for (const [chainUID, successorUID] of Object.entries(successors)) {
+    params[`successors[${chainUID}]`] = successorUID;
+  }
+  return axios.delete<never>("v2/user/purge", { params });
 }
 
 export function userHasNewsletter(
@@ -84,3 +90,41 @@ export function userCheckEmailExists(email: string) {
     params: { email },
   });
 }
+
+// Sets the token cookie of the user, the root admin returns to their own account when it expires
+export function userImpersonate(userUID: UID) {
+  return axios.post<{ token: string; user_uid: UID; expires_at: string }>(
+    "/v2/impersonate",
+    { user_uid: userUID },
+  );
+}
+
+export function userEmailChangeRequest(userUID: UID, email: string) {
+  return axios.post<never>("/v2/user/email-change", {
+    user_uid: userUID,
+    email,
+  });
+}
+
+export function userEmailChangeConfirm(token: string) {
+  return axios.post<{ user_uid: UID; email: string }>(
+    "/v2/user/email-change/confirm",
+    { token },
+  );
+}
+
+export function userHostInvitationGetAll() {
+  return axios.get<ChainHostInvitation[]>("/v2/user/host-invitations");
+}
+
+// Accepting also accepts the Terms of the Hosts
+export function userHostInvitationAccept(uid: UID) {
+  return axios.post<{ chain_uid: UID }>(
+    `/v2/user/host-invitations/${uid}/accept`,
+    { allow_toh: true },
+  );
+}
+
+export function userHostInvitationDecline(uid: UID) {
+  return axios.post<never>(`/v2/user/host-invitations/${uid}/decline`);
+}
@@ -0,0 +1,87 @@
+import { useTranslation } from "react-i18next";
+
+import type {
+  ChainQuestion,
+  RequestChainQuestionAnswer,
+} from "../../../api/types";
+
+// Same as ChainQuestionAnswerMaxSize on the server
+const ANSWER_MAX_LENGTH = 1000;
+
+// Reads the answers from the form values, unanswered questions are left out
+export function chainQuestionAnswersFromForm(
+  questions: ChainQuestion[],
+  values: Record<string, FormDataEntryValue> | undefined,
+): RequestChainQuestionAnswer[] {
+  return questions
+    .map((q) => ({
+      question_uid: q.uid,
+      answer: ((values?.[q.uid] as string | undefined) || "").trim(),
+    }))
+    .filter((a) => a.answer);
+}
+
+export function chainQuestionUnanswered(
+  questions: ChainQuestion[],
+  answers: RequestChainQuestionAnswer[],
+): ChainQuestion | undefined {
+  return questions.find(
+    (q) => q.is_required && !answers.find((a) => a.question_uid === q.uid),
+  );
+}
+
+interface Props {
+  questions: ChainQuestion[];
+  // Connects the inputs to a form they are not part of
+  form?: string;
+}
+
+// The questions of a loop as form inputs, named by the uid of the question
+export default function ChainQuestionsInputs({ questions, form }: Props) {
+  const { t } = useTranslation();
+
+  if (!questions.length) return null;
+  return (
+    <div className="flex flex-col gap-3 my-4">
+      {questions.map((q) => (
+        <label key={q.uid} className="block text-start">
+          <span className="block text-sm font-semibold mb-1">
+            {q.question}
+            {q.is_required ? " *" : ""}
+          </span>
+          {q.type === "text" ? (
+            <textarea
+              className="textarea textarea-secondary w-full rounded-none"
+              name={q.uid}
+              form={form}
+              required={q.is_required}
+              maxLength={ANSWER_MAX_LENGTH}
+            />
+          ) : (
+            <select
+              className="select select-secondary select-sm w-full rounded-none"
+              name={q.uid}
+              form={form}
+              required={q.is_required}
+              defaultValue=""
+            >
+              <option value="" />
+              {q.type === "yes_no" ? (
+                <>
+                  <option value="yes">{t("yes")}</option>
+                  <option value="no">{t("no")}</option>
+                </>
+              ) : (
+                q.options?.map((o) => (
+                  <option key={o} value={o}>
+                    {o}
+                  </option>
+                ))
+              )}
+            </select>
+          )}
+        </label>
+      ))}
+    </div>
+  );
+}
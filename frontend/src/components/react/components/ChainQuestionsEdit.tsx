@@ -0,0 +1,170 @@
+import { useEffect, useState } from "react";
+import { useTranslation } from "react-i18next";
+
+import type { ChainQuestion, ChainQuestionType, UID } from "../../../api/types";
+import { chainQuestionGetAll, chainQuestionSetAll } from "../../../api/chain";
+import { addToast, addToastError } from "../../../stores/toast";
+import { GinParseErrors } from "../util/gin-errors";
+
+// Same as the limits on the server
+const QUESTIONS_MAX = 10;
+const QUESTION_MAX_LENGTH = 300;
+const OPTIONS_MAX = 20;
+
+const QUESTION_TYPES: ChainQuestionType[] = ["text", "single_choice", "yes_no"];
+
+type EditQuestion = Pick<
+  ChainQuestion,
+  "uid" | "type" | "question" | "options" | "is_required"
+>;
+
+// Hosts set the questions users answer when joining the loop
+export default function ChainQuestionsEdit({ chainUID }: { chainUID: UID }) {
+  const { t } = useTranslation();
+  const [questions, setQuestions] = useState<EditQuestion[]>([]);
+
+  useEffect(() => {
+    chainQuestionGetAll(chainUID)
+      .then((res) => setQuestions(res.data))
+      .catch((err) => {
+        addToastError(GinParseErrors(t, err), err?.status);
+      });
+  }, [chainUID]);
+
+  function handleChange(index: number, q: Partial<EditQuestion>) {
+    setQuestions((s) => s.map((v, i) => (i === index ? { ...v, ...q } : v)));
+  }
+
+  function handleChangeType(index: number, type: ChainQuestionType) {
+    handleChange(index, {
+      type,
+      options: type === "single_choice" ? ["", ""] : null,
+    });
+  }
+
+  function handleClickAdd() {
+    setQuestions((s) => [
+      ...s,
+      {
+        uid: "",
+        type: "text",
+        question: "",
+        options: null,
+        is_required: false,
+      },
+    ]);
+  }
+
+  function handleClickRemove(index: number) {
+    setQuestions((s) => s.filter((_, i) => i !== index));
+  }
+
+  function handleClickSave() {
+    const body = questions.map((q) => ({
+      ...q,
+      options:
+        q.type === "single_choice"
+          ? (q.options || []).map((o) => o.trim()).filter((o) => o)
+          : null,
+    }));
+    chainQuestionSetAll(chainUID, body)
+      .then((res) => {
+        setQuestions(res.data);
+        addToast({ message: t("questionsSaved"), type: "success" });
+      })
+      .catch((err) => {
+        addToastError(GinParseErrors(t, err), err?.status);
+      });
+  }
+
+  return (
+    <section className="max-w-screen-sm mx-auto my-10">
+      <h2 className="font-sans font-semibold text-secondary text-2xl mb-2">
+        {t("questions")}
+      </h2>
+      <p className="text-sm mb-4">{t("questionsInfo")}</p>
+
+      {questions.map((q, i) => (
+        <div key={q.uid || i} className="border-2 border-secondary p-4 mb-4">
+          <div className="flex gap-3 mb-3">
+            <input
+              type="text"
+              className="input input-secondary input-sm w-full rounded-none"
+              placeholder={t("question")}
+              value={q.question}
+              maxLength={QUESTION_MAX_LENGTH}
+              onChange={(e) => handleChange(i, { question: e.target.value })}
+            />
+            <button
+              type="button"
+              className="btn btn-sm btn-ghost"
+              aria-label={t("delete")}
+              onClick={() => handleClickRemove(i)}
+            >
+              <span className="feather feather-trash" />
+            </button>
+          </div>
+          <div className="flex flex-wrap items-center gap-3">
+            <select
+              className="select select-secondary select-sm rounded-none"
+              value={q.type}
+              onChange={(e) =>
+                handleChangeType(i, e.target.value as ChainQuestionType)
+              }
+            >
+              {QUESTION_TYPES.map((type) => (
+                <option key={type} value={type}>
+                  {t("questionType_" + type)}
+                </option>
+              ))}
+            </select>
+            <label className="label cursor-pointer gap-2">
+              <input
+                type="checkbox"
+                className="checkbox checkbox-sm checkbox-secondary"
+                checked={q.is_required}
+                onChange={(e) =>
+                  handleChange(i, { is_required: e.target.checked })
+                }
+              />
+              <span className="label-text">{t("required")}</span>
+            </label>
+          </div>
+          {q.type === "single_choice" ? (
+            <label className="block mt-3">
+              <span className="block text-sm mb-1">{t("questionOptions")}</span>
+              <textarea
+                className="textarea textarea-secondary w-full rounded-none"
+                rows={Math.max(2, q.options?.length || 0)}
+                value={(q.options || []).join("\n")}
+                onChange={(e) =>
+                  handleChange(i, {
+                    options: e.target.value.split("\n").slice(0, OPTIONS_MAX),
+                  })
+                }
+              />
+            </label>
+          ) : null}
+        </div>
+      ))}
+
+      <div className="flex gap-3">
+        <button
+          type="button"
+          className="btn btn-secondary btn-outline"
+          disabled={questions.length >= QUESTIONS_MAX}
+          onClick={handleClickAdd}
+        >
+          {t("addQuestion")}
+        </button>
+        <button
+          type="button"
+          className="btn btn-primary"
+          onClick={handleClickSave}
+        >
+          {t("save")}
+        </button>
+      </div>
+    </section>
+  );
+}
@@ -15,7 +15,8 @@ import {
   chainPoke,
   chainRemoveUser,
 } from "../../../api/chain";
-import type { Chain, UID } from "../../../api/types";
+import { userGetAllByChain } from "../../../api/user";
+import type { Chain, UID, User } from "../../../api/types";
 import { addModal, addToast, addToastError } from "../../../stores/toast";
 import { GinParseErrors } from "../util/gin-errors";
 import dayjs from "../util/dayjs";
@@ -23,6 +24,7 @@ import useToClipboard from "../util/to-clipboard.hooks";
 import { useStore } from "@nanostores/react";
 import { useTranslation } from "react-i18next";
 import useLocalizePath from "../util/localize_path.hooks";
+import SuccessorSelect, { successorCandidates } from "./SuccessorSelect";
 
 const PUBLIC_BASE_URL = import.meta.env.PUBLIC_BASE_URL;
 
@@ -93,20 +95,37 @@ export default function ChainsList({ chains, setChains }: Props) {
       });
   }
 
-  function handleClickUnsubscribe(e: MouseEvent, chain: Chain) {
+  async function handleClickUnsubscribe(e: MouseEvent, chain: Chain) {
     e.preventDefault();
     if (!authUser) return;
+
+    // the last host can choose who is invited to take over
+    const isLastHost =
+      !!authUser.chains.find((uc) => uc.chain_uid === chain.uid)
+        ?.is_chain_admin && chain.total_hosts === 1;
+    let candidates: User[] = [];
+    if (isLastHost) {
+      candidates = await userGetAllByChain(chain.uid)
+        .then((res) => successorCandidates(res.data, chain.uid, authUser.uid))
+        .catch(() => []);
+    }
+
     addModal({
       message: t("areYouSureLeaveLoop", {
         name: authUser.name,
         chain: chain.name,
       }),
+      content: candidates.length
+        ? () => <SuccessorSelect name="successor" users={candidates} />
+        : undefined,
       actions: [
         {
           text: t("leave"),
           type: "error",
-          fn: () => {
-            chainRemoveUser(chain.uid, authUser!.uid)
+          submit: true,
+          fn: (formValues) => {
+            const successorUID = formValues?.successor || undefined;
+            chainRemoveUser(chain.uid, authUser!.uid, successorUID)
               .catch((err: any) => {
                 console.error(
                   "Unable to unsubscribe from Loop",
@@ -0,0 +1,87 @@
+import { useEffect, useState } from "react";
+import { useStore } from "@nanostores/react";
+import { useTranslation } from "react-i18next";
+
+import { $authUser, authUserRefresh } from "../../../stores/auth";
+import {
+  type ChainDeleted,
+  chainGetAllDeleted,
+  chainRestore,
+} from "../../../api/chain";
+import { addToast, addToastError } from "../../../stores/toast";
+import { GinParseErrors } from "../util/gin-errors";
+import dayjs from "../util/dayjs";
+
+// Same as ChainDeletedRetentionDays on the server
+const RESTORE_DAYS = 30;
+
+// Lists the deleted loops the authenticated user is allowed to restore
+export default function DeletedChainsList() {
+  const { t } = useTranslation();
+  const authUser = useStore($authUser);
+  const [chains, setChains] = useState<ChainDeleted[]>([]);
+
+  useEffect(() => {
+    if (!authUser) return;
+    chainGetAllDeleted()
+      .then((res) => setChains(res.data))
+      .catch((err) => {
+        console.error("Unable to load deleted chains", err);
+      });
+  }, [authUser]);
+
+  function handleClickRestore(chain: ChainDeleted) {
+    chainRestore(chain.uid)
+      .then(() => {
+        addToast({
+          message: t("loopRestored", { chain: chain.name }),
+          type: "success",
+        });
+        setChains((s) => s.filter((c) => c.uid !== chain.uid));
+        authUserRefresh(true);
+      })
+      .catch((err) => {
+        addToastError(GinParseErrors(t, err), err?.status);
+      });
+  }
+
+  if (!chains.length) return null;
+
+  return (
+    <div className="container mx-auto mt-8 lg:mt-16">
+      <div className="flex flex-row px-4 md:px-20 py-4">
+        <h2 className="text-2xl font-bold mb-3">{t("deletedLoops")}</h2>
+      </div>
+
+      <div className="mb-20 border-b-2 border-base-200">
+        <table className="table table-compact w-full">
+          <tbody>
+            {chains.map((chain) => (
+              <tr key={chain.uid}>
+                <td className="font-bold w-32 whitespace-normal">
+                  {chain.name}
+                </td>
+                <td align="left" className="whitespace-normal max-xs:hidden">
+                  {t("restorableUntil", {
+                    date: dayjs(chain.deleted_at)
+                      .add(RESTORE_DAYS, "days")
+                      .format("LL"),
+                  })}
+                </td>
+                <td align="right">
+                  <button
+                    type="button"
+                    className="btn btn-sm btn-primary"
+                    onClick={() => handleClickRestore(chain)}
+                  >
+                    {t("restore")}
+                  </button>
+                </td>
+              </tr>
+            ))}
+          </tbody>
+        </table>
+      </div>
+    </div>
+  );
+}
@@ -2,14 +2,18 @@ import { type MouseEvent, useMemo } from "react";
 import type { User, Chain } from "../../../../api/types";
 import { $authUser, authUserRefresh } from "../../../../stores/auth";
 
-import { addModal, addToastError } from "../../../../stores/toast";
-import { chainAddUser } from "../../../../api/chain";
+import { addModal, addToast, addToastError } from "../../../../stores/toast";
+import { chainAddUser, chainQuestionGetAll } from "../../../../api/chain";
 
 import { GinParseErrors } from "../../util/gin-errors";
 import { SizeBadges } from "../Badges";
 import { useStore } from "@nanostores/react";
 import { useTranslation } from "react-i18next";
 import useLocalizePath from "../../util/localize_path.hooks";
+import ChainQuestionsInputs, {
+  chainQuestionAnswersFromForm,
+  chainQuestionUnanswered,
+} from "../ChainQuestions";
 
 enum ListChainType {
   Focused,
@@ -56,19 +60,47 @@ export default function SideBar({
     setFocusedChain(chain);
   }
 
-  function handleClickJoin(chain: Chain) {
+  async function handleClickJoin(chain: Chain) {
     if (authUser && chain.uid) {
+      const questions = await chainQuestionGetAll(chain.uid)
+        .then((res) => res.data)
+        .catch(() => []);
+
       addModal({
         message: t("AreYouSureJoinLoop", {
           chainName: chain.name,
         }),
+        content: questions.length
+          ? () => <ChainQuestionsInputs questions={questions} />
+          : undefined,
         actions: [
           {
             text: t("join"),
             type: "secondary",
-            fn: () => {
-              chainAddUser(chain.uid, authUser.uid, false)
-                .then(() => {
+            submit: true,
+            fn: (formValues) => {
+              const answers = chainQuestionAnswersFromForm(
+                questions,
+                formValues,
+              );
+              const unanswered = chainQuestionUnanswered(questions, answers);
+              if (unanswered) {
+                const message = t("answerRequired", {
+                  question: unanswered.question,
+                });
+                addToastError(message, 400);
+                return Error(message);
+              }
+              chainAddUser(chain.uid, authUser.uid, false, answers)
+                .then((res) => {
+                  // a closed loop puts the user on its waitlist
+                  if (res.status === 202) {
+                    addToast({
+                      message: t("joinedWaitlist", { chainName: chain.name }),
+                      type: "success",
+                    });
+                    return;
+                  }
                   authUserRefresh(true);
                   window.location.href = localizePath("/thankyou");
                 })
@@ -317,6 +349,15 @@ function SideBarChainItem(props: {
               {t("join")}
             </p>
           )
+        ) : isLarge && props.chain.published ? (
+          <button
+            onClick={handleClickJoin}
+            type="button"
+            className="btn btn-sm btn-secondary btn-outline"
+          >
+            {t("joinWaitlist")}
+            <span className="feather feather-clock ml-3 rtl:ml-0 rtl:mr-3"></span>
+          </button>
         ) : (
           <p className="px-3 font-semibold text-sm border border-secondary h-8 inline-flex items-center text-secondary">
             {t("closed")}
@@ -0,0 +1,45 @@
+import { useTranslation } from "react-i18next";
+
+import type { UID, User } from "../../../api/types";
+
+// Approved members of the loop other than the leaving host
+export function successorCandidates(
+  users: User[],
+  chainUID: UID,
+  leavingUserUID: UID,
+): User[] {
+  return users.filter(
+    (u) =>
+      u.uid !== leavingUserUID &&
+      u.chains.find((uc) => uc.chain_uid === chainUID)?.is_approved,
+  );
+}
+
+interface Props {
+  name: string;
+  users: User[];
+}
+
+// Picks the member that is invited to become host after the last host leaves,
+// without a choice the members are asked to volunteer.
+export default function SuccessorSelect({ name, users }: Props) {
+  const { t } = useTranslation();
+
+  return (
+    <label className="block text-start my-2">
+      <span className="block text-sm mb-1">{t("successorInfo")}</span>
+      <select
+        className="w-full select select-sm rounded-none border-2 border-black"
+        name={name}
+        defaultValue=""
+      >
+        <option value="">{t("noSuccessor")}</option>
+        {users.map((u) => (
+          <option key={u.uid} value={u.uid}>
+            {u.name}
+          </option>
+        ))}
+      </select>
+    </label>
+  );
+}
@@ -0,0 +1,56 @@
+import { useState, type FormEvent } from "react";
+import { useTranslation } from "react-i18next";
+
+import type { User } from "../../../api/types";
+import { userEmailChangeRequest } from "../../../api/user";
+import { TextForm } from "./FormFields";
+import { GinParseErrors } from "../util/gin-errors";
+import { addToast, addToastError } from "../../../stores/toast";
+
+// The email address only changes after the link sent to the new address is opened
+export default function UserEmailChange({ user }: { user: User }) {
+  const { t } = useTranslation();
+  const [email, setEmail] = useState("");
+
+  function onSubmit(e: FormEvent<HTMLFormElement>) {
+    e.preventDefault();
+    const newEmail = email.trim();
+
+    userEmailChangeRequest(user.uid, newEmail)
+      .then(() => {
+        setEmail("");
+        addToast({
+          type: "success",
+          message: t("emailChangeSent", { email: newEmail }),
+        });
+      })
+      .catch((err) => {
+        addToastError(GinParseErrors(t, err), err?.status);
+      });
+  }
+
+  return (
+    <section className="mb-6">
+      <h2 className="font-sans font-semibold text-xl text-secondary mb-2">
+        {t("changeEmail")}
+      </h2>
+      <p className="text-sm mb-1">
+        {t("currentEmail", { email: user.email })}
+      </p>
+      <form onSubmit={onSubmit} className="flex items-end">
+        <TextForm
+          label={t("newEmail")}
+          name="email"
+          type="email"
+          required
+          value={email}
+          onChange={(e) => setEmail(e.target.value)}
+        />
+        <button type="submit" className="btn btn-secondary ms-3">
+          {t("send")}
+          <span className="feather feather-mail ms-3" />
+        </button>
+      </form>
+    </section>
+  );
+}
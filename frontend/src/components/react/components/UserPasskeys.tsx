@@ -0,0 +1,123 @@
+import { useEffect, useState, type FormEvent } from "react";
+import { useTranslation } from "react-i18next";
+
+import {
+  passkeyDelete,
+  passkeyGetAll,
+  passkeyIsSupported,
+  passkeyRegister,
+  type Passkey,
+} from "../../../api/passkey";
+import { GinParseErrors } from "../util/gin-errors";
+import { addModal, addToast, addToastError } from "../../../stores/toast";
+
+// Passkeys of the logged in user, only the user itself can add or delete them
+export default function UserPasskeys() {
+  const { t } = useTranslation();
+  const [passkeys, setPasskeys] = useState<Passkey[]>([]);
+
+  function refresh() {
+    passkeyGetAll()
+      .then((res) => setPasskeys(res.data))
+      .catch((err) => {
+        addToastError(GinParseErrors(t, err), err?.status);
+      });
+  }
+
+  useEffect(() => {
+    refresh();
+  }, []);
+
+  function onSubmit(e: FormEvent<HTMLFormElement>) {
+    e.preventDefault();
+    const form = e.currentTarget;
+    const name = (new FormData(form).get("name") as string).trim();
+
+    passkeyRegister(name)
+      .then(() => {
+        form.reset();
+        addToast({ type: "success", message: t("passkeyAdded") });
+        refresh();
+      })
+      .catch((err) => {
+        // the browser rejects when the user cancels the passkey dialog
+        if (err?.name === "NotAllowedError") return;
+        addToastError(GinParseErrors(t, err), err?.status);
+      });
+  }
+
+  function onDelete(passkey: Passkey) {
+    addModal({
+      message: t("areYouSureDeletePasskey", { name: passkey.name }),
+      actions: [
+        {
+          text: t("delete"),
+          type: "error",
+          fn: () => {
+            passkeyDelete(passkey.uid)
+              .catch((err) => {
+                addToastError(GinParseErrors(t, err), err?.status);
+              })
+              .finally(() => refresh());
+          },
+        },
+      ],
+    });
+  }
+
+  return (
+    <section className="mb-6">
+      <h2 className="font-sans font-semibold text-xl text-secondary mb-2">
+        {t("passkeys")}
+      </h2>
+      <p className="text-sm mb-3">{t("passkeysInfo")}</p>
+      {passkeys.length ? (
+        <ul className="mb-3">
+          {passkeys.map((p) => (
+            <li
+              key={p.uid}
+              className="flex items-center justify-between py-2 border-b border-grey-light"
+            >
+              <div>
+                <p className="font-semibold">{p.name}</p>
+                <p className="text-xs text-grey">
+                  {p.last_used_at
+                    ? t("passkeyLastUsed", {
+                        date: new Date(p.last_used_at).toLocaleDateString(),
+                      })
+                    : t("passkeyNeverUsed")}
+                </p>
+              </div>
+              <button
+                type="button"
+                className="btn btn-sm btn-ghost text-red"
+                aria-label={t("delete")}
+                onClick={() => onDelete(p)}
+              >
+                <span className="feather feather-trash" />
+              </button>
+            </li>
+          ))}
+        </ul>
+      ) : null}
+      {passkeyIsSupported() ? (
+        <form onSubmit={onSubmit} className="flex">
+          <input
+            type="text"
+            name="name"
+            required
+            maxLength={100}
+            placeholder={t("passkeyName")}
+            className="input input-bordered input-secondary flex-grow"
+          />
+          <button type="submit" className="btn btn-secondary ms-3">
+            {t("addPasskey")}
+            <span className="feather feather-key ms-3" />
+          </button>
+        </form>
+      ) : (
+        <p className="text-sm text-grey">{t("passkeysNotSupported")}</p>
+      )}
+    </section>
+  );
+}
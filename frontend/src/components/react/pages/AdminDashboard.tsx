@@ -2,16 +2,21 @@
 
 import { useEffect, useMemo, useState } from "react";
 
-import { userPurge } from "../../../api/user";
+import { userGetAllByChain, userPurge } from "../../../api/user";
 
 import ChainsList from "../components/ChainsList";
+import SuccessorSelect, {
+  successorCandidates,
+} from "../components/SuccessorSelect";
+import DeletedChainsList from "../components/DeletedChainsList";
 import { useEscape } from "../util/escape.hooks";
-import type { Chain } from "../../../api/types";
+import type { Chain, UID, User } from "../../../api/types";
 import PopupLegal from "../components/PopupLegal";
 import { useTranslation } from "react-i18next";
 import { useStore } from "@nanostores/react";
 import { $authUser, authUserRefresh } from "../../../stores/auth";
-import { addModal } from "../../../stores/toast";
+import { addModal, addToastError } from "../../../stores/toast";
+import { GinParseErrors } from "../util/gin-errors";
 import useLocalizePath from "../util/localize_path.hooks";
 
 export default function AdminDashboard() {
@@ -27,32 +32,45 @@ export default function AdminDashboard() {
     [authUser],
   );
 
-  function deleteClicked() {
+  async function deleteClicked() {
     if (!authUser) return;
-    const chainNames = authUser.is_root_admin
+    const lastHostChains = authUser.is_root_admin
       ? undefined
       : (authUser.chains
           .filter((uc) => uc.is_chain_admin)
           .map((uc) => chains.find((c) => c.uid === uc.chain_uid))
-          .filter((c) => c && c.total_hosts && c.total_hosts === 1)
-          .map((c) => c!.name) as string[]);
+          .filter((c) => c && c.total_hosts && c.total_hosts === 1) as Chain[]);
+
+    // for each loop the last host can choose who is invited to take over
+    const candidates = await Promise.all(
+      (lastHostChains || []).map((c) =>
+        userGetAllByChain(c.uid)
+          .then((res) => successorCandidates(res.data, c.uid, authUser.uid))
+          .catch(() => [] as User[]),
+      ),
+    );
 
     addModal({
       message: t("deleteAccount"),
       content:
-        chainNames && chainNames.length
+        lastHostChains && lastHostChains.length
           ? () => (
               <>
                 <p className="mb-2">{t("deleteAccountWithLoops")}</p>
                 <ul
                   className={`text-sm font-semibold mx-8 ${
-                    chainNames.length > 1
+                    lastHostChains.length > 1
                       ? "list-disc"
                       : "list-none text-center"
                   }`}
                 >
-                  {chainNames.map((name) => (
-                    <li key={name}>{name}</li>
+                  {lastHostChains.map((c, i) => (
+                    <li key={c.uid}>
+                      {c.name}
+                      {candidates[i].length ? (
+                        <SuccessorSelect name={c.uid} users={candidates[i]} />
+                      ) : null}
+                    </li>
                   ))}
                 </ul>
               </>
@@ -62,10 +80,21 @@ export default function AdminDashboard() {
         {
           text: t("delete"),
           type: "error",
-          fn: () => {
-            userPurge(authUser!.uid).then(() => {
-              window.location.href = localizePath("/users/logout");
-            });
+          submit: true,
+          fn: (formValues) => {
+            const successors: Record<UID, UID> = {};
+            for (const [chainUID, successorUID] of Object.entries(
+              formValues || {},
+            )) {
+              if (successorUID) successors[chainUID] = successorUID as UID;
+            }
+            userPurge(authUser!.uid, successors)
+              .then(() => {
+                window.location.href = localizePath("/users/logout");
+              })
+              .catch((err) => {
+                addToastError(GinParseErrors(t, err), err?.status);
+              });
           },
         },
       ],
@@ -208,6 +237,7 @@ export default function AdminDashboard() {
           </div>
         </section>
         <ChainsList chains={chains} setChains={setChains} />
+        <DeletedChainsList />
       </main>
     </>
   );
@@ -0,0 +1,110 @@
+import { useEffect, useState } from "react";
+import { Trans, useTranslation } from "react-i18next";
+import { useStore } from "@nanostores/react";
+
+import type { Chain } from "../../../api/types";
+import { chainBecomeHost, chainGet } from "../../../api/chain";
+import { $authUser, authUserRefresh } from "../../../stores/auth";
+import { addToast, addToastError } from "../../../stores/toast";
+import { GinParseErrors } from "../util/gin-errors";
+import getQuery from "../util/query";
+import useLocalizePath from "../util/localize_path.hooks";
+
+// Members volunteer here to host a loop of which the last host left
+export default function BecomeHost() {
+  const { t, i18n } = useTranslation();
+  const localizePath = useLocalizePath(i18n);
+  const authUser = useStore($authUser);
+  const [chain, setChain] = useState<Chain | null>();
+  const [acceptedToh, setAcceptedToh] = useState(false);
+
+  useEffect(() => {
+    if (!authUser) return;
+    const [chainUID] = getQuery("chain");
+    if (!chainUID) {
+      setChain(null);
+      return;
+    }
+    chainGet(chainUID)
+      .then((res) => setChain(res.data))
+      .catch((err) => {
+        setChain(null);
+        addToastError(GinParseErrors(t, err), err?.status);
+      });
+  }, [authUser]);
+
+  function onBecomeHost() {
+    if (!chain) return;
+    chainBecomeHost(chain.uid)
+      .then(() => {
+        addToast({
+          message: t("hostInvitationAccepted", { chain: chain.name }),
+          type: "success",
+        });
+        authUserRefresh(true).then(() => {
+          window.location.href = localizePath(
+            "/loops/members/?chain=" + chain.uid,
+          );
+        });
+      })
+      .catch((err) => {
+        addToastError(GinParseErrors(t, err), err?.status);
+      });
+  }
+
+  if (authUser === null) {
+    window.location.href = localizePath("/users/login");
+    return <div />;
+  }
+  if (!authUser || chain === undefined) return null;
+  return (
+    <main>
+      <div className="bg-teal-light w-full container sm:max-w-screen-sm mx-auto p-6">
+        <h1 className="font-sans font-semibold text-3xl text-secondary mb-4">
+          {t("becomeHost")}
+        </h1>
+        {chain ? (
+          <>
+            <p className="mb-4">
+              {t("becomeHostBody", { chain: chain.name })}
+            </p>
+            <div className="form-control mb-6">
+              <label className="label cursor-pointer">
+                <span className="label-text">
+                  <Trans
+                    i18nKey="iAccept<1>Toh</1>Star"
+                    components={{
+                      "1": (
+                        <a
+                          href={localizePath("/terms-of-hosts")}
+                          target="_blank"
+                          className="link"
+                        ></a>
+                      ),
+                    }}
+                  ></Trans>
+                </span>
+                <input
+                  type="checkbox"
+                  className="checkbox border-black"
+                  checked={acceptedToh}
+                  onChange={(e) => setAcceptedToh(e.target.checked)}
+                />
+              </label>
+            </div>
+            <button
+              type="button"
+              onClick={onBecomeHost}
+              disabled={!acceptedToh}
+              className="btn btn-primary"
+            >
+              {t("becomeHost")}
+            </button>
+          </>
+        ) : (
+          <p>{t("becomeHostNotFound")}</p>
+        )}
+      </div>
+    </main>
+  );
+}
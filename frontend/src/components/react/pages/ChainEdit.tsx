@@ -4,6 +4,7 @@ import { useState, useEffect } from "react";
 import ChainDetailsForm, {
   type RegisterChainForm,
 } from "../components/ChainDetailsForm";
+import ChainQuestionsEdit from "../components/ChainQuestionsEdit";
 import {
   chainGet,
   chainUpdate,
@@ -76,6 +77,7 @@ export default function ChainEdit() {
           showAllowedDPA={!authUser?.accepted_dpa}
           submitText={t("save")}
         />
+        <ChainQuestionsEdit chainUID={chain.uid} />
       </main>
     </>
   ) : null;
@@ -0,0 +1,130 @@
+import { type FormEvent, useEffect, useState } from "react";
+import { useTranslation } from "react-i18next";
+import { useStore } from "@nanostores/react";
+
+import type { ChainInvite, ChainQuestion } from "../../../api/types";
+import {
+  chainInviteAccept,
+  chainInviteGet,
+  chainQuestionGetAll,
+} from "../../../api/chain";
+import { $authUser, authUserRefresh } from "../../../stores/auth";
+import { addToastError } from "../../../stores/toast";
+import { GinParseErrors } from "../util/gin-errors";
+import getQuery from "../util/query";
+import useLocalizePath from "../util/localize_path.hooks";
+import dayjs from "../util/dayjs";
+import ChainQuestionsInputs, {
+  chainQuestionAnswersFromForm,
+  chainQuestionUnanswered,
+} from "../components/ChainQuestions";
+
+type InviteInfo = Pick<
+  ChainInvite,
+  "chain_uid" | "chain_name" | "is_auto_approved" | "expires_at"
+>;
+
+// Invite links and invite codes lead here, also for closed loops
+export default function ChainInvitePage() {
+  const { t, i18n } = useTranslation();
+  const localizePath = useLocalizePath(i18n);
+  const authUser = useStore($authUser);
+  const [invite] = getQuery("invite");
+  const [info, setInfo] = useState<InviteInfo | null>();
+  const [questions, setQuestions] = useState<ChainQuestion[]>([]);
+
+  useEffect(() => {
+    if (!invite) {
+      setInfo(null);
+      return;
+    }
+    chainInviteGet(invite)
+      .then(async (res) => {
+        setInfo(res.data);
+        setQuestions((await chainQuestionGetAll(res.data.chain_uid)).data);
+      })
+      .catch(() => setInfo(null));
+  }, [invite]);
+
+  function onSubmit(e: FormEvent<HTMLFormElement>) {
+    e.preventDefault();
+    if (!info) return;
+
+    const answers = chainQuestionAnswersFromForm(
+      questions,
+      Object.fromEntries(new FormData(e.currentTarget)),
+    );
+    const unanswered = chainQuestionUnanswered(questions, answers);
+    if (unanswered) {
+      addToastError(
+        t("answerRequired", { question: unanswered.question }),
+        400,
+      );
+      return;
+    }
+
+    chainInviteAccept(invite, answers)
+      .then(() => {
+        authUserRefresh(true);
+        window.location.href = localizePath("/thankyou");
+      })
+      .catch((err) => {
+        addToastError(GinParseErrors(t, err), err?.status);
+      });
+  }
+
+  if (authUser === undefined || info === undefined) return null;
+  return (
+    <main>
+      <div className="bg-teal-light w-full container sm:max-w-screen-sm mx-auto p-6">
+        {info ? (
+          <>
+            <h1 className="font-sans font-semibold text-3xl text-secondary mb-4">
+              {t("join")}
+              <span> {info.chain_name}</span>
+            </h1>
+            <p className="mb-2">
+              {t(
+                info.is_auto_approved
+                  ? "inviteAutoApproved"
+                  : "inviteNeedsApproval",
+              )}
+            </p>
+            <p className="text-sm mb-4">
+              {t("inviteValidUntil", {
+                date: dayjs(info.expires_at).format("LL"),
+              })}
+            </p>
+            {authUser ? (
+              <form onSubmit={onSubmit}>
+                <ChainQuestionsInputs questions={questions} />
+                <button type="submit" className="btn btn-primary">
+                  {t("join")}
+                </button>
+              </form>
+            ) : (
+              <div className="flex">
+                <a
+                  href={localizePath(
+                    `/loops/users/signup/?chain=${info.chain_uid}&invite=${invite}`,
+                  )}
+                  className="btn btn-primary"
+                >
+                  {t("signup")}
+                </a>
+                <a
+                  href={localizePath("/users/login")}
+                  className="btn btn-secondary btn-outline ml-4"
+                >
+                  {t("login")}
+                </a>
+              </div>
+            )}
+          </>
+        ) : (
+          <p>{t("inviteNotFound")}</p>
+        )}
+      </div>
+    </main>
+  );
+}
@@ -16,18 +16,35 @@ import simplifyDays from "../util/simplify-days";
 import { UserDataExport } from "../components/DataExport";
 import {
   chainAddUser,
+  chainConfirmStillActive,
   chainDelete,
   chainDeleteUnapproved,
   chainGet,
   chainGetAll,
   chainRemoveUser,
+  chainSetUserRole,
   chainUpdate,
   chainUserApprove,
+  chainWaitlistGetAll,
+  chainWaitlistLeave,
+  chainWaitlistSetOrder,
   UnapprovedReason,
   type ChainUpdateBody,
 } from "../../../api/chain";
-import type { Bag, Chain, UID, User, UserChain } from "../../../api/types";
-import { userGetAllByChain, userTransferChain } from "../../../api/user";
+import type {
+  Bag,
+  Chain,
+  ChainWaitlist,
+  UID,
+  User,
+  UserChain,
+  UserChainRole,
+} from "../../../api/types";
+import {
+  userGetAllByChain,
+  userImpersonate,
+  userTransferChain,
+} from "../../../api/user";
 
 import { SizeBadges } from "../components/Badges";
 import { GinParseErrors } from "../util/gin-errors";
@@ -46,7 +63,7 @@ import { useTranslation } from "react-i18next";
 import getQuery from "../util/query";
 import { useStore } from "@nanostores/react";
 import { $authUser, authUserRefresh } from "../../../stores/auth";
-import { addModal, addToastError } from "../../../stores/toast";
+import { addModal, addToast, addToastError } from "../../../stores/toast";
 import useLocalizePath from "../util/localize_path.hooks";
 
 enum LoadingState {
@@ -59,14 +76,14 @@ enum LoadingState {
 interface Params {
   chainUID: string;
 }
-type SelectedTable = "route" | "participants" | "unapproved";
+type SelectedTable = "route" | "participants" | "waitlist" | "unapproved";
 
 const PUBLIC_BASE_URL = import.meta.env.PUBLIC_BASE_URL;
 
 export default function ChainMemberList() {
   const { t, i18n } = useTranslation();
   const localizePath = useLocalizePath(i18n);
-  const [chainUID] = getQuery("chain");
+  const [chainUID, stillActive] = getQuery("chain", "still_active");
   const authUser = useStore($authUser);
 
   const [hostChains, setHostChains] = useState<Chain[]>([]);
@@ -84,6 +101,7 @@ export default function ChainMemberList() {
   const [unapprovedUsers, setUnapprovedUsers] = useState<User[] | null>(null);
   const [published, setPublished] = useState(true);
   const [openToNewMembers, setOpenToNewMembers] = useState(true);
+  const [maxMembers, setMaxMembers] = useState("");
   const [isAppDisabled, setIsAppDisabled] = useState(false);
   const [error, setError] = useState("");
   const [selectedTable, setSelectedTable] = useState<SelectedTable>("route");
@@ -181,6 +199,21 @@ export default function ChainMemberList() {
     }
   }
 
+  async function handleBlurMaxMembers() {
+    const value = parseInt(maxMembers) || 0;
+    if (value === (chain?.max_members || 0)) return;
+
+    try {
+      await chainUpdate({ uid: chainUID, max_members: value });
+      // reaching or lifting the limit can open or close the loop
+      await refresh();
+    } catch (err: any) {
+      console.error("Error updating chain:", err);
+      setError(err?.data || `Error: ${JSON.stringify(err)}`);
+      setMaxMembers(chain?.max_members ? String(chain.max_members) : "");
+    }
+  }
+
   async function handleChangeIsAppEnabled(e: ChangeEvent<HTMLInputElement>) {
     let isEnabled = e.target.checked;
     let oldValue = chain?.is_app_disabled || false;
@@ -326,6 +359,18 @@ export default function ChainMemberList() {
     refresh(true);
   }, [authUser]);
 
+  // confirmation from the "Is your Loop still active?" email
+  useEffect(() => {
+    if (!authUser || stillActive !== "true") return;
+    chainConfirmStillActive(chainUID)
+      .then(() => {
+        addToast({ type: "success", message: t("loopConfirmedStillActive") });
+      })
+      .catch((err) => {
+        addToastError(GinParseErrors(t, err), err?.status);
+      });
+  }, [authUser]);
+
   const [filteredUsersHost, filteredUsersNotHost] = useMemo(() => {
     let host: User[] = [];
     let notHost: User[] = [];
@@ -396,6 +441,9 @@ export default function ChainMemberList() {
         setSelectedTable("unapproved");
       setPublished(chainData.data.published);
       setOpenToNewMembers(chainData.data.open_to_new_members);
+      setMaxMembers(
+        chainData.data.max_members ? String(chainData.data.max_members) : "",
+      );
       setIsAppDisabled(chainData.data?.is_app_disabled || false);
       if (!firstPageLoad && _unapprovedUsers.length === 0) {
         authUserRefresh(true);
@@ -519,6 +567,25 @@ export default function ChainMemberList() {
                         />
                       </label>
                     </div>
+                    <div className="form-control w-full">
+                      <label className="label">
+                        <span className="label-text">{t("maxMembers")}</span>
+                        <input
+                          type="number"
+                          min={0}
+                          className={`input input-sm input-bordered input-secondary w-24 ${
+                            error === "maxMembers" ? "input-error" : ""
+                          }`}
+                          name="maxMembers"
+                          value={maxMembers}
+                          onChange={(e) => setMaxMembers(e.target.value)}
+                          onBlur={handleBlurMaxMembers}
+                        />
+                      </label>
+                      <span className="label-text-alt px-1">
+                        {t("maxMembersInfo")}
+                      </span>
+                    </div>
                     <div className="form-control w-full">
                       <label className="cursor-pointer label">
                         <span className="label-text">
@@ -630,6 +697,23 @@ export default function ChainMemberList() {
                   <span className="skew-x-6 w-4 h-12 bg-[inherit] absolute -right-3"></span>
                 </div>
               </label>
+              {isUserAdmin || authUser?.is_root_admin ? (
+                <label>
+                  <input
+                    type="radio"
+                    name="table-type"
+                    value="waitlist"
+                    checked={selectedTable === "waitlist"}
+                    onChange={(e) => setSelectedTable("waitlist")}
+                    className="hidden peer"
+                  />
+                  <div className="relative btn no-animation bg-transparent hover:bg-black hover:text-secondary-content transition-none text-black px-2 ms-3 border-0 peer-checked:btn-secondary peer-checked:hover:bg-secondary">
+                    <span className="-skew-x-6 w-4 h-12 bg-[inherit] absolute -left-3"></span>
+                    {t("waitlist")}
+                    <span className="skew-x-6 w-4 h-12 bg-[inherit] absolute -right-3"></span>
+                  </div>
+                </label>
+              ) : null}
               <label>
                 <input
                   type="radio"
@@ -685,7 +769,8 @@ export default function ChainMemberList() {
                 </button>
               ) : null}
 
-              {selectedTable !== "unapproved" ? (
+              {selectedTable !== "unapproved" &&
+              selectedTable !== "waitlist" ? (
                 <UserDataExport
                   chainName={chain.name}
                   chainUsers={
@@ -709,6 +794,8 @@ export default function ChainMemberList() {
               refresh={refresh}
               onReasonLoopNotActive={handleReasonLoopNotActive}
             />
+          ) : selectedTable === "waitlist" ? (
+            <WaitlistTable key="waitlist" chain={chain} />
           ) : selectedTable === "participants" ? (
             <ParticipantsTable
               key="participants"
@@ -1103,6 +1190,68 @@ function ParticipantsTable(props: {
           submit: true,
           fn: () => {
             chainAddUser(props.chain.uid, user.uid, true)
+              .then(() => {
+                addToast({
+                  message: t("hostInvitationSent"),
+                  type: "success",
+                });
+              })
+              .catch((err) => {
+                addToastError(GinParseErrors(t, err), err.status);
+              })
+              .finally(() => {
+                props.refresh();
+              });
+          },
+        },
+      ],
+    });
+  }
+  function onImpersonate(user: User) {
+    userImpersonate(user.uid)
+      .then(() => {
+        window.location.href = localizePath("/admin/dashboard");
+      })
+      .catch((err) => {
+        addToastError(GinParseErrors(t, err), err.status);
+      });
+  }
+  function onSetRole(user: User, userChain: UserChain) {
+    const roles: UserChainRole[] = [
+      "",
+      "bag_manager",
+      "route_manager",
+      "moderator",
+    ];
+    addModal({
+      message: t("setRole"),
+      content: () => (
+        <div>
+          <p className="text-center mb-4">
+            <span className="feather feather-user inline-block mr-1" />
+            {user.name}
+          </p>
+          <select
+            className="w-full select select-sm rounded-none border-2 border-black"
+            name="role"
+            defaultValue={userChain.is_chain_admin ? "" : userChain.role}
+          >
+            {roles.map((role) => (
+              <option key={role} value={role}>
+                {t(role ? "role_" + role : "participant")}
+              </option>
+            ))}
+          </select>
+        </div>
+      ),
+      actions: [
+        {
+          text: t("setRole"),
+          type: "success",
+          submit: true,
+          fn(formValues) {
+            const role = (formValues?.role || "") as UserChainRole;
+            chainSetUserRole(props.chain.uid, user.uid, role)
               .catch((err) => {
                 addToastError(GinParseErrors(t, err), err.status);
               })
@@ -1239,6 +1388,12 @@ function ParticipantsTable(props: {
                         <button type="button" onClick={() => onAddCoHost(u)}>
                           {t("addCoHost")}
                         </button>,
+                        <button
+                          type="button"
+                          onClick={() => onSetRole(u, userChain)}
+                        >
+                          {t("setRole")}
+                        </button>,
                       ]
                     : []),
                   ...((props.hostChains.length &&
@@ -1262,6 +1417,13 @@ function ParticipantsTable(props: {
                         </button>,
                       ]
                     : []),
+                  ...(props.authUser?.is_root_admin && !u.is_root_admin
+                    ? [
+                        <button type="button" onClick={() => onImpersonate(u)}>
+                          {t("impersonate")}
+                        </button>,
+                      ]
+                    : []),
                 ];
 
                 let dropdownClasses = "ltr:dropdown-right rtl:dropdown-left";
@@ -1470,6 +1632,109 @@ function RouteTable(props: {
   );
 }
 
+function WaitlistTable(props: { chain: Chain }) {
+  const { t } = useTranslation();
+  const [waitlist, setWaitlist] = useState<ChainWaitlist[]>([]);
+
+  function refresh() {
+    chainWaitlistGetAll(props.chain.uid)
+      .then((res) => setWaitlist(res.data))
+      .catch((err) => {
+        addToastError(GinParseErrors(t, err), err?.status);
+      });
+  }
+  function handleInputChangePosition(
+    e: ChangeEvent<HTMLInputElement>,
+    uid: UID,
+  ) {
+    const userUIDs = waitlist.map((w) => w.user_uid);
+    const toIndex = e.target.valueAsNumber - 1;
+    const fromIndex = userUIDs.indexOf(uid);
+    chainWaitlistSetOrder(
+      props.chain.uid,
+      reOrder(userUIDs, fromIndex, toIndex),
+    )
+      .catch((err) => {
+        addToastError(GinParseErrors(t, err), err?.status);
+      })
+      .finally(refresh);
+  }
+  function onRemove(w: ChainWaitlist) {
+    chainWaitlistLeave(props.chain.uid, w.user_uid)
+      .catch((err) => {
+        addToastError(GinParseErrors(t, err), err?.status);
+      })
+      .finally(refresh);
+  }
+
+  useEffect(() => {
+    refresh();
+  }, [props.chain.uid]);
+
+  return (
+    <div className="mt-6 relative overflow-hidden">
+      <div className="overflow-x-auto">
+        <table className="table table-compact w-full mb-20">
+          <thead>
+            <tr>
+              <th className="w-[0.1%]">
+                <span>{t("position")}</span>
+              </th>
+              <th>
+                <span>{t("name")}</span>
+              </th>
+              <th>
+                <span>{t("contact")}</span>
+              </th>
+              <th>
+                <span>{t("invited")}</span>
+              </th>
+              <th className="w-[0.1%]"></th>
+            </tr>
+          </thead>
+          <tbody>
+            {waitlist.map((w) => (
+              <tr key={w.user_uid} className="[&>td]:hover:bg-base-200/[0.6]">
+                <td className="text-center">
+                  <input
+                    onClick={(e) => (e.target as any).select()}
+                    onChange={(e) => handleInputChangePosition(e, w.user_uid)}
+                    max={waitlist.length}
+                    min={1}
+                    type="number"
+                    className="inline-block input-reset w-14 py-1 px-2 bg-base-200 rounded-lg font-semibold text-center"
+                    value={w.position}
+                  />
+                </td>
+                <td>{w.user_name}</td>
+                <td className="text-sm">{w.user_email}</td>
+                <td className="text-sm">
+                  {w.invited_at ? dayjs(w.invited_at).format("LL") : null}
+                </td>
+                <td className="text-right">
+                  <button
+                    type="button"
+                    aria-label={t("removeFromWaitlist")}
+                    className="btn btn-circle btn-sm btn-ghost bg-base-100 feather feather-x"
+                    onClick={() => onRemove(w)}
+                  ></button>
+                </td>
+              </tr>
+            ))}
+            {waitlist.length === 0 ? (
+              <tr>
+                <td colSpan={5} className="text-center">
+                  {t("waitlistEmpty")}
+                </td>
+              </tr>
+            ) : null}
+          </tbody>
+        </table>
+      </div>
+    </div>
+  );
+}
+
 function SortButton(props: {
   className?: string;
   onClick: MouseEventHandler;
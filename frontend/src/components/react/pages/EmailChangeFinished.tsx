@@ -0,0 +1,36 @@
+import { useEffect } from "react";
+import { useTranslation } from "react-i18next";
+
+import { userEmailChangeConfirm } from "../../../api/user";
+import { addToast, addToastError } from "../../../stores/toast";
+import getQuery from "../util/query";
+import useLocalizePath from "../util/localize_path.hooks";
+import isSSR from "../util/is_ssr";
+
+export default function EmailChangeFinished() {
+  const { i18n, t } = useTranslation();
+  const localizePath = useLocalizePath(i18n);
+
+  useEffect(() => {
+    if (isSSR()) return;
+    const [token] = getQuery("t");
+    (async () => {
+      try {
+        if (!token) {
+          throw "Token is not included in request";
+        }
+        await userEmailChangeConfirm(token);
+        addToast({
+          message: t("emailChanged"),
+          type: "success",
+        });
+      } catch (err: any) {
+        addToastError(t("errorChangingEmail"), err?.status);
+        console.error("Error changing email", err);
+      }
+      window.location.href = localizePath("/");
+    })();
+  }, []);
+
+  return <div />;
+}
@@ -5,7 +5,12 @@ import mapboxgl, { type MapboxGeoJSONFeature } from "mapbox-gl";
 
 // Project resources
 import type { Chain, UID } from "../../../api/types";
-import { chainGetAll } from "../../../api/chain";
+import {
+  type ChainGeoJSON,
+  type RequestChainSearchParams,
+  chainGetGeoJSON,
+  chainSearch,
+} from "../../../api/chain";
 import SearchBar, {
   type SearchValues,
   toUrlSearchParams,
@@ -17,89 +22,92 @@ import {
   circleRadiusKm,
   useMapZoom,
 } from "../util/maps";
-import clothingCategories from "../util/categories";
-import { Genders, Sizes } from "../../../api/enums";
 import { addToastError } from "../../../stores/toast";
 
 const MAPBOX_TOKEN = import.meta.env.PUBLIC_MAPBOX_KEY;
 
 const MAX_ZOOM = 13;
 const MIN_ZOOM = 3;
-
-export type ChainPredicate = (chain: Chain) => boolean;
+// Below this zoom level the server returns clusters of loops
+const CLUSTER_MAX_ZOOM = 10;
+// Amount of loops listed in the sidebar after a search
+const SEARCH_LIMIT = 10;
 
 type GeoJSONChains = GeoJSONTypes.FeatureCollection<
   GeoJSONTypes.Point,
   {
-    uid: UID;
-    chainIndex: number;
-    radius: number;
-    gender: string;
-    size: string;
-    open_to_new_members: boolean;
-    cluster?: boolean;
+    uid?: UID;
+    radius?: number;
+    open_to_new_members?: boolean;
+    point_count?: number;
+    point_count_open?: number;
   }
 >;
 
+interface ChainFilters {
+  sizes: string[];
+  genders: string[];
+}
+
+// Loops are stored in chainsByUID, below the cluster zoom level
+// a single loop is drawn as a cluster of one.
 function mapToGeoJSONChains(
-  chains: Chain[],
-  filterFunc: (c: Chain) => boolean = () => true,
+  data: ChainGeoJSON,
+  zoom: number,
+  chainsByUID: Map<UID, Chain>,
 ): GeoJSONChains {
   return {
     type: "FeatureCollection",
-    features: chains.filter(filterFunc).map((chain, chainIndex) => {
+    features: data.features.map((f) => {
+      if ("cluster" in f.properties) {
+        return {
+          type: "Feature",
+          geometry: f.geometry,
+          properties: {
+            point_count: f.properties.point_count,
+            point_count_open: f.properties.point_count_open,
+          },
+        };
+      }
+
+      const chain = f.properties;
+      chainsByUID.set(chain.uid, chain);
       return {
         type: "Feature",
-        geometry: {
-          type: "Point",
-          coordinates: [chain.longitude, chain.latitude],
-        },
+        geometry: f.geometry,
         properties: {
           uid: chain.uid,
-          chainIndex: chainIndex,
           radius: circleRadiusKm((chain.radius * 1000) / 6, chain.latitude),
-          gender: chain.genders?.join("") || "",
-          size: chain.sizes?.join("") || "",
           open_to_new_members: chain.open_to_new_members,
+          ...(zoom < CLUSTER_MAX_ZOOM
+            ? {
+                point_count: 1,
+                point_count_open: chain.open_to_new_members ? 1 : 0,
+              }
+            : {}),
         },
       };
     }),
   };
 }
 
-function createFilterFunc(
-  genders: string[],
-  sizes: string[],
-): (c: Chain) => boolean {
-  let filterFunc = (c: Chain) => true;
-  if (sizes?.length) {
-    filterFunc = (c) => {
-      for (let s of sizes) {
-        if (c.sizes?.includes(s)) return true;
-      }
-      return false;
-    };
-  } else if (genders?.length) {
-    filterFunc = (c) => {
-      for (let g of genders) {
-        if (c.genders?.includes(g)) return true;
-        if (
-          c.sizes?.find((s) =>
-            clothingCategories[g as Genders].includes(s as Sizes),
-          )
-        )
-          return true;
-      }
-      return false;
-    };
-  }
-  return filterFunc;
+// Returns [min_longitude, min_latitude, max_longitude, max_latitude]
+// or undefined when the whole world is in view.
+function mapBBox(map: mapboxgl.Map): number[] | undefined {
+  const bounds = map.getBounds();
+  if (bounds.getEast() - bounds.getWest() >= 360) return undefined;
+  const wrap = (lng: number) => ((((lng + 180) % 360) + 360) % 360) - 180;
+  return [
+    wrap(bounds.getWest()),
+    bounds.getSouth(),
+    wrap(bounds.getEast()),
+    bounds.getNorth(),
+  ];
 }
 
 export default function FindChain() {
   const urlParams = new URLSearchParams(location.search);
 
-  const [chains, setChains] = useState<Chain[]>();
   const [map, setMap] = useState<mapboxgl.Map>();
   const { zoom, setZoom, mapZoom } = useMapZoom(4, MIN_ZOOM, MAX_ZOOM);
   const [locationLoading, setLocationLoading] = useState(false);
@@ -109,6 +117,12 @@ export default function FindChain() {
   const [sidebarOpen, setSidebarOpen] = useState(false);
 
   const mapRef = useRef<any>();
+  const chainsByUIDRef = useRef(new Map<UID, Chain>());
+  const chainsRequestRef = useRef(0);
+  const filtersRef = useRef<ChainFilters>({
+    sizes: urlParams.getAll("s"),
+    genders: urlParams.getAll("g"),
+  });
 
   useEffect(() => {
     console.log("MAPBOX_TOKEN", MAPBOX_TOKEN);
@@ -135,207 +149,160 @@ export default function FindChain() {
 
     setZoom(4);
 
-    const clusterMaxZoom = 8;
-    chainGetAll({ filter_out_unpublished: true }).then((res) => {
-      const _chains = res.data;
+    _map.on("zoomend", (e) => {
+      setZoom(e.target.getZoom());
+    });
 
-      _map.on("zoomend", (e) => {
-        setZoom(e.target.getZoom());
+    _map.on("load", () => {
+      _map.addSource("chains", {
+        type: "geojson",
+        data: { type: "FeatureCollection", features: [] },
+        promoteId: "uid",
       });
 
-      const filterFunc = createFilterFunc(
-        urlParams.getAll("g"),
-        urlParams.getAll("s"),
-      );
-
-      _map.on("load", () => {
-        _map.addSource("chains", {
-          type: "geojson",
-          data: mapToGeoJSONChains(_chains, filterFunc),
-          cluster: true,
-          clusterMaxZoom: clusterMaxZoom,
-          clusterMinPoints: 1,
-          clusterRadius: 25,
-          clusterProperties: {
-            sum_open_to_new_members: [
-              "+",
-              ["case", ["get", "open_to_new_members"], 1, 0],
-            ],
-          },
-          generateId: true,
-        });
-
-        _map.addLayer({
-          id: "chain-cluster",
-          type: "circle",
-          source: "chains",
-          filter: ["<=", ["zoom"], clusterMaxZoom],
-          paint: {
-            "circle-color": [
-              "case",
-              [
-                "any",
-                [">", ["coalesce", ["get", "sum_open_to_new_members"], 0], 0],
-                [
-                  "all",
-                  ["<", ["coalesce", ["get", "point_count_abbreviated"], 0], 2],
-                  ["has", "open_to_new_members"],
-                  ["get", "open_to_new_members"],
-                ],
-              ],
-              ["rgba", 239, 149, 61, 0.6], // #ef953d
-              ["rgba", 0, 0, 0, 0.1], // grey
-            ],
-            "circle-radius": 15,
-            "circle-stroke-width": 0,
-          },
-        });
+      _map.addLayer({
+        id: "chain-cluster",
+        type: "circle",
+        source: "chains",
+        filter: ["has", "point_count"],
+        paint: {
+          "circle-color": [
+            "case",
+            [">", ["get", "point_count_open"], 0],
+            ["rgba", 239, 149, 61, 0.6], // #ef953d
+            ["rgba", 0, 0, 0, 0.1], // grey
+          ],
+          "circle-radius": 15,
+          "circle-stroke-width": 0,
+        },
+      });
 
-        _map.addLayer({
-          id: "chain-cluster-count",
-          type: "symbol",
-          source: "chains",
-          filter: ["<=", ["zoom"], clusterMaxZoom],
-          layout: {
-            "text-field": ["coalesce", ["get", "point_count_abbreviated"], "1"],
-            "text-font": ["DIN Offc Pro Medium", "Arial Unicode MS Bold"],
-            "text-size": 12,
-          },
-        });
-        _map.addLayer({
-          id: "chain-single",
-          type: "circle",
-          source: "chains",
-          filter: [">", ["zoom"], clusterMaxZoom],
-          paint: {
-            "circle-color": [
+      _map.addLayer({
+        id: "chain-cluster-count",
+        type: "symbol",
+        source: "chains",
+        filter: ["has", "point_count"],
+        layout: {
+          "text-field": ["to-string", ["get", "point_count"]],
+          "text-font": ["DIN Offc Pro Medium", "Arial Unicode MS Bold"],
+          "text-size": 12,
+        },
+      });
+      _map.addLayer({
+        id: "chain-single",
+        type: "circle",
+        source: "chains",
+        filter: ["!", ["has", "point_count"]],
+        paint: {
+          "circle-color": [
+            "case",
+            ["==", ["feature-state", "clicked"], true],
+            ["rgba", 81, 141, 126, 0.4],
+            [
               "case",
-              ["==", ["feature-state", "clicked"], true],
-              ["rgba", 81, 141, 126, 0.4],
-              [
-                "case",
-                ["get", "open_to_new_members"],
-                ["rgba", 240, 196, 73, 0.4], // #f0c449
-                ["rgba", 0, 0, 0, 0.1],
-              ],
-            ],
-            "circle-radius": [
-              "interpolate",
-              ["exponential", 2],
-              ["zoom"],
-              0,
-              0,
-              20,
-              ["get", "radius"],
+              ["get", "open_to_new_members"],
+              ["rgba", 240, 196, 73, 0.4], // #f0c449
+              ["rgba", 0, 0, 0, 0.1],
             ],
-            "circle-stroke-width": 2,
-            "circle-stroke-color": [
+          ],
+          "circle-radius": [
+            "interpolate",
+            ["exponential", 2],
+            ["zoom"],
+            0,
+            0,
+            20,
+            ["get", "radius"],
+          ],
+          "circle-stroke-width": 2,
+          "circle-stroke-color": [
+            "case",
+            ["==", ["feature-state", "clicked"], true],
+            ["rgba", 72, 128, 139, 0.4],
+            [
               "case",
-              ["==", ["feature-state", "clicked"], true],
-              ["rgba", 72, 128, 139, 0.4],
-              [
-                "case",
-                ["get", "open_to_new_members"],
-                ["rgba", 240, 196, 73, 0.4], // #f0c449
-                ["rgba", 0, 0, 0, 0.1],
-              ],
+              ["get", "open_to_new_members"],
+              ["rgba", 240, 196, 73, 0.4], // #f0c449
+              ["rgba", 0, 0, 0, 0.1],
             ],
-          },
-        });
+          ],
+        },
+      });
 
-        const _marker = new mapboxgl.Marker({
-          color: "#518d7e",
-        });
+      const _marker = new mapboxgl.Marker({
+        color: "#518d7e",
+      });
 
-        // Initalize chainsInView
-        _map.on("idle", () => {
-          getVisibleChains(_map, _chains);
-        });
+      loadChains(_map);
 
-        _map.on("moveend", () => {
-          getVisibleChains(_map, _chains);
-        });
+      // Initalize chainsInView
+      _map.on("idle", () => {
+        getVisibleChains(_map);
+      });
 
-        _map.on("click", "chain-single", (e) => {
-          if (e.features) {
-            let uids = e.features
-              .map((f) => f.properties?.uid)
-              .filter((f) => f) as UID[];
-            // filter unique
-            uids = [...new Set(uids)];
-
-            let _selectedChains = uids.map((uid) =>
-              _chains.find((c) => c.uid === uid),
-            ) as Chain[];
-
-            e.clickOnLayer = true;
-            setSidebarOpen(true);
-            setMapClickedChains(_selectedChains);
-            if (_selectedChains.length === 1) {
-              handleSetFocusedChain(_selectedChains[0], true, _map);
-            }
-          }
-        });
+      _map.on("moveend", () => {
+        loadChains(_map);
+        getVisibleChains(_map);
+      });
 
-        _map.on("click", (e) => {
-          if (!e.clickOnLayer) {
-            setSidebarOpen(false);
+      _map.on("click", "chain-single", (e) => {
+        if (e.features) {
+          let uids = e.features
+            .map((f) => f.properties?.uid)
+            .filter((f) => f) as UID[];
+          // filter unique
+          uids = [...new Set(uids)];
+
+          let _selectedChains = uids
+            .map((uid) => chainsByUIDRef.current.get(uid))
+            .filter((c) => c) as Chain[];
+
+          e.clickOnLayer = true;
+          setSidebarOpen(true);
+          setMapClickedChains(_selectedChains);
+          if (_selectedChains.length === 1) {
+            handleSetFocusedChain(_selectedChains[0], true, _map);
           }
+        }
+      });
 
-          _marker.setLngLat(e.lngLat).addTo(_map);
-        });
+      _map.on("click", (e) => {
+        if (!e.clickOnLayer) {
+          setSidebarOpen(false);
+        }
+
+        _marker.setLngLat(e.lngLat).addTo(_map);
+      });
 
-        // zoom during click on a cluster
-        _map.on("click", "chain-cluster", (e) => {
-          console.log("cluster click event", e);
-          const features = _map.queryRenderedFeatures(e.point, {
-            layers: ["chain-cluster"],
+      // zoom during click on a cluster
+      _map.on("click", "chain-cluster", (e) => {
+        const feature = e.features?.[0];
+        if (!feature) return;
+        const center = (feature.geometry as GeoJSONTypes.Point)
+          .coordinates as mapboxgl.LngLatLike;
+
+        // a cluster of one is the loop itself
+        const chain = feature.properties?.uid
+          ? chainsByUIDRef.current.get(feature.properties.uid)
+          : undefined;
+        if (!chain) {
+          _map.easeTo({
+            center,
+            zoom: Math.min(_map.getZoom() + 2, MAX_ZOOM),
           });
-          console.log("features", features);
-          const clusterId = features[0].properties?.cluster_id;
-
-          try {
-            if (!clusterId) throw "no cluster id";
-            (
-              _map.getSource("chains") as mapboxgl.GeoJSONSource
-            ).getClusterExpansionZoom(clusterId, (err, zoom) => {
-              if (err) return;
-              _map.easeTo({
-                center: (features[0].geometry as any).coordinates,
-                zoom: zoom * 1.2,
-              });
-            });
-          } catch (err) {
-            try {
-              if (!features[0].properties?.uid) throw "no chain uid found";
-              const chain = _chains.find(
-                (c) => c.uid === features[0].properties!.uid,
-              );
-              if (!chain) throw "no chain found";
-              _map.easeTo({
-                center: [chain.longitude, chain.latitude],
-                zoom: 11,
-              });
-
-              // auto select
-              setMapClickedChains([chain]);
-              setSidebarOpen(true);
-
-              const uniqueFeatures = [...new Set(features.map((f) => f.id))];
-              if (uniqueFeatures.length === 1) {
-                handleSetFocusedChain(chain, true, _map);
-              }
-            } catch (err) {
-              console.warn(err);
-              _map.easeTo({
-                center: e.lngLat,
-                zoom: 11,
-              });
-            }
-          }
+          return;
+        }
+
+        _map.easeTo({
+          center: [chain.longitude, chain.latitude],
+          zoom: CLUSTER_MAX_ZOOM + 1,
         });
+
+        // auto select
+        setMapClickedChains([chain]);
+        setSidebarOpen(true);
+        handleSetFocusedChain(chain, false, _map);
       });
-      setChains(_chains);
     });
 
     setMap(_map);
@@ -346,6 +313,29 @@ export default function FindChain() {
     };
   }, []);
 
+  // Only the loops in view are requested
+  function loadChains(_map: mapboxgl.Map) {
+    const requestID = ++chainsRequestRef.current;
+    const _zoom = _map.getZoom();
+    chainGetGeoJSON(_zoom, mapBBox(_map), {
+      filter_sizes: filtersRef.current.sizes,
+      filter_genders: filtersRef.current.genders,
+    })
+      .then((res) => {
+        // ignore the response when the map has moved since
+        if (requestID !== chainsRequestRef.current) return;
+        const source = _map.getSource("chains") as
+          | mapboxgl.GeoJSONSource
+          | undefined;
+        source?.setData(
+          mapToGeoJSONChains(res.data, _zoom, chainsByUIDRef.current),
+        );
+      })
+      .catch((err) => {
+        console.error("Unable to load loops:", err);
+      });
+  }
+
   function handleSetFocusedChain(
     _focusedChain: Chain | null,
     shouldMove = false,
@@ -360,8 +350,9 @@ export default function FindChain() {
       layers: ["chain-cluster", "chain-single"],
     }) as MapboxGeoJSONFeature[];
 
-    // set the Feature
+    // set the Feature, clusters have no id
     features.forEach((f) => {
+      if (f.id === undefined) return;
       _map!.setFeatureState(
         {
           source: "chains",
@@ -382,7 +373,7 @@ export default function FindChain() {
       });
   }
 
-  function getVisibleChains(map: mapboxgl.Map, chains: Chain[]) {
+  function getVisibleChains(map: mapboxgl.Map) {
     const center = map.getCenter();
     const features = map!.queryRenderedFeatures(undefined, {
       layers: ["chain-cluster", "chain-single"],
@@ -392,7 +383,7 @@ export default function FindChain() {
       // Get UID of each chain in view
       for (let i = 0; i < features.length; i++) {
         let f = features[i];
-        if (!f.properties?.cluster) {
+        if (f.properties?.uid) {
           if (f.geometry.type !== "Point") continue;
           let fLngLat = new mapboxgl.LngLat(
             f.geometry.coordinates[GEOJSON_LONGITUDE_INDEX],
@@ -415,9 +406,9 @@ export default function FindChain() {
         );
         return aLngLat.distanceTo(bLngLat);
       })
-      .map((f) => f.properties.chainIndex)
+      .map((f) => f.properties.uid!)
       .filter((value, index, arr) => arr.indexOf(value) === index)
-      .map((chainIndex) => chains[chainIndex])
+      .map((uid) => chainsByUIDRef.current.get(uid))
       .filter((c) => c) as Chain[];
 
     setVisibleChains(ans);
@@ -428,21 +419,17 @@ export default function FindChain() {
     search: SearchValues,
     longLat: GeoJSON.Position | undefined,
   ) {
-    if (!chains || !map) return;
+    if (!map) return;
 
     // filter map by gender or sizes
-    (map.getSource("chains") as mapboxgl.GeoJSONSource).setData(
-      mapToGeoJSONChains(
-        chains,
-        createFilterFunc(search.genders, search.sizes),
-      ),
-    );
+    filtersRef.current = { sizes: search.sizes, genders: search.genders };
 
     // search
     if (longLat) {
       map.setCenter(longLat as mapboxgl.LngLatLike);
       map.setZoom(10);
     }
+    loadChains(map);
 
     window.history.replaceState(
       {},
@@ -453,6 +440,34 @@ export default function FindChain() {
     );
 
     setMapClickedChains([]);
+
+    // list the nearest loops, or the loops matching the search term
+    const params: RequestChainSearchParams = { limit: SEARCH_LIMIT };
+    if (search.sizes.length) params.filter_sizes = search.sizes;
+    if (search.genders.length) params.filter_genders = search.genders;
+    if (longLat) {
+      params.latitude = longLat[GEOJSON_LATITUDE_INDEX];
+      params.longitude = longLat[GEOJSON_LONGITUDE_INDEX];
+      params.sort = "distance";
+    } else if (search.searchTerm) {
+      params.q = search.searchTerm;
+    } else return;
+    chainSearch(params)
+      .then((res) => {
+        const _chains = res.data.chains;
+        _chains.forEach((c) => chainsByUIDRef.current.set(c.uid, c));
+        setMapClickedChains(_chains);
+        setSidebarOpen(_chains.length > 0);
+        if (!longLat && _chains.length) {
+          map.easeTo({
+            center: [_chains[0].longitude, _chains[0].latitude],
+            zoom: CLUSTER_MAX_ZOOM + 1,
+          });
+        }
+      })
+      .catch((err) => {
+        console.error("Unable to search loops:", err);
+      });
   }
 
   function handleLocation() {
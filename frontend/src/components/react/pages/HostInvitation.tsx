@@ -0,0 +1,137 @@
+import { useEffect, useState } from "react";
+import { Trans, useTranslation } from "react-i18next";
+import { useStore } from "@nanostores/react";
+
+import type { ChainHostInvitation } from "../../../api/types";
+import {
+  userHostInvitationAccept,
+  userHostInvitationDecline,
+  userHostInvitationGetAll,
+} from "../../../api/user";
+import { $authUser } from "../../../stores/auth";
+import { addToast, addToastError } from "../../../stores/toast";
+import { GinParseErrors } from "../util/gin-errors";
+import getQuery from "../util/query";
+import useLocalizePath from "../util/localize_path.hooks";
+
+export default function HostInvitation() {
+  const { t, i18n } = useTranslation();
+  const localizePath = useLocalizePath(i18n);
+  const authUser = useStore($authUser);
+  const [invitation, setInvitation] = useState<ChainHostInvitation | null>();
+  const [acceptedToh, setAcceptedToh] = useState(false);
+
+  useEffect(() => {
+    if (!authUser) return;
+    const [uid] = getQuery("uid");
+    userHostInvitationGetAll()
+      .then((res) => {
+        setInvitation(res.data.find((inv) => inv.uid === uid) || null);
+      })
+      .catch((err) => {
+        addToastError(GinParseErrors(t, err), err?.status);
+      });
+  }, [authUser]);
+
+  function onAccept() {
+    if (!invitation) return;
+    userHostInvitationAccept(invitation.uid)
+      .then(() => {
+        addToast({
+          message: t("hostInvitationAccepted", {
+            chain: invitation.chain_name,
+          }),
+          type: "success",
+        });
+        window.location.href = localizePath(
+          "/loops/members/?chain=" + invitation.chain_uid,
+        );
+      })
+      .catch((err) => {
+        addToastError(GinParseErrors(t, err), err?.status);
+      });
+  }
+
+  function onDecline() {
+    if (!invitation) return;
+    userHostInvitationDecline(invitation.uid)
+      .then(() => {
+        window.location.href = localizePath("/admin/dashboard");
+      })
+      .catch((err) => {
+        addToastError(GinParseErrors(t, err), err?.status);
+      });
+  }
+
+  if (authUser === null) {
+    window.location.href = localizePath("/users/login");
+    return <div />;
+  }
+  if (!authUser || invitation === undefined) return null;
+  return (
+    <main>
+      <div className="bg-teal-light w-full container sm:max-w-screen-sm mx-auto p-6">
+        <h1 className="font-sans font-semibold text-3xl text-secondary mb-4">
+          {t("hostInvitation")}
+        </h1>
+        {invitation ? (
+          <>
+            <p className="mb-4">
+              {invitation.invited_by_name
+                ? t("hostInvitationBody", {
+                    name: invitation.invited_by_name,
+                    chain: invitation.chain_name,
+                  })
+                : t("hostInvitationBodyNoInviter", {
+                    chain: invitation.chain_name,
+                  })}
+            </p>
+            <div className="form-control mb-6">
+              <label className="label cursor-pointer">
+                <span className="label-text">
+                  <Trans
+                    i18nKey="iAccept<1>Toh</1>Star"
+                    components={{
+                      "1": (
+                        <a
+                          href={localizePath("/terms-of-hosts")}
+                          target="_blank"
+                          className="link"
+                        ></a>
+                      ),
+                    }}
+                  ></Trans>
+                </span>
+                <input
+                  type="checkbox"
+                  className="checkbox border-black"
+                  checked={acceptedToh}
+                  onChange={(e) => setAcceptedToh(e.target.checked)}
+                />
+              </label>
+            </div>
+            <div className="flex">
+              <button
+                type="button"
+                onClick={onDecline}
+                className="btn btn-secondary btn-outline"
+              >
+                {t("decline")}
+              </button>
+              <button
+                type="button"
+                onClick={onAccept}
+                disabled={!acceptedToh}
+                className="btn btn-primary ml-4"
+              >
+                {t("accept")}
+              </button>
+            </div>
+          </>
+        ) : (
+          <p>{t("hostInvitationNotFound")}</p>
+        )}
+      </div>
+    </main>
+  );
+}
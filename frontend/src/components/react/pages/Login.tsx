@@ -1,8 +1,14 @@
-import { type FormEvent, useState } from "react";
+import { type FormEvent, useEffect, useState } from "react";
 import { Trans, useTranslation } from "react-i18next";
 
 import { TwoColumnLayout } from "../components/Layouts";
-import { loginEmail, loginEmailAndAddToChain } from "../../../api/login";
+import {
+  type OidcProvider,
+  loginEmail,
+  loginEmailAndAddToChain,
+  loginOidcProviders,
+  loginOidcURL,
+} from "../../../api/login";
 
 import FormJup from "../util/form-jup";
 
@@ -10,7 +16,8 @@ import { GinParseErrors } from "../util/gin-errors";
 import type { Response } from "redaxios";
 import { useStore } from "@nanostores/react";
 import { addToast, addToastError } from "../../../stores/toast";
-import { $authUser } from "../../../stores/auth";
+import { $authUser, authLoginPasskey } from "../../../stores/auth";
+import { passkeyIsSupported } from "../../../api/passkey";
 import getQuery from "../util/query";
 import useLocalizePath from "../util/localize_path.hooks";
 import getLanguages from "../../../languages";
@@ -31,7 +38,27 @@ export default function Login() {
   const [active, setActive] = useState(false);
   const [loading, setLoading] = useState(false);
 
-  const [chainUID, defaultEmail] = getQuery("chain", "email");
+  const [oidcProviders, setOidcProviders] = useState<OidcProvider[]>([]);
+
+  const [chainUID, defaultEmail, oidcError] = getQuery(
+    "chain",
+    "email",
+    "oidc_error",
+  );
+
+  useEffect(() => {
+    if (oidcError) {
+      addToastError(
+        oidcError === "not_registered"
+          ? t("oidcErrorNotRegistered")
+          : t("errorLoggingIn"),
+        401,
+      );
+    }
+    loginOidcProviders()
+      .then((res) => setOidcProviders(res.data))
+      .catch((err) => console.warn("Unable to get login providers", err));
+  }, []);
 
   function onSubmit(e: FormEvent<HTMLFormElement>) {
     e.preventDefault();
@@ -90,6 +117,12 @@ export default function Login() {
     })();
   }
 
+  function onClickPasskey() {
+    authLoginPasskey().then((user) => {
+      if (!user) addToastError(t("errorLoggingIn"), 401);
+    });
+  }
+
   if (authUser) {
     addToast({
       type: "success",
@@ -153,6 +186,24 @@ export default function Login() {
                     <span className="feather feather-arrow-left mr-4 ltr:hidden"></span>
                   </button>
                 )}
+                {passkeyIsSupported() ? (
+                  <button
+                    type="button"
+                    onClick={onClickPasskey}
+                    className="btn btn-secondary btn-outline w-full mt-4"
+                  >
+                    {t("loginWithPasskey")}
+                  </button>
+                ) : null}
+                {oidcProviders.map((p) => (
+                  <a
+                    key={p.id}
+                    href={loginOidcURL(p.id, chainUID)}
+                    className="btn btn-secondary btn-outline w-full mt-4"
+                  >
+                    {t("loginWith", { name: p.name })}
+                  </a>
+                ))}
                 <div className="mt-4 prose">
                   {t("newToTheClothingLoop") + " "}
                   <Trans
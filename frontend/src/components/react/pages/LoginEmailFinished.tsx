@@ -2,7 +2,11 @@ import { useEffect, useState } from "react";
 
 import type { User } from "../../../api/types";
 import { useTranslation } from "react-i18next";
-import { authLoginValidate } from "../../../stores/auth";
+import {
+  authLoginOidc,
+  authLoginValidate,
+  authLoginValidateMagicLink,
+} from "../../../stores/auth";
 import { addToast, addToastError } from "../../../stores/toast";
 import getQuery from "../util/query";
 import useLocalizePath from "../util/localize_path.hooks";
@@ -14,24 +18,54 @@ export default function LoginEmailFinished() {
 
   useEffect(() => {
     if (isSSR()) return;
-    const [otp, emailBase64, chainUID] = getQuery("apiKey", "u", "c");
+    const [
+      magicLink,
+      otp,
+      emailBase64,
+      queryChainUID,
+      oidcUserUID,
+      oidcJoinForm,
+    ] = getQuery("t", "apiKey", "u", "c", "oidc", "join_form");
     (async () => {
       let user: User | undefined | null;
+      let chainUID = queryChainUID;
+      // the loop has questions to answer, so the user is not yet added to it
+      let isJoinFormRequired = false;
       try {
-        if (!otp) {
-          throw "One time password does not exist";
+        if (magicLink) {
+          const res = await authLoginValidateMagicLink(magicLink);
+          user = res?.user;
+          chainUID = res?.chain_uid || "";
+          isJoinFormRequired = !!res?.join_form;
+        } else if (oidcUserUID) {
+          user = await authLoginOidc(oidcUserUID);
+          isJoinFormRequired = oidcJoinForm === "true";
+        } else {
+          if (!otp) {
+            throw "One time password does not exist";
+          }
+          if (!emailBase64) {
+            throw "Email is not included in request";
+          }
+          const res = await authLoginValidate(emailBase64, otp!, chainUID);
+          user = res?.user;
+          isJoinFormRequired = !!res?.join_form;
         }
-        if (!emailBase64) {
-          throw "Email is not included in request";
+        if (!user) {
+          throw "Unable to login";
         }
-        user = await authLoginValidate(emailBase64, otp!, chainUID);
         addToast({
           message: t("userIsLoggedIn"),
           type: "success",
         });
 
         const locale = user?.i18n || "en";
-        if (chainUID) {
+        if (chainUID && isJoinFormRequired) {
+          window.location.href = localizePath(
+            "/loops/users/signup/?chain=" + chainUID,
+            locale,
+          );
+        } else if (chainUID) {
           window.location.href = localizePath("/thankyou/", locale);
         } else {
           window.location.href = localizePath("/admin/dashboard", locale);
@@ -1,11 +1,21 @@
 // React / plugins
-import { useState, useEffect } from "react";
+import { useState, useEffect, type FormEvent } from "react";
 import { Trans, useTranslation } from "react-i18next";
 
 import { TwoColumnLayout } from "../components/Layouts";
 import AddressForm, { type ValuesForm } from "../components/AddressForm";
-import type { Chain, User } from "../../../api/types";
-import { chainAddUser, chainGet } from "../../../api/chain";
+import type {
+  Chain,
+  ChainQuestion,
+  RequestChainQuestionAnswer,
+  User,
+} from "../../../api/types";
+import {
+  chainAddUser,
+  chainGet,
+  chainInviteAccept,
+  chainQuestionGetAll,
+} from "../../../api/chain";
 import { registerBasicUser, registerOrphanedUser } from "../../../api/login";
 
 import { GinParseErrors } from "../util/gin-errors";
@@ -13,16 +23,22 @@ import type { TFunction } from "i18next";
 import { Genders } from "../../../api/enums";
 import { useStore } from "@nanostores/react";
 import { $authUser, authUserRefresh } from "../../../stores/auth";
-import { addModal, addToastError } from "../../../stores/toast";
+import { addModal, addToast, addToastError } from "../../../stores/toast";
 import getQuery from "../util/query";
 import useLocalizePath from "../util/localize_path.hooks";
+import ChainQuestionsInputs, {
+  chainQuestionAnswersFromForm,
+  chainQuestionUnanswered,
+} from "../components/ChainQuestions";
 
 export default function Signup() {
   const { t, i18n } = useTranslation();
   const localizePath = useLocalizePath(i18n);
   const authUser = useStore($authUser);
-  const [chainUID] = getQuery("chain");
+  // an invite from the invite page also works for loops closed to new members
+  const [chainUID, invite] = getQuery("chain", "invite");
   const [chain, setChain] = useState<Chain | null>(null);
+  const [questions, setQuestions] = useState<ChainQuestion[]>([]);
   const [submitted, setSubmitted] = useState(false);
 
   // Get chain id from the URL and save to state
@@ -32,6 +48,7 @@ export default function Signup() {
         try {
           const chain = (await chainGet(chainUID)).data;
           setChain(chain);
+          setQuestions((await chainQuestionGetAll(chainUID)).data);
         } catch (err) {
           console.error(`chain ${chainUID} does not exist`);
         }
@@ -39,10 +56,47 @@ export default function Signup() {
     })();
   }, [chainUID]);
 
-  function onSubmitCurrentUser() {
+  // Returns undefined when a required question is not answered
+  function getAnswers(
+    form: HTMLFormElement,
+  ): RequestChainQuestionAnswer[] | undefined {
+    const answers = chainQuestionAnswersFromForm(
+      questions,
+      Object.fromEntries(new FormData(form)),
+    );
+    const unanswered = chainQuestionUnanswered(questions, answers);
+    if (unanswered) {
+      addToastError(
+        t("answerRequired", { question: unanswered.question }),
+        400,
+      );
+      return undefined;
+    }
+    return answers;
+  }
+
+  function onSubmitCurrentUser(e: FormEvent<HTMLFormElement>) {
+    e.preventDefault();
     if (authUser && chainUID) {
-      chainAddUser(chainUID, authUser.uid, false)
-        .then(() => {
+      const answers = getAnswers(e.currentTarget);
+      if (!answers) return;
+      if (invite) {
+        chainInviteAccept(invite, answers)
+          .then(() => authUserRefresh(true))
+          .catch((err) => {
+            addToastError(GinParseErrors(t, err), err?.status);
+          });
+        return;
+      }
+      chainAddUser(chainUID, authUser.uid, false, answers)
+        .then((res) => {
+          if (res.status === 202) {
+            addToast({
+              message: t("joinedWaitlist", { chainName: chain?.name }),
+              type: "success",
+            });
+            return;
+          }
           authUserRefresh(true);
         })
         .catch((err) => {
@@ -62,6 +116,14 @@ export default function Signup() {
       return;
     }
 
+    let answers: RequestChainQuestionAnswer[] | undefined;
+    if (chainUID) {
+      answers = getAnswers(
+        document.getElementById("address-form") as HTMLFormElement,
+      );
+      if (!answers) return;
+    }
+
     (async () => {
       try {
         if (chainUID) {
@@ -77,6 +139,8 @@ export default function Signup() {
               longitude: values.longitude || 0,
             },
             chainUID,
+            answers,
+            invite || undefined,
           );
         } else {
           console.info("register orphaned user");
@@ -191,6 +255,7 @@ export default function Signup() {
                     </dt>
                     <dd className="inline mb-2">{authUser.name}</dd>
                   </dl>
+                  <ChainQuestionsInputs questions={questions} />
                   <div className="my-4">
                     <button
                       type="button"
@@ -199,12 +264,17 @@ export default function Signup() {
                     >
                       {t("back")}
                     </button>
-                    <SubmitButton t={t} chain={chain} user={authUser} />
+                    <SubmitButton
+                      t={t}
+                      chain={chain}
+                      user={authUser}
+                      hasInvite={!!invite}
+                    />
                   </div>
                 </form>
               ) : (
                 <div>
-                  {!chain || (chain.open_to_new_members && chain.published) ? (
+                  {!chain || chain.published ? (
                     <>
                       <div className="mt-4 prose">
                         {t("doYouHaveAnAccount") + " "}
@@ -234,6 +304,10 @@ export default function Signup() {
                         onEmailExist={onEmailExist}
                         classes="mb-4"
                       />
+                      <ChainQuestionsInputs
+                        questions={questions}
+                        form="address-form"
+                      />
                     </>
                   ) : null}
                   <div className="mb-4">
@@ -244,7 +318,7 @@ export default function Signup() {
                     >
                       {t("back")}
                     </button>
-                    <SubmitButton t={t} chain={chain} />
+                    <SubmitButton t={t} chain={chain} hasInvite={!!invite} />
                   </div>
                 </div>
               )}
@@ -269,10 +343,12 @@ function SubmitButton({
   t,
   chain,
   user,
+  hasInvite,
 }: {
   t: TFunction;
   chain: Chain | null;
   user?: User | null;
+  hasInvite?: boolean;
 }) {
   if (user && chain) {
     let userChain = user.chains.find((uc) => uc.chain_uid === chain.uid);
@@ -294,7 +370,7 @@ function SubmitButton({
       }
     }
   }
-  if (chain?.open_to_new_members == false || chain?.published == false) {
+  if (chain?.published == false) {
     return (
       <p className="px-3 font-semibold text-sm border border-secondary h-12 inline-flex items-center text-secondary">
         {t("closed")}
@@ -303,6 +379,19 @@ function SubmitButton({
     );
   }
 
+  if (chain?.open_to_new_members == false && !hasInvite) {
+    return (
+      <button
+        type="submit"
+        className="btn btn-secondary btn-outline"
+        form="address-form"
+      >
+        {t("joinWaitlist")}
+        <span className="feather feather-clock ml-4 rtl:ml-0 rtl:mr-4"></span>
+      </button>
+    );
+  }
+
   return (
     <button type="submit" className="btn btn-primary" form="address-form">
       {t("join")}
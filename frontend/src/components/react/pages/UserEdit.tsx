@@ -14,6 +14,8 @@ import { useStore } from "@nanostores/react";
 import { $authUser } from "../../../stores/auth";
 import getQuery from "../util/query";
 import useLocalizePath from "../util/localize_path.hooks";
+import UserPasskeys from "../components/UserPasskeys";
+import UserEmailChange from "../components/UserEmailChange";
 
 interface Params {
   userUID: UID;
@@ -111,6 +113,12 @@ export default function UserEdit() {
             isNewsletterRequired={userIsAnyChainAdmin && !user.is_root_admin}
           />
 
+          {/* a root admin can also change the email address of other users */}
+          {(isMe && !chainUID) || authUser.is_root_admin ? (
+            <UserEmailChange user={user} />
+          ) : null}
+          {isMe && !chainUID ? <UserPasskeys /> : null}
+
           <div className="flex">
             <button
               type="button"
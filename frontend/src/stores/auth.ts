@@ -1,6 +1,12 @@
 import { atom } from "nanostores";
 import type { User } from "../api/types";
-import { loginValidate, logout, refreshToken } from "../api/login";
+import {
+  loginValidate,
+  loginValidateMagicLink,
+  logout,
+  type LoginValidateResponse,
+} from "../api/login";
+import { loginPasskey } from "../api/passkey";
 import { userGetByUID } from "../api/user";
 import {
   cookieUserUID,
@@ -24,12 +30,41 @@ export function authLoginValidate(
   emailBase64: string,
   otp: string,
   chainUID: string,
+): Promise<undefined | LoginValidateResponse> {
+  return authLoginValidateRequest(() =>
+    loginValidate(emailBase64, otp, chainUID),
+  );
+}
+
+// The chain UID of a magic link is only known by the server
+export function authLoginValidateMagicLink(
+  token: string,
+): Promise<undefined | LoginValidateResponse> {
+  return authLoginValidateRequest(() => loginValidateMagicLink(token));
+}
+
+// The session cookie is set by the server before redirecting back from the login provider
+export function authLoginOidc(
+  userUID: string,
 ): Promise<undefined | null | User> {
+  cookieUserUID.set(userUID);
+  return authUserRefresh(true).then((state) =>
+    state === UserRefreshState.LoggedIn ? $authUser.get() : undefined,
+  );
+}
+
+export function authLoginPasskey(): Promise<undefined | null | User> {
+  return authLoginValidateRequest(loginPasskey).then((res) => res?.user);
+}
+
+function authLoginValidateRequest(
+  request: () => Promise<{ data: LoginValidateResponse }>,
+): Promise<undefined | LoginValidateResponse> {
   $loading.set(true);
   return (async () => {
-    let user: User | null | undefined = undefined;
+    let res: LoginValidateResponse;
     try {
-      user = (await loginValidate(emailBase64, otp, chainUID)).data.user;
+      res = (await request()).data;
     } catch (err) {
       $authUser.set(null);
       $loading.set(false);
@@ -37,11 +72,12 @@ export function authLoginValidate(
       console.error(err);
       return undefined;
     }
+    const user = res.user;
     cookieUserUID.set(user.uid);
     sessionAuthUser.set(user);
     $authUser.set(user);
     $loading.set(false);
-    return user;
+    return res;
   })();
 }
 
@@ -75,7 +111,7 @@ export function authUserRefresh(force = false): Promise<UserRefreshState> {
     }
     if (!user || force) {
       try {
-        await refreshToken();
+        // an expired access token is refreshed by the api client
         user = (
           await userGetByUID(undefined, userUID, {
             addApprovedTOH: true,
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/pkg/throttle"
+	"gopkg.in/guregu/null.v3/zero"
+	"gorm.io/gorm"
+)
+
+const apiKeyContextKey = "AuthApiKey"
+
+// makes keys recognizable in logs and secret scanners
+const apiKeyPrefix = "cl_"
+
+// Every api key is limited separately from the limits of the routes
+var apiKeyThrottle = throttle.Policy(&throttle.Quota{
+	Limit:  1000,
+	Within: time.Hour,
+}, &throttle.Options{
+	KeyPrefix:              "api_key",
+	IdentificationFunction: apiKeyIdentify,
+})
+
+// Returns the key, this is only shown once to the root admin
+func ApiKeyCreate(db *gorm.DB, createdByUserID uint, name string, scopes []string, chainID uint) (string, *models.ApiKey, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	apiKey := &models.ApiKey{
+		UID:             uuid.NewV4().String(),
+		Name:            name,
+		KeyHash:         models.TokenHash(key),
+		KeyPrefix:       key[:len(apiKeyPrefix)+6],
+		Scopes:          scopes,
+		CreatedByUserID: zero.IntFrom(int64(createdByUserID)),
+	}
+	if chainID != 0 {
+		apiKey.ChainID.SetValid(int64(chainID))
+	}
+	if err := db.Create(apiKey).Error; err != nil {
+		return "", nil, err
+	}
+
+	return key, apiKey, nil
+}
+
+// Only applies the throttle to requests made with an api key
+func apiKeyThrottleMiddleware(c *gin.Context) {
+	if _, scheme, ok := TokenReadFromRequest(c); ok && scheme == TokenSchemeApiKey {
+		apiKeyThrottle(c)
+	}
+}
+
+func apiKeyIdentify(req *http.Request) string {
+	_, key, _ := strings.Cut(req.Header.Get("Authorization"), TokenSchemeApiKey+" ")
+	return models.TokenHash(key)
+}
+
+// Authenticates a request made with an api key for a route that accepts the scope.
+// Keys of a chain scope only have access to the chain they are issued for,
+// these act as the root admin that issued the key without root admin privileges.
+func authenticateApiKey(c *gin.Context, db *gorm.DB, key string, p Policy, chainUID string) (ok bool, authUser *models.User, chain *models.Chain) {
+	apiKey, err := models.ApiKeyGetActiveByHash(db, models.TokenHash(key))
+	if err != nil {
+		c.String(http.StatusUnauthorized, "Invalid api key")
+		return false, nil, nil
+	}
+	if p.ApiKeyScope == "" {
+		c.String(http.StatusForbidden, "Route does not accept api keys")
+		return false, nil, nil
+	}
+	if !apiKey.HasScope(p.ApiKeyScope) {
+		c.String(http.StatusForbidden, fmt.Sprintf("Api key requires the %s scope", p.ApiKeyScope))
+		return false, nil, nil
+	}
+
+	if !apiKey.LastUsedAt.Valid || apiKey.LastUsedAt.Time.Before(time.Now().Add(-5*time.Minute)) {
+		apiKey.UpdateLastUsed(db)
+	}
+	c.Set(apiKeyContextKey, apiKey)
+
+	if p.MinimumAuthState == AuthState0Guest {
+		return true, nil, nil
+	}
+
+	authUser = &models.User{}
+	db.Raw(`SELECT * FROM users WHERE id = ? LIMIT 1`, apiKey.CreatedByUserID.Int64).Scan(authUser)
+	if authUser.ID == 0 {
+		c.String(http.StatusUnauthorized, "Invalid api key")
+		return false, nil, nil
+	}
+	authUser.IsRootAdmin = false
+
+	if p.MinimumAuthState >= AuthState2UserOfChain || chainUID != "" {
+		if chainUID == "" {
+			c.String(http.StatusBadRequest, "chain_uid is required when using an api key")
+			return false, nil, nil
+		}
+		chain = &models.Chain{}
+		db.Raw(`SELECT * FROM chains WHERE chains.uid = ? AND chains.deleted_at IS NULL LIMIT 1`, chainUID).Scan(chain)
+		if chain.ID == 0 {
+			c.String(http.StatusBadRequest, models.ErrChainNotFound.Error())
+			return false, nil, nil
+		}
+		if !apiKey.ChainID.Valid || uint(apiKey.ChainID.Int64) != chain.ID {
+			c.String(http.StatusForbidden, "Api key is not issued for this loop")
+			return false, nil, nil
+		}
+	}
+
+	return true, authUser, chain
+}
+
+// Returns the api key the request is made with, nil for requests made by a user
+func GetAuthApiKey(c *gin.Context) *models.ApiKey {
+	v, ok := c.Get(apiKeyContextKey)
+	if !ok {
+		return nil
+	}
+	return v.(*models.ApiKey)
+}
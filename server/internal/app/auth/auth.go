@@ -2,6 +2,7 @@ package auth
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/the-clothing-loop/website/server/internal/app/goscope"
 	"github.com/the-clothing-loop/website/server/internal/models"
@@ -11,46 +12,64 @@ import (
 )
 
 const (
-	AuthState0Guest          = 0
-	AuthState1AnyUser        = 1
-	AuthState2UserOfChain    = 2
-	AuthState3AdminChainUser = 3
-	AuthState4RootUser       = 4
+	AuthState0Guest           = 0
+	AuthState1AnyUser         = 1
+	AuthState2UserOfChain     = 2
+	AuthState3ChainPermission = 3
+	AuthState4RootUser        = 4
 )
 
 // There are 4 different states to authenticate
 // 0. Guest - this middleware is then not required
 // 1. User of a different/unknown chain
 // 2. User connected to the chain in question
-// 3. Admin User of the chain in question
+// 3. User with the permission in the chain in question, hosts have all permissions
 // 4. Root User
-func Authenticate(c *gin.Context, db *gorm.DB, minimumAuthState int, chainUID string) (ok bool, authUser *models.User, chain *models.Chain) {
+//
+// The permission is only used by AuthState3ChainPermission, when empty the host role is required.
+func Authenticate(c *gin.Context, db *gorm.DB, minimumAuthState int, chainUID, permission string) (ok bool, authUser *models.User, chain *models.Chain) {
 	// 0. Guest - this middleware is then not required
 	if minimumAuthState == AuthState0Guest {
 		return true, nil, nil
 	}
 
-	token, ok := TokenReadFromRequest(c)
+	token, scheme, ok := TokenReadFromRequest(c)
 	if !ok {
 		c.String(http.StatusUnauthorized, "Token not received")
 		return false, nil, nil
 	}
+	if scheme != TokenSchemeBearer {
+		c.String(http.StatusUnauthorized, "Invalid token")
+		return false, nil, nil
+	}
 
 	var err error
 	var usedOldToken bool
-	authUser, usedOldToken, err = AuthenticateToken(db, token)
+	var session *models.UserSession
+	authUser, session, usedOldToken, err = AuthenticateToken(db, token)
 	if err != nil {
 		c.String(http.StatusUnauthorized, "Invalid token")
 		return false, nil, nil
 	}
 
 	if usedOldToken {
-		token, err := JwtGenerate(authUser)
+		session, err = SessionCreateLegacy(db, authUser.ID, token, SessionInfoFromRequest(c))
+		if err != nil {
+			c.String(http.StatusUnauthorized, "Invalid token")
+			return false, nil, nil
+		}
+		tokens, err := TokensGenerate(db, authUser, session)
 		if err != nil {
 			c.String(http.StatusUnauthorized, "Invalid token")
 			return false, nil, nil
 		}
-		CookieSet(c, token)
+		CookieSet(c, tokens)
+	} else if session.LastSeenAt.Before(time.Now().Add(-5 * time.Minute)) {
+		session.UpdateLastSeen(db, c.ClientIP())
+	}
+	sessionSet(c, session)
+	if session.IsImpersonated() && !impersonationSet(c, db, authUser, session) {
+		return false, nil, nil
 	}
 
 	// 1. User of a different/unknown chain
@@ -66,7 +85,7 @@ func Authenticate(c *gin.Context, db *gorm.DB, minimumAuthState int, chainUID st
 
 	chain = &models.Chain{}
 	err = db.Raw(`SELECT * FROM chains WHERE chains.uid = ? AND chains.deleted_at IS NULL LIMIT 1`, chainUID).Scan(chain).Error
-	if err != nil {
+	if err != nil || chain.ID == 0 {
 		c.String(http.StatusBadRequest, models.ErrChainNotFound.Error())
 		return false, nil, nil
 	}
@@ -85,23 +104,26 @@ func Authenticate(c *gin.Context, db *gorm.DB, minimumAuthState int, chainUID st
 	isUserOfUnknownChain := minimumAuthState == AuthState1AnyUser
 
 	isUserParticipantOfChain := false
-	isUserAdminOfChain := false
-	if minimumAuthState == AuthState2UserOfChain || minimumAuthState == AuthState3AdminChainUser {
+	isUserPermittedInChain := false
+	if minimumAuthState == AuthState2UserOfChain || minimumAuthState == AuthState3ChainPermission {
 		for _, userChain := range authUser.Chains {
 			if userChain.ChainID == chain.ID {
 				if minimumAuthState == AuthState2UserOfChain {
 					// 2. User connected to the chain in question
 					isUserParticipantOfChain = true
-				} else if minimumAuthState == AuthState3AdminChainUser && userChain.IsChainAdmin {
-					// 3. Admin User of the chain in question
-					isUserAdminOfChain = true
+				} else if permission == "" {
+					// 3. Host of the chain in question
+					isUserPermittedInChain = userChain.IsChainAdmin
+				} else {
+					// 3. User with the permission in the chain in question
+					isUserPermittedInChain = userChain.HasPermission(permission)
 				}
 				break
 			}
 		}
 	}
 
-	if !(isRootUser || isUserOfUnknownChain || isUserParticipantOfChain || isUserAdminOfChain) {
+	if !(isRootUser || isUserOfUnknownChain || isUserParticipantOfChain || isUserPermittedInChain) {
 		c.String(http.StatusUnauthorized, "User role not high enough")
 		return false, nil, nil
 	}
@@ -109,27 +131,25 @@ func Authenticate(c *gin.Context, db *gorm.DB, minimumAuthState int, chainUID st
 	return true, authUser, chain
 }
 
-// This runs Authenticate and defines minimumAuthState depending on the input
+// Authorizes access to a user, this requires the route policy to have authenticated the request
 // Any of the following rules pass authentication
 //
 // 1. authUser UID is the same as the given userUID
-// 2. authUser is a chain admin of chain and user is part that same chain
+// 2. authUser may manage the members of chain and user is part that same chain,
+// only hosts may manage other hosts
 // 3. authUser is a root admin
-func AuthenticateUserOfChain(c *gin.Context, db *gorm.DB, chainUID, userUID string) (ok bool, user, authUser *models.User, chain *models.Chain) {
-	if chainUID != "" && userUID == "" {
+func AuthorizeUserOfChain(c *gin.Context, db *gorm.DB, userUID string) (ok bool, user *models.User) {
+	authUser := GetAuthUser(c)
+	chain := GetAuthChain(c)
+	if chain != nil && userUID == "" {
 		c.String(http.StatusBadRequest, "user UID must be set if chain UID is set")
-		return false, nil, nil, nil
-	}
-
-	ok, authUser, chain = Authenticate(c, db, AuthState1AnyUser, chainUID)
-	if !ok {
-		return ok, nil, nil, nil
+		return false, nil
 	}
 
 	// 1. authUser UID is the same as the given userUID
 	// 3. authUser is a root admin
 	if authUser.UID == userUID || (authUser.IsRootAdmin && userUID == "") {
-		return true, authUser, authUser, chain
+		return true, authUser
 	}
 
 	// get user
@@ -140,55 +160,65 @@ func AuthenticateUserOfChain(c *gin.Context, db *gorm.DB, chainUID, userUID stri
 	if err != nil {
 		goscope.Log.Errorf("%v", err)
 		c.String(http.StatusBadRequest, "user UID must be set if chain UID is set")
-		return false, nil, nil, nil
+		return false, nil
 	}
 
 	// 3. authUser is a root admin
 	if authUser.IsRootAdmin {
-		return true, user, authUser, chain
+		return true, user
 	}
 
-	_, isAuthUserChainAdmin := authUser.IsPartOfChain(chainUID)
-	isUserPartOfChain, _ := user.IsPartOfChain(chainUID)
+	// authUser chains are added by Authenticate when a chain is given
+	if chain != nil {
+		isAuthUserMemberManager := authUser.HasChainPermission(chain.UID, models.ChainPermissionManageMembers)
+		_, isAuthUserChainAdmin := authUser.IsPartOfChain(chain.UID)
+		isUserPartOfChain, isUserChainAdmin := user.IsPartOfChain(chain.UID)
 
-	//	2. authUser is a chain admin of chain and user is part of chain
-	if isAuthUserChainAdmin && isUserPartOfChain {
-		return true, user, authUser, chain
+		//	2. authUser may manage the members of chain and user is part of chain
+		if isAuthUserMemberManager && isUserPartOfChain {
+			if isUserChainAdmin && !isAuthUserChainAdmin {
+				c.String(http.StatusUnauthorized, "Only a host can alter a host")
+				return false, nil
+			}
+			return true, user
+		}
 	}
 
 	c.String(http.StatusUnauthorized, "Must be a chain admin or higher to alter a different user")
-	return false, nil, nil, nil
+	return false, nil
 }
 
-func AuthenticateEvent(c *gin.Context, db *gorm.DB, eventUID string) (ok bool, authUser *models.User, event *models.Event) {
-	ok, authUser, _ = Authenticate(c, db, AuthState1AnyUser, "")
-	if !ok {
-		return false, nil, nil
-	}
+// Authorizes access to an event, this requires the route policy to have authenticated the request
+func AuthorizeEvent(c *gin.Context, db *gorm.DB, eventUID string) (ok bool, event *models.Event) {
+	authUser := GetAuthUser(c)
 
 	event = &models.Event{}
 	err := db.Raw(models.EventGetSql+`WHERE events.uid = ? LIMIT 1`, eventUID).Scan(event).Error
-	if err != nil {
+	if err != nil || event.ID == 0 {
 		c.String(http.StatusNotFound, "event not found")
-		return false, nil, nil
+		return false, nil
 	}
 
-	if event.UserID == authUser.ID || authUser.IsRootAdmin {
-		return true, authUser, event
+	// api keys only have access to the events of their loop
+	if apiKey := GetAuthApiKey(c); apiKey != nil {
+		if apiKey.ChainID.Valid && event.ChainID.Valid && apiKey.ChainID.Int64 == event.ChainID.Int64 {
+			return true, event
+		}
+	} else if event.UserID == authUser.ID || authUser.IsRootAdmin {
+		return true, event
 	} else if event.ChainUID.Valid {
 		err = authUser.AddUserChainsToObject(db)
 		if err != nil {
 			goscope.Log.Errorf("%v", err)
 			c.String(http.StatusInternalServerError, "Unable to retrieve user related loops")
-			return false, nil, nil
+			return false, nil
 		}
 
-		_, isChainAdmin := authUser.IsPartOfChain(event.ChainUID.String)
-		if isChainAdmin {
-			return true, authUser, event
+		if authUser.HasChainPermission(event.ChainUID.String, models.ChainPermissionManageEvents) {
+			return true, event
 		}
 	}
 
 	c.String(http.StatusUnauthorized, "user must be connected to event")
-	return false, nil, nil
+	return false, nil
 }
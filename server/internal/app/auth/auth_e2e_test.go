@@ -55,7 +55,7 @@ func TestAuthenticate(t *testing.T) {
 			if i > auth.AuthState1AnyUser {
 				chainUID = chain.UID
 			}
-			ok, resultUser, resultChain := auth.Authenticate(c, db, i, chainUID)
+			ok, resultUser, resultChain := auth.Authenticate(c, db, i, chainUID, "")
 
 			expectedOk := sut.ExpectedResults[i]
 			assert.Equalf(t, expectedOk, ok, "minimumAuthState: %d\nchain.ID: %d user.ID: %d\noptions: %+v", i, chain.ID, user.ID, sut.MockOptions)
@@ -77,7 +77,7 @@ func TestAuthenticate(t *testing.T) {
 	}
 }
 
-func TestAuthenticateUserOfChain(t *testing.T) {
+func TestAuthorizeUserOfChain(t *testing.T) {
 	type Sut struct {
 		MockAuthOptions mocks.MockChainAndUserOptions
 		MockUserOptions mocks.MockChainAndUserOptions
@@ -140,8 +140,10 @@ func TestAuthenticateUserOfChain(t *testing.T) {
 			}
 		}
 
-		c, _ := mocks.MockGinContext(db, http.MethodGet, "/", nil, token)
-		ok, resultUser, resultAuthUser, resultChain := auth.AuthenticateUserOfChain(c, db, chain.UID, user.UID)
+		c, _ := mocks.MockGinContext(db, http.MethodGet, "/?chain_uid="+chain.UID, nil, token)
+		auth.AnyUser().WithChain(auth.ChainUIDFromQuery("chain_uid")).Middleware()(c)
+		resultAuthUser, resultChain := auth.GetAuthUser(c), auth.GetAuthChain(c)
+		ok, resultUser := auth.AuthorizeUserOfChain(c, db, user.UID)
 
 		assert.Equalf(t, sut.ExpectedResult, ok, "sut: %+v", sut)
 
@@ -163,7 +165,7 @@ func TestAuthenticateUserOfChain(t *testing.T) {
 	}
 }
 
-func TestAuthenticateEvent(t *testing.T) {
+func TestAuthorizeEvent(t *testing.T) {
 	type Sut struct {
 		MockAuthOptions mocks.MockChainAndUserOptions
 		IsSameUser      bool
@@ -247,7 +249,9 @@ func TestAuthenticateEvent(t *testing.T) {
 		event := mocks.MockEvent(t, db, eventUser.ID, eventChainID)
 
 		c, _ := mocks.MockGinContext(db, http.MethodGet, "/", nil, token)
-		ok, resultAuthUser, resultEvent := auth.AuthenticateEvent(c, db, event.UID)
+		auth.AnyUser().Middleware()(c)
+		resultAuthUser := auth.GetAuthUser(c)
+		ok, resultEvent := auth.AuthorizeEvent(c, db, event.UID)
 
 		assert.Equalf(t, sut.ExpectedResult, ok, "sut index: %v\nsut: %++v\nevent: %++v", i, sut, []any{*event, eventChainID, *eventUser})
 
@@ -264,7 +268,7 @@ func TestAuthenticateEvent(t *testing.T) {
 				assert.NotEqualf(t, resultEvent.ChainID, eventChain.ID, "sut index: %v", i)
 			}
 		} else {
-			assert.Nilf(t, resultAuthUser, "sut index: %v", i)
+			assert.Nilf(t, resultEvent, "sut index: %v", i)
 		}
 	}
 }
@@ -3,38 +3,77 @@ package auth
 import (
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/the-clothing-loop/website/server/internal/app"
 )
 
-// seconds in one year
-const cookieMaxAge = 3600 * 24 * 365
-
 func cookieRead(c *gin.Context) (string, bool) {
 	token, err := c.Cookie("token")
 
 	return token, err == nil
 }
 
+func CookieReadRefresh(c *gin.Context) (string, bool) {
+	token, err := c.Cookie("refresh_token")
+
+	return token, err == nil
+}
+
 func CookieRemove(c *gin.Context) {
+	for _, name := range []string{"token", "refresh_token"} {
+		http.SetCookie(c.Writer, &http.Cookie{
+			Name:     name,
+			Value:    url.QueryEscape(""),
+			MaxAge:   -1,
+			Path:     "/",
+			Domain:   "",
+			SameSite: http.SameSiteStrictMode,
+			Secure:   app.Config.COOKIE_HTTPS_ONLY,
+			HttpOnly: true,
+		})
+	}
+}
+
+func CookieSet(c *gin.Context, tokens *Tokens) {
+	cookieSetAccess(c, tokens.Access, accessTokenMaxAge)
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     "refresh_token",
+		Value:    url.QueryEscape(tokens.Refresh),
+		MaxAge:   int(refreshTokenMaxAge.Seconds()),
+		Path:     "/",
+		Domain:   app.Config.COOKIE_DOMAIN,
+		SameSite: http.SameSiteStrictMode,
+		Secure:   app.Config.COOKIE_HTTPS_ONLY,
+		HttpOnly: true,
+	})
+}
+
+// Only replaces the access token, the refresh token of the root admin is kept
+// so that the admin returns to their own account once impersonation ends.
+func CookieSetImpersonation(c *gin.Context, token string, maxAge time.Duration) {
+	cookieSetAccess(c, token, maxAge)
+}
+
+func CookieRemoveImpersonation(c *gin.Context) {
 	http.SetCookie(c.Writer, &http.Cookie{
 		Name:     "token",
 		Value:    url.QueryEscape(""),
 		MaxAge:   -1,
 		Path:     "/",
-		Domain:   "",
+		Domain:   app.Config.COOKIE_DOMAIN,
 		SameSite: http.SameSiteStrictMode,
 		Secure:   app.Config.COOKIE_HTTPS_ONLY,
 		HttpOnly: true,
 	})
 }
 
-func CookieSet(c *gin.Context, token string) {
+func cookieSetAccess(c *gin.Context, token string, maxAge time.Duration) {
 	http.SetCookie(c.Writer, &http.Cookie{
 		Name:     "token",
 		Value:    url.QueryEscape(token),
-		MaxAge:   cookieMaxAge,
+		MaxAge:   int(maxAge.Seconds()),
 		Path:     "/",
 		Domain:   app.Config.COOKIE_DOMAIN,
 		SameSite: http.SameSiteStrictMode,
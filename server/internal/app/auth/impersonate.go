@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+	"github.com/the-clothing-loop/website/server/internal/app"
+	"github.com/the-clothing-loop/website/server/internal/app/goscope"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"gopkg.in/guregu/null.v3/zero"
+	"gorm.io/gorm"
+)
+
+const impersonatorContextKey = "AuthImpersonator"
+
+// how long a root admin can act as a user before starting again
+const impersonationMaxAge = 30 * time.Minute
+
+var ErrImpersonationNotAllowed = errors.New("Root admins can not be impersonated")
+
+// Creates a session for the user that is marked as impersonated by the root admin,
+// only an access token is returned which expires with the session.
+func ImpersonationStart(db *gorm.DB, admin, user *models.User, info SessionInfo) (string, *models.UserSession, error) {
+	if user.IsRootAdmin || user.ID == admin.ID {
+		return "", nil, ErrImpersonationNotAllowed
+	}
+
+	session := &models.UserSession{
+		UID:                  uuid.NewV4().String(),
+		UserID:               user.ID,
+		DeviceName:           "Impersonated by " + admin.Email.String,
+		UserAgent:            info.UserAgent,
+		IPAddress:            info.IPAddress,
+		LastSeenAt:           time.Now(),
+		ImpersonatedByUserID: zero.IntFrom(int64(admin.ID)),
+		ExpiresAt:            zero.TimeFrom(time.Now().Add(impersonationMaxAge)),
+	}
+	if err := db.Create(session).Error; err != nil {
+		return "", nil, err
+	}
+
+	token, err := JwtGenerate(user, session)
+	if err != nil {
+		return "", nil, err
+	}
+
+	goscope.Log.Infof("Root admin %s started impersonating user %s (session %s)", admin.UID, user.UID, session.UID)
+	return token, session, nil
+}
+
+// Sets the root admin behind an impersonation session,
+// emails are not sent for the rest of the request.
+func impersonationSet(c *gin.Context, db *gorm.DB, authUser *models.User, session *models.UserSession) bool {
+	admin := &models.User{}
+	db.Raw(`SELECT * FROM users WHERE id = ? LIMIT 1`, session.ImpersonatedByUserID.Int64).Scan(admin)
+	if admin.ID == 0 || !admin.IsRootAdmin {
+		c.String(http.StatusUnauthorized, "Invalid token")
+		return false
+	}
+
+	c.Set(impersonatorContextKey, admin)
+	c.Set("DB", db.WithContext(app.MailDisabledContext(c.Request.Context())))
+	c.Header("X-Impersonated-By", admin.UID)
+	goscope.Log.Infof("Root admin %s impersonating user %s: %s %s", admin.UID, authUser.UID, c.Request.Method, c.Request.URL.Path)
+	return true
+}
+
+// Returns the root admin impersonating the authenticated user, nil if the user is not impersonated
+func GetImpersonator(c *gin.Context) *models.User {
+	v, ok := c.Get(impersonatorContextKey)
+	if !ok {
+		return nil
+	}
+	return v.(*models.User)
+}
+
+// Records a request made while impersonating, this is called after the request is handled
+func impersonationLogCreate(c *gin.Context, db *gorm.DB, admin, user *models.User) {
+	log := &models.UserImpersonationLog{
+		AdminUserID: zero.IntFrom(int64(admin.ID)),
+		UserID:      zero.IntFrom(int64(user.ID)),
+		Method:      c.Request.Method,
+		Path:        c.Request.URL.RequestURI(),
+		Status:      c.Writer.Status(),
+	}
+	if session := SessionGet(c); session != nil {
+		log.SessionUID = session.UID
+	}
+	if err := db.Create(log).Error; err != nil {
+		goscope.Log.Errorf("Unable to record impersonated request: %v", err)
+	}
+}
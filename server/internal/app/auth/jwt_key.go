@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/the-clothing-loop/website/server/internal/app"
+)
+
+// Returns the key new jwts are signed with
+func jwtKeyActive() (*app.ConfigJwtKey, error) {
+	for i := range app.JwtKeys {
+		if app.JwtKeys[i].Active {
+			return &app.JwtKeys[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("No active jwt key")
+}
+
+// Finds the key by the kid header, tokens without a kid are signed by the jwt_secret
+func jwtKeyFunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	for _, k := range app.JwtKeys {
+		if k.ID != kid {
+			continue
+		}
+		if k.RetiredAt != nil && time.Now().After(k.RetiredAt.Add(app.Config.JWT_KEY_GRACE_PERIOD)) {
+			return nil, fmt.Errorf("Jwt key is retired (%s)", kid)
+		}
+
+		return []byte(k.Secret), nil
+	}
+
+	return nil, fmt.Errorf("Jwt key not found (%s)", kid)
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/the-clothing-loop/website/server/internal/app"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"gopkg.in/guregu/null.v3/zero"
+)
+
+func TestJwtKeyRotation(t *testing.T) {
+	oldJwtKeys := app.JwtKeys
+	oldGracePeriod := app.Config.JWT_KEY_GRACE_PERIOD
+	t.Cleanup(func() {
+		app.JwtKeys = oldJwtKeys
+		app.Config.JWT_KEY_GRACE_PERIOD = oldGracePeriod
+	})
+	app.Config.JWT_KEY_GRACE_PERIOD = time.Hour
+
+	user := &models.User{UID: "user"}
+	session := &models.UserSession{UID: "session"}
+	parse := func(tokenString string) error {
+		_, err := jwt.ParseWithClaims(tokenString, &MyJwtClaims{}, jwtKeyFunc)
+		return err
+	}
+
+	// sign with the first key
+	app.JwtKeys = []app.ConfigJwtKey{
+		{ID: "2024", Secret: "first", Active: true},
+	}
+	tokenFirst, err := JwtGenerate(user, session)
+	assert.Nil(t, err)
+	token, _, _ := jwt.NewParser().ParseUnverified(tokenFirst, &MyJwtClaims{})
+	assert.Equal(t, "2024", token.Header["kid"])
+
+	// rotate, the retired key is still accepted during the grace period
+	retiredAt := time.Now()
+	app.JwtKeys = []app.ConfigJwtKey{
+		{ID: "2024", Secret: "first", RetiredAt: &retiredAt},
+		{ID: "2025", Secret: "second", Active: true},
+	}
+	tokenSecond, err := JwtGenerate(user, session)
+	assert.Nil(t, err)
+	assert.Nil(t, parse(tokenFirst))
+	assert.Nil(t, parse(tokenSecond))
+
+	// after the grace period the retired key is rejected
+	retiredAt = time.Now().Add(-2 * time.Hour)
+	assert.NotNil(t, parse(tokenFirst))
+	assert.Nil(t, parse(tokenSecond))
+
+	// removed keys are rejected
+	app.JwtKeys = []app.ConfigJwtKey{
+		{ID: "2026", Secret: "third", Active: true},
+	}
+	assert.NotNil(t, parse(tokenSecond))
+}
+
+func TestJwtImpersonation(t *testing.T) {
+	oldJwtKeys := app.JwtKeys
+	t.Cleanup(func() {
+		app.JwtKeys = oldJwtKeys
+	})
+	app.JwtKeys = []app.ConfigJwtKey{
+		{ID: "2024", Secret: "first", Active: true},
+	}
+
+	user := &models.User{UID: "user"}
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	session := &models.UserSession{
+		UID:                  "session",
+		ImpersonatedByUserID: zero.IntFrom(1),
+		ExpiresAt:            zero.TimeFrom(expiresAt),
+	}
+
+	tokenString, err := JwtGenerate(user, session)
+	assert.Nil(t, err)
+	claims := &MyJwtClaims{}
+	_, err = jwt.ParseWithClaims(tokenString, claims, jwtKeyFunc)
+	assert.Nil(t, err)
+	assert.True(t, claims.Impersonated)
+	// the token expires with the impersonation session instead of the access token max age
+	assert.Equal(t, expiresAt, claims.ExpiresAt.Time)
+
+	session.ExpiresAt = zero.TimeFrom(time.Now().Add(-time.Minute))
+	assert.True(t, session.IsExpired())
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"gorm.io/gorm"
+)
+
+const magicLinkAudience = "magic_link"
+
+// same as the expiry of a one time password
+const magicLinkMaxAge = 2 * 24 * time.Hour
+
+type MagicLink struct {
+	// Entered manually in the app
+	Otp string
+	// Opaque signed token used in the link of an email
+	Token string
+}
+
+// Creates a one time password together with a signed token for the login link,
+// so that the email address is not part of the url.
+func MagicLinkCreate(db *gorm.DB, userID uint, chainUID string) (*MagicLink, error) {
+	userToken, err := otpCreate(db, userID, chainUID)
+	if err != nil {
+		return nil, err
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		ID:        userToken.LinkNonce,
+		Audience:  jwt.ClaimStrings{magicLinkAudience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(magicLinkMaxAge)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+	})
+
+	key, err := jwtKeyActive()
+	if err != nil {
+		return nil, err
+	}
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+
+	tokenString, err := token.SignedString([]byte(key.Secret))
+	if err != nil {
+		return nil, err
+	}
+
+	return &MagicLink{
+		Otp:   userToken.Token,
+		Token: tokenString,
+	}, nil
+}
+
+// Logs in with the token of a magic link, this can only be used once.
+// Returns the chain UID that was set when the link was created.
+func MagicLinkVerify(db *gorm.DB, tokenString string, sessionInfo SessionInfo) (*models.User, *Tokens, string, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, jwtKeyFunc,
+		jwt.WithAudience(magicLinkAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if claims.ID == "" {
+		return nil, nil, "", fmt.Errorf("Magic link has no nonce")
+	}
+
+	userToken := &models.UserToken{}
+	db.Raw(`
+SELECT * FROM user_tokens
+WHERE link_nonce = ?
+	AND verified = FALSE
+	AND created_at > ADDDATE(NOW(), INTERVAL -2 DAY)
+LIMIT 1
+	`, claims.ID).Scan(userToken)
+	if userToken.ID == 0 {
+		return nil, nil, "", fmt.Errorf("Magic link is already used")
+	}
+
+	user, tokens, err := otpLogin(db, userToken, sessionInfo)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if user.Email.Valid {
+		otpAttemptReset(db, user.Email.String)
+	}
+
+	return user, tokens, userToken.ChainUID, nil
+}
@@ -0,0 +1,321 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	authUserContextKey  = "AuthUser"
+	authChainContextKey = "AuthChain"
+)
+
+var authStateNames = map[int]string{
+	AuthState0Guest:           "guest",
+	AuthState1AnyUser:         "any_user",
+	AuthState2UserOfChain:     "user_of_chain",
+	AuthState3ChainPermission: "chain_permission",
+	AuthState4RootUser:        "root_user",
+}
+
+// Where the chain UID of a request is read from
+type ChainUIDSource struct {
+	// query, json or uri
+	In  string
+	Key string
+	// When the chain UID is empty only AuthState1AnyUser is required
+	IsOptional bool
+}
+
+func ChainUIDFromQuery(key string) ChainUIDSource {
+	return ChainUIDSource{In: "query", Key: key}
+}
+
+func ChainUIDFromJSON(key string) ChainUIDSource {
+	return ChainUIDSource{In: "json", Key: key}
+}
+
+func ChainUIDFromURI(key string) ChainUIDSource {
+	return ChainUIDSource{In: "uri", Key: key}
+}
+
+func (s ChainUIDSource) Optional() ChainUIDSource {
+	s.IsOptional = true
+	return s
+}
+
+func (s ChainUIDSource) String() string {
+	str := fmt.Sprintf("%s:%s", s.In, s.Key)
+	if s.IsOptional {
+		str += "?"
+	}
+	return str
+}
+
+func (s ChainUIDSource) read(c *gin.Context) (string, error) {
+	switch s.In {
+	case "query":
+		return c.Query(s.Key), nil
+	case "uri":
+		return c.Param(s.Key), nil
+	case "json":
+		if c.Request.Body == nil {
+			return "", nil
+		}
+		b, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return "", err
+		}
+		// the controller must be able to read the body again
+		c.Request.Body = io.NopCloser(bytes.NewReader(b))
+		if len(b) == 0 {
+			return "", nil
+		}
+
+		body := map[string]any{}
+		if err := json.Unmarshal(b, &body); err != nil {
+			return "", err
+		}
+		// the controller binds keys case insensitively, the same key in a different case is refused
+		// so that the chain authorized is always the chain the controller reads
+		v := ""
+		isFound := false
+		for key, value := range body {
+			if !strings.EqualFold(key, s.Key) {
+				continue
+			}
+			if isFound {
+				return "", fmt.Errorf("%s is set more than once", s.Key)
+			}
+			isFound = true
+			v, _ = value.(string)
+		}
+		return v, nil
+	}
+
+	return "", fmt.Errorf("Unknown chain UID source: %s", s.In)
+}
+
+// The minimum role required to access a route, declared in server.Routes()
+type Policy struct {
+	MinimumAuthState int
+	ChainUID         *ChainUIDSource
+	// The permission required in the chain by ChainPermission
+	Permission string
+	// The scope an api key requires to access the route, api keys are refused if empty
+	ApiKeyScope string
+	// Refuses root admins impersonating a user
+	DenyImpersonation bool
+}
+
+func Guest() Policy {
+	return Policy{MinimumAuthState: AuthState0Guest}
+}
+
+func AnyUser() Policy {
+	return Policy{MinimumAuthState: AuthState1AnyUser}
+}
+
+func UserOfChain(chainUID ChainUIDSource) Policy {
+	return Policy{MinimumAuthState: AuthState2UserOfChain, ChainUID: &chainUID}
+}
+
+// Requires a permission in the chain, hosts have all permissions
+func ChainPermission(permission string, chainUID ChainUIDSource) Policy {
+	return Policy{MinimumAuthState: AuthState3ChainPermission, ChainUID: &chainUID, Permission: permission}
+}
+
+func RootUser() Policy {
+	return Policy{MinimumAuthState: AuthState4RootUser}
+}
+
+// Adds the chain to the context without requiring the user to be connected to it
+func (p Policy) WithChain(chainUID ChainUIDSource) Policy {
+	p.ChainUID = &chainUID
+	return p
+}
+
+// Allows requests made with an api key that has the scope
+func (p Policy) WithApiKeyScope(scope string) Policy {
+	p.ApiKeyScope = scope
+	return p
+}
+
+// Refuses root admins impersonating a user, for actions that can not be undone
+func (p Policy) WithoutImpersonation() Policy {
+	p.DenyImpersonation = true
+	return p
+}
+
+func (p Policy) String() string {
+	str := authStateNames[p.MinimumAuthState]
+	if p.Permission != "" {
+		str += ":" + p.Permission
+	}
+	if p.ChainUID != nil {
+		str += " " + p.ChainUID.String()
+	}
+	if p.ApiKeyScope != "" {
+		str += " api_key:" + p.ApiKeyScope
+	}
+	if p.DenyImpersonation {
+		str += " no_impersonation"
+	}
+	return str
+}
+
+// Authenticates the request and sets the authenticated user and chain in the context
+func (p Policy) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := c.MustGet("DB").(*gorm.DB)
+
+		chainUID := ""
+		if p.ChainUID != nil {
+			var err error
+			chainUID, err = p.ChainUID.read(c)
+			if err != nil {
+				c.String(http.StatusBadRequest, err.Error())
+				c.Abort()
+				return
+			}
+			if chainUID == "" && !p.ChainUID.IsOptional {
+				c.String(http.StatusBadRequest, fmt.Sprintf("%s is required", p.ChainUID.Key))
+				c.Abort()
+				return
+			}
+		}
+
+		var ok bool
+		var authUser *models.User
+		var chain *models.Chain
+		if token, scheme, _ := TokenReadFromRequest(c); scheme == TokenSchemeApiKey {
+			ok, authUser, chain = authenticateApiKey(c, db, token, p, chainUID)
+		} else if p.MinimumAuthState == AuthState0Guest {
+			c.Next()
+			return
+		} else {
+			minimumAuthState := p.MinimumAuthState
+			if chainUID == "" {
+				minimumAuthState = AuthState1AnyUser
+			}
+			ok, authUser, chain = Authenticate(c, db, minimumAuthState, chainUID, p.Permission)
+		}
+		if !ok {
+			c.Abort()
+			return
+		}
+
+		if p.MinimumAuthState == AuthState4RootUser && !authUser.IsRootAdmin {
+			c.String(http.StatusUnauthorized, "User role not high enough")
+			c.Abort()
+			return
+		}
+
+		if authUser != nil {
+			c.Set(authUserContextKey, authUser)
+		}
+		if chain != nil {
+			c.Set(authChainContextKey, chain)
+		}
+
+		if impersonator := GetImpersonator(c); impersonator != nil {
+			defer impersonationLogCreate(c, db, impersonator, authUser)
+			if p.DenyImpersonation {
+				c.String(http.StatusForbidden, "Not allowed while impersonating a user")
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// Returns the user authenticated by the route policy
+func GetAuthUser(c *gin.Context) *models.User {
+	v, ok := c.Get(authUserContextKey)
+	if !ok {
+		return nil
+	}
+	return v.(*models.User)
+}
+
+// Returns the chain authenticated by the route policy
+func GetAuthChain(c *gin.Context) *models.Chain {
+	v, ok := c.Get(authChainContextKey)
+	if !ok {
+		return nil
+	}
+	return v.(*models.Chain)
+}
+
+type RoutePolicy struct {
+	Method string
+	Path   string
+	Policy Policy
+}
+
+// Registers routes with their policy, this keeps all access rules in one place
+type RouterGroup struct {
+	group    *gin.RouterGroup
+	policies []RoutePolicy
+}
+
+func NewRouterGroup(group *gin.RouterGroup) *RouterGroup {
+	return &RouterGroup{group: group}
+}
+
+func (g *RouterGroup) Handle(method, path string, policy Policy, handlers ...gin.HandlerFunc) {
+	g.policies = append(g.policies, RoutePolicy{
+		Method: method,
+		Path:   g.group.BasePath() + path,
+		Policy: policy,
+	})
+
+	if policy.MinimumAuthState != AuthState0Guest || policy.ApiKeyScope != "" {
+		handlers = append([]gin.HandlerFunc{policy.Middleware()}, handlers...)
+	}
+	if policy.ApiKeyScope != "" {
+		handlers = append([]gin.HandlerFunc{apiKeyThrottleMiddleware}, handlers...)
+	}
+	if method == "ANY" {
+		g.group.Any(path, handlers...)
+	} else {
+		g.group.Handle(method, path, handlers...)
+	}
+}
+
+func (g *RouterGroup) GET(path string, policy Policy, handlers ...gin.HandlerFunc) {
+	g.Handle(http.MethodGet, path, policy, handlers...)
+}
+
+func (g *RouterGroup) POST(path string, policy Policy, handlers ...gin.HandlerFunc) {
+	g.Handle(http.MethodPost, path, policy, handlers...)
+}
+
+func (g *RouterGroup) PUT(path string, policy Policy, handlers ...gin.HandlerFunc) {
+	g.Handle(http.MethodPut, path, policy, handlers...)
+}
+
+func (g *RouterGroup) PATCH(path string, policy Policy, handlers ...gin.HandlerFunc) {
+	g.Handle(http.MethodPatch, path, policy, handlers...)
+}
+
+func (g *RouterGroup) DELETE(path string, policy Policy, handlers ...gin.HandlerFunc) {
+	g.Handle(http.MethodDelete, path, policy, handlers...)
+}
+
+func (g *RouterGroup) Any(path string, policy Policy, handlers ...gin.HandlerFunc) {
+	g.Handle("ANY", path, policy, handlers...)
+}
+
+func (g *RouterGroup) Policies() []RoutePolicy {
+	return g.policies
+}
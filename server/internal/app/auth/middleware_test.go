@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainUIDSourceReadJSON(t *testing.T) {
+	read := func(body string) (string, error) {
+		c := &gin.Context{
+			Request: &http.Request{
+				Body: io.NopCloser(bytes.NewBufferString(body)),
+			},
+		}
+		return ChainUIDFromJSON("chain_uid").read(c)
+	}
+
+	v, err := read(`{"chain_uid": "a"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", v)
+
+	// bound the same way by the controller
+	v, err = read(`{"Chain_UID": "a"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", v)
+
+	_, err = read(`{"chain_uid": "a", "CHAIN_UID": "b"}`)
+	assert.Error(t, err, "The same key in a different case must be refused")
+
+	v, err = read(``)
+	assert.NoError(t, err)
+	assert.Equal(t, "", v)
+}
@@ -0,0 +1,266 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/samber/lo"
+	"github.com/the-clothing-loop/website/server/internal/app"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOidcProviderNotFound  = errors.New("Login provider not found")
+	ErrOidcStateInvalid      = errors.New("Login request is invalid or has expired")
+	ErrOidcUserNotFound      = errors.New("Email is not yet registered")
+	ErrOidcEmailNotVerified  = errors.New("Email is not verified by the login provider")
+	errOidcNonceMismatch     = errors.New("Id token nonce does not match")
+	errOidcIDTokenNotPresent = errors.New("Login provider returned no id token")
+)
+
+// how long a user has to log in at the identity provider
+const oidcStateMaxAge = 10 * time.Minute
+
+// used for discovery, the code exchange and fetching the signing keys
+var oidcHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+type OidcProviderInfo struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+var oidcProviders = map[string]*oidcProvider{}
+var oidcProviderInfos = []OidcProviderInfo{}
+
+// Discovery is done on first use and cached,
+// so that an unavailable identity provider does not prevent the server from starting.
+type oidcProvider struct {
+	config app.ConfigOidcProvider
+
+	mu       sync.Mutex
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// Kept server side, only the state key is sent to the identity provider
+type oidcState struct {
+	ProviderID string
+	Nonce      string
+	Verifier   string
+	ChainUID   string
+}
+
+type oidcClaims struct {
+	Email         string   `json:"email"`
+	EmailVerified oidcBool `json:"email_verified"`
+}
+
+// Some identity providers send email_verified as a string
+type oidcBool bool
+
+func (b *oidcBool) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), `"`) {
+	case "true":
+		*b = true
+	case "false", "null":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean %s", data)
+	}
+	return nil
+}
+
+// Creates the providers of the oidc_providers config
+func OidcInit() {
+	providers := map[string]*oidcProvider{}
+	infos := []OidcProviderInfo{}
+	for _, p := range app.Config.OIDC_PROVIDERS {
+		providers[p.ID] = &oidcProvider{config: p}
+		infos = append(infos, OidcProviderInfo{ID: p.ID, Name: p.Name})
+	}
+
+	oidcProviders = providers
+	oidcProviderInfos = infos
+}
+
+func OidcProviderList() []OidcProviderInfo {
+	return oidcProviderInfos
+}
+
+func OidcRedirectURL(providerID string) string {
+	return fmt.Sprintf("%s/v2/login/oidc/%s/callback", strings.TrimSuffix(app.Config.SITE_BASE_URL_API, "/"), providerID)
+}
+
+func oidcContext(ctx context.Context) context.Context {
+	return oidc.ClientContext(ctx, oidcHTTPClient)
+}
+
+func (p *oidcProvider) discover(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.oauth2 != nil {
+		return p.oauth2, p.verifier, nil
+	}
+
+	// the signing keys are fetched later with this context, it must outlive the request
+	provider, err := oidc.NewProvider(oidcContext(context.Background()), p.config.Issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to discover issuer: %w", err)
+	}
+
+	p.oauth2 = &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  OidcRedirectURL(p.config.ID),
+		Scopes:       lo.Uniq(append([]string{oidc.ScopeOpenID, "email"}, p.config.Scopes...)),
+	}
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.config.ClientID})
+	return p.oauth2, p.verifier, nil
+}
+
+func oidcRandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Returns the url of the identity provider to redirect the user to
+func OidcAuthCodeURL(ctx context.Context, providerID, chainUID string) (string, error) {
+	provider, ok := oidcProviders[providerID]
+	if !ok {
+		return "", ErrOidcProviderNotFound
+	}
+	config, _, err := provider.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := oidcRandomString()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := oidcRandomString()
+	if err != nil {
+		return "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	app.Cache.Set("oidc_state_"+state, &oidcState{
+		ProviderID: providerID,
+		Nonce:      nonce,
+		Verifier:   verifier,
+		ChainUID:   chainUID,
+	}, oidcStateMaxAge)
+
+	return config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchanges the code of the callback and logs in the user the identity belongs to.
+// On the first login the identity is linked to the user with the same verified email.
+// Returns the chain UID that was passed to OidcAuthCodeURL.
+func OidcCallback(ctx context.Context, db *gorm.DB, providerID, stateKey, code string, sessionInfo SessionInfo) (*models.User, *Tokens, string, error) {
+	provider, ok := oidcProviders[providerID]
+	if !ok {
+		return nil, nil, "", ErrOidcProviderNotFound
+	}
+
+	// a state can only be used once
+	stateAny, found := app.Cache.Get("oidc_state_" + stateKey)
+	if !found {
+		return nil, nil, "", ErrOidcStateInvalid
+	}
+	app.Cache.Delete("oidc_state_" + stateKey)
+	state := stateAny.(*oidcState)
+	if state.ProviderID != providerID {
+		return nil, nil, "", ErrOidcStateInvalid
+	}
+
+	config, verifier, err := provider.discover(ctx)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	ctx = oidcContext(ctx)
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(state.Verifier))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, nil, "", errOidcIDTokenNotPresent
+	}
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if idToken.Nonce != state.Nonce {
+		return nil, nil, "", errOidcNonceMismatch
+	}
+
+	user, err := oidcUser(db, providerID, idToken)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	tokens, err := SessionLogin(db, user, sessionInfo)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	return user, tokens, state.ChainUID, nil
+}
+
+func oidcUser(db *gorm.DB, providerID string, idToken *oidc.IDToken) (*models.User, error) {
+	identity, err := models.UserOidcIdentityGet(db, providerID, idToken.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		user := &models.User{}
+		db.Raw(`SELECT * FROM users WHERE id = ? LIMIT 1`, identity.UserID).Scan(user)
+		if user.ID == 0 {
+			return nil, ErrOidcUserNotFound
+		}
+		return user, nil
+	}
+
+	claims := &oidcClaims{}
+	if err := idToken.Claims(claims); err != nil {
+		return nil, err
+	}
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrOidcEmailNotVerified
+	}
+	user, err := models.UserGetByEmail(db, claims.Email)
+	if err != nil {
+		return nil, ErrOidcUserNotFound
+	}
+
+	err = db.Create(&models.UserOidcIdentity{
+		UserID:   user.ID,
+		Provider: providerID,
+		Subject:  idToken.Subject,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// the identity provider has verified the email
+	if err := userSetEmailVerified(db, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
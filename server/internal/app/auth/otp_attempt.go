@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// after this many failed attempts a one time password can no longer be used
+const otpMaxFailedAttempts = 5
+
+// every this many failed attempts of an email the email is locked out,
+// each following lockout lasts twice as long
+const otpLockoutThreshold = 5
+const otpLockoutBase = 5 * time.Minute
+const otpLockoutMax = 24 * time.Hour
+
+type OtpLockedError struct {
+	LockedUntil time.Time
+	// Is true for the attempt that caused the lockout
+	IsNew bool
+}
+
+func (e *OtpLockedError) Error() string {
+	return fmt.Sprintf("Too many failed attempts, try again after %s", e.LockedUntil.Format(time.RFC3339))
+}
+
+func otpLockoutDuration(failedAttempts int) time.Duration {
+	d := otpLockoutBase
+	for i := otpLockoutThreshold; i < failedAttempts; i += otpLockoutThreshold {
+		d *= 2
+		if d >= otpLockoutMax {
+			return otpLockoutMax
+		}
+	}
+	return d
+}
+
+// Returns an error if the email is locked out
+func otpAttemptCheck(db *gorm.DB, email string) error {
+	attempt, err := models.OtpAttemptGetByEmail(db, email)
+	if err != nil {
+		return err
+	}
+	if attempt.IsLocked() {
+		return &OtpLockedError{LockedUntil: attempt.LockedUntil.Time}
+	}
+	return nil
+}
+
+// Counts a failed attempt for the email and all its one time passwords,
+// returns an OtpLockedError if the email is now locked out.
+func otpAttemptFailed(db *gorm.DB, email string) error {
+	err := db.Exec(`
+INSERT INTO otp_attempts (email, failed_attempts, updated_at)
+VALUES (?, 1, NOW())
+ON DUPLICATE KEY UPDATE failed_attempts = failed_attempts + 1, updated_at = NOW()
+	`, email).Error
+	if err != nil {
+		return err
+	}
+
+	db.Exec(`
+UPDATE user_tokens SET failed_attempts = failed_attempts + 1
+WHERE verified = FALSE AND user_id IN (SELECT id FROM users WHERE email = ?)
+	`, email)
+	db.Exec(`
+DELETE FROM user_tokens
+WHERE verified = FALSE AND failed_attempts >= ?
+	`, otpMaxFailedAttempts)
+
+	attempt, err := models.OtpAttemptGetByEmail(db, email)
+	if err != nil {
+		return err
+	}
+	if attempt.FailedAttempts%otpLockoutThreshold != 0 {
+		return nil
+	}
+
+	lockedUntil := time.Now().Add(otpLockoutDuration(attempt.FailedAttempts))
+	err = db.Exec(`UPDATE otp_attempts SET locked_until = ? WHERE id = ?`, lockedUntil, attempt.ID).Error
+	if err != nil {
+		return err
+	}
+	return &OtpLockedError{LockedUntil: lockedUntil, IsNew: true}
+}
+
+func otpAttemptReset(db *gorm.DB, email string) {
+	db.Exec(`DELETE FROM otp_attempts WHERE email = ?`, email)
+}
+
+// Removes failed attempts that are no longer locked out and have not been updated for a day
+func OtpAttemptDeleteOld(db *gorm.DB) {
+	db.Exec(`
+DELETE FROM otp_attempts
+WHERE (locked_until IS NULL OR locked_until < NOW())
+	AND updated_at < ADDDATE(NOW(), INTERVAL -1 DAY)
+	`)
+}
@@ -0,0 +1,15 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOtpLockoutDuration(t *testing.T) {
+	assert.Equal(t, 5*time.Minute, otpLockoutDuration(5))
+	assert.Equal(t, 10*time.Minute, otpLockoutDuration(10))
+	assert.Equal(t, 20*time.Minute, otpLockoutDuration(15))
+	assert.Equal(t, otpLockoutMax, otpLockoutDuration(100))
+}
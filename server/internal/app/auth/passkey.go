@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	uuid "github.com/satori/go.uuid"
+	"github.com/the-clothing-loop/website/server/internal/app"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPasskeyChallengeInvalid = errors.New("Passkey challenge is invalid or has expired")
+	errPasskeyCloned           = errors.New("Passkey sign count went backwards, the authenticator may be cloned")
+)
+
+// how long the browser has to complete a passkey ceremony
+const passkeyChallengeMaxAge = 5 * time.Minute
+
+// Wraps a user and its passkeys for the webauthn library
+type passkeyUser struct {
+	user     *models.User
+	passkeys []models.UserPasskey
+}
+
+func (u *passkeyUser) WebAuthnID() []byte          { return []byte(u.user.UID) }
+func (u *passkeyUser) WebAuthnName() string        { return u.user.Email.String }
+func (u *passkeyUser) WebAuthnDisplayName() string { return u.user.Name }
+
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := []webauthn.Credential{}
+	for _, p := range u.passkeys {
+		id, err := base64.RawURLEncoding.DecodeString(p.CredentialID)
+		if err != nil {
+			continue
+		}
+		credentials = append(credentials, webauthn.Credential{
+			ID:        id,
+			PublicKey: p.PublicKey,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: p.BackupEligible,
+			},
+			Authenticator: webauthn.Authenticator{
+				SignCount: p.SignCount,
+			},
+		})
+	}
+	return credentials
+}
+
+// Passkeys are registered for the domain of the website
+func passkeyWebAuthn() (*webauthn.WebAuthn, error) {
+	config := &webauthn.Config{
+		RPID:          app.Config.WEBAUTHN_RP_ID,
+		RPDisplayName: "The Clothing Loop",
+		// user verification is preferred but not required, the passkey itself is possession of the device
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			UserVerification: protocol.VerificationPreferred,
+		},
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        webauthn.TimeoutConfig{Enforce: true, Timeout: passkeyChallengeMaxAge},
+			Registration: webauthn.TimeoutConfig{Enforce: true, Timeout: passkeyChallengeMaxAge},
+		},
+	}
+	if u, err := url.Parse(app.Config.SITE_BASE_URL_FE); err == nil {
+		config.RPOrigins = []string{u.Scheme + "://" + u.Host}
+		if config.RPID == "" {
+			config.RPID = u.Hostname()
+		}
+	}
+	return webauthn.New(config)
+}
+
+func passkeySessionSet(session *webauthn.SessionData) {
+	app.Cache.Set("passkey_session_"+session.Challenge, session, passkeyChallengeMaxAge)
+}
+
+// A session can only be used once
+func passkeySessionUse(challenge string) (*webauthn.SessionData, error) {
+	v, found := app.Cache.Get("passkey_session_" + challenge)
+	if !found {
+		return nil, ErrPasskeyChallengeInvalid
+	}
+	app.Cache.Delete("passkey_session_" + challenge)
+	return v.(*webauthn.SessionData), nil
+}
+
+// Returns the options for navigator.credentials.create()
+func PasskeyRegisterBegin(db *gorm.DB, user *models.User) (*protocol.PublicKeyCredentialCreationOptions, error) {
+	passkeys, err := models.UserPasskeyGetAllByUser(db, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	w, err := passkeyWebAuthn()
+	if err != nil {
+		return nil, err
+	}
+
+	pu := &passkeyUser{user: user, passkeys: passkeys}
+	exclusions := []protocol.CredentialDescriptor{}
+	for _, c := range pu.WebAuthnCredentials() {
+		exclusions = append(exclusions, c.Descriptor())
+	}
+	creation, session, err := w.BeginRegistration(pu,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithConveyancePreference(protocol.PreferNoAttestation),
+	)
+	if err != nil {
+		return nil, err
+	}
+	passkeySessionSet(session)
+
+	return &creation.Response, nil
+}
+
+// The credential is the PublicKeyCredential returned by navigator.credentials.create() as json
+func PasskeyRegisterFinish(db *gorm.DB, user *models.User, name string, credentialJSON []byte) (*models.UserPasskey, error) {
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(credentialJSON)
+	if err != nil {
+		return nil, err
+	}
+	session, err := passkeySessionUse(parsed.Response.CollectedClientData.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	w, err := passkeyWebAuthn()
+	if err != nil {
+		return nil, err
+	}
+
+	// also checks that the session was started by this user
+	credential, err := w.CreateCredential(&passkeyUser{user: user}, *session, parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	passkey := &models.UserPasskey{
+		UID:            uuid.NewV4().String(),
+		UserID:         user.ID,
+		Name:           name,
+		CredentialID:   base64.RawURLEncoding.EncodeToString(credential.ID),
+		PublicKey:      credential.PublicKey,
+		SignCount:      credential.Authenticator.SignCount,
+		BackupEligible: credential.Flags.BackupEligible,
+	}
+	if err := db.Create(passkey).Error; err != nil {
+		return nil, err
+	}
+	return passkey, nil
+}
+
+// Passkeys are discoverable, so no credentials are listed and the user does not need to enter an email
+func PasskeyLoginBegin() (*protocol.PublicKeyCredentialRequestOptions, error) {
+	w, err := passkeyWebAuthn()
+	if err != nil {
+		return nil, err
+	}
+
+	assertion, session, err := w.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationPreferred))
+	if err != nil {
+		return nil, err
+	}
+	passkeySessionSet(session)
+
+	return &assertion.Response, nil
+}
+
+// Verifies the PublicKeyCredential returned by navigator.credentials.get() as json
+// and logs in the user the passkey belongs to
+func PasskeyLoginFinish(db *gorm.DB, credentialJSON []byte, sessionInfo SessionInfo) (*models.User, *Tokens, error) {
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(credentialJSON)
+	if err != nil {
+		return nil, nil, err
+	}
+	session, err := passkeySessionUse(parsed.Response.CollectedClientData.Challenge)
+	if err != nil {
+		return nil, nil, err
+	}
+	w, err := passkeyWebAuthn()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var passkey *models.UserPasskey
+	var user *models.User
+	credential, err := w.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		passkey, err = models.UserPasskeyGetByCredentialID(db, base64.RawURLEncoding.EncodeToString(rawID))
+		if err != nil {
+			return nil, err
+		}
+		user = &models.User{}
+		db.Raw(`SELECT * FROM users WHERE id = ? LIMIT 1`, passkey.UserID).Scan(user)
+		if user.ID == 0 || !bytes.Equal(userHandle, []byte(user.UID)) {
+			return nil, models.ErrUserNotFound
+		}
+		return &passkeyUser{user: user, passkeys: []models.UserPasskey{*passkey}}, nil
+	}, *session, parsed)
+	if err != nil {
+		return nil, nil, err
+	}
+	if credential.Authenticator.CloneWarning {
+		return nil, nil, errPasskeyCloned
+	}
+	if err := passkey.UpdateSignCount(db, credential.Authenticator.SignCount); err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := SessionLogin(db, user, sessionInfo)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, tokens, nil
+}
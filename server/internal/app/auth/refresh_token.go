@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/the-clothing-loop/website/server/internal/app/goscope"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// an access token is short lived, a refresh token is used to receive a new one
+const accessTokenMaxAge = 15 * time.Minute
+const refreshTokenMaxAge = sessionMaxAge
+
+// concurrent requests, like two open tabs, may use the same refresh token at the same time
+const refreshTokenReuseGracePeriod = 30 * time.Second
+
+var ErrRefreshTokenReused = errors.New("Refresh token has already been used")
+
+type Tokens struct {
+	Access  string `json:"token"`
+	Refresh string `json:"refresh_token"`
+}
+
+// Generates an access token and a new refresh token of the same session
+func TokensGenerate(db *gorm.DB, user *models.User, session *models.UserSession) (*Tokens, error) {
+	accessToken, err := JwtGenerate(user, session)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := refreshTokenCreate(db, user.ID, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{
+		Access:  accessToken,
+		Refresh: refreshToken,
+	}, nil
+}
+
+// Exchanges a refresh token for a new pair of tokens.
+//
+// When a refresh token is used more than once after the grace period it is likely stolen,
+// in that case the whole session and all its refresh tokens are revoked.
+func RefreshTokenRotate(db *gorm.DB, refreshToken string) (*models.User, *Tokens, error) {
+	hash := models.TokenHash(refreshToken)
+	rt, err := models.UserRefreshTokenGetByHash(db, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	session := &models.UserSession{}
+	db.Raw(`SELECT * FROM user_sessions WHERE id = ? LIMIT 1`, rt.UserSessionID).Scan(session)
+	if session.ID == 0 || session.IsRevoked() {
+		return nil, nil, fmt.Errorf("Session is revoked")
+	}
+
+	isFirstUse := false
+	if !rt.IsUsed() {
+		isFirstUse, err = rt.MarkUsed(db)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	isWithinGracePeriod := false
+	if !isFirstUse {
+		// read when the token was first used, a concurrent request may have just used it
+		rt, err = models.UserRefreshTokenGetByHash(db, hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		isWithinGracePeriod = rt.UsedAt.Valid && rt.UsedAt.Time.After(time.Now().Add(-refreshTokenReuseGracePeriod))
+	}
+	if !isFirstUse && !isWithinGracePeriod {
+		goscope.Log.Warningf("Refresh token reused, revoking session %s", session.UID)
+		session.Revoke(db)
+		models.UserRefreshTokenDeleteAllBySession(db, session.ID)
+		return nil, nil, ErrRefreshTokenReused
+	}
+
+	if rt.ExpiresAt.Before(time.Now()) {
+		return nil, nil, fmt.Errorf("Refresh token expired")
+	}
+
+	user := &models.User{}
+	db.Raw(`SELECT * FROM users WHERE id = ? LIMIT 1`, rt.UserID).Scan(user)
+	if user.ID == 0 {
+		return nil, nil, models.ErrUserNotFound
+	}
+
+	tokens, err := TokensGenerate(db, user, session)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, tokens, nil
+}
+
+func refreshTokenCreate(db *gorm.DB, userID, userSessionID uint) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	err := db.Create(&models.UserRefreshToken{
+		TokenHash:     models.TokenHash(token),
+		UserID:        userID,
+		UserSessionID: userSessionID,
+		ExpiresAt:     time.Now().Add(refreshTokenMaxAge),
+	}).Error
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func RefreshTokenDeleteOld(db *gorm.DB) {
+	db.Exec(`
+DELETE FROM user_refresh_tokens
+WHERE expires_at < NOW()
+	OR user_session_id NOT IN (SELECT id FROM user_sessions)
+	`)
+}
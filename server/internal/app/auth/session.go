@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"gopkg.in/guregu/null.v3/zero"
+	"gorm.io/gorm"
+)
+
+const sessionContextKey = "AuthSession"
+
+// how long a session is kept after it was last seen, same as the refresh token expiry
+const sessionMaxAge = 52 * 7 * 24 * time.Hour
+
+// Describes the device a session is created for
+type SessionInfo struct {
+	DeviceName string
+	UserAgent  string
+	IPAddress  string
+}
+
+// The app sends the device name in the X-Device-Name header,
+// the website falls back to the user agent.
+func SessionInfoFromRequest(c *gin.Context) SessionInfo {
+	return SessionInfo{
+		DeviceName: c.GetHeader("X-Device-Name"),
+		UserAgent:  c.Request.UserAgent(),
+		IPAddress:  c.ClientIP(),
+	}
+}
+
+func SessionCreate(db *gorm.DB, userID uint, info SessionInfo) (*models.UserSession, error) {
+	return sessionCreate(db, userID, info, zero.String{})
+}
+
+// Creates the session of a token issued before sessions existed,
+// AuthenticateToken returns this session on every next use of the same token.
+func SessionCreateLegacy(db *gorm.DB, userID uint, token string, info SessionInfo) (*models.UserSession, error) {
+	return sessionCreate(db, userID, info, zero.StringFrom(models.TokenHash(token)))
+}
+
+func sessionCreate(db *gorm.DB, userID uint, info SessionInfo, legacyTokenHash zero.String) (*models.UserSession, error) {
+	session := &models.UserSession{
+		UID:             uuid.NewV4().String(),
+		UserID:          userID,
+		DeviceName:      info.DeviceName,
+		UserAgent:       info.UserAgent,
+		IPAddress:       info.IPAddress,
+		LastSeenAt:      time.Now(),
+		LegacyTokenHash: legacyTokenHash,
+	}
+	if err := db.Create(session).Error; err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// Creates a new session for the user and returns the first tokens of it
+func SessionLogin(db *gorm.DB, user *models.User, info SessionInfo) (*Tokens, error) {
+	session, err := SessionCreate(db, user.ID, info)
+	if err != nil {
+		return nil, err
+	}
+
+	return TokensGenerate(db, user, session)
+}
+
+// Returns the session of the request, this is set by Authenticate
+func SessionGet(c *gin.Context) *models.UserSession {
+	v, ok := c.Get(sessionContextKey)
+	if !ok {
+		return nil
+	}
+
+	return v.(*models.UserSession)
+}
+
+func sessionSet(c *gin.Context, session *models.UserSession) {
+	c.Set(sessionContextKey, session)
+}
+
+// Revoked sessions of old tokens are kept until they expire, so that the old token stays revoked
+func SessionDeleteOld(db *gorm.DB) {
+	db.Exec(`
+DELETE FROM user_sessions
+WHERE (revoked_at IS NOT NULL AND legacy_token_hash IS NULL)
+	OR last_seen_at < ?
+	`, time.Now().Add(-sessionMaxAge))
+}
@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"strings"
 	"time"
@@ -9,7 +11,6 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/the-clothing-loop/website/server/internal/app"
 	"github.com/the-clothing-loop/website/server/internal/models"
 	"gorm.io/gorm"
 )
@@ -19,50 +20,78 @@ var validate = validator.New()
 type MyJwtClaims struct {
 	jwt.RegisteredClaims
 	Pepper int `json:"pepper"`
+	// Is true when a root admin impersonates the user
+	Impersonated bool `json:"impersonated,omitempty"`
 }
 
-func TokenReadFromRequest(c *gin.Context) (string, bool) {
+const (
+	TokenSchemeBearer = "Bearer"
+	TokenSchemeApiKey = "ApiKey"
+)
+
+// Returns the token of the cookie or authorization header with its scheme,
+// either a user jwt (Bearer) or a partner api key (ApiKey).
+func TokenReadFromRequest(c *gin.Context) (token string, scheme string, ok bool) {
 	// read cookie first
-	token, ok := cookieRead(c)
+	token, ok = cookieRead(c)
 	if ok {
-		return token, true
+		return token, TokenSchemeBearer, true
 	}
 
 	// if no cookie set then read authorization header
-	prefix := "Bearer "
 	a := c.Request.Header.Get("Authorization")
-	_, token, ok = strings.Cut(a, prefix)
-	if ok {
-		return token, true
+	for _, scheme := range []string{TokenSchemeBearer, TokenSchemeApiKey} {
+		token, ok = strings.CutPrefix(a, scheme+" ")
+		if ok {
+			return token, scheme, true
+		}
 	}
 
 	// none found
-	return "", false
+	return "", "", false
 }
 
 func OtpCreate(db *gorm.DB, userID uint) (string, error) {
+	userToken, err := otpCreate(db, userID, "")
+	if err != nil {
+		return "", err
+	}
+	return userToken.Token, nil
+}
+
+func otpCreate(db *gorm.DB, userID uint, chainUID string) (*models.UserToken, error) {
 	// create token
 	tokenB, err := atoll.NewPassword(8, []atoll.Level{atoll.Digit})
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
 	}
-	token := string(tokenB)
 
 	// set token in database
-	res := db.Create(&models.UserToken{
-		Token:    token,
-		Verified: false,
-		UserID:   userID,
-	})
-	if res.Error != nil {
-		return "", res.Error
+	userToken := &models.UserToken{
+		Token:     string(tokenB),
+		Verified:  false,
+		UserID:    userID,
+		LinkNonce: hex.EncodeToString(nonce),
+		ChainUID:  chainUID,
+	}
+	if err := db.Create(userToken).Error; err != nil {
+		return nil, err
 	}
 
-	return token, nil
+	return userToken, nil
 }
 
 // Returns the user before it was verified
-func OtpVerify(db *gorm.DB, userEmail, otp string) (*models.User, string, error) {
+func OtpVerify(db *gorm.DB, userEmail, otp string, sessionInfo SessionInfo) (*models.User, *Tokens, error) {
+	if err := otpAttemptCheck(db, userEmail); err != nil {
+		return nil, nil, err
+	}
+
 	// check if otp is valid
 	userToken := &models.UserToken{}
 	db.Raw(`
@@ -75,25 +104,53 @@ WHERE ut.token = ?
 LIMIT 1
 	`, otp, userEmail).Scan(userToken)
 	if userToken.ID == 0 {
-		return nil, "", fmt.Errorf("User token not found in database")
+		if err := otpAttemptFailed(db, userEmail); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("User token not found in database")
 	}
 
-	db.Delete(userToken)
+	otpAttemptReset(db, userEmail)
+
+	return otpLogin(db, userToken, sessionInfo)
+}
+
+// Uses up the one time password and logs in the user
+func otpLogin(db *gorm.DB, userToken *models.UserToken, sessionInfo SessionInfo) (*models.User, *Tokens, error) {
+	// a concurrent request may have used the token already
+	res := db.Exec(`DELETE FROM user_tokens WHERE id = ?`, userToken.ID)
+	if res.Error != nil || res.RowsAffected != 1 {
+		return nil, nil, fmt.Errorf("User token is already used")
+	}
 
 	user := &models.User{}
 	db.Raw(`SELECT * FROM users WHERE id = ? LIMIT 1`, userToken.UserID).Scan(user)
 	if user.ID == 0 {
-		return nil, "", fmt.Errorf("User not found in database")
+		return nil, nil, fmt.Errorf("User not found in database")
+	}
+
+	if err := userSetEmailVerified(db, user); err != nil {
+		return nil, nil, err
+	}
+
+	// generate new jwt
+	tokenString, err := SessionLogin(db, user, sessionInfo)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// setup user as verified
+	return user, tokenString, nil
+}
+
+// Sets up the user as verified and allows sending newsletters to the email
+func userSetEmailVerified(db *gorm.DB, user *models.User) error {
 	if !user.IsEmailVerified {
 		if db.Exec(`
 UPDATE users
 SET is_email_verified = TRUE
 WHERE id = ?
 	`, user.ID).Error != nil {
-			return nil, "", fmt.Errorf("Unable to update user to verified email")
+			return fmt.Errorf("Unable to update user to verified email")
 		}
 	}
 
@@ -103,50 +160,80 @@ UPDATE newsletters
 SET verified = TRUE
 WHERE email = ?
 	`, user.Email.String); res.Error != nil {
-			return nil, "", fmt.Errorf("Unable to allow sending newsletters to user")
+			return fmt.Errorf("Unable to allow sending newsletters to user")
 		}
 	}
 
-	// generate new jwt
-	tokenString, err := JwtGenerate(user)
-	if err != nil {
-		return nil, "", err
-	}
-
-	return user, tokenString, nil
+	return nil
 }
 
-func JwtGenerate(user *models.User) (string, error) {
+// The session UID is stored as the jwt ID,
+// a token of an impersonation session expires with the session.
+func JwtGenerate(user *models.User, session *models.UserSession) (string, error) {
+	expiresAt := time.Now().Add(accessTokenMaxAge)
+	if session.IsImpersonated() {
+		expiresAt = session.ExpiresAt.Time
+	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, MyJwtClaims{
-		Pepper: user.JwtTokenPepper,
+		Pepper:       user.JwtTokenPepper,
+		Impersonated: session.IsImpersonated(),
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        session.UID,
 			Issuer:    user.UID,
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(52 * 7 * 24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	})
 
-	tokenString, err := token.SignedString([]byte(app.Config.JWT_SECRET))
+	key, err := jwtKeyActive()
+	if err != nil {
+		return "", err
+	}
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+
+	tokenString, err := token.SignedString([]byte(key.Secret))
 	if err != nil {
 		return "", err
 	}
 	return tokenString, nil
 }
 
-func AuthenticateToken(db *gorm.DB, tokenString string) (*models.User, bool, error) {
+// Returns the session used, this is nil if an old token is used for the first time
+func AuthenticateToken(db *gorm.DB, tokenString string) (*models.User, *models.UserSession, bool, error) {
 	usedOldToken := false
+	var session *models.UserSession
 	user, err := authenticateOldToken(db, tokenString)
 	if err == nil {
 		usedOldToken = true
 	} else {
-		user, err = authenticateJwt(db, tokenString)
+		user, session, err = authenticateJwt(db, tokenString)
 		if err != nil {
-			return nil, false, err
+			return nil, nil, false, err
 		}
+		// tokens issued before sessions existed must be replaced
+		usedOldToken = session == nil
 	}
 
-	shouldUpdateLastSignedInAt := true
-	if user.LastSignedInAt.Valid {
+	// clients that keep sending an old token use the session created for it the first time
+	if usedOldToken {
+		session, err = models.UserSessionGetByLegacyTokenHash(db, user.ID, models.TokenHash(tokenString))
+		if err == nil {
+			if session.IsRevoked() {
+				return nil, nil, false, fmt.Errorf("Session is revoked (%s)", session.UID)
+			}
+			usedOldToken = false
+		} else if err == models.ErrUserSessionNotFound {
+			session = nil
+		} else {
+			return nil, nil, false, err
+		}
+	}
+
+	// an impersonating root admin is not the user signing in
+	shouldUpdateLastSignedInAt := session == nil || !session.IsImpersonated()
+	if shouldUpdateLastSignedInAt && user.LastSignedInAt.Valid {
 		// if user last signed in earlier than an hour ago, we should update last signed in value
 		shouldUpdateLastSignedInAt = user.LastSignedInAt.Time.Before(time.Now().Add(time.Duration(-1 * time.Hour)))
 	}
@@ -158,33 +245,49 @@ WHERE users.id = ?
 	`, user.ID)
 	}
 
-	return user, usedOldToken, nil
+	return user, session, usedOldToken, nil
 }
 
-func authenticateJwt(db *gorm.DB, tokenString string) (*models.User, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &MyJwtClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(app.Config.JWT_SECRET), nil
-	})
+func authenticateJwt(db *gorm.DB, tokenString string) (*models.User, *models.UserSession, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &MyJwtClaims{}, jwtKeyFunc)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	claims, ok := token.Claims.(*MyJwtClaims)
 	if !ok {
-		return nil, fmt.Errorf("invalid claims")
+		return nil, nil, fmt.Errorf("invalid claims")
 	}
 
 	user := &models.User{}
 	err = db.Raw(`SELECT * FROM users WHERE uid = ? LIMIT 1`, claims.Issuer).Scan(user).Error
 	if err != nil || user.ID == 0 {
 		fmt.Print(err)
-		return nil, fmt.Errorf("Unable to find user in database (%s)", claims.Issuer)
+		return nil, nil, fmt.Errorf("Unable to find user in database (%s)", claims.Issuer)
 	}
 
 	if user.JwtTokenPepper != claims.Pepper {
-		return nil, fmt.Errorf("pepper incorrect: %d vs %d\n", user.JwtTokenPepper, claims.Pepper)
+		return nil, nil, fmt.Errorf("pepper incorrect: %d vs %d\n", user.JwtTokenPepper, claims.Pepper)
 	}
 
-	return user, nil
+	if claims.ID == "" {
+		if claims.Impersonated {
+			return nil, nil, fmt.Errorf("Impersonation token has no session")
+		}
+		return user, nil, nil
+	}
+
+	session, err := models.UserSessionGetByUID(db, claims.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if session.UserID != user.ID || session.IsRevoked() {
+		return nil, nil, fmt.Errorf("Session is revoked (%s)", claims.ID)
+	}
+	if session.IsImpersonated() != claims.Impersonated || session.IsExpired() {
+		return nil, nil, fmt.Errorf("Session is expired (%s)", claims.ID)
+	}
+
+	return user, session, nil
 }
 func authenticateOldToken(db *gorm.DB, token string) (*models.User, error) {
 	if len(token) != 36 {
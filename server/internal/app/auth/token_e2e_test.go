@@ -3,9 +3,13 @@
 package auth_test
 
 import (
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/the-clothing-loop/website/server/internal/app"
 	"github.com/the-clothing-loop/website/server/internal/app/auth"
 	"github.com/the-clothing-loop/website/server/internal/models"
 	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
@@ -36,16 +40,17 @@ LIMIT 1
 	assert.Equalf(t, user.ID, userToken.UserID, "New token (%s) not found in search", userToken)
 
 	// ensure unverified token is not usable for authenticate
-	_, _, err = auth.AuthenticateToken(db, token)
+	_, _, _, err = auth.AuthenticateToken(db, token)
 	assert.NotNilf(t, err, "Unverified token (%s) should not be useable", token)
 
 	// verify token
-	_, newToken, err := auth.OtpVerify(db, user.Email.String, token)
+	_, tokens, err := auth.OtpVerify(db, user.Email.String, token, auth.SessionInfo{DeviceName: "test"})
 	assert.Nil(t, err, "Token should pass verification (%s) %v", token, err)
 
 	// ensure verified token is usable for authenticate
-	_, _, err = auth.AuthenticateToken(db, newToken)
+	_, session, _, err := auth.AuthenticateToken(db, tokens.Access)
 	assert.Nil(t, err, "Verified token should be useable (%s) %v", token, err)
+	assert.NotNil(t, session, "Verified token should reference a session")
 
 	// check that user token is removed
 	userTokens := []models.UserToken{}
@@ -53,6 +58,176 @@ LIMIT 1
 SELECT *
 FROM user_tokens
 WHERE token = ?
-	`, newToken).Scan(&userTokens)
+	`, tokens.Access).Scan(&userTokens)
 	assert.Equal(t, 0, len(userTokens), "user token exists (%v)", userTokens)
 }
+
+func TestSessionRevoke(t *testing.T) {
+	_, user, token := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+
+	// a second device of the same user
+	otherTokens, err := auth.SessionLogin(db, user, auth.SessionInfo{DeviceName: "tablet"})
+	assert.Nil(t, err)
+	otherToken := otherTokens.Access
+
+	_, session, _, err := auth.AuthenticateToken(db, otherToken)
+	assert.Nil(t, err)
+	assert.NotNil(t, session)
+	assert.Equal(t, "tablet", session.DeviceName)
+
+	err = session.Revoke(db)
+	assert.Nil(t, err)
+
+	_, _, _, err = auth.AuthenticateToken(db, otherToken)
+	assert.NotNil(t, err, "Token of a revoked session should not be useable")
+
+	_, _, _, err = auth.AuthenticateToken(db, token)
+	assert.Nil(t, err, "Token of a different session should still be useable")
+}
+
+func TestLegacyTokenSession(t *testing.T) {
+	_, user, _ := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+
+	// a token issued before sessions existed, it has no jwt ID
+	secret := ""
+	for _, k := range app.JwtKeys {
+		if k.ID == "" {
+			secret = k.Secret
+		}
+	}
+	legacyToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.MyJwtClaims{
+		Pepper: user.JwtTokenPepper,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    user.UID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}).SignedString([]byte(secret))
+	assert.Nil(t, err)
+
+	_, session, usedOldToken, err := auth.AuthenticateToken(db, legacyToken)
+	assert.Nil(t, err)
+	assert.True(t, usedOldToken)
+	assert.Nil(t, session)
+
+	created, err := auth.SessionCreateLegacy(db, user.ID, legacyToken, auth.SessionInfo{DeviceName: "phone"})
+	assert.Nil(t, err)
+
+	// every next use of the old token reuses the same session
+	_, session, usedOldToken, err = auth.AuthenticateToken(db, legacyToken)
+	assert.Nil(t, err)
+	assert.False(t, usedOldToken)
+	assert.Equal(t, created.ID, session.ID)
+
+	err = session.Revoke(db)
+	assert.Nil(t, err)
+
+	_, _, _, err = auth.AuthenticateToken(db, legacyToken)
+	assert.NotNil(t, err, "Old token of a revoked session should not be useable")
+}
+
+func TestRefreshTokenRotate(t *testing.T) {
+	_, user, _ := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+
+	tokens, err := auth.SessionLogin(db, user, auth.SessionInfo{DeviceName: "phone"})
+	assert.Nil(t, err)
+
+	// first use rotates the refresh token
+	_, rotated, err := auth.RefreshTokenRotate(db, tokens.Refresh)
+	assert.Nil(t, err)
+	assert.NotEqual(t, tokens.Refresh, rotated.Refresh)
+
+	_, _, _, err = auth.AuthenticateToken(db, rotated.Access)
+	assert.Nil(t, err, "Access token of a rotated refresh token should be useable")
+
+	// reuse right away, like a second tab refreshing at the same time, is allowed
+	_, concurrent, err := auth.RefreshTokenRotate(db, tokens.Refresh)
+	assert.Nil(t, err)
+	assert.NotEqual(t, rotated.Refresh, concurrent.Refresh)
+
+	// reuse of the old refresh token after the grace period revokes the whole family
+	db.Exec(`UPDATE user_refresh_tokens SET used_at = ADDDATE(NOW(), INTERVAL -1 HOUR) WHERE user_id = ? AND used_at IS NOT NULL`, user.ID)
+	_, _, err = auth.RefreshTokenRotate(db, tokens.Refresh)
+	assert.ErrorIs(t, err, auth.ErrRefreshTokenReused)
+
+	_, _, err = auth.RefreshTokenRotate(db, rotated.Refresh)
+	assert.NotNil(t, err, "Refresh token of a revoked family should not be useable")
+
+	_, _, _, err = auth.AuthenticateToken(db, rotated.Access)
+	assert.NotNil(t, err, "Access token of a revoked family should not be useable")
+}
+
+func TestOtpBruteForceLockout(t *testing.T) {
+	_, user, _ := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM otp_attempts WHERE email = ?`, user.Email.String)
+	})
+
+	token, err := auth.OtpCreate(db, user.ID)
+	assert.NoError(t, err)
+
+	for i := 1; i <= 5; i++ {
+		_, _, err = auth.OtpVerify(db, user.Email.String, "00000000", auth.SessionInfo{})
+		assert.Error(t, err)
+
+		lockedErr := &auth.OtpLockedError{}
+		isLocked := errors.As(err, &lockedErr)
+		if i < 5 {
+			assert.Falsef(t, isLocked, "attempt %d should not lock the email", i)
+		} else {
+			assert.True(t, isLocked, "fifth attempt should lock the email")
+			assert.True(t, lockedErr.IsNew)
+		}
+	}
+
+	// the one time password is invalidated
+	count := -1
+	db.Raw(`SELECT COUNT(*) FROM user_tokens WHERE token = ?`, token).Scan(&count)
+	assert.Equal(t, 0, count)
+
+	// a new one time password is refused while locked out
+	token, err = auth.OtpCreate(db, user.ID)
+	assert.NoError(t, err)
+	_, _, err = auth.OtpVerify(db, user.Email.String, token, auth.SessionInfo{})
+	lockedErr := &auth.OtpLockedError{}
+	assert.True(t, errors.As(err, &lockedErr))
+	assert.False(t, lockedErr.IsNew)
+
+	// after the lockout the one time password works and resets the counter
+	db.Exec(`UPDATE otp_attempts SET locked_until = NULL WHERE email = ?`, user.Email.String)
+	_, _, err = auth.OtpVerify(db, user.Email.String, token, auth.SessionInfo{})
+	assert.NoError(t, err)
+
+	attempt, err := models.OtpAttemptGetByEmail(db, user.Email.String)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(0), attempt.ID)
+}
+
+func TestMagicLink(t *testing.T) {
+	chain, user, _ := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+
+	link, err := auth.MagicLinkCreate(db, user.ID, chain.UID)
+	assert.NoError(t, err)
+	assert.NotContains(t, link.Token, user.Email.String)
+
+	// a tampered token is refused
+	_, _, _, err = auth.MagicLinkVerify(db, link.Token+"a", auth.SessionInfo{})
+	assert.Error(t, err)
+
+	resultUser, tokens, chainUID, err := auth.MagicLinkVerify(db, link.Token, auth.SessionInfo{})
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, resultUser.ID)
+	assert.Equal(t, chain.UID, chainUID)
+
+	// single use
+	_, _, _, err = auth.MagicLinkVerify(db, link.Token, auth.SessionInfo{})
+	assert.Error(t, err)
+
+	// an access token is not a magic link
+	_, _, _, err = auth.MagicLinkVerify(db, tokens.Access, auth.SessionInfo{})
+	assert.Error(t, err)
+
+	// the one time password of the link is used up as well
+	_, _, err = auth.OtpVerify(db, user.Email.String, link.Otp, auth.SessionInfo{})
+	assert.Error(t, err)
+	db.Exec(`DELETE FROM otp_attempts WHERE email = ?`, user.Email.String)
+}
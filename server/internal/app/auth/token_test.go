@@ -21,7 +21,23 @@ func TestReadFromRequest(t *testing.T) {
 	}
 	c.Request.Header.Set("Authorization", "Bearer "+token)
 
-	result, ok := auth.TokenReadFromRequest(c)
+	result, scheme, ok := auth.TokenReadFromRequest(c)
 	assert.True(t, ok)
+	assert.Equal(t, auth.TokenSchemeBearer, scheme)
 	assert.Equal(t, result, token)
 }
+
+func TestReadApiKeyFromRequest(t *testing.T) {
+	key := faker.UUID().V4()
+	c := &gin.Context{
+		Request: &http.Request{
+			Header: http.Header{},
+		},
+	}
+	c.Request.Header.Set("Authorization", "ApiKey "+key)
+
+	result, scheme, ok := auth.TokenReadFromRequest(c)
+	assert.True(t, ok)
+	assert.Equal(t, auth.TokenSchemeApiKey, scheme)
+	assert.Equal(t, result, key)
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"time"
 
 	"github.com/stripe/stripe-go/v73"
 	"gopkg.in/yaml.v3"
@@ -16,34 +18,61 @@ const (
 	EnvEnumDevelopment = "development"
 )
 
+// A key used to sign and verify jwts, identified by the kid header
+type ConfigJwtKey struct {
+	ID     string `yaml:"id"`
+	Secret string `yaml:"secret"`
+	// Only one key may be active, this key is used to sign new jwts
+	Active bool `yaml:"active"`
+	// A retired key is accepted until the grace period has passed
+	RetiredAt *time.Time `yaml:"retired_at"`
+}
+
+// An OpenID Connect identity provider users can log in with,
+// the redirect url to register at the provider is {site_base_url_api}/v2/login/oidc/{id}/callback
+type ConfigOidcProvider struct {
+	// Used in the login urls
+	ID string `yaml:"id"`
+	// Shown on the login button
+	Name         string   `yaml:"name"`
+	Issuer       string   `yaml:"issuer"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	Scopes       []string `yaml:"scopes"`
+}
+
 var Config struct {
-	ENV                     string `yaml:"-"`
-	HOST                    string `yaml:"host"`
-	PORT                    int    `yaml:"port"`
-	SITE_BASE_URL_API       string `yaml:"site_base_url_api"`
-	SITE_BASE_URL_FE        string `yaml:"site_base_url_fe"`
-	COOKIE_DOMAIN           string `yaml:"cookie_domain"`
-	COOKIE_HTTPS_ONLY       bool   `yaml:"cookie_https_only"`
-	JWT_SECRET              string `yaml:"jwt_secret"`
-	STRIPE_SECRET_KEY       string `yaml:"stripe_secret_key"`
-	STRIPE_WEBHOOK          string `yaml:"stripe_webhook"`
-	DB_HOST                 string `yaml:"db_host"`
-	DB_PORT                 int    `yaml:"db_port"`
-	DB_NAME                 string `yaml:"db_name"`
-	DB_USER                 string `yaml:"db_user"`
-	DB_PASS                 string `yaml:"db_pass"`
-	SMTP_HOST               string `yaml:"smtp_host"`
-	SMTP_PORT               int    `yaml:"smtp_port"`
-	SMTP_SENDER             string `yaml:"smtp_sender"`
-	SMTP_USER               string `yaml:"smtp_user"`
-	SMTP_PASS               string `yaml:"smtp_pass"`
-	GOSCOPE2_USER           string `yaml:"goscope2_user"`
-	GOSCOPE2_PASS           string `yaml:"goscope2_pass"`
-	SENDINBLUE_API_KEY      string `yaml:"sendinblue_api_key"`
-	IMGBB_KEY               string `yaml:"imgbb_key"`
-	ONESIGNAL_APP_ID        string `yaml:"onesignal_app_id"`
-	ONESIGNAL_REST_API_KEY  string `yaml:"onesignal_rest_api_key"`
-	APPSTORE_REVIEWER_EMAIL string `yaml:"appstore_reviewer_email"`
+	ENV                     string               `yaml:"-"`
+	HOST                    string               `yaml:"host"`
+	PORT                    int                  `yaml:"port"`
+	SITE_BASE_URL_API       string               `yaml:"site_base_url_api"`
+	SITE_BASE_URL_FE        string               `yaml:"site_base_url_fe"`
+	COOKIE_DOMAIN           string               `yaml:"cookie_domain"`
+	COOKIE_HTTPS_ONLY       bool                 `yaml:"cookie_https_only"`
+	JWT_SECRET              string               `yaml:"jwt_secret"`
+	JWT_KEYS                []ConfigJwtKey       `yaml:"jwt_keys"`
+	JWT_KEY_GRACE_PERIOD    time.Duration        `yaml:"jwt_key_grace_period"`
+	STRIPE_SECRET_KEY       string               `yaml:"stripe_secret_key"`
+	STRIPE_WEBHOOK          string               `yaml:"stripe_webhook"`
+	DB_HOST                 string               `yaml:"db_host"`
+	DB_PORT                 int                  `yaml:"db_port"`
+	DB_NAME                 string               `yaml:"db_name"`
+	DB_USER                 string               `yaml:"db_user"`
+	DB_PASS                 string               `yaml:"db_pass"`
+	SMTP_HOST               string               `yaml:"smtp_host"`
+	SMTP_PORT               int                  `yaml:"smtp_port"`
+	SMTP_SENDER             string               `yaml:"smtp_sender"`
+	SMTP_USER               string               `yaml:"smtp_user"`
+	SMTP_PASS               string               `yaml:"smtp_pass"`
+	GOSCOPE2_USER           string               `yaml:"goscope2_user"`
+	GOSCOPE2_PASS           string               `yaml:"goscope2_pass"`
+	SENDINBLUE_API_KEY      string               `yaml:"sendinblue_api_key"`
+	IMGBB_KEY               string               `yaml:"imgbb_key"`
+	ONESIGNAL_APP_ID        string               `yaml:"onesignal_app_id"`
+	ONESIGNAL_REST_API_KEY  string               `yaml:"onesignal_rest_api_key"`
+	APPSTORE_REVIEWER_EMAIL string               `yaml:"appstore_reviewer_email"`
+	OIDC_PROVIDERS          []ConfigOidcProvider `yaml:"oidc_providers"`
+	WEBAUTHN_RP_ID          string               `yaml:"webauthn_rp_id"`
 }
 
 func ConfigInit(path string) {
@@ -76,9 +105,16 @@ func ConfigInit(path string) {
 		panic(fmt.Errorf("error reading config: %s", err))
 	}
 
-	if Config.JWT_SECRET == "" {
+	if Config.JWT_SECRET == "" && len(Config.JWT_KEYS) == 0 {
 		panic(fmt.Errorf("no jwt secret in config file: %s", fpath))
 	}
+	if err := configJwtKeysInit(); err != nil {
+		panic(fmt.Errorf("%s in config file: %s", err, fpath))
+	}
+
+	if err := configOidcProvidersCheck(); err != nil {
+		panic(fmt.Errorf("%s in config file: %s", err, fpath))
+	}
 
 	Config.ENV = env
 	stripe.Key = Config.STRIPE_SECRET_KEY
@@ -89,3 +125,65 @@ func ConfigTestInit(path string) {
 	os.Setenv("SERVER_NO_MIGRATE", "true")
 	ConfigInit(path)
 }
+
+// All keys of jwt_keys, including the jwt_secret
+var JwtKeys []ConfigJwtKey
+
+// The jwt_secret is added to the keyring without an id,
+// this keeps tokens signed before key rotation was introduced valid.
+func configJwtKeysInit() error {
+	keys := append([]ConfigJwtKey{}, Config.JWT_KEYS...)
+	if Config.JWT_SECRET != "" {
+		keys = append(keys, ConfigJwtKey{
+			ID:     "",
+			Secret: Config.JWT_SECRET,
+			Active: len(Config.JWT_KEYS) == 0,
+		})
+	}
+	if Config.JWT_KEY_GRACE_PERIOD == 0 {
+		Config.JWT_KEY_GRACE_PERIOD = 24 * time.Hour
+	}
+
+	amountActive := 0
+	ids := map[string]bool{}
+	for _, k := range keys {
+		if k.Secret == "" {
+			return fmt.Errorf("jwt key %q has no secret", k.ID)
+		}
+		if ids[k.ID] {
+			return fmt.Errorf("jwt key %q is defined twice", k.ID)
+		}
+		ids[k.ID] = true
+		if k.Active {
+			if k.RetiredAt != nil {
+				return fmt.Errorf("jwt key %q can not be both active and retired", k.ID)
+			}
+			amountActive++
+		}
+	}
+	if amountActive != 1 {
+		return fmt.Errorf("exactly one jwt key must be active, found %d", amountActive)
+	}
+
+	JwtKeys = keys
+	return nil
+}
+
+var configOidcProviderIDRegex = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+func configOidcProvidersCheck() error {
+	ids := map[string]bool{}
+	for _, p := range Config.OIDC_PROVIDERS {
+		if !configOidcProviderIDRegex.MatchString(p.ID) {
+			return fmt.Errorf("oidc provider id %q must only contain lowercase letters, numbers, - and _", p.ID)
+		}
+		if ids[p.ID] {
+			return fmt.Errorf("oidc provider %q is defined twice", p.ID)
+		}
+		ids[p.ID] = true
+		if p.Issuer == "" || p.ClientID == "" {
+			return fmt.Errorf("oidc provider %q requires an issuer and client_id", p.ID)
+		}
+	}
+	return nil
+}
@@ -32,6 +32,7 @@ func DatabaseInit() *gorm.DB {
 
 func DatabaseAutoMigrate(db *gorm.DB) {
 	hadIsApprovedColumn := db.Migrator().HasColumn(&models.UserChain{}, "is_approved")
+	hadRoleColumn := db.Migrator().HasColumn(&models.UserChain{}, "role")
 
 	// User Tokens
 	if db.Migrator().HasTable("user_tokens") {
@@ -92,6 +93,11 @@ func DatabaseAutoMigrate(db *gorm.DB) {
 	if db.Migrator().HasTable("mails") {
 		db.Exec(`DROP TABLE mails`)
 	}
+	// Replaced by the loop lifecycle
+	if db.Migrator().HasColumn(&models.Chain{}, "last_abandoned_at") {
+		db.Migrator().DropColumn(&models.Chain{}, "last_abandoned_at")
+		db.Migrator().DropColumn(&models.Chain{}, "last_abandoned_recruitment_email")
+	}
 
 	db.AutoMigrate(
 		&models.Chain{},
@@ -99,6 +105,20 @@ func DatabaseAutoMigrate(db *gorm.DB) {
 		&models.User{},
 		&models.Event{},
 		&models.UserToken{},
+		&models.UserSession{},
+		&models.UserRefreshToken{},
+		&models.OtpAttempt{},
+		&models.UserEmailChange{},
+		&models.UserOidcIdentity{},
+		&models.UserPasskey{},
+		&models.ApiKey{},
+		&models.UserImpersonationLog{},
+		&models.ChainHostInvitation{},
+		&models.ChainWaitlist{},
+		&models.ChainAuditLog{},
+		&models.ChainQuestion{},
+		&models.ChainQuestionAnswer{},
+		&models.ChainInvite{},
 		&models.UserChain{},
 		&models.UserOnesignal{},
 		&models.Bag{},
@@ -114,6 +134,10 @@ ADD CONSTRAINT uci_user_id_chain_id
 UNIQUE (user_id, chain_id)
 		`)
 	}
+	if !hadRoleColumn {
+		// existing chain admins become hosts
+		db.Exec(`UPDATE user_chains SET role = 'host' WHERE is_chain_admin = TRUE`)
+	}
 	if !hadIsApprovedColumn {
 		db.Exec(`
 UPDATE user_chains SET is_approved = TRUE WHERE id IN (
@@ -2,6 +2,7 @@ package app
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -17,6 +18,19 @@ import (
 
 var smtpClient *gomail.Client
 
+type mailDisabledContextKey struct{}
+
+// Emails and push notifications are not sent by database sessions with this context,
+// this is used while a root admin impersonates a user.
+func MailDisabledContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, mailDisabledContextKey{}, true)
+}
+
+func mailIsDisabled(db *gorm.DB) bool {
+	ctx := db.Statement.Context
+	return ctx != nil && ctx.Value(mailDisabledContextKey{}) != nil
+}
+
 func MailInit() {
 	var err error
 	smtpClient, err = gomail.NewClient(Config.SMTP_HOST, gomail.WithPort(Config.SMTP_PORT))
@@ -46,6 +60,11 @@ func MailSend(db *gorm.DB, m *models.Mail) error {
 	// 	return nil
 	// }
 
+	if mailIsDisabled(db) {
+		goscope.Log.Infof("Email not sent while impersonating: %s to: %s", m.Subject, m.ToAddress)
+		return nil
+	}
+
 	var err error
 	if Config.SENDINBLUE_API_KEY != "" {
 		err = mailSendByBrevoApi(m)
@@ -6,6 +6,7 @@ import (
 
 	"github.com/OneSignal/onesignal-go-api"
 	uuid "github.com/satori/go.uuid"
+	"github.com/the-clothing-loop/website/server/internal/app/goscope"
 	"gorm.io/gorm"
 )
 
@@ -27,6 +28,10 @@ func OneSignalCreateNotification(db *gorm.DB, userUIDs []string, notificationTit
 	if len(userUIDs) == 0 {
 		return fmt.Errorf("No users to send a notification to")
 	}
+	if mailIsDisabled(db) {
+		goscope.Log.Infof("Push notification not sent while impersonating to: %d users", len(userUIDs))
+		return nil
+	}
 
 	notification := onesignal.NewNotification(Config.ONESIGNAL_APP_ID)
 	notification.SetId(uuid.NewV4().String())
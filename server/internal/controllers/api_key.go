@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/the-clothing-loop/website/server/internal/app/auth"
+	"github.com/the-clothing-loop/website/server/internal/app/goscope"
+	"github.com/the-clothing-loop/website/server/internal/models"
+)
+
+func ApiKeyGetAll(c *gin.Context) {
+	db := getDB(c)
+
+	keys, err := models.ApiKeyGetAll(db)
+	if err != nil {
+		goscope.Log.Errorf("Unable to retrieve api keys: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to retrieve api keys")
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// The key is only returned once, it can not be retrieved afterwards
+func ApiKeyCreate(c *gin.Context) {
+	db := getDB(c)
+
+	var body struct {
+		Name     string   `json:"name" binding:"required,max=100"`
+		Scopes   []string `json:"scopes" binding:"required,min=1"`
+		ChainUID string   `json:"chain_uid" binding:"omitempty,uuid"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	for _, scope := range body.Scopes {
+		if !lo.Contains(models.ApiKeyScopes, scope) {
+			c.String(http.StatusBadRequest, "Unknown scope: "+scope)
+			return
+		}
+	}
+	body.Scopes = lo.Uniq(body.Scopes)
+
+	var chainID uint
+	if body.ChainUID != "" {
+		var found bool
+		var err error
+		chainID, found, err = models.ChainCheckIfExist(db, body.ChainUID, false)
+		if err != nil {
+			goscope.Log.Errorf("Unable to find loop: %v", err)
+			c.String(http.StatusInternalServerError, "Unable to find loop")
+			return
+		}
+		if !found {
+			c.String(http.StatusNotFound, models.ErrChainNotFound.Error())
+			return
+		}
+	} else if len(lo.Intersect(body.Scopes, models.ApiKeyScopesOfChain)) > 0 {
+		c.String(http.StatusBadRequest, "chain_uid is required for the scopes of a loop")
+		return
+	}
+
+	authUser := auth.GetAuthUser(c)
+
+	key, apiKey, err := auth.ApiKeyCreate(db, authUser.ID, body.Name, body.Scopes, chainID)
+	if err != nil {
+		goscope.Log.Errorf("Unable to create api key: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to create api key")
+		return
+	}
+	apiKey.ChainUID = body.ChainUID
+
+	c.JSON(http.StatusOK, gin.H{
+		"key":     key,
+		"api_key": apiKey,
+	})
+}
+
+func ApiKeyRevoke(c *gin.Context) {
+	db := getDB(c)
+
+	var uri struct {
+		UID string `uri:"uid" binding:"required,uuid"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	apiKey, err := models.ApiKeyGetByUID(db, uri.UID)
+	if err != nil {
+		c.String(http.StatusNotFound, models.ErrApiKeyNotFound.Error())
+		return
+	}
+
+	err = apiKey.Revoke(db)
+	if err != nil {
+		goscope.Log.Errorf("Unable to revoke api key: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to revoke api key")
+		return
+	}
+}
@@ -27,10 +27,7 @@ func BagGetAll(c *gin.Context) {
 		return
 	}
 
-	ok, _, _, chain := auth.AuthenticateUserOfChain(c, db, query.ChainUID, query.UserUID)
-	if !ok {
-		return
-	}
+	chain := auth.GetAuthChain(c)
 
 	bags := []models.Bag{}
 	err := db.Raw(fmt.Sprintf(`
@@ -77,10 +74,8 @@ func BagPut(c *gin.Context) {
 		return
 	}
 
-	ok, authUser, chain := auth.Authenticate(c, db, auth.AuthState2UserOfChain, body.ChainUID)
-	if !ok {
-		return
-	}
+	authUser := auth.GetAuthUser(c)
+	chain := auth.GetAuthChain(c)
 
 	bag := models.Bag{}
 	if body.BagID != 0 {
@@ -92,9 +87,8 @@ func BagPut(c *gin.Context) {
 		`, body.BagID, chain.ID).Scan(&bag)
 	}
 
-	// if authUser is not host user can only set the bag holder
-	_, isChainAdmin := authUser.IsPartOfChain(chain.UID)
-	if !isChainAdmin {
+	// if authUser is not allowed to manage bags user can only set the bag holder
+	if !authUser.HasChainPermission(chain.UID, models.ChainPermissionManageBags) {
 		isAllowed := bag.ID != 0 && body.Number == nil && body.Color == nil
 		if !isAllowed {
 			c.AbortWithError(401, fmt.Errorf("As participant you are not allowed to change the bag colour or name"))
@@ -167,10 +161,7 @@ func BagRemove(c *gin.Context) {
 		return
 	}
 
-	ok, _, chain := auth.Authenticate(c, db, auth.AuthState3AdminChainUser, query.ChainUID)
-	if !ok {
-		return
-	}
+	chain := auth.GetAuthChain(c)
 
 	err := db.Exec(`
 DELETE FROM bags
@@ -24,10 +24,7 @@ func BulkyGetAll(c *gin.Context) {
 		return
 	}
 
-	ok, _, chain := auth.Authenticate(c, db, auth.AuthState2UserOfChain, query.ChainUID)
-	if !ok {
-		return
-	}
+	chain := auth.GetAuthChain(c)
 
 	bulkyItems := []models.BulkyItem{}
 	err := db.Raw(`
@@ -73,10 +70,7 @@ func BulkyPut(c *gin.Context) {
 		return
 	}
 
-	ok, _, chain := auth.Authenticate(c, db, auth.AuthState2UserOfChain, body.ChainUID)
-	if !ok {
-		return
-	}
+	chain := auth.GetAuthChain(c)
 
 	// Create a notification
 	if isNew := body.ID == 0; isNew {
@@ -85,8 +79,7 @@ func BulkyPut(c *gin.Context) {
 			SELECT u.uid
 			FROM users AS u
 			JOIN user_chains AS uc ON uc.user_id = u.id
-			JOIN chains AS c ON c.id = uc.chain_id
-			WHERE c.uid = ? AND u.uid != ?`, body.ChainUID, body.UserUID).Scan(&userUIDs)
+			WHERE uc.chain_id = ? AND u.uid != ?`, chain.ID, body.UserUID).Scan(&userUIDs)
 
 		if len(userUIDs) > 0 {
 			err := app.OneSignalCreateNotification(db, userUIDs,
@@ -103,7 +96,18 @@ func BulkyPut(c *gin.Context) {
 	// Set the bulkyItem object
 	bulkyItem := &models.BulkyItem{}
 	if body.ID != 0 {
-		db.Raw(`SELECT * FROM bulky_items WHERE id = ? LIMIT 1`, body.ID).Scan(bulkyItem)
+		db.Raw(`
+SELECT * FROM bulky_items
+WHERE id = ? AND user_chain_id IN (
+	SELECT id FROM user_chains
+	WHERE chain_id = ?
+)
+LIMIT 1
+	`, body.ID, chain.ID).Scan(bulkyItem)
+		if bulkyItem.ID == 0 {
+			c.String(http.StatusNotFound, "Bulky item not found")
+			return
+		}
 	}
 	if body.Title != nil {
 		bulkyItem.Title = *(body.Title)
@@ -153,10 +157,7 @@ func BulkyRemove(c *gin.Context) {
 		return
 	}
 
-	ok, _, chain := auth.Authenticate(c, db, auth.AuthState2UserOfChain, query.ChainUID)
-	if !ok {
-		return
-	}
+	chain := auth.GetAuthChain(c)
 
 	err := db.Exec(`
 DELETE FROM bulky_items
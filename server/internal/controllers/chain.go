@@ -1,7 +1,6 @@
 package controllers
 
 import (
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,10 +12,12 @@ import (
 	"github.com/the-clothing-loop/website/server/internal/models"
 	"github.com/the-clothing-loop/website/server/internal/services"
 	"github.com/the-clothing-loop/website/server/internal/views"
+	"github.com/the-clothing-loop/website/server/pkg/geocluster"
 	"github.com/the-clothing-loop/website/server/pkg/tsp"
 
 	"github.com/gin-gonic/gin"
 	uuid "github.com/satori/go.uuid"
+	"gopkg.in/guregu/null.v3/zero"
 )
 
 const (
@@ -44,10 +45,7 @@ type ChainCreateRequestBody struct {
 
 func ChainCreate(c *gin.Context) {
 	db := getDB(c)
-	ok, user, _ := auth.Authenticate(c, db, auth.AuthState1AnyUser, "")
-	if !ok {
-		return
-	}
+	user := auth.GetAuthUser(c)
 
 	var body ChainCreateRequestBody
 	if err := c.ShouldBindJSON(&body); err != nil {
@@ -138,7 +136,7 @@ func ChainGet(c *gin.Context) {
 		sql += `,
 		chains.is_app_disabled`
 	}
-	sql += ` FROM chains WHERE uid = ? LIMIT 1`
+	sql += ` FROM chains WHERE uid = ? AND deleted_at IS NULL LIMIT 1`
 	err := db.Raw(sql, query.ChainUID).Scan(chain).Error
 	if err != nil || chain.ID == 0 {
 		c.String(http.StatusBadRequest, models.ErrChainNotFound.Error())
@@ -157,6 +155,7 @@ func ChainGet(c *gin.Context) {
 		Genders:          chain.Genders,
 		Published:        chain.Published,
 		OpenToNewMembers: chain.OpenToNewMembers,
+		MaxMembers:       chain.MaxMembers,
 	}
 
 	if query.AddRules {
@@ -249,8 +248,9 @@ func ChainGetAll(c *gin.Context) {
 	if query.FilterPublished {
 		whereOrSql = append(whereOrSql, "chains.published = TRUE")
 	}
+	sql = fmt.Sprintf("%s WHERE chains.deleted_at IS NULL", sql)
 	if len(whereOrSql) > 0 {
-		sql = fmt.Sprintf("%s WHERE %s", sql, strings.Join(whereOrSql, " OR "))
+		sql = fmt.Sprintf("%s AND ( %s )", sql, strings.Join(whereOrSql, " OR "))
 	}
 	if err := db.Raw(sql, args...).Scan(&chains).Error; err != nil {
 		goscope.Log.Warningf("Chain not found: %v", err)
@@ -278,7 +278,7 @@ func ChainGetNear(c *gin.Context) {
 	sql := "SELECT uid, name, genders FROM chains"
 	args := []any{}
 
-	sql = fmt.Sprintf("%s WHERE %s <= ? AND chains.published = TRUE", sql, sqlCalcDistance("chains.latitude", "chains.longitude", "?", "?"))
+	sql = fmt.Sprintf("%s WHERE %s <= ? AND chains.published = TRUE AND chains.deleted_at IS NULL", sql, sqlCalcDistance("chains.latitude", "chains.longitude", "?", "?"))
 	args = append(args, query.Latitude, query.Longitude, query.Radius)
 
 	if err := db.Raw(sql, args...).Scan(&chains).Error; err != nil {
@@ -317,6 +317,7 @@ func ChainUpdate(c *gin.Context) {
 		HeadersOverride  *string   `json:"headers_override,omitempty"`
 		Published        *bool     `json:"published,omitempty"`
 		OpenToNewMembers *bool     `json:"open_to_new_members,omitempty"`
+		MaxMembers       *int      `json:"max_members,omitempty" binding:"omitempty,gte=0"` // 0 removes the member limit
 		Theme            *string   `json:"theme,omitempty"`
 		RoutePrivacy     *int      `json:"route_privacy"`
 		IsAppDisabled    *bool     `json:"is_app_disabled,omitempty"`
@@ -339,10 +340,9 @@ func ChainUpdate(c *gin.Context) {
 		}
 	}
 
-	ok, _, chain := auth.Authenticate(c, db, auth.AuthState3AdminChainUser, body.UID)
-	if !ok {
-		return
-	}
+	authUser := auth.GetAuthUser(c)
+	chain := auth.GetAuthChain(c)
+	oldValues := chain.AuditValues()
 
 	valuesToUpdate := map[string]any{}
 	if body.Name != nil {
@@ -382,15 +382,14 @@ func ChainUpdate(c *gin.Context) {
 	}
 	if body.Published != nil {
 		valuesToUpdate["published"] = *(body.Published)
-
-		// reset abandoned status to NULL
-		if *(body.Published) && chain.LastAbandonedAt.Valid {
-			valuesToUpdate["last_abandoned_at"] = sql.NullTime{}
-			valuesToUpdate["last_abandoned_recruitment_email"] = sql.NullTime{}
-		}
 	}
 	if body.OpenToNewMembers != nil {
 		valuesToUpdate["open_to_new_members"] = *(body.OpenToNewMembers)
+		// a manual change overrides the automatic reopening at the member limit
+		valuesToUpdate["is_closed_at_capacity"] = false
+	}
+	if body.MaxMembers != nil {
+		valuesToUpdate["max_members"] = zero.NewInt(int64(*(body.MaxMembers)), *(body.MaxMembers) > 0)
 	}
 	if body.Theme != nil {
 		valuesToUpdate["theme"] = *(body.Theme)
@@ -401,10 +400,28 @@ func ChainUpdate(c *gin.Context) {
 	if body.IsAppDisabled != nil {
 		valuesToUpdate["is_app_disabled"] = *(body.IsAppDisabled)
 	}
+	isReopened := body.OpenToNewMembers != nil && *(body.OpenToNewMembers) && !chain.OpenToNewMembers
 	err := db.Model(chain).Updates(valuesToUpdate).Error
 	if err != nil {
 		goscope.Log.Errorf("Unable to update loop values: %v", err)
 		c.String(http.StatusInternalServerError, "Unable to update loop values")
+		return
+	}
+
+	db.Raw(`SELECT * FROM chains WHERE id = ? LIMIT 1`, chain.ID).Scan(chain)
+	if changes := models.ChainAuditDiff(oldValues, chain.AuditValues()); len(changes) > 0 {
+		if err := models.ChainAuditLogCreate(db, chain.ID, authUser.ID, 0, models.ChainAuditActionUpdate, changes); err != nil {
+			goscope.Log.Errorf("Unable to add to loop history: %v", err)
+		}
+	}
+	if err := services.ChainUpdateCapacity(db, chain); err != nil {
+		goscope.Log.Errorf("Unable to update loop capacity: %v", err)
+	}
+
+	if isReopened && chain.OpenToNewMembers {
+		if err := services.ChainWaitlistInvite(db, chain); err != nil {
+			goscope.Log.Errorf("Unable to invite waitlist: %v", err)
+		}
 	}
 }
 
@@ -419,10 +436,8 @@ func ChainDelete(c *gin.Context) {
 		return
 	}
 
-	ok, authUser, chain := auth.Authenticate(c, db, auth.AuthState3AdminChainUser, query.ChainUID)
-	if !ok {
-		return
-	}
+	authUser := auth.GetAuthUser(c)
+	chain := auth.GetAuthChain(c)
 
 	totals := chain.GetTotals(db)
 
@@ -439,6 +454,266 @@ func ChainDelete(c *gin.Context) {
 	}
 }
 
+// Lists the most recent changes made to the loop
+func ChainGetAuditLog(c *gin.Context) {
+	db := getDB(c)
+
+	var query struct {
+		ChainUID string `form:"chain_uid" binding:"required,uuid"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	chain := auth.GetAuthChain(c)
+	logs, err := models.ChainAuditLogGetAll(db, chain.ID, 500)
+	if err != nil {
+		goscope.Log.Errorf("Unable to retrieve loop history: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to retrieve loop history")
+		return
+	}
+
+	c.JSON(http.StatusOK, logs)
+}
+
+// A host confirms that the loop is still active, which resets its lifecycle
+func ChainConfirmStillActive(c *gin.Context) {
+	db := getDB(c)
+
+	var body struct {
+		ChainUID string `json:"chain_uid" binding:"required,uuid"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	chain := auth.GetAuthChain(c)
+	if err := chain.ConfirmStillActive(db); err != nil {
+		goscope.Log.Errorf("Unable to confirm loop is still active: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to confirm loop is still active")
+		return
+	}
+}
+
+// Restores a deleted loop with its members and bags, only allowed for hosts of the loop
+// before the retention period has passed.
+func ChainRestore(c *gin.Context) {
+	db := getDB(c)
+
+	var body struct {
+		ChainUID string `json:"chain_uid" binding:"required,uuid"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	authUser := auth.GetAuthUser(c)
+
+	chain := &models.Chain{}
+	db.Raw(`SELECT * FROM chains WHERE uid = ? AND deleted_at IS NOT NULL LIMIT 1`, body.ChainUID).Scan(chain)
+	if chain.ID == 0 {
+		c.String(http.StatusNotFound, models.ErrChainNotFound.Error())
+		return
+	}
+
+	// the memberships of a deleted loop are not part of the authenticated user
+	if !authUser.IsRootAdmin {
+		userChain := &models.UserChain{}
+		db.Raw(`SELECT * FROM user_chains WHERE chain_id = ? AND user_id = ? AND is_approved = TRUE LIMIT 1`, chain.ID, authUser.ID).Scan(userChain)
+		if userChain.ID == 0 || !userChain.HasPermission(models.ChainPermissionManageLoop) {
+			c.String(http.StatusUnauthorized, "Only hosts of the loop can restore it")
+			return
+		}
+	}
+
+	if !chain.IsRestorable() {
+		c.String(http.StatusGone, "This loop can no longer be restored")
+		return
+	}
+
+	if err := chain.Restore(db); err != nil {
+		goscope.Log.Errorf("Unable to restore loop: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to restore loop")
+		return
+	}
+}
+
+type chainDeletedResult struct {
+	models.ChainResponse
+	DeletedAt    zero.Time `json:"deleted_at" gorm:"chains.deleted_at"`
+	IsChainAdmin bool      `json:"-"`
+	Role         string    `json:"-"`
+}
+
+// Lists the deleted loops the authenticated user can still restore
+func ChainGetAllDeleted(c *gin.Context) {
+	db := getDB(c)
+
+	authUser := auth.GetAuthUser(c)
+
+	chains := []chainDeletedResult{}
+	err := db.Raw(models.ChainResponseSQLSelect+`,
+chains.deleted_at,
+uc.is_chain_admin,
+uc.role
+FROM chains
+JOIN user_chains AS uc ON uc.chain_id = chains.id
+WHERE uc.user_id = ? AND uc.is_approved = TRUE
+	AND chains.deleted_at > (NOW() - INTERVAL ? DAY)
+	AND chains.merged_into_chain_id IS NULL
+ORDER BY chains.deleted_at DESC
+	`, authUser.ID, models.ChainDeletedRetentionDays).Scan(&chains).Error
+	if err != nil {
+		goscope.Log.Errorf("Unable to retrieve deleted loops: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to retrieve deleted loops")
+		return
+	}
+
+	result := []chainDeletedResult{}
+	for _, chain := range chains {
+		userChain := &models.UserChain{IsChainAdmin: chain.IsChainAdmin, Role: chain.Role}
+		if userChain.HasPermission(models.ChainPermissionManageLoop) {
+			result = append(result, chain)
+		}
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Merges the source loop into the target loop, only allowed for hosts of both loops
+func ChainMerge(c *gin.Context) {
+	db := getDB(c)
+
+	var body struct {
+		SourceChainUID string `json:"source_chain_uid" binding:"required,uuid"`
+		TargetChainUID string `json:"target_chain_uid" binding:"required,uuid"`
+		DryRun         bool   `json:"dry_run"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	authUser := auth.GetAuthUser(c)
+	target := auth.GetAuthChain(c)
+	if !authUser.IsRootAdmin && !authUser.HasChainPermission(body.SourceChainUID, models.ChainPermissionManageLoop) {
+		c.String(http.StatusUnauthorized, "You must be a host of both loops")
+		return
+	}
+
+	source := &models.Chain{}
+	db.Raw(`SELECT * FROM chains WHERE uid = ? AND deleted_at IS NULL LIMIT 1`, body.SourceChainUID).Scan(source)
+	if source.ID == 0 {
+		c.String(http.StatusNotFound, models.ErrChainNotFound.Error())
+		return
+	}
+
+	result, httperr := services.ChainMerge(db, source, target, authUser, body.DryRun)
+	if httperr != nil {
+		c.String(httperr.Status, httperr.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Suggests how to divide the approved members of a loop into two groups by where they live
+func ChainSplitPreview(c *gin.Context) {
+	db := getDB(c)
+
+	var query struct {
+		ChainUID string `form:"chain_uid" binding:"required,uuid"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	chain := auth.GetAuthChain(c)
+	users := []struct {
+		UID       string
+		Latitude  float64
+		Longitude float64
+	}{}
+	err := db.Raw(`
+SELECT u.uid, u.latitude, u.longitude FROM users AS u
+JOIN user_chains AS uc ON uc.user_id = u.id
+WHERE uc.chain_id = ? AND uc.is_approved = TRUE
+ORDER BY uc.route_order ASC
+	`, chain.ID).Scan(&users).Error
+	if err != nil {
+		goscope.Log.Errorf("Unable to retrieve members: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to retrieve members")
+		return
+	}
+
+	type group struct {
+		Latitude  float64  `json:"latitude"`
+		Longitude float64  `json:"longitude"`
+		UserUIDs  []string `json:"user_uids"`
+	}
+	groups := []group{{UserUIDs: []string{}}, {UserUIDs: []string{}}}
+	points := make([]geocluster.Point, len(users))
+	for i, u := range users {
+		points[i] = geocluster.Point{Latitude: u.Latitude, Longitude: u.Longitude}
+	}
+	for i, label := range geocluster.KMeans(points, len(groups)) {
+		g := &groups[label]
+		g.UserUIDs = append(g.UserUIDs, users[i].UID)
+		g.Latitude += users[i].Latitude
+		g.Longitude += users[i].Longitude
+	}
+	for i := range groups {
+		if n := len(groups[i].UserUIDs); n > 0 {
+			groups[i].Latitude /= float64(n)
+			groups[i].Longitude /= float64(n)
+		}
+	}
+
+	c.JSON(http.StatusOK, groups)
+}
+
+// Moves the given members to a new loop, the routes of both loops are optimized afterwards
+func ChainSplit(c *gin.Context) {
+	db := getDB(c)
+
+	var body struct {
+		ChainUID string   `json:"chain_uid" binding:"required,uuid"`
+		Name     string   `json:"name" binding:"required"`
+		UserUIDs []string `json:"user_uids" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	authUser := auth.GetAuthUser(c)
+	chain := auth.GetAuthChain(c)
+
+	newChain, httperr := services.ChainSplit(db, chain, authUser, body.Name, body.UserUIDs)
+	if httperr != nil {
+		c.String(httperr.Status, httperr.Error())
+		return
+	}
+
+	for _, ch := range []*models.Chain{chain, newChain} {
+		if cities := retrieveChainUsersAsTspCities(db, ch.ID); len(cities) > 0 {
+			route, _ := tsp.RunOptimizeRouteWithCitiesMST[string](cities)
+			ch.SetRouteOrderByUserUIDs(db, route)
+		}
+		if err := services.ChainUpdateCapacity(db, ch); err != nil {
+			goscope.Log.Errorf("Unable to update loop capacity: %v", err)
+		}
+	}
+
+	services.ChainSplitNotify(db, chain, newChain)
+
+	c.JSON(http.StatusOK, gin.H{"chain_uid": newChain.UID})
+}
+
 func ChainAddUser(c *gin.Context) {
 	db := getDB(c)
 
@@ -446,20 +721,37 @@ func ChainAddUser(c *gin.Context) {
 		UserUID      string `json:"user_uid" binding:"required,uuid"`
 		ChainUID     string `json:"chain_uid" binding:"required,uuid"`
 		IsChainAdmin bool   `json:"is_chain_admin"`
+		// Overrides is_chain_admin when set
+		Role *string `json:"role"`
+		// Answers to the questions of the loop, required when users join by themselves
+		Answers []models.ChainQuestionAnswerRequest `json:"answers" binding:"dive"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.String(http.StatusBadRequest, err.Error())
 		return
 	}
 
-	var ok bool
-	var chain *models.Chain
+	role := ""
 	if body.IsChainAdmin {
-		ok, _, chain = auth.Authenticate(c, db, auth.AuthState3AdminChainUser, body.ChainUID)
-	} else {
-		ok, _, _, chain = auth.AuthenticateUserOfChain(c, db, body.ChainUID, body.UserUID)
+		role = models.UserChainRoleHost
 	}
-	if !ok {
+	if body.Role != nil {
+		role = *body.Role
+	}
+	if !models.ValidateUserChainRole(role) {
+		c.String(http.StatusBadRequest, models.ErrUserChainRoleInvalid.Error())
+		return
+	}
+
+	authUser := auth.GetAuthUser(c)
+	chain := auth.GetAuthChain(c)
+	canManageRoles := authUser.IsRootAdmin || authUser.HasChainPermission(chain.UID, models.ChainPermissionManageLoop)
+	if role != "" {
+		if !canManageRoles {
+			c.String(http.StatusUnauthorized, "User role not high enough")
+			return
+		}
+	} else if ok, _ := auth.AuthorizeUserOfChain(c, db, body.UserUID); !ok {
 		return
 	}
 
@@ -473,8 +765,31 @@ LIMIT 1
 	`, user.ID, chain.ID).Scan(userChain)
 	}
 
+	// A host is only promoted after accepting an invitation,
+	// this also works for loops that are not open to new members.
+	if err == nil && role == models.UserChainRoleHost && !userChain.IsChainAdmin {
+		inv, httperr := services.ChainHostInvite(db, chain, authUser, user, "")
+		if httperr != nil {
+			c.String(httperr.Status, httperr.Error())
+			return
+		}
+		c.JSON(http.StatusAccepted, inv)
+		return
+	}
+
 	// If patch is editing an existing userChain instead of creating a new one, then conflict will be ignored
-	if !chain.OpenToNewMembers && userChain.ID == 0 {
+	if (!chain.OpenToNewMembers || chain.IsFull(db)) && userChain.ID == 0 {
+		// users joining by themselves wait for the loop to reopen
+		if err == nil && user.ID == authUser.ID {
+			w, err := models.ChainWaitlistJoin(db, chain.ID, user.ID)
+			if err != nil {
+				goscope.Log.Errorf("Unable to join waitlist: %v", err)
+				c.String(http.StatusInternalServerError, "Unable to join waitlist")
+				return
+			}
+			c.JSON(http.StatusAccepted, w)
+			return
+		}
 		c.String(http.StatusConflict, "Loop is not open to new members")
 		return
 	}
@@ -484,21 +799,56 @@ LIMIT 1
 	}
 
 	if userChain.ID != 0 {
-		if (!userChain.IsChainAdmin && body.IsChainAdmin) || (userChain.IsChainAdmin && !body.IsChainAdmin) {
-			userChain.IsChainAdmin = body.IsChainAdmin
-			db.Save(userChain)
+		if userChain.Role != role {
+			if !canManageRoles {
+				c.String(http.StatusUnauthorized, "User role not high enough")
+				return
+			}
+			if err := models.UserChainSetRole(db, userChain.ID, role); err != nil {
+				goscope.Log.Errorf("Unable to change role: %v", err)
+				c.String(http.StatusInternalServerError, "Unable to change role")
+				return
+			}
+			if err := models.ChainAuditLogCreate(db, chain.ID, authUser.ID, user.ID, models.ChainAuditActionRoleChange, map[string]models.ChainAuditChange{
+				"role": {Old: userChain.Role, New: role},
+			}); err != nil {
+				goscope.Log.Errorf("Unable to add to loop history: %v", err)
+			}
 		}
 	} else {
+		questions, err := models.ChainQuestionGetAllByChain(db, chain.ID)
+		if err != nil {
+			goscope.Log.Errorf("Unable to retrieve questions: %v", err)
+			c.String(http.StatusInternalServerError, "Unable to retrieve questions")
+			return
+		}
+		if err := models.ChainQuestionValidateAnswers(questions, body.Answers, user.ID == authUser.ID); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// the host role is set by accepting an invitation above, other roles are set right away
 		if err := db.Create(&models.UserChain{
 			UserID:       user.ID,
 			ChainID:      chain.ID,
 			IsChainAdmin: false,
+			Role:         role,
 		}).Error; err != nil {
 			goscope.Log.Errorf("User could not be added to chain: %v", err)
 			c.String(http.StatusInternalServerError, "User could not be added to chain due to unknown error")
 			return
 		}
-		err := services.EmailLoopAdminsOnUserJoin(db, user, chain.ID)
+		if role != "" {
+			if err := models.ChainAuditLogCreate(db, chain.ID, authUser.ID, user.ID, models.ChainAuditActionRoleChange, map[string]models.ChainAuditChange{
+				"role": {Old: "", New: role},
+			}); err != nil {
+				goscope.Log.Errorf("Unable to add to loop history: %v", err)
+			}
+		}
+		if err := models.ChainQuestionAnswerSetAll(db, chain.ID, user.ID, questions, body.Answers); err != nil {
+			goscope.Log.Errorf("Unable to save answers: %v", err)
+		}
+		err = services.EmailLoopAdminsOnUserJoin(db, user, chain.ID)
 		if err != nil {
 			goscope.Log.Errorf("Unable to send email to associated loop admins: %v", err)
 			c.String(http.StatusInternalServerError, err.Error())
@@ -514,27 +864,51 @@ func ChainRemoveUser(c *gin.Context) {
 	var body struct {
 		UserUID  string `json:"user_uid" binding:"required,uuid"`
 		ChainUID string `json:"chain_uid" binding:"required,uuid"`
+		// Is invited to become host when the last host leaves
+		SuccessorUserUID string `json:"successor_user_uid" binding:"omitempty,uuid"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.String(http.StatusBadRequest, err.Error())
 		return
 	}
 
-	ok, user, authUser, chain := auth.AuthenticateUserOfChain(c, db, body.ChainUID, body.UserUID)
+	authUser := auth.GetAuthUser(c)
+	chain := auth.GetAuthChain(c)
+	ok, user := auth.AuthorizeUserOfChain(c, db, body.UserUID)
 	if !ok {
 		return
 	}
 
-	if authUser.ID == user.ID && !authUser.IsRootAdmin {
-		if _, isChainAdmin := user.IsPartOfChain(chain.UID); isChainAdmin {
-			amountChainAdmins := -1
-			err := db.Raw(`SELECT COUNT(*) FROM user_chains WHERE chain_id = ? AND is_chain_admin = TRUE`, chain.ID).Scan(&amountChainAdmins).Error
+	isLastHost := false
+	if _, isChainAdmin := user.IsPartOfChain(chain.UID); isChainAdmin {
+		amountChainAdmins := -1
+		db.Raw(`SELECT COUNT(*) FROM user_chains WHERE chain_id = ? AND is_chain_admin = TRUE`, chain.ID).Scan(&amountChainAdmins)
+		isLastHost = amountChainAdmins == 1
+	}
+	var successor *models.User
+	if body.SuccessorUserUID != "" {
+		if !isLastHost {
+			c.String(http.StatusBadRequest, "A successor can only be chosen by the last host of the loop")
+			return
+		}
+		s, httperr := services.ChainGetSuccessor(db, chain, user.ID, body.SuccessorUserUID)
+		if httperr != nil {
+			c.String(httperr.Status, httperr.Error())
+			return
+		}
+		successor = s
+	}
 
-			if amountChainAdmins == 1 {
-				goscope.Log.Warningf("Unable to remove last host of loop: %v", err)
-				c.String(http.StatusConflict, "Unable to remove last host of loop")
-				return
+	// the last member leaving deletes the loop, the membership is kept
+	// so that the host can still restore the loop until it is purged
+	if isLastHost {
+		amountMembers := 0
+		db.Raw(`SELECT COUNT(*) FROM user_chains WHERE chain_id = ?`, chain.ID).Scan(&amountMembers)
+		if amountMembers == 1 {
+			if httperr := services.ChainDelete(db, chain); httperr != nil {
+				c.String(httperr.Status, httperr.Error())
 			}
+			return
 		}
 	}
 
@@ -547,9 +921,24 @@ func ChainRemoveUser(c *gin.Context) {
 
 	chain.ClearAllLastNotifiedIsUnapprovedAt(db)
 
+	if err := models.ChainAuditLogCreate(db, chain.ID, authUser.ID, user.ID, models.ChainAuditActionUserRemove, nil); err != nil {
+		goscope.Log.Errorf("Unable to add to loop history: %v", err)
+	}
+
+	// the loop is kept without a host until someone volunteers
+	if isLastHost {
+		if httperr := services.ChainOrphan(db, chain, user, successor); httperr != nil {
+			goscope.Log.Errorf("Unable to hand over loop: %v", httperr)
+		}
+	}
+
+	if err := services.ChainUpdateCapacity(db, chain); err != nil {
+		goscope.Log.Errorf("Unable to update loop capacity: %v", err)
+	}
+
 	// if the user is removed by an admin, do not send an email to this one
 	var excludedEmail string
-	if _, isChainAdmin := authUser.IsPartOfChain(chain.UID); isChainAdmin {
+	if authUser.HasChainPermission(chain.UID, models.ChainPermissionManageMembers) {
 		excludedEmail = authUser.Email.String
 	}
 	// send email to chain admins
@@ -568,11 +957,22 @@ func ChainApproveUser(c *gin.Context) {
 		return
 	}
 
-	ok, user, _, chain := auth.AuthenticateUserOfChain(c, db, body.ChainUID, body.UserUID)
+	authUser := auth.GetAuthUser(c)
+	chain := auth.GetAuthChain(c)
+	ok, user := auth.AuthorizeUserOfChain(c, db, body.UserUID)
 	if !ok {
 		return
 	}
 
+	if chain.IsFull(db) {
+		isApproved := false
+		db.Raw(`SELECT is_approved FROM user_chains WHERE user_id = ? AND chain_id = ? LIMIT 1`, user.ID, chain.ID).Scan(&isApproved)
+		if !isApproved {
+			c.String(http.StatusConflict, "Loop has reached its member limit")
+			return
+		}
+	}
+
 	db.Exec(`
 UPDATE user_chains
 SET is_approved = TRUE, created_at = NOW()
@@ -581,6 +981,14 @@ WHERE user_id = ? AND chain_id = ?
 
 	chain.ClearAllLastNotifiedIsUnapprovedAt(db)
 
+	if err := models.ChainAuditLogCreate(db, chain.ID, authUser.ID, user.ID, models.ChainAuditActionUserApprove, nil); err != nil {
+		goscope.Log.Errorf("Unable to add to loop history: %v", err)
+	}
+
+	if err := services.ChainUpdateCapacity(db, chain); err != nil {
+		goscope.Log.Errorf("Unable to update loop capacity: %v", err)
+	}
+
 	// Given a ChainID and the UID of the new user returns the list of UserUIDs of the chain considering the addition of the new user
 	cities := retrieveChainUsersAsTspCities(db, chain.ID)
 	newRoute, _ := tsp.RunAddOptimalOrderNewCity[string](cities, user.UID)
@@ -603,7 +1011,9 @@ func ChainDeleteUnapproved(c *gin.Context) {
 		return
 	}
 
-	ok, user, _, chain := auth.AuthenticateUserOfChain(c, db, query.ChainUID, query.UserUID)
+	authUser := auth.GetAuthUser(c)
+	chain := auth.GetAuthChain(c)
+	ok, user := auth.AuthorizeUserOfChain(c, db, query.UserUID)
 	if !ok {
 		return
 	}
@@ -617,6 +1027,12 @@ func ChainDeleteUnapproved(c *gin.Context) {
 
 	chain.ClearAllLastNotifiedIsUnapprovedAt(db)
 
+	if err := models.ChainAuditLogCreate(db, chain.ID, authUser.ID, user.ID, models.ChainAuditActionUserDeny, map[string]models.ChainAuditChange{
+		"reason": {New: query.Reason},
+	}); err != nil {
+		goscope.Log.Errorf("Unable to add to loop history: %v", err)
+	}
+
 	if user.Email.Valid {
 		views.EmailAnAdminDeniedYourJoinRequest(db, user.I18n, user.Name, user.Email.String, chain.Name,
 			query.Reason)
@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/the-clothing-loop/website/server/internal/app/goscope"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/pkg/geocluster"
+)
+
+// From this zoom level onwards individual loops are returned instead of clusters
+const chainGeoJSONClusterMaxZoom = 10
+
+// Amount of cells per map tile axis, a tile is 256 pixels wide
+const chainGeoJSONCellsPerTile = 4
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string         `json:"type"`
+	Geometry   geoJSONPoint   `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+type geoJSONPoint struct {
+	Type string `json:"type"`
+	// Longitude, latitude
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+func newGeoJSONFeature(latitude, longitude float64, properties map[string]any) geoJSONFeature {
+	return geoJSONFeature{
+		Type: "Feature",
+		Geometry: geoJSONPoint{
+			Type:        "Point",
+			Coordinates: [2]float64{longitude, latitude},
+		},
+		Properties: properties,
+	}
+}
+
+// Uses the json tags of ChainResponse for the feature properties
+func chainGeoJSONProperties(chain *models.ChainResponse) map[string]any {
+	properties := map[string]any{}
+	j, _ := json.Marshal(chain)
+	json.Unmarshal(j, &properties)
+	return properties
+}
+
+// Parses "min_longitude,min_latitude,max_longitude,max_latitude"
+func parseBBox(s string) (bbox [4]float64, ok bool) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return bbox, false
+	}
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return bbox, false
+		}
+		bbox[i] = v
+	}
+	if bbox[1] < -90 || bbox[3] > 90 || bbox[1] > bbox[3] {
+		return bbox, false
+	}
+	return bbox, true
+}
+
+// Returns published loops as a GeoJSON FeatureCollection,
+// at low zoom levels loops that are close to each other are clustered.
+// Loops can be filtered by sizes and genders the same way as ChainSearch.
+func ChainGetGeoJSON(c *gin.Context) {
+	db := getDB(c)
+
+	var query struct {
+		BBox          string   `form:"bbox"`
+		Zoom          int      `form:"zoom" binding:"min=0,max=22"`
+		FilterSizes   []string `form:"filter_sizes"`
+		FilterGenders []string `form:"filter_genders"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	query.FilterSizes = splitCommaSeparated(query.FilterSizes)
+	query.FilterGenders = splitCommaSeparated(query.FilterGenders)
+	if ok := models.ValidateAllSizeEnum(query.FilterSizes); !ok {
+		c.String(http.StatusBadRequest, models.ErrSizeInvalid.Error())
+		return
+	}
+	if ok := models.ValidateAllGenderEnum(query.FilterGenders); !ok {
+		c.String(http.StatusBadRequest, models.ErrGenderInvalid.Error())
+		return
+	}
+
+	sql := models.ChainResponseSQLSelect + ` FROM chains
+WHERE chains.published = TRUE AND chains.deleted_at IS NULL`
+	args := []any{}
+	if query.BBox != "" {
+		bbox, ok := parseBBox(query.BBox)
+		if !ok {
+			c.String(http.StatusBadRequest, "Invalid bounding box")
+			return
+		}
+		sql += " AND chains.latitude BETWEEN ? AND ?"
+		args = append(args, bbox[1], bbox[3])
+		// the bounding box crosses the antimeridian
+		if bbox[0] > bbox[2] {
+			sql += " AND (chains.longitude >= ? OR chains.longitude <= ?)"
+		} else {
+			sql += " AND chains.longitude BETWEEN ? AND ?"
+		}
+		args = append(args, bbox[0], bbox[2])
+	}
+	filterSql, filterArgs := chainFilterSql(query.FilterSizes, query.FilterGenders)
+	for _, where := range filterSql {
+		sql += " AND " + where
+	}
+	args = append(args, filterArgs...)
+	sql += " ORDER BY chains.id ASC"
+
+	chains := []models.ChainResponse{}
+	if err := db.Raw(sql, args...).Scan(&chains).Error; err != nil {
+		goscope.Log.Errorf("Unable to retrieve loops: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to retrieve loops")
+		return
+	}
+
+	collection := geoJSONFeatureCollection{
+		Type:     "FeatureCollection",
+		Features: []geoJSONFeature{},
+	}
+	if query.Zoom < chainGeoJSONClusterMaxZoom {
+		points := make([]geocluster.Point, len(chains))
+		for i, chain := range chains {
+			points[i] = geocluster.Point{Latitude: chain.Latitude, Longitude: chain.Longitude}
+		}
+		for _, cluster := range geocluster.Grid(points, query.Zoom, chainGeoJSONCellsPerTile) {
+			// a cluster of one is shown as the loop itself
+			if len(cluster.Indexes) == 1 {
+				chain := &chains[cluster.Indexes[0]]
+				collection.Features = append(collection.Features, newGeoJSONFeature(chain.Latitude, chain.Longitude, chainGeoJSONProperties(chain)))
+				continue
+			}
+
+			countOpen := 0
+			for _, i := range cluster.Indexes {
+				if chains[i].OpenToNewMembers {
+					countOpen++
+				}
+			}
+			collection.Features = append(collection.Features, newGeoJSONFeature(cluster.Latitude, cluster.Longitude, map[string]any{
+				"cluster":          true,
+				"point_count":      len(cluster.Indexes),
+				"point_count_open": countOpen,
+			}))
+		}
+	} else {
+		for i := range chains {
+			chain := &chains[i]
+			collection.Features = append(collection.Features, newGeoJSONFeature(chain.Latitude, chain.Longitude, chainGeoJSONProperties(chain)))
+		}
+	}
+
+	body, err := json.Marshal(collection)
+	if err != nil {
+		goscope.Log.Errorf("Unable to encode loops: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to retrieve loops")
+		return
+	}
+
+	etag := fmt.Sprintf(`"%x"`, sha1.Sum(body))
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "public, max-age=300")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/geo+json", body)
+}
@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/the-clothing-loop/website/server/internal/app/auth"
+	"github.com/the-clothing-loop/website/server/internal/app/goscope"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/internal/services"
+	"github.com/the-clothing-loop/website/server/pkg/tsp"
+)
+
+// Invites a registered user by uid or anyone by email address to become host of the loop
+func ChainHostInvitationCreate(c *gin.Context) {
+	db := getDB(c)
+
+	var body struct {
+		ChainUID string `json:"chain_uid" binding:"required,uuid"`
+		UserUID  string `json:"user_uid" binding:"required_without=Email,omitempty,uuid"`
+		Email    string `json:"email" binding:"required_without=UserUID,omitempty,email"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	authUser := auth.GetAuthUser(c)
+	chain := auth.GetAuthChain(c)
+
+	var user *models.User
+	email := strings.TrimSpace(body.Email)
+	if body.UserUID != "" {
+		var err error
+		user, err = models.UserGetByUID(db, body.UserUID, false)
+		if err != nil {
+			c.String(http.StatusNotFound, models.ErrUserNotFound.Error())
+			return
+		}
+	} else {
+		userID, found, err := models.UserCheckEmail(db, email)
+		if err != nil {
+			goscope.Log.Errorf("Unable to check email: %v", err)
+			c.String(http.StatusInternalServerError, "Unable to check email")
+			return
+		}
+		if found {
+			user = &models.User{}
+			db.Raw(`SELECT * FROM users WHERE id = ? LIMIT 1`, userID).Scan(user)
+		}
+	}
+
+	inv, httperr := services.ChainHostInvite(db, chain, authUser, user, email)
+	if httperr != nil {
+		c.String(httperr.Status, httperr.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, inv)
+}
+
+func ChainHostInvitationGetAll(c *gin.Context) {
+	db := getDB(c)
+
+	chain := auth.GetAuthChain(c)
+	invitations, err := models.ChainHostInvitationGetAllByChain(db, chain.ID)
+	if err != nil {
+		goscope.Log.Errorf("Unable to retrieve host invitations: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to retrieve host invitations")
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+func ChainHostInvitationRevoke(c *gin.Context) {
+	db := getDB(c)
+
+	var uri struct {
+		UID string `uri:"uid" binding:"required,uuid"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	chain := auth.GetAuthChain(c)
+	inv, err := models.ChainHostInvitationGetByUID(db, uri.UID)
+	if err != nil || inv.ChainID != chain.ID {
+		c.String(http.StatusNotFound, models.ErrChainHostInvitationNotFound.Error())
+		return
+	}
+
+	if err := inv.Delete(db); err != nil {
+		goscope.Log.Errorf("Unable to revoke host invitation: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to revoke host invitation")
+		return
+	}
+}
+
+// Lists the pending invitations of the authenticated user to become host
+func UserHostInvitationGetAll(c *gin.Context) {
+	db := getDB(c)
+
+	authUser := auth.GetAuthUser(c)
+	invitations, err := models.ChainHostInvitationGetAllByUser(db, authUser)
+	if err != nil {
+		goscope.Log.Errorf("Unable to retrieve host invitations: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to retrieve host invitations")
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+func UserHostInvitationAccept(c *gin.Context) {
+	db := getDB(c)
+
+	var uri struct {
+		UID string `uri:"uid" binding:"required,uuid"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	var body struct {
+		AllowTOH bool `json:"allow_toh" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || !body.AllowTOH {
+		c.String(http.StatusBadRequest, ErrAllowTOHFalse)
+		return
+	}
+
+	authUser := auth.GetAuthUser(c)
+	inv, err := models.ChainHostInvitationGetByUID(db, uri.UID)
+	if err != nil || !inv.IsFor(authUser) {
+		c.String(http.StatusNotFound, models.ErrChainHostInvitationNotFound.Error())
+		return
+	}
+
+	isNewMember, httperr := services.ChainHostInvitationAccept(db, authUser, inv)
+	if httperr != nil {
+		c.String(httperr.Status, httperr.Error())
+		return
+	}
+
+	if isNewMember {
+		chain := &models.Chain{}
+		db.Raw(`SELECT * FROM chains WHERE id = ? LIMIT 1`, inv.ChainID).Scan(chain)
+		cities := retrieveChainUsersAsTspCities(db, chain.ID)
+		newRoute, _ := tsp.RunAddOptimalOrderNewCity[string](cities, authUser.UID)
+		chain.SetRouteOrderByUserUIDs(db, newRoute)
+
+		if err := services.ChainUpdateCapacity(db, chain); err != nil {
+			goscope.Log.Errorf("Unable to update loop capacity: %v", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"chain_uid": inv.ChainUID})
+}
+
+func UserHostInvitationDecline(c *gin.Context) {
+	db := getDB(c)
+
+	var uri struct {
+		UID string `uri:"uid" binding:"required,uuid"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	authUser := auth.GetAuthUser(c)
+	inv, err := models.ChainHostInvitationGetByUID(db, uri.UID)
+	if err != nil || !inv.IsFor(authUser) {
+		c.String(http.StatusNotFound, models.ErrChainHostInvitationNotFound.Error())
+		return
+	}
+
+	if err := inv.Delete(db); err != nil {
+		goscope.Log.Errorf("Unable to decline host invitation: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to decline host invitation")
+		return
+	}
+}
@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/the-clothing-loop/website/server/internal/app/auth"
+	"github.com/the-clothing-loop/website/server/internal/app/goscope"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/internal/services"
+	"gopkg.in/guregu/null.v3/zero"
+)
+
+const chainInviteDefaultExpiresInDays = 7
+
+func ChainInviteCreate(c *gin.Context) {
+	db := getDB(c)
+
+	var body struct {
+		ChainUID      string `json:"chain_uid" binding:"required,uuid"`
+		ExpiresInDays int    `json:"expires_in_days" binding:"omitempty,min=1,max=90"`
+		// Leave empty for an unlimited amount of uses
+		MaxUses        *int `json:"max_uses" binding:"omitempty,min=1"`
+		IsAutoApproved bool `json:"is_auto_approved"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if body.ExpiresInDays == 0 {
+		body.ExpiresInDays = chainInviteDefaultExpiresInDays
+	}
+	maxUses := zero.Int{}
+	if body.MaxUses != nil {
+		maxUses = zero.IntFrom(int64(*body.MaxUses))
+	}
+
+	authUser := auth.GetAuthUser(c)
+	chain := auth.GetAuthChain(c)
+	expiresAt := time.Now().Add(time.Duration(body.ExpiresInDays) * 24 * time.Hour)
+	inv, err := models.ChainInviteCreate(db, chain.ID, authUser.ID, expiresAt, maxUses, body.IsAutoApproved)
+	if err != nil {
+		goscope.Log.Errorf("Unable to create invite: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to create invite")
+		return
+	}
+	inv.ChainUID = chain.UID
+	inv.ChainName = chain.Name
+	inv.CreatedByName = authUser.Name
+
+	c.JSON(http.StatusOK, inv)
+}
+
+// Lists the invites of the loop that can still be used
+func ChainInviteGetAll(c *gin.Context) {
+	db := getDB(c)
+
+	chain := auth.GetAuthChain(c)
+	invites, err := models.ChainInviteGetAllByChain(db, chain.ID)
+	if err != nil {
+		goscope.Log.Errorf("Unable to retrieve invites: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to retrieve invites")
+		return
+	}
+
+	c.JSON(http.StatusOK, invites)
+}
+
+func ChainInviteRevoke(c *gin.Context) {
+	db := getDB(c)
+
+	var uri struct {
+		UID string `uri:"uid" binding:"required,uuid"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	chain := auth.GetAuthChain(c)
+	inv, err := models.ChainInviteGetByUID(db, chain.ID, uri.UID)
+	if err != nil {
+		c.String(http.StatusNotFound, models.ErrChainInviteNotFound.Error())
+		return
+	}
+
+	if err := inv.Delete(db); err != nil {
+		goscope.Log.Errorf("Unable to revoke invite: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to revoke invite")
+		return
+	}
+}
+
+// Shows which loop an invite link or code is for, before the user joins
+func ChainInviteGet(c *gin.Context) {
+	db := getDB(c)
+
+	var query struct {
+		Invite string `form:"invite" binding:"required"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	inv, err := models.ChainInviteGetUsable(db, query.Invite)
+	if err != nil {
+		c.String(http.StatusNotFound, models.ErrChainInviteNotFound.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"chain_uid":        inv.ChainUID,
+		"chain_name":       inv.ChainName,
+		"is_auto_approved": inv.IsAutoApproved,
+		"expires_at":       inv.ExpiresAt,
+	})
+}
+
+// Joins the loop of an invite link or code as the authenticated user
+func ChainInviteAccept(c *gin.Context) {
+	db := getDB(c)
+
+	var body struct {
+		Invite  string                              `json:"invite" binding:"required"`
+		Answers []models.ChainQuestionAnswerRequest `json:"answers" binding:"dive"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	authUser := auth.GetAuthUser(c)
+	inv, err := models.ChainInviteGetUsable(db, body.Invite)
+	if err != nil {
+		c.String(http.StatusNotFound, models.ErrChainInviteNotFound.Error())
+		return
+	}
+
+	isApproved, httperr := services.ChainInviteJoin(db, inv, authUser, body.Answers)
+	if httperr != nil {
+		c.String(httperr.Status, httperr.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"chain_uid":   inv.ChainUID,
+		"is_approved": isApproved,
+	})
+}
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/the-clothing-loop/website/server/internal/app/auth"
+	"github.com/the-clothing-loop/website/server/internal/app/goscope"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/internal/services"
+)
+
+// Lists the loops of which the last host left, for root admins
+func ChainGetAllOrphaned(c *gin.Context) {
+	db := getDB(c)
+
+	chains := []struct {
+		models.ChainResponse
+		OrphanedAt time.Time `json:"orphaned_at" gorm:"orphaned_at"`
+	}{}
+	err := db.Raw(models.ChainResponseSQLSelect + `,
+	chains.orphaned_at,
+	(
+		SELECT COUNT(uc.id) FROM user_chains AS uc
+		WHERE uc.chain_id = chains.id AND uc.is_approved = TRUE
+	) AS total_members
+FROM chains
+WHERE chains.orphaned_at IS NOT NULL AND chains.deleted_at IS NULL
+ORDER BY chains.orphaned_at ASC
+	`).Scan(&chains).Error
+	if err != nil {
+		goscope.Log.Errorf("Unable to retrieve loops without host: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to retrieve loops without host")
+		return
+	}
+
+	c.JSON(http.StatusOK, chains)
+}
+
+// A member volunteers to become host of a loop of which the last host left
+func ChainBecomeHost(c *gin.Context) {
+	db := getDB(c)
+
+	var body struct {
+		ChainUID string `json:"chain_uid" binding:"required,uuid"`
+		AllowTOH bool   `json:"allow_toh" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || !body.AllowTOH {
+		c.String(http.StatusBadRequest, ErrAllowTOHFalse)
+		return
+	}
+
+	authUser := auth.GetAuthUser(c)
+	chain := auth.GetAuthChain(c)
+
+	if httperr := services.ChainBecomeHost(db, chain, authUser); httperr != nil {
+		c.String(httperr.Status, httperr.Error())
+		return
+	}
+}
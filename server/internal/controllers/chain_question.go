@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/the-clothing-loop/website/server/internal/app/auth"
+	"github.com/the-clothing-loop/website/server/internal/app/goscope"
+	"github.com/the-clothing-loop/website/server/internal/models"
+)
+
+// Lists the questions users answer when joining the loop
+func ChainQuestionGetAll(c *gin.Context) {
+	db := getDB(c)
+
+	var query struct {
+		ChainUID string `form:"chain_uid" binding:"required,uuid"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	chainID, _, err := models.ChainCheckIfExist(db, query.ChainUID, false)
+	if err != nil || chainID == 0 {
+		c.String(http.StatusNotFound, models.ErrChainNotFound.Error())
+		return
+	}
+
+	questions, err := models.ChainQuestionGetAllByChain(db, chainID)
+	if err != nil {
+		goscope.Log.Errorf("Unable to retrieve questions: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to retrieve questions")
+		return
+	}
+
+	c.JSON(http.StatusOK, questions)
+}
+
+// Replaces the questions of the loop, the order of the list is kept
+func ChainQuestionSetAll(c *gin.Context) {
+	db := getDB(c)
+
+	var body struct {
+		ChainUID  string                 `json:"chain_uid" binding:"required,uuid"`
+		Questions []models.ChainQuestion `json:"questions"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(body.Questions) > models.ChainQuestionsMax {
+		c.String(http.StatusBadRequest, fmt.Sprintf("A loop can have at most %d questions", models.ChainQuestionsMax))
+		return
+	}
+	for i := range body.Questions {
+		if !body.Questions[i].Validate() {
+			c.String(http.StatusBadRequest, models.ErrChainQuestionInvalid.Error())
+			return
+		}
+	}
+
+	chain := auth.GetAuthChain(c)
+	if err := models.ChainQuestionSetAll(db, chain.ID, body.Questions); err != nil {
+		goscope.Log.Errorf("Unable to update questions: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to update questions")
+		return
+	}
+
+	c.JSON(http.StatusOK, body.Questions)
+}
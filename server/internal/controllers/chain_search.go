@@ -0,0 +1,241 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/the-clothing-loop/website/server/internal/app/goscope"
+	"github.com/the-clothing-loop/website/server/internal/models"
+)
+
+const (
+	ChainSearchSortName     = "name"
+	ChainSearchSortDistance = "distance"
+	ChainSearchSortNewest   = "newest"
+)
+
+const chainSearchDefaultLimit = 20
+
+type chainSearchResult struct {
+	models.ChainResponse
+	ID       uint     `json:"-"`
+	Distance *float64 `json:"distance,omitempty"`
+}
+
+// Points to the last result of a page, the fields used depend on the sort order
+type chainSearchCursor struct {
+	Name     string  `json:"n,omitempty"`
+	Distance float64 `json:"d,omitempty"`
+	ID       uint    `json:"i"`
+}
+
+func (cur *chainSearchCursor) encode() string {
+	j, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(j)
+}
+
+func chainSearchCursorDecode(s string) (*chainSearchCursor, error) {
+	j, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	cur := &chainSearchCursor{}
+	if err := json.Unmarshal(j, cur); err != nil {
+		return nil, err
+	}
+	return cur, nil
+}
+
+// Turns user input into a boolean mode full-text query where every word must match as a prefix
+func chainSearchFullTextQuery(q string) string {
+	words := strings.FieldsFunc(q, func(r rune) bool {
+		return strings.ContainsRune(" \t\n+-<>()~*\"@", r)
+	})
+	for i, word := range words {
+		words[i] = "+" + word + "*"
+	}
+	return strings.Join(words, " ")
+}
+
+func splitCommaSeparated(values []string) []string {
+	result := []string{}
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			if s != "" {
+				result = append(result, s)
+			}
+		}
+	}
+	return result
+}
+
+// Loops must contain all of the given sizes and genders
+func chainFilterSql(sizes, genders []string) (whereSql []string, args []any) {
+	if len(sizes) > 0 {
+		j, _ := json.Marshal(sizes)
+		whereSql = append(whereSql, "JSON_CONTAINS(IF(JSON_VALID(chains.sizes), chains.sizes, '[]'), ?)")
+		args = append(args, string(j))
+	}
+	if len(genders) > 0 {
+		j, _ := json.Marshal(genders)
+		whereSql = append(whereSql, "JSON_CONTAINS(IF(JSON_VALID(chains.genders), chains.genders, '[]'), ?)")
+		args = append(args, string(j))
+	}
+	return whereSql, args
+}
+
+// Searches published loops, results are paginated with a cursor.
+// Loops must contain all of the given sizes and genders.
+func ChainSearch(c *gin.Context) {
+	db := getDB(c)
+
+	var query struct {
+		Query         string   `form:"q"`
+		Latitude      *float64 `form:"latitude" binding:"omitempty,latitude"`
+		Longitude     *float64 `form:"longitude" binding:"omitempty,longitude"`
+		Radius        float64  `form:"radius" binding:"omitempty,gt=0"`
+		CountryCode   string   `form:"country_code" binding:"omitempty,len=2"`
+		FilterSizes   []string `form:"filter_sizes"`
+		FilterGenders []string `form:"filter_genders"`
+		Sort          string   `form:"sort" binding:"omitempty,oneof='name' 'distance' 'newest'"`
+		Cursor        string   `form:"cursor"`
+		Limit         int      `form:"limit" binding:"omitempty,min=1,max=100"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil && err != io.EOF {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// arrays may also be sent comma separated
+	query.FilterSizes = splitCommaSeparated(query.FilterSizes)
+	query.FilterGenders = splitCommaSeparated(query.FilterGenders)
+	if ok := models.ValidateAllSizeEnum(query.FilterSizes); !ok {
+		c.String(http.StatusBadRequest, models.ErrSizeInvalid.Error())
+		return
+	}
+	if ok := models.ValidateAllGenderEnum(query.FilterGenders); !ok {
+		c.String(http.StatusBadRequest, models.ErrGenderInvalid.Error())
+		return
+	}
+	hasPoint := query.Latitude != nil && query.Longitude != nil
+	if !hasPoint && (query.Latitude != nil || query.Longitude != nil) {
+		c.String(http.StatusBadRequest, "Both latitude and longitude are required")
+		return
+	}
+	if query.Sort == "" {
+		query.Sort = ChainSearchSortName
+		if hasPoint {
+			query.Sort = ChainSearchSortDistance
+		}
+	}
+	if !hasPoint && (query.Sort == ChainSearchSortDistance || query.Radius != 0) {
+		c.String(http.StatusBadRequest, "Sorting or filtering by distance requires a latitude and longitude")
+		return
+	}
+	if query.Limit == 0 {
+		query.Limit = chainSearchDefaultLimit
+	}
+	var cursor *chainSearchCursor
+	if query.Cursor != "" {
+		var err error
+		cursor, err = chainSearchCursorDecode(query.Cursor)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+	}
+
+	sql := models.ChainResponseSQLSelect
+	args := []any{}
+	if hasPoint {
+		sql += fmt.Sprintf(`,
+%s AS distance`, sqlCalcDistance("chains.latitude", "chains.longitude", "?", "?"))
+		args = append(args, *query.Latitude, *query.Longitude)
+	}
+	sql += `
+FROM chains`
+
+	whereSql := []string{"chains.published = TRUE", "chains.deleted_at IS NULL"}
+	if ftQuery := chainSearchFullTextQuery(query.Query); ftQuery != "" {
+		whereSql = append(whereSql, "MATCH(chains.name, chains.description, chains.address) AGAINST(? IN BOOLEAN MODE)")
+		args = append(args, ftQuery)
+	}
+	if query.CountryCode != "" {
+		whereSql = append(whereSql, "chains.country_code = ?")
+		args = append(args, strings.ToUpper(query.CountryCode))
+	}
+	if query.Radius != 0 {
+		whereSql = append(whereSql, fmt.Sprintf("%s <= ?", sqlCalcDistance("chains.latitude", "chains.longitude", "?", "?")))
+		args = append(args, *query.Latitude, *query.Longitude, query.Radius)
+	}
+	filterSql, filterArgs := chainFilterSql(query.FilterSizes, query.FilterGenders)
+	whereSql = append(whereSql, filterSql...)
+	args = append(args, filterArgs...)
+	sql = fmt.Sprintf("%s WHERE %s", sql, strings.Join(whereSql, " AND "))
+
+	total := 0
+	err := db.Raw(fmt.Sprintf(`SELECT COUNT(*) FROM (%s) AS results`, sql), args...).Scan(&total).Error
+	if err != nil {
+		goscope.Log.Errorf("Unable to search loops: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to search loops")
+		return
+	}
+
+	pageSql := fmt.Sprintf(`SELECT * FROM (%s) AS results`, sql)
+	pageArgs := append([]any{}, args...)
+	var orderSql string
+	switch query.Sort {
+	case ChainSearchSortName:
+		orderSql = "results.name ASC, results.id ASC"
+		if cursor != nil {
+			pageSql += " WHERE (results.name > ? OR (results.name = ? AND results.id > ?))"
+			pageArgs = append(pageArgs, cursor.Name, cursor.Name, cursor.ID)
+		}
+	case ChainSearchSortDistance:
+		orderSql = "results.distance ASC, results.id ASC"
+		if cursor != nil {
+			pageSql += " WHERE (results.distance > ? OR (results.distance = ? AND results.id > ?))"
+			pageArgs = append(pageArgs, cursor.Distance, cursor.Distance, cursor.ID)
+		}
+	case ChainSearchSortNewest:
+		orderSql = "results.id DESC"
+		if cursor != nil {
+			pageSql += " WHERE results.id < ?"
+			pageArgs = append(pageArgs, cursor.ID)
+		}
+	}
+	// one extra result to find out if there is a next page
+	pageSql += fmt.Sprintf(" ORDER BY %s LIMIT ?", orderSql)
+	pageArgs = append(pageArgs, query.Limit+1)
+
+	chains := []chainSearchResult{}
+	err = db.Raw(pageSql, pageArgs...).Scan(&chains).Error
+	if err != nil {
+		goscope.Log.Errorf("Unable to search loops: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to search loops")
+		return
+	}
+
+	var nextCursor *string
+	if len(chains) > query.Limit {
+		chains = chains[:query.Limit]
+		last := chains[len(chains)-1]
+		cur := &chainSearchCursor{Name: last.Name, ID: last.ID}
+		if last.Distance != nil {
+			cur.Distance = *last.Distance
+		}
+		s := cur.encode()
+		nextCursor = &s
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"chains":      chains,
+		"total":       total,
+		"next_cursor": nextCursor,
+	})
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/the-clothing-loop/website/server/internal/app/auth"
+	"github.com/the-clothing-loop/website/server/internal/app/goscope"
+	"github.com/the-clothing-loop/website/server/internal/models"
+)
+
+// Adds the authenticated user to the waitlist of a loop that is closed to new members or full
+func ChainWaitlistJoin(c *gin.Context) {
+	db := getDB(c)
+
+	var body struct {
+		ChainUID string `json:"chain_uid" binding:"required,uuid"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	authUser := auth.GetAuthUser(c)
+	chain := auth.GetAuthChain(c)
+	if chain.OpenToNewMembers && !chain.IsFull(db) {
+		c.String(http.StatusConflict, "Loop is open to new members, join the loop instead")
+		return
+	}
+	if ok, _ := authUser.IsPartOfChain(chain.UID); ok {
+		c.String(http.StatusConflict, "User is already part of this loop")
+		return
+	}
+
+	w, err := models.ChainWaitlistJoin(db, chain.ID, authUser.ID)
+	if err != nil {
+		goscope.Log.Errorf("Unable to join waitlist: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to join waitlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, w)
+}
+
+// Users can leave a waitlist themselves, hosts can remove anyone
+func ChainWaitlistLeave(c *gin.Context) {
+	db := getDB(c)
+
+	var query struct {
+		ChainUID string `form:"chain_uid" binding:"required,uuid"`
+		UserUID  string `form:"user_uid" binding:"omitempty,uuid"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	authUser := auth.GetAuthUser(c)
+	chain := auth.GetAuthChain(c)
+	user := authUser
+	if query.UserUID != "" && query.UserUID != authUser.UID {
+		if !authUser.IsRootAdmin && !authUser.HasChainPermission(chain.UID, models.ChainPermissionManageMembers) {
+			c.String(http.StatusUnauthorized, "User role not high enough")
+			return
+		}
+
+		var err error
+		user, err = models.UserGetByUID(db, query.UserUID, false)
+		if err != nil {
+			c.String(http.StatusNotFound, models.ErrUserNotFound.Error())
+			return
+		}
+	}
+
+	err := models.ChainWaitlistLeave(db, chain.ID, user.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrChainWaitlistNotFound) {
+			c.String(http.StatusNotFound, err.Error())
+			return
+		}
+		goscope.Log.Errorf("Unable to leave waitlist: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to leave waitlist")
+		return
+	}
+}
+
+func ChainWaitlistGetAll(c *gin.Context) {
+	db := getDB(c)
+
+	chain := auth.GetAuthChain(c)
+	waitlist, err := models.ChainWaitlistGetAllByChain(db, chain.ID)
+	if err != nil {
+		goscope.Log.Errorf("Unable to retrieve waitlist: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to retrieve waitlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, waitlist)
+}
+
+func ChainWaitlistOrder(c *gin.Context) {
+	db := getDB(c)
+
+	var body struct {
+		ChainUID string   `json:"chain_uid" binding:"required,uuid"`
+		UserUIDs []string `json:"user_uids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	chain := auth.GetAuthChain(c)
+	err := models.ChainWaitlistSetOrder(db, chain.ID, body.UserUIDs)
+	if err != nil {
+		if errors.Is(err, models.ErrChainWaitlistNotFound) {
+			c.String(http.StatusBadRequest, "The order must contain every user on the waitlist")
+			return
+		}
+		goscope.Log.Errorf("Unable to reorder waitlist: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to reorder waitlist")
+		return
+	}
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/OneSignal/onesignal-go-api"
 	"github.com/go-playground/validator/v10"
@@ -10,6 +11,7 @@ import (
 	"github.com/the-clothing-loop/website/server/internal/app"
 	"github.com/the-clothing-loop/website/server/internal/app/auth"
 	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/internal/services"
 	"github.com/the-clothing-loop/website/server/internal/views"
 	"gorm.io/gorm"
 )
@@ -17,14 +19,23 @@ import (
 var validate = validator.New()
 
 func CronMonthly(db *gorm.DB) {
-	//closeChainsWithOldPendingParticipants(db)
 	emailHostsOldPendingParticipants(db)
 }
 
 func CronDaily(db *gorm.DB) {
 	emailSendAgain(db)
 	emailAbandonedChainRecruitment(db)
+	emailOrphanedChainRecruitment(db)
+	archiveOrphanedChains(db)
+	updateChainLifecycles(db)
 	auth.OtpDeleteOld(db)
+	auth.OtpAttemptDeleteOld(db)
+	auth.SessionDeleteOld(db)
+	auth.RefreshTokenDeleteOld(db)
+	models.UserEmailChangeDeleteOld(db)
+	models.ChainHostInvitationDeleteOld(db)
+	models.ChainInviteDeleteOld(db)
+	purgeDeletedChains(db)
 }
 
 func CronHourly(db *gorm.DB) {
@@ -47,6 +58,7 @@ FROM user_chains AS uc
 JOIN users AS u ON u.id = uc.user_id
 JOIN chains AS c ON c.id = uc.chain_id
 WHERE uc.is_approved = FALSE
+	AND c.deleted_at IS NULL
 	AND u.is_email_verified = TRUE
 	AND uc.created_at < (NOW() - INTERVAL 60 DAY)
 	AND uc.last_notified_is_unapproved_at IS NULL
@@ -137,27 +149,7 @@ ORDER BY u.email
 	}
 }
 
-// // Close chains if pending participants are still pending 30 days after reminder email is sent
-//
-//	func closeChainsWithOldPendingParticipants(db *gorm.DB) {
-//		glog.Info("Running closeChainsWithOldPendingParticipants")
-//		db.Exec(`
-//		UPDATE chains SET published = FALSE, open_to_new_members = FALSE, last_abandoned_at = NOW() WHERE id IN (
-//			SELECT DISTINCT(uc.chain_id)
-//			FROM user_chains AS uc
-//			JOIN chains AS c ON c.id = uc.chain_id
-//			WHERE uc.is_approved = FALSE
-//			AND uc.last_notified_is_unapproved_at < (NOW() - INTERVAL 30 DAY)
-//			AND c.published = TRUE
-//			AND c.id NOT IN (
-//				SELECT DISTINCT(uc2.chain_id) FROM user_chains AS uc2
-//				JOIN users AS u2 ON u2.id = uc2.user_id
-//				WHERE u2.last_signed_in_at > (NOW() - INTERVAL 90 DAY)
-//				AND uc2.is_chain_admin = TRUE
-//			)
-//		)
-//		`)
-//	}
+// Asks the participants of loops abandoned for a week to restart the loop as host
 func emailAbandonedChainRecruitment(db *gorm.DB) {
 	glog.Info("Running emailAbandonedChainRecruitment")
 	// Get the abandoned chains older than 7 days
@@ -166,11 +158,13 @@ func emailAbandonedChainRecruitment(db *gorm.DB) {
 SELECT UNIQUE(c2.id) FROM chains AS c2
 JOIN user_chains AS uc ON uc.chain_id = c2.id AND uc.is_chain_admin = FALSE
 JOIN users ON uc.user_id = users.id AND users.is_email_verified = TRUE
-WHERE c2.last_abandoned_at < (NOW() - INTERVAL 7 DAY)
-	AND c2.last_abandoned_recruitment_email IS NULL
+WHERE c2.lifecycle_state = ?
+	AND c2.abandoned_at < (NOW() - INTERVAL 7 DAY)
+	AND c2.abandoned_recruitment_email IS NULL
+	AND c2.deleted_at IS NULL
 GROUP BY c2.id
 HAVING COUNT(uc.id) > 0
-	`).Scan(&chainIDs).Error
+	`, models.ChainLifecycleAbandoned).Scan(&chainIDs).Error
 	if err != nil {
 		glog.Infof("Unable to get abandoned chains %s", err)
 		return
@@ -202,17 +196,138 @@ WHERE chains.id IN ? AND uc.is_chain_admin = FALSE
 		if !u.Email.Valid {
 			continue
 		}
-		views.EmailDoYouWantToBeHost(db, u.I18n, u.Name, u.Email.String, u.ChainName)
+		views.EmailDoYouWantToBeHost(db, u.I18n, u.Name, u.Email.String, u.ChainName, "", false)
 	}
 
 	// prevent duplicate emails
-	err = db.Exec(`UPDATE chains AS c SET c.last_abandoned_recruitment_email = NOW() WHERE c.id IN ?`, chainIDs).Error
+	err = db.Exec(`UPDATE chains AS c SET c.abandoned_recruitment_email = NOW() WHERE c.id IN ?`, chainIDs).Error
 	if err != nil {
 		glog.Errorf("Unable to prevent duplicate emails for abandoned chains %s", err)
 		return
 	}
 }
 
+// Invites the participants of loops without a host to volunteer as host,
+// loops of which the successor can still accept the host invitation are skipped.
+func emailOrphanedChainRecruitment(db *gorm.DB) {
+	glog.Info("Running emailOrphanedChainRecruitment")
+	chainIDs := []uint{}
+	err := db.Raw(`
+SELECT c.id FROM chains AS c
+WHERE c.orphaned_at IS NOT NULL
+	AND c.orphaned_recruitment_email IS NULL
+	AND c.deleted_at IS NULL
+	AND NOT EXISTS (
+		SELECT chi.id FROM chain_host_invitations AS chi
+		WHERE chi.chain_id = c.id AND chi.expires_at > NOW()
+	)
+	`).Scan(&chainIDs).Error
+	if err != nil {
+		glog.Errorf("Unable to get chains without host %s", err)
+		return
+	}
+	if len(chainIDs) == 0 {
+		return
+	}
+
+	users := []struct {
+		models.UserContactData
+		ChainUID string `gorm:"chain_uid"`
+	}{}
+	err = db.Raw(`
+SELECT
+	users.name AS name,
+	users.email AS email,
+	users.i18n AS i18n,
+	chains.name AS chain_name,
+	chains.uid AS chain_uid
+FROM user_chains AS uc
+JOIN users ON uc.user_id = users.id
+JOIN chains ON uc.chain_id = chains.id
+WHERE chains.id IN ? AND uc.is_approved = TRUE
+	`, chainIDs).Scan(&users).Error
+	if err != nil {
+		glog.Errorf("Unable to get participants of chains without host %s", err)
+		return
+	}
+
+	for _, u := range users {
+		if !u.Email.Valid {
+			continue
+		}
+		views.EmailDoYouWantToBeHost(db, u.I18n, u.Name, u.Email.String, u.ChainName, u.ChainUID, true)
+	}
+
+	// prevent duplicate emails
+	err = db.Exec(`UPDATE chains SET orphaned_recruitment_email = NOW() WHERE id IN ?`, chainIDs).Error
+	if err != nil {
+		glog.Errorf("Unable to prevent duplicate emails for chains without host %s", err)
+	}
+}
+
+// Archives the loops without a host for which no participant volunteered within the grace period
+func archiveOrphanedChains(db *gorm.DB) {
+	glog.Info("Running archiveOrphanedChains")
+	chains := []models.Chain{}
+	err := db.Raw(`
+SELECT * FROM chains
+WHERE orphaned_at < (NOW() - INTERVAL ? DAY) AND deleted_at IS NULL
+	`, models.ChainOrphanedGraceDays).Scan(&chains).Error
+	if err != nil {
+		glog.Errorf("Unable to get chains without host %s", err)
+		return
+	}
+
+	for i := range chains {
+		if httperr := services.ChainDelete(db, &chains[i]); httperr != nil {
+			glog.Errorf("Unable to archive chain without host %s: %s", chains[i].UID, httperr)
+		}
+	}
+}
+
+// Moves loops through their lifecycle, from active to inactive warning to abandoned to archived
+func updateChainLifecycles(db *gorm.DB) {
+	glog.Info("Running updateChainLifecycles")
+	lifecycles, err := models.ChainLifecycleGetAll(db)
+	if err != nil {
+		glog.Errorf("Unable to get chain lifecycles %s", err)
+		return
+	}
+
+	now := time.Now()
+	for i := range lifecycles {
+		l := &lifecycles[i]
+		state := l.NextState(now)
+		var err error
+		if state != l.State {
+			err = services.ChainLifecycleTransition(db, l, state)
+		} else if l.IsReminderDue(now) {
+			err = services.ChainLifecycleRemind(db, l)
+		}
+		if err != nil {
+			glog.Errorf("Unable to update lifecycle of chain %d: %s", l.ChainID, err)
+		}
+	}
+}
+
+// Permanently deletes the loops that have been soft deleted longer than the retention period
+func purgeDeletedChains(db *gorm.DB) {
+	glog.Info("Running purgeDeletedChains")
+	chains := []models.Chain{}
+	err := db.Raw(`SELECT * FROM chains WHERE deleted_at < (NOW() - INTERVAL ? DAY)`, models.ChainDeletedRetentionDays).Scan(&chains).Error
+	if err != nil {
+		glog.Errorf("Unable to get deleted chains %s", err)
+		return
+	}
+
+	for i := range chains {
+		err := chains[i].Delete(db)
+		if err != nil {
+			glog.Errorf("Unable to purge deleted chain %s: %s", chains[i].UID, err)
+		}
+	}
+}
+
 func notifyIfIsHoldingABagForTooLong(db *gorm.DB) {
 	glog.Info("Running notifyIfIsHoldingABagForTooLong")
 	res := &[]struct {
@@ -225,7 +340,9 @@ SELECT b.number as bag_number, u.uid as user_uid, b.id as bag_id
 FROM bags as b
 JOIN user_chains as uc ON b.user_chain_id = uc.id
 JOIN users as u ON uc.user_id = u.id
+JOIN chains as c ON uc.chain_id = c.id
 WHERE b.updated_at < ADDDATE(NOW(), INTERVAL -7 DAY)
+AND c.deleted_at IS NULL
 AND b.last_notified_at IS NULL
 	`).Scan(res)
 
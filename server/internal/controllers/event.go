@@ -46,16 +46,8 @@ func EventCreate(c *gin.Context) {
 		return
 	}
 
-	var minimumAuthState int
-	if body.ChainUID == "" {
-		minimumAuthState = auth.AuthState1AnyUser
-	} else {
-		minimumAuthState = auth.AuthState3AdminChainUser
-	}
-	ok, user, chain := auth.Authenticate(c, db, minimumAuthState, body.ChainUID)
-	if !ok {
-		return
-	}
+	user := auth.GetAuthUser(c)
+	chain := auth.GetAuthChain(c)
 
 	event := &models.Event{
 		UID:            uuid.NewV4().String(),
@@ -208,7 +200,7 @@ func EventDelete(c *gin.Context) {
 		return
 	}
 
-	ok, _, event := auth.AuthenticateEvent(c, db, uri.UID)
+	ok, event := auth.AuthorizeEvent(c, db, uri.UID)
 	if !ok {
 		return
 	}
@@ -256,7 +248,8 @@ func EventUpdate(c *gin.Context) {
 		}
 	}
 
-	ok, user, event := auth.AuthenticateEvent(c, db, body.UID)
+	user := auth.GetAuthUser(c)
+	ok, event := auth.AuthorizeEvent(c, db, body.UID)
 	if !ok {
 		return
 	}
@@ -270,7 +263,13 @@ func EventUpdate(c *gin.Context) {
 			return
 		}
 
-		if *body.ChainUID != "" {
+		if apiKey := auth.GetAuthApiKey(c); apiKey != nil {
+			// an api key can not move an event to a different loop
+			if *body.ChainUID != event.ChainUID.String {
+				c.AbortWithError(http.StatusForbidden, errors.New("Api key is not issued for this loop"))
+				return
+			}
+		} else if *body.ChainUID != "" {
 			found := false
 			for _, uc := range user.Chains {
 				if uc.ChainUID == *body.ChainUID {
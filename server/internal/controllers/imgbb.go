@@ -10,7 +10,6 @@ import (
 	"github.com/disintegration/imaging"
 	"github.com/gin-gonic/gin"
 	"github.com/the-clothing-loop/website/server/internal/app"
-	"github.com/the-clothing-loop/website/server/internal/app/auth"
 	"github.com/the-clothing-loop/website/server/internal/app/goscope"
 	"github.com/the-clothing-loop/website/server/pkg/imgbb"
 )
@@ -51,7 +50,6 @@ func imgBBupload(c *gin.Context, size int, expiration int) (imgRes *imgbb.ImgbbR
 	return image, nil
 }
 func ImageUpload(c *gin.Context) {
-	db := getDB(c)
 	var query struct {
 		Size       int `form:"size" binding:"required,min=10,max=800"`
 		Expiration int `form:"expiration" binding:"omitempty,min=60,max=15552000"`
@@ -60,11 +58,6 @@ func ImageUpload(c *gin.Context) {
 		c.String(http.StatusBadRequest, err.Error())
 		return
 	}
-	ok, _, _ := auth.Authenticate(c, db, auth.AuthState1AnyUser, "")
-	if !ok {
-		return
-	}
-
 	res, err := imgBBupload(c, query.Size, query.Expiration)
 	if err != nil {
 		goscope.Log.Warningf("Unable to upload image: %v", err)
@@ -86,7 +79,6 @@ func ImageUpload(c *gin.Context) {
 }
 
 func ImageDelete(c *gin.Context) {
-	db := getDB(c)
 	var query struct {
 		Url string `form:"url" binding:"required,url"`
 	}
@@ -94,10 +86,5 @@ func ImageDelete(c *gin.Context) {
 		c.String(http.StatusBadRequest, err.Error())
 		return
 	}
-	ok, _, _ := auth.Authenticate(c, db, auth.AuthState1AnyUser, "")
-	if !ok {
-		return
-	}
-
 	imgbb.DeleteAll([]string{query.Url})
 }
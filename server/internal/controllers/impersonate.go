@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/the-clothing-loop/website/server/internal/app/auth"
+	"github.com/the-clothing-loop/website/server/internal/app/goscope"
+	"github.com/the-clothing-loop/website/server/internal/models"
+)
+
+// Lets a root admin use the app as the user, the token is also set as cookie
+func ImpersonateStart(c *gin.Context) {
+	db := getDB(c)
+
+	var body struct {
+		UserUID string `json:"user_uid" binding:"required,uuid"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, err := models.UserGetByUID(db, body.UserUID, false)
+	if err != nil {
+		c.String(http.StatusNotFound, models.ErrUserNotFound.Error())
+		return
+	}
+
+	admin := auth.GetAuthUser(c)
+	token, session, err := auth.ImpersonationStart(db, admin, user, auth.SessionInfoFromRequest(c))
+	if err != nil {
+		if errors.Is(err, auth.ErrImpersonationNotAllowed) {
+			c.String(http.StatusForbidden, err.Error())
+			return
+		}
+		goscope.Log.Errorf("Unable to impersonate user: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to impersonate user")
+		return
+	}
+
+	auth.CookieSetImpersonation(c, token, time.Until(session.ExpiresAt.Time))
+	c.JSON(http.StatusOK, gin.H{
+		"token":      token,
+		"user_uid":   user.UID,
+		"expires_at": session.ExpiresAt.Time,
+	})
+}
+
+// Ends the impersonation session, the root admin continues with their own refresh token
+func ImpersonateStop(c *gin.Context) {
+	db := getDB(c)
+
+	session := auth.SessionGet(c)
+	if auth.GetImpersonator(c) == nil || session == nil {
+		c.String(http.StatusBadRequest, "Not impersonating a user")
+		return
+	}
+
+	err := session.Revoke(db)
+	if err != nil {
+		goscope.Log.Errorf("Unable to revoke session: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to revoke session")
+		return
+	}
+
+	auth.CookieRemoveImpersonation(c)
+}
+
+func ImpersonateLogGetAll(c *gin.Context) {
+	db := getDB(c)
+
+	var query struct {
+		UserUID string `form:"user_uid" binding:"omitempty,uuid"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var userID uint
+	if query.UserUID != "" {
+		user, err := models.UserGetByUID(db, query.UserUID, false)
+		if err != nil {
+			c.String(http.StatusNotFound, models.ErrUserNotFound.Error())
+			return
+		}
+		userID = user.ID
+	}
+
+	logs, err := models.UserImpersonationLogGetAll(db, userID, 500)
+	if err != nil {
+		goscope.Log.Errorf("Unable to retrieve impersonation log: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to retrieve impersonation log")
+		return
+	}
+
+	c.JSON(http.StatusOK, logs)
+}
@@ -1,8 +1,15 @@
 package controllers
 
 import (
+	"bytes"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/golang/glog"
 	"github.com/the-clothing-loop/website/server/internal/app"
@@ -11,10 +18,12 @@ import (
 	"github.com/the-clothing-loop/website/server/internal/models"
 	"github.com/the-clothing-loop/website/server/internal/services"
 	"github.com/the-clothing-loop/website/server/internal/views"
+	"github.com/the-clothing-loop/website/server/pkg/httperror"
 
 	"github.com/gin-gonic/gin"
 	uuid "github.com/satori/go.uuid"
 	"gopkg.in/guregu/null.v3/zero"
+	"gorm.io/gorm"
 )
 
 func LoginEmail(c *gin.Context) {
@@ -37,17 +46,17 @@ func LoginEmail(c *gin.Context) {
 		return
 	}
 
-	token, err := auth.OtpCreate(db, user.ID)
+	link, err := auth.MagicLinkCreate(db, user.ID, body.ChainUID)
 	if err != nil {
 		c.String(http.StatusInternalServerError, "Unable to create token")
 		return
 	}
 	if body.Email == app.Config.APPSTORE_REVIEWER_EMAIL {
-		c.String(http.StatusOK, token)
+		c.String(http.StatusOK, link.Otp)
 		return
 	}
 
-	err = views.EmailLoginVerification(c, db, user.Name, user.Email.String, token, body.IsApp, body.ChainUID)
+	err = views.EmailLoginVerification(c, db, user.Name, user.Email.String, link.Otp, link.Token, body.IsApp)
 	if err != nil {
 		glog.Errorf("Unable to send email: %v", err)
 		c.String(http.StatusInternalServerError, "Unable to send email")
@@ -55,34 +64,103 @@ func LoginEmail(c *gin.Context) {
 	}
 }
 
+// Identifies requests to /login/email by the email address in the body,
+// this is used to limit the amount of emails sent to a single address.
+func LoginEmailIdentify(req *http.Request) string {
+	if req.Body == nil {
+		return ""
+	}
+	b, _ := io.ReadAll(req.Body)
+	req.Body = io.NopCloser(bytes.NewReader(b))
+
+	var body struct {
+		Email string `json:"email"`
+	}
+	json.Unmarshal(b, &body)
+	return strings.ToLower(strings.TrimSpace(body.Email))
+}
+
 func LoginValidate(c *gin.Context) {
 	db := getDB(c)
 
+	// The website uses a magic link token, the app uses the email and one time password
 	var query struct {
-		OTP          string `form:"apiKey,required"`
-		EmailEncoded string `form:"u,required"`
+		MagicLink    string `form:"t"`
+		OTP          string `form:"apiKey"`
+		EmailEncoded string `form:"u"`
 		ChainUID     string `form:"c" binding:"omitempty,uuid"`
 	}
 	if err := c.ShouldBindQuery(&query); err != nil {
 		c.String(http.StatusBadRequest, "Malformed url: one time password required")
 		return
 	}
-	userEmail, err := base64.StdEncoding.DecodeString(query.EmailEncoded)
-	if err != nil {
-		c.String(http.StatusBadRequest, "Malformed url: email required")
-		return
+
+	var user *models.User
+	var tokens *auth.Tokens
+	var err error
+	chainUID := query.ChainUID
+	if query.MagicLink != "" {
+		user, tokens, chainUID, err = auth.MagicLinkVerify(db, query.MagicLink, auth.SessionInfoFromRequest(c))
+		if err != nil {
+			c.String(http.StatusUnauthorized, "Invalid token")
+			return
+		}
+	} else {
+		if query.OTP == "" {
+			c.String(http.StatusBadRequest, "Malformed url: one time password required")
+			return
+		}
+		userEmail, err := base64.StdEncoding.DecodeString(query.EmailEncoded)
+		if err != nil || len(userEmail) == 0 {
+			c.String(http.StatusBadRequest, "Malformed url: email required")
+			return
+		}
+		user, tokens, err = auth.OtpVerify(db, string(userEmail), query.OTP, auth.SessionInfoFromRequest(c))
+		if err != nil {
+			lockedErr := &auth.OtpLockedError{}
+			if errors.As(err, &lockedErr) {
+				if lockedErr.IsNew {
+					emailLoginLocked(db, string(userEmail), lockedErr.LockedUntil)
+				}
+				c.Header("Retry-After", strconv.Itoa(int(time.Until(lockedErr.LockedUntil).Seconds())+1))
+				c.String(http.StatusTooManyRequests, lockedErr.Error())
+				return
+			}
+			c.String(http.StatusUnauthorized, "Invalid token")
+			return
+		}
 	}
-	user, newToken, err := auth.OtpVerify(db, string(userEmail), query.OTP)
-	if err != nil {
-		c.String(http.StatusUnauthorized, "Invalid token")
+
+	isJoinFormRequired, httperr := loginFinish(db, user, chainUID)
+	if httperr != nil {
+		c.String(httperr.Status, httperr.Error())
 		return
 	}
 
-	err = user.AddUserChainsToObject(db)
+	// re-add IsEmailVerified, see TokenVerify
+	user.IsEmailVerified = true
+
+	// set token as cookie
+	auth.CookieSet(c, tokens)
+	c.JSON(200, gin.H{
+		"user":          user,
+		"token":         tokens.Access,
+		"refresh_token": tokens.Refresh,
+		"chain_uid":     chainUID,
+		// the loop of chain_uid has questions to answer before joining
+		"join_form": isJoinFormRequired,
+	})
+}
+
+// Runs after the user is verified by any login method.
+// On the first login the hosted loops are published and the hosts of the joined loops are notified,
+// otherwise the user joins the loop of chainUID.
+// Returns true if the loop has required questions, the user has to join through the join form instead.
+func loginFinish(db *gorm.DB, user *models.User, chainUID string) (bool, *httperror.HttpError) {
+	err := user.AddUserChainsToObject(db)
 	if err != nil {
 		goscope.Log.Errorf("%v: %v", models.ErrAddUserChainsToObject, err)
-		c.String(http.StatusInternalServerError, models.ErrAddUserChainsToObject.Error())
-		return
+		return false, httperror.New(http.StatusInternalServerError, models.ErrAddUserChainsToObject.Error())
 	}
 
 	// Is the first time verifying the user account
@@ -96,12 +174,26 @@ func LoginValidate(c *gin.Context) {
 
 		// Add all chains to be notified
 		chainIDs := []uint{}
+		approvedChainIDs := []uint{}
 		for _, uc := range user.Chains {
-			if !uc.IsChainAdmin {
+			if uc.IsChainAdmin {
+				continue
+			}
+			// members approved by an invite do not need to be approved by a host
+			if uc.IsApproved {
+				approvedChainIDs = append(approvedChainIDs, uc.ChainID)
+			} else {
 				chainIDs = append(chainIDs, uc.ChainID)
 			}
 		}
 
+		if len(approvedChainIDs) > 0 {
+			chainNames, _ := models.ChainGetNamesByIDs(db, approvedChainIDs...)
+			for _, chainName := range chainNames {
+				go views.EmailAnAdminApprovedYourJoinRequest(db, user.I18n, user.Name, user.Email.String, chainName)
+			}
+		}
+
 		if len(chainIDs) > 0 {
 			err = services.EmailLoopAdminsOnUserJoin(db, user, chainIDs...)
 			if err != nil {
@@ -112,46 +204,53 @@ func LoginValidate(c *gin.Context) {
 			chainNames, _ := models.ChainGetNamesByIDs(db, chainIDs...)
 			go services.EmailYouSignedUpForLoop(db, user, chainNames...)
 		}
-	} else if query.ChainUID != "" {
-		chainID, found, err := models.ChainCheckIfExist(db, query.ChainUID, true)
+	} else if chainUID != "" {
+		chain := &models.Chain{}
+		err := db.Raw(`SELECT * FROM chains WHERE uid = ? AND deleted_at IS NULL LIMIT 1`, chainUID).Scan(chain).Error
 		if err != nil {
 			goscope.Log.Errorf("Chain cannot be found: %v", err)
-			c.String(http.StatusInternalServerError, "Loop does not exist")
-			return
+			return false, httperror.New(http.StatusInternalServerError, "Loop does not exist")
 		}
-		if !found {
-			c.String(http.StatusFailedDependency, "Loop does not exist")
-			return
+		if chain.ID == 0 {
+			return false, httperror.New(http.StatusFailedDependency, "Loop does not exist")
 		}
-		_, found, err = models.UserChainCheckIfRelationExist(db, chainID, user.ID, false)
+		_, found, err := models.UserChainCheckIfRelationExist(db, chain.ID, user.ID, false)
 		if err != nil {
 			goscope.Log.Errorf("Chain connection unable to lookup: %v", err)
-			c.String(http.StatusInternalServerError, "Loop connection unable to lookup")
-			return
+			return false, httperror.New(http.StatusInternalServerError, "Loop connection unable to lookup")
 		}
 		if !found {
+			// a loop that is closed or full puts the user on its waitlist, like ChainAddUser
+			if !chain.OpenToNewMembers || chain.IsFull(db) {
+				if _, err := models.ChainWaitlistJoin(db, chain.ID, user.ID); err != nil {
+					goscope.Log.Errorf("Unable to join waitlist: %v", err)
+					return false, httperror.New(http.StatusInternalServerError, "Unable to join waitlist")
+				}
+				return false, nil
+			}
+
+			questions, err := models.ChainQuestionGetAllByChain(db, chain.ID)
+			if err != nil {
+				goscope.Log.Errorf("Unable to retrieve questions: %v", err)
+				return false, httperror.New(http.StatusInternalServerError, "Unable to retrieve questions")
+			}
+			if err := models.ChainQuestionValidateAnswers(questions, nil, true); err != nil {
+				return true, nil
+			}
+
 			db.Create(&models.UserChain{
 				UserID:       user.ID,
-				ChainID:      chainID,
+				ChainID:      chain.ID,
 				IsChainAdmin: false,
 				IsApproved:   false,
 			})
 
-			chainNames, _ := models.ChainGetNamesByIDs(db, chainID)
-			services.EmailYouSignedUpForLoop(db, user, chainNames...)
-			services.EmailLoopAdminsOnUserJoin(db, user, chainID)
+			services.EmailYouSignedUpForLoop(db, user, chain.Name)
+			services.EmailLoopAdminsOnUserJoin(db, user, chain.ID)
 		}
 	}
 
-	// re-add IsEmailVerified, see TokenVerify
-	user.IsEmailVerified = true
-
-	// set token as cookie
-	auth.CookieSet(c, newToken)
-	c.JSON(200, gin.H{
-		"user":  user,
-		"token": newToken,
-	})
+	return false, nil
 }
 
 // Sizes and Address is set to the user and the chain
@@ -237,14 +336,14 @@ func RegisterChainAdmin(c *gin.Context) {
 		Verified: false,
 	})
 
-	token, err := auth.OtpCreate(db, user.ID)
+	link, err := auth.MagicLinkCreate(db, user.ID, chain.UID)
 	if err != nil {
 		goscope.Log.Errorf("Unable to create token: %v", err)
 		c.String(http.StatusInternalServerError, "Unable to create token")
 		return
 	}
 
-	go views.EmailRegisterVerification(c, db, user.Name, user.Email.String, token, chain.UID)
+	go views.EmailRegisterVerification(c, db, user.Name, user.Email.String, link.Token)
 }
 
 func RegisterBasicUser(c *gin.Context) {
@@ -253,6 +352,10 @@ func RegisterBasicUser(c *gin.Context) {
 	var body struct {
 		ChainUID string                `json:"chain_uid" binding:"omitempty,uuid"`
 		User     UserCreateRequestBody `json:"user" binding:"required"`
+		// Answers to the questions of the loop
+		Answers []models.ChainQuestionAnswerRequest `json:"answers" binding:"dive"`
+		// Uid of an invite link or an invite code, replaces chain_uid
+		Invite string `json:"invite"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.String(http.StatusBadRequest, err.Error())
@@ -264,17 +367,46 @@ func RegisterBasicUser(c *gin.Context) {
 	}
 
 	var chainID uint
+	// a loop that is closed to new members or full puts the user on its waitlist
+	isWaitlisted := false
 	if body.ChainUID != "" {
-		var row struct {
-			ID uint `gorm:"id"`
-		}
-		err := db.Raw("SELECT id FROM chains WHERE uid = ? AND deleted_at IS NULL AND open_to_new_members = TRUE LIMIT 1", body.ChainUID).Scan(&row).Error
-		chainID = row.ID
+		chain := &models.Chain{}
+		err := db.Raw("SELECT * FROM chains WHERE uid = ? AND deleted_at IS NULL LIMIT 1", body.ChainUID).Scan(chain).Error
+		chainID = chain.ID
 		if chainID == 0 {
 			goscope.Log.Warningf("Chain does not exist: %v", err)
 			c.String(http.StatusBadRequest, "Chain does not exist")
 			return
 		}
+		isWaitlisted = !chain.OpenToNewMembers || chain.IsFull(db)
+	}
+	// an invite also works for loops that are closed to new members
+	var invite *models.ChainInvite
+	if body.Invite != "" {
+		var err error
+		invite, err = models.ChainInviteGetUsable(db, body.Invite)
+		if err != nil {
+			c.String(http.StatusNotFound, models.ErrChainInviteNotFound.Error())
+			return
+		}
+		chainID = invite.ChainID
+		body.ChainUID = invite.ChainUID
+		isWaitlisted = false
+	}
+	// the questions are answered again when joining from the waitlist
+	var questions []models.ChainQuestion
+	if chainID != 0 && !isWaitlisted {
+		var err error
+		questions, err = models.ChainQuestionGetAllByChain(db, chainID)
+		if err != nil {
+			goscope.Log.Errorf("Unable to retrieve questions: %v", err)
+			c.String(http.StatusInternalServerError, "Unable to retrieve questions")
+			return
+		}
+		if err := models.ChainQuestionValidateAnswers(questions, body.Answers, true); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
 	}
 
 	user := &models.User{
@@ -289,18 +421,45 @@ func RegisterBasicUser(c *gin.Context) {
 		Latitude:        body.User.Latitude,
 		Longitude:       body.User.Longitude,
 	}
-	if res := db.Create(user); res.Error != nil {
+	// the user is not created if joining the loop fails
+	tx := db.Begin()
+	if res := tx.Create(user); res.Error != nil {
+		tx.Rollback()
 		goscope.Log.Warningf("User already exists: %v", res.Error)
 		c.String(http.StatusConflict, "User already exists")
 		return
 	}
-	if body.ChainUID != "" {
-		db.Create(&models.UserChain{
+	var waitlist *models.ChainWaitlist
+	if isWaitlisted {
+		var err error
+		waitlist, err = models.ChainWaitlistJoin(tx, chainID, user.ID)
+		if err != nil {
+			tx.Rollback()
+			goscope.Log.Errorf("Unable to join waitlist: %v", err)
+			c.String(http.StatusInternalServerError, "Unable to join waitlist")
+			return
+		}
+	} else if invite != nil {
+		if _, httperr := services.ChainInviteJoin(tx, invite, user, body.Answers); httperr != nil {
+			tx.Rollback()
+			c.String(httperr.Status, httperr.Error())
+			return
+		}
+	} else if body.ChainUID != "" {
+		tx.Create(&models.UserChain{
 			UserID:       user.ID,
 			ChainID:      chainID,
 			IsChainAdmin: false,
 			IsApproved:   false,
 		})
+		if err := models.ChainQuestionAnswerSetAll(tx, chainID, user.ID, questions, body.Answers); err != nil {
+			goscope.Log.Errorf("Unable to save answers: %v", err)
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		goscope.Log.Errorf("Unable to create user: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to create user")
+		return
 	}
 	if body.User.Newsletter {
 		n := &models.Newsletter{
@@ -311,37 +470,86 @@ func RegisterBasicUser(c *gin.Context) {
 		n.CreateOrUpdate(db)
 	}
 
-	token, err := auth.OtpCreate(db, user.ID)
+	linkChainUID := body.ChainUID
+	if isWaitlisted {
+		linkChainUID = ""
+	}
+	link, err := auth.MagicLinkCreate(db, user.ID, linkChainUID)
 	if err != nil {
 		goscope.Log.Errorf("Unable to create token: %v", err)
 		c.String(http.StatusInternalServerError, "Unable to create token")
 		return
 	}
-	views.EmailRegisterVerification(c, db, user.Name, user.Email.String, token, body.ChainUID)
+	views.EmailRegisterVerification(c, db, user.Name, user.Email.String, link.Token)
+
+	if waitlist != nil {
+		c.JSON(http.StatusAccepted, waitlist)
+	}
 }
 
 func Logout(c *gin.Context) {
-	_, ok := auth.TokenReadFromRequest(c)
-	if !ok {
+	db := getDB(c)
+
+	token, scheme, ok := auth.TokenReadFromRequest(c)
+	if !ok || scheme != auth.TokenSchemeBearer {
 		c.String(http.StatusBadRequest, "No token received")
 		return
 	}
 
+	// revoke the session of this device
+	_, session, _, err := auth.AuthenticateToken(db, token)
+	if err == nil && session != nil {
+		if err := session.Revoke(db); err != nil {
+			goscope.Log.Errorf("Unable to revoke session: %v", err)
+		}
+		// the root admin stays logged in to their own account
+		if session.IsImpersonated() {
+			auth.CookieRemoveImpersonation(c)
+			return
+		}
+	}
+
 	auth.CookieRemove(c)
 }
 
+// The refresh token is single use, a new one is returned alongside the access token
 func RefreshToken(c *gin.Context) {
 	db := getDB(c)
 
-	ok, authUser, _ := auth.Authenticate(c, db, auth.AuthState1AnyUser, "")
-	if !ok {
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil && err != io.EOF {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if body.RefreshToken == "" {
+		body.RefreshToken, _ = auth.CookieReadRefresh(c)
+	}
+	if body.RefreshToken == "" {
+		c.String(http.StatusUnauthorized, "Refresh token not received")
 		return
 	}
 
-	token, err := auth.JwtGenerate(authUser)
+	_, tokens, err := auth.RefreshTokenRotate(db, body.RefreshToken)
 	if err != nil {
-		c.String(http.StatusUnauthorized, "Invalid token")
+		auth.CookieRemove(c)
+		c.String(http.StatusUnauthorized, "Invalid refresh token")
 		return
 	}
-	auth.CookieSet(c, token)
+
+	auth.CookieSet(c, tokens)
+	c.JSON(http.StatusOK, tokens)
+}
+
+func emailLoginLocked(db *gorm.DB, email string, lockedUntil time.Time) {
+	user, err := models.UserGetByEmail(db, email)
+	if err != nil {
+		return
+	}
+
+	err = views.EmailLoginLocked(db, user.I18n, user.Name, user.Email.String, lockedUntil.UTC().Format("2006-01-02 15:04 MST"))
+	if err != nil {
+		goscope.Log.Errorf("Unable to send email: %v", err)
+	}
 }
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/the-clothing-loop/website/server/internal/app"
+	"github.com/the-clothing-loop/website/server/internal/app/auth"
+	"github.com/the-clothing-loop/website/server/internal/app/goscope"
+)
+
+func LoginOidcProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, auth.OidcProviderList())
+}
+
+// Redirects the user to the identity provider
+func LoginOidcStart(c *gin.Context) {
+	var query struct {
+		ChainUID string `form:"c" binding:"omitempty,uuid"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	authURL, err := auth.OidcAuthCodeURL(c.Request.Context(), c.Param("provider"), query.ChainUID)
+	if err != nil {
+		if errors.Is(err, auth.ErrOidcProviderNotFound) {
+			c.String(http.StatusNotFound, err.Error())
+			return
+		}
+		goscope.Log.Errorf("Unable to start oidc login: %v", err)
+		c.String(http.StatusBadGateway, "Login provider is unavailable")
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// The identity provider redirects back to here.
+// The session is set as cookie before redirecting to the website,
+// which loads the logged in user by the user UID in the url.
+func LoginOidcCallback(c *gin.Context) {
+	db := getDB(c)
+
+	var query struct {
+		Code             string `form:"code"`
+		State            string `form:"state"`
+		Error            string `form:"error"`
+		ErrorDescription string `form:"error_description"`
+	}
+	c.ShouldBindQuery(&query)
+	if query.Error != "" || query.Code == "" || query.State == "" {
+		goscope.Log.Warningf("Oidc login canceled: %s %s", query.Error, query.ErrorDescription)
+		loginOidcRedirectError(c, "canceled")
+		return
+	}
+
+	user, tokens, chainUID, err := auth.OidcCallback(c.Request.Context(), db, c.Param("provider"), query.State, query.Code, auth.SessionInfoFromRequest(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrOidcUserNotFound):
+			loginOidcRedirectError(c, "not_registered")
+		case errors.Is(err, auth.ErrOidcEmailNotVerified):
+			loginOidcRedirectError(c, "email_not_verified")
+		case errors.Is(err, auth.ErrOidcStateInvalid), errors.Is(err, auth.ErrOidcProviderNotFound):
+			loginOidcRedirectError(c, "invalid")
+		default:
+			goscope.Log.Errorf("Unable to login with oidc: %v", err)
+			loginOidcRedirectError(c, "failed")
+		}
+		return
+	}
+
+	isJoinFormRequired, httperr := loginFinish(db, user, chainUID)
+	if httperr != nil {
+		loginOidcRedirectError(c, "failed")
+		return
+	}
+
+	auth.CookieSet(c, tokens)
+	q := url.Values{}
+	q.Set("oidc", user.UID)
+	if chainUID != "" {
+		q.Set("c", chainUID)
+	}
+	if isJoinFormRequired {
+		q.Set("join_form", "true")
+	}
+	c.Redirect(http.StatusFound, fmt.Sprintf("%s/users/login/validate?%s", app.Config.SITE_BASE_URL_FE, q.Encode()))
+}
+
+func loginOidcRedirectError(c *gin.Context, reason string) {
+	c.Redirect(http.StatusFound, fmt.Sprintf("%s/users/login?oidc_error=%s", app.Config.SITE_BASE_URL_FE, reason))
+}
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/the-clothing-loop/website/server/internal/app/auth"
+	"github.com/the-clothing-loop/website/server/internal/app/goscope"
+	"github.com/the-clothing-loop/website/server/internal/models"
+)
+
+// Returns the options for navigator.credentials.get()
+func LoginPasskeyBegin(c *gin.Context) {
+	options, err := auth.PasskeyLoginBegin()
+	if err != nil {
+		goscope.Log.Errorf("Unable to begin passkey login: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to begin passkey login")
+		return
+	}
+
+	c.JSON(http.StatusOK, options)
+}
+
+// An alternative to LoginValidate, responds the same way
+func LoginPasskeyFinish(c *gin.Context) {
+	db := getDB(c)
+
+	// the PublicKeyCredential returned by navigator.credentials.get()
+	body, err := c.GetRawData()
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, tokens, err := auth.PasskeyLoginFinish(db, body, auth.SessionInfoFromRequest(c))
+	if err != nil {
+		c.String(http.StatusUnauthorized, "Invalid passkey")
+		return
+	}
+
+	err = user.AddUserChainsToObject(db)
+	if err != nil {
+		goscope.Log.Errorf("%v: %v", models.ErrAddUserChainsToObject, err)
+		c.String(http.StatusInternalServerError, models.ErrAddUserChainsToObject.Error())
+		return
+	}
+
+	auth.CookieSet(c, tokens)
+	c.JSON(http.StatusOK, gin.H{
+		"user":          user,
+		"token":         tokens.Access,
+		"refresh_token": tokens.Refresh,
+	})
+}
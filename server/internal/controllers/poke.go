@@ -20,10 +20,7 @@ func Poke(c *gin.Context) {
 		return
 	}
 
-	ok, user, _ := auth.Authenticate(c, db, auth.AuthState1AnyUser, "")
-	if !ok {
-		return
-	}
+	user := auth.GetAuthUser(c)
 
 	if user.LastPokeTooRecent() {
 		c.String(http.StatusTooManyRequests, "Please wait a week before poking again")
@@ -23,10 +23,7 @@ func RouteOrderGet(c *gin.Context) {
 		return
 	}
 
-	ok, _, chain := auth.Authenticate(c, db, auth.AuthState2UserOfChain, query.ChainUID)
-	if !ok {
-		return
-	}
+	chain := auth.GetAuthChain(c)
 
 	routeOrder, err := chain.GetRouteOrderByUserUID(db)
 	if err != nil {
@@ -49,16 +46,22 @@ func RouteOrderSet(c *gin.Context) {
 		return
 	}
 
-	ok, _, chain := auth.Authenticate(c, db, auth.AuthState2UserOfChain, query.ChainUID)
-	if !ok {
-		return
-	}
+	authUser := auth.GetAuthUser(c)
+	chain := auth.GetAuthChain(c)
 
+	oldRouteOrder, _ := chain.GetRouteOrderByUserUID(db)
 	err := chain.SetRouteOrderByUserUIDs(db, query.RouteOrder)
 	if err != nil {
 		c.String(http.StatusBadRequest, models.ErrChainNotFound.Error())
 		return
 	}
+
+	newRouteOrder, _ := chain.GetRouteOrderByUserUID(db)
+	if changes := models.ChainAuditDiff(map[string]any{"route_order": oldRouteOrder}, map[string]any{"route_order": newRouteOrder}); len(changes) > 0 {
+		if err := models.ChainAuditLogCreate(db, chain.ID, authUser.ID, 0, models.ChainAuditActionRouteOrder, changes); err != nil {
+			goscope.Log.Errorf("Unable to add to loop history: %v", err)
+		}
+	}
 }
 
 func RouteOptimize(c *gin.Context) {
@@ -73,10 +76,7 @@ func RouteOptimize(c *gin.Context) {
 	}
 
 	// the authenticated user should be a chain admin
-	ok, _, chain := auth.Authenticate(c, db, auth.AuthState3AdminChainUser, query.ChainUID)
-	if !ok {
-		return
-	}
+	chain := auth.GetAuthChain(c)
 
 	// Given a ChainUID return an optimized route for all the approved participant of the loop
 	// with latitude and longitude.
@@ -101,10 +101,7 @@ func GetRouteCoordinates(c *gin.Context) {
 	}
 
 	// the authenticated user should be a chain admin
-	ok, _, chain := auth.Authenticate(c, db, auth.AuthState3AdminChainUser, query.ChainUID)
-	if !ok {
-		return
-	}
+	chain := auth.GetAuthChain(c)
 
 	cities := retrieveChainUsersAsTspCities(db, chain.ID)
 
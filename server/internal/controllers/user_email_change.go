@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/the-clothing-loop/website/server/internal/app/auth"
+	"github.com/the-clothing-loop/website/server/internal/app/goscope"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/internal/services"
+	"github.com/the-clothing-loop/website/server/internal/views"
+)
+
+// Sends a confirmation link to the new email address and a notice to the current one,
+// root admins can request this on behalf of a user.
+func UserEmailChangeRequest(c *gin.Context) {
+	db := getDB(c)
+
+	var body struct {
+		UserUID string `json:"user_uid" binding:"required,uuid"`
+		Email   string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	newEmail := strings.TrimSpace(body.Email)
+
+	authUser := auth.GetAuthUser(c)
+	user := authUser
+	if body.UserUID != authUser.UID {
+		if !authUser.IsRootAdmin {
+			c.String(http.StatusUnauthorized, "Only a root admin can change the email of a different user")
+			return
+		}
+
+		var err error
+		user, err = models.UserGetByUID(db, body.UserUID, false)
+		if err != nil {
+			c.String(http.StatusNotFound, models.ErrUserNotFound.Error())
+			return
+		}
+	}
+
+	if strings.EqualFold(user.Email.String, newEmail) {
+		c.String(http.StatusBadRequest, "New email is the same as the current email")
+		return
+	}
+	_, found, err := models.UserCheckEmail(db, newEmail)
+	if err != nil {
+		goscope.Log.Errorf("Unable to check email: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to check email")
+		return
+	}
+	if found {
+		c.String(http.StatusConflict, "Email is already in use")
+		return
+	}
+
+	token, err := models.UserEmailChangeCreate(db, user.ID, authUser.ID, newEmail)
+	if err != nil {
+		goscope.Log.Errorf("Unable to create email change: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to create email change")
+		return
+	}
+
+	err = views.EmailChangeEmailConfirmation(c, db, user.Name, newEmail, token)
+	if err != nil {
+		goscope.Log.Errorf("Unable to send email: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to send email")
+		return
+	}
+	if user.Email.Valid {
+		err = views.EmailChangeEmailNotice(db, user.I18n, user.Name, user.Email.String, newEmail)
+		if err != nil {
+			goscope.Log.Errorf("Unable to send email: %v", err)
+		}
+	}
+}
+
+// The token of the confirmation link is proof of access to the new email address
+func UserEmailChangeConfirm(c *gin.Context) {
+	db := getDB(c)
+
+	var body struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ec, err := models.UserEmailChangeGetByToken(db, body.Token)
+	if err != nil {
+		c.String(http.StatusNotFound, models.ErrUserEmailChangeNotFound.Error())
+		return
+	}
+
+	user, httperr := services.UserChangeEmail(c.Request.Context(), db, ec)
+	if httperr != nil {
+		c.String(httperr.Status, httperr.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_uid": user.UID,
+		"email":    user.Email.String,
+	})
+}
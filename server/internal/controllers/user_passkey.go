@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/the-clothing-loop/website/server/internal/app/auth"
+	"github.com/the-clothing-loop/website/server/internal/app/goscope"
+	"github.com/the-clothing-loop/website/server/internal/models"
+)
+
+func UserPasskeyGetAll(c *gin.Context) {
+	db := getDB(c)
+
+	authUser := auth.GetAuthUser(c)
+
+	passkeys, err := models.UserPasskeyGetAllByUser(db, authUser.ID)
+	if err != nil {
+		goscope.Log.Errorf("Unable to retrieve passkeys: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to retrieve passkeys")
+		return
+	}
+
+	c.JSON(http.StatusOK, passkeys)
+}
+
+// Returns the options for navigator.credentials.create()
+func UserPasskeyRegisterBegin(c *gin.Context) {
+	db := getDB(c)
+
+	authUser := auth.GetAuthUser(c)
+
+	options, err := auth.PasskeyRegisterBegin(db, authUser)
+	if err != nil {
+		goscope.Log.Errorf("Unable to begin passkey registration: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to begin passkey registration")
+		return
+	}
+
+	c.JSON(http.StatusOK, options)
+}
+
+func UserPasskeyRegisterFinish(c *gin.Context) {
+	db := getDB(c)
+
+	var body struct {
+		Name string `json:"name" binding:"required,max=100"`
+		// the PublicKeyCredential returned by navigator.credentials.create()
+		Credential json.RawMessage `json:"credential" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	authUser := auth.GetAuthUser(c)
+
+	passkey, err := auth.PasskeyRegisterFinish(db, authUser, body.Name, body.Credential)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Unable to register passkey: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, passkey)
+}
+
+func UserPasskeyDelete(c *gin.Context) {
+	db := getDB(c)
+
+	var uri struct {
+		UID string `uri:"uid" binding:"required,uuid"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	authUser := auth.GetAuthUser(c)
+
+	passkey, err := models.UserPasskeyGetByUID(db, uri.UID)
+	if err != nil || passkey.UserID != authUser.ID {
+		c.String(http.StatusNotFound, models.ErrUserPasskeyNotFound.Error())
+		return
+	}
+
+	err = passkey.Delete(db)
+	if err != nil {
+		goscope.Log.Errorf("Unable to delete passkey: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to delete passkey")
+		return
+	}
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/the-clothing-loop/website/server/internal/app/auth"
+	"github.com/the-clothing-loop/website/server/internal/app/goscope"
+	"github.com/the-clothing-loop/website/server/internal/models"
+)
+
+func UserSessionGetAll(c *gin.Context) {
+	db := getDB(c)
+
+	authUser := auth.GetAuthUser(c)
+
+	sessions, err := models.UserSessionGetAllActiveByUser(db, authUser.ID)
+	if err != nil {
+		goscope.Log.Errorf("Unable to retrieve sessions: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to retrieve sessions")
+		return
+	}
+
+	if current := auth.SessionGet(c); current != nil {
+		for i := range sessions {
+			sessions[i].IsCurrent = sessions[i].ID == current.ID
+		}
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+func UserSessionDelete(c *gin.Context) {
+	db := getDB(c)
+
+	var uri struct {
+		UID string `uri:"uid" binding:"required,uuid"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	authUser := auth.GetAuthUser(c)
+
+	session, err := models.UserSessionGetByUID(db, uri.UID)
+	if err != nil || session.UserID != authUser.ID {
+		c.String(http.StatusNotFound, models.ErrUserSessionNotFound.Error())
+		return
+	}
+
+	err = session.Revoke(db)
+	if err != nil {
+		goscope.Log.Errorf("Unable to revoke session: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to revoke session")
+		return
+	}
+
+	// logging out this device
+	if current := auth.SessionGet(c); current != nil && current.ID == session.ID {
+		auth.CookieRemove(c)
+	}
+}
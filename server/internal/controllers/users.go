@@ -6,7 +6,6 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/golang/glog"
 	"github.com/samber/lo"
 	"github.com/the-clothing-loop/website/server/internal/app"
 	"github.com/the-clothing-loop/website/server/internal/app/auth"
@@ -53,19 +52,9 @@ func UserGet(c *gin.Context) {
 		return
 	}
 
-	ok := false
-	var authUser *models.User
-	if query.ChainUID == "" {
-		ok, authUser, _ = auth.Authenticate(c, db, auth.AuthState1AnyUser, "")
-
-		if !ok || query.UserUID != authUser.UID {
-			c.String(http.StatusUnauthorized, "For elevated privileges include a chain_uid")
-			return
-		}
-	} else {
-		ok, authUser, _ = auth.Authenticate(c, db, auth.AuthState3AdminChainUser, query.ChainUID)
-	}
-	if !ok {
+	authUser := auth.GetAuthUser(c)
+	if query.ChainUID == "" && query.UserUID != authUser.UID {
+		c.String(http.StatusUnauthorized, "For elevated privileges include a chain_uid")
 		return
 	}
 	isMe := authUser.UID == query.UserUID
@@ -113,13 +102,11 @@ func UserGetAllOfChain(c *gin.Context) {
 		return
 	}
 
-	ok, authUser, chain := auth.Authenticate(c, db, auth.AuthState2UserOfChain, query.ChainUID)
-	if !ok {
-		return
-	}
+	authUser := auth.GetAuthUser(c)
+	chain := auth.GetAuthChain(c)
 
-	_, isAuthUserChainAdmin := authUser.IsPartOfChain(chain.UID)
-	isAuthState3AdminChainUser := isAuthUserChainAdmin || authUser.IsRootAdmin
+	// hosts and moderators see the details of all members
+	isAuthUserMemberManager := authUser.IsRootAdmin || authUser.HasChainPermission(chain.UID, models.ChainPermissionManageMembers)
 
 	// retrieve user from query
 	tx := db.Begin()
@@ -150,8 +137,34 @@ func UserGetAllOfChain(c *gin.Context) {
 		users[i].Chains = thisUserChains
 	}
 
+	// answers to the questions of the loop help to approve or deny a join request
+	if isAuthUserMemberManager {
+		pendingUserIDs := []uint{}
+		for _, userChain := range allUserChains {
+			if userChain.ChainID == chain.ID && !userChain.IsApproved {
+				pendingUserIDs = append(pendingUserIDs, userChain.UserID)
+			}
+		}
+		if len(pendingUserIDs) > 0 {
+			answers, err := models.ChainQuestionAnswerGetAllByUsers(db, chain.ID, pendingUserIDs...)
+			if err != nil {
+				goscope.Log.Errorf("Unable to retrieve answers: %v", err)
+				c.String(http.StatusInternalServerError, "Unable to retrieve answers")
+				return
+			}
+			for i := range users {
+				for ii := range users[i].Chains {
+					userChain := &users[i].Chains[ii]
+					if userChain.ChainID == chain.ID && !userChain.IsApproved {
+						userChain.JoinAnswers = answers[userChain.UserID]
+					}
+				}
+			}
+		}
+	}
+
 	// omit user data from participants
-	if !isAuthState3AdminChainUser {
+	if !isAuthUserMemberManager {
 		users, err = omitUserData(db, chain, users, authUser.UID)
 
 		if err != nil {
@@ -176,7 +189,7 @@ func UserHasNewsletter(c *gin.Context) {
 		return
 	}
 
-	ok, user, _, _ := auth.AuthenticateUserOfChain(c, db, query.ChainUID, query.UserUID)
+	ok, user := auth.AuthorizeUserOfChain(c, db, query.UserUID)
 	if !ok {
 		return
 	}
@@ -209,7 +222,7 @@ func UserUpdate(c *gin.Context) {
 		return
 	}
 
-	ok, user, _, _ := auth.AuthenticateUserOfChain(c, db, body.ChainUID, body.UserUID)
+	ok, user := auth.AuthorizeUserOfChain(c, db, body.UserUID)
 	if !ok {
 		return
 	}
@@ -257,22 +270,10 @@ func UserUpdate(c *gin.Context) {
 			userChanges["accepted_toh"] = *body.AcceptedLegal
 			userChanges["accepted_dpa"] = *body.AcceptedLegal
 			if !*body.AcceptedLegal {
-				// set as participant for all connected chains
-				db.Exec(`UPDATE user_chains SET is_chain_admin = FALSE WHERE user_id = ?`, user.ID)
-				// find chains that don't have 1+ host and if connected to this user set to draft
-				db.Exec(`
-UPDATE chains AS c
-JOIN user_chains AS uc ON uc.chain_id = c.id
-SET c.open_to_new_members = FALSE, c.published = FALSE
-WHERE uc.user_id = ? AND c.id IN (
-	SELECT UNIQUE(c2.id)
-	FROM chains AS c2
-	LEFT JOIN user_chains AS uc2 ON uc2.chain_id = c2.id AND uc2.is_chain_admin = TRUE
-	WHERE c2.published = TRUE
-	GROUP BY c2.id
-	HAVING COUNT(uc2.id) = 0
-)
-				`, user.ID)
+				if httperr := services.UserDeclineLegal(db, user); httperr != nil {
+					c.String(httperr.Status, httperr.Error())
+					return
+				}
 			}
 		}
 		if len(userChanges) > 0 {
@@ -328,10 +329,7 @@ func UserPurge(c *gin.Context) {
 		return
 	}
 
-	ok, user, _ := auth.Authenticate(c, db, auth.AuthState1AnyUser, "")
-	if !ok {
-		return
-	}
+	user := auth.GetAuthUser(c)
 	if user.UID != query.UserUID {
 		if user.IsRootAdmin {
 			err := db.Raw(`SELECT * FROM users WHERE uid = ? LIMIT 1`, query.UserUID).Scan(user).Error
@@ -359,18 +357,42 @@ func UserPurge(c *gin.Context) {
 	services.EmailLoopAdminsOnUserLeft(db, user.Name, user.Email.String, "", chainIDs...)
 
 	// find chains where user is the last chain admin
-	chainIDsToDelete := []uint{}
-	db.Raw(`
-SELECT uc.chain_id
-FROM  user_chains AS uc
-WHERE uc.chain_id IN (
-	SELECT uc2.chain_id
-	FROM user_chains AS uc2
-	WHERE uc2.is_chain_admin = TRUE AND uc2.user_id = ?
-) AND uc.is_chain_admin = TRUE
-GROUP BY uc.chain_id
-HAVING COUNT(uc.id) = 1
-	`, user.ID).Scan(&chainIDsToDelete)
+	lastHostChainIDs, err := user.GetLastHostChainIDs(db)
+	if err != nil {
+		goscope.Log.Errorf("UserPurge: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to find hosted loops")
+		return
+	}
+
+	// loops with other members are kept without a host, empty loops are soft deleted
+	chainsToOrphan := []models.Chain{}
+	if len(lastHostChainIDs) > 0 {
+		db.Raw(`
+SELECT * FROM chains
+WHERE id IN ? AND id IN (SELECT chain_id FROM user_chains WHERE user_id != ?)
+		`, lastHostChainIDs, user.ID).Scan(&chainsToOrphan)
+	}
+	chainIDsToDelete := lo.Without(lastHostChainIDs, lo.Map(chainsToOrphan, func(chain models.Chain, _ int) uint {
+		return chain.ID
+	})...)
+
+	// successors are given per loop as successors[chain_uid]=user_uid
+	successors := map[uint]*models.User{}
+	for chainUID, successorUID := range c.QueryMap("successors") {
+		chain, ok := lo.Find(chainsToOrphan, func(chain models.Chain) bool {
+			return chain.UID == chainUID
+		})
+		if !ok {
+			c.String(http.StatusBadRequest, "A successor can only be chosen for a loop you are the last host of")
+			return
+		}
+		successor, httperr := services.ChainGetSuccessor(db, &chain, user.ID, successorUID)
+		if httperr != nil {
+			c.String(httperr.Status, httperr.Error())
+			return
+		}
+		successors[chain.ID] = successor
+	}
 
 	tx := db.Begin()
 
@@ -407,6 +429,111 @@ UPDATE events SET user_id = (
 		c.String(http.StatusInternalServerError, "Unable to remove token connections")
 		return
 	}
+	err = tx.Exec(`DELETE FROM user_refresh_tokens WHERE user_id = ?`, user.ID).Error
+	if err != nil {
+		tx.Rollback()
+		goscope.Log.Errorf("UserPurge: Unable to remove refresh token connections: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to remove refresh token connections")
+		return
+	}
+	err = tx.Exec(`DELETE FROM user_passkeys WHERE user_id = ?`, user.ID).Error
+	if err != nil {
+		tx.Rollback()
+		goscope.Log.Errorf("UserPurge: Unable to remove passkeys: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to remove passkeys")
+		return
+	}
+	// the audit trail of impersonations is kept without the user
+	err = tx.Exec(`UPDATE user_impersonation_logs SET user_id = NULL WHERE user_id = ?`, user.ID).Error
+	if err != nil {
+		tx.Rollback()
+		goscope.Log.Errorf("UserPurge: Unable to remove user from impersonation logs: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to remove user from impersonation logs")
+		return
+	}
+	err = tx.Exec(`UPDATE user_impersonation_logs SET admin_user_id = NULL WHERE admin_user_id = ?`, user.ID).Error
+	if err != nil {
+		tx.Rollback()
+		goscope.Log.Errorf("UserPurge: Unable to remove user from impersonation logs: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to remove user from impersonation logs")
+		return
+	}
+	// partner integrations keep working, their api keys are handed over to another root admin
+	err = tx.Exec(`
+UPDATE api_keys
+SET created_by_user_id = (SELECT id FROM users WHERE is_root_admin = TRUE AND id != ? ORDER BY id ASC LIMIT 1)
+WHERE created_by_user_id = ?
+	`, user.ID, user.ID).Error
+	if err != nil {
+		tx.Rollback()
+		goscope.Log.Errorf("UserPurge: Unable to hand over api keys: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to hand over api keys")
+		return
+	}
+	err = tx.Exec(`DELETE FROM chain_host_invitations WHERE user_id = ? OR invited_by_user_id = ?`, user.ID, user.ID).Error
+	if err != nil {
+		tx.Rollback()
+		goscope.Log.Errorf("UserPurge: Unable to remove host invitations: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to remove host invitations")
+		return
+	}
+	err = tx.Exec(`DELETE FROM chain_waitlists WHERE user_id = ?`, user.ID).Error
+	if err != nil {
+		tx.Rollback()
+		goscope.Log.Errorf("UserPurge: Unable to remove waitlist entries: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to remove waitlist entries")
+		return
+	}
+	err = tx.Exec(`DELETE FROM chain_invites WHERE created_by_user_id = ?`, user.ID).Error
+	if err != nil {
+		tx.Rollback()
+		goscope.Log.Errorf("UserPurge: Unable to remove invites: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to remove invites")
+		return
+	}
+	err = tx.Exec(`DELETE FROM chain_question_answers WHERE user_id = ?`, user.ID).Error
+	if err != nil {
+		tx.Rollback()
+		goscope.Log.Errorf("UserPurge: Unable to remove answers to loop questions: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to remove answers to loop questions")
+		return
+	}
+	// the history of loops is kept without the user
+	err = tx.Exec(`UPDATE chain_audit_logs SET actor_user_id = NULL WHERE actor_user_id = ?`, user.ID).Error
+	if err != nil {
+		tx.Rollback()
+		goscope.Log.Errorf("UserPurge: Unable to remove user from loop history: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to remove user from loop history")
+		return
+	}
+	err = tx.Exec(`UPDATE chain_audit_logs SET target_user_id = NULL WHERE target_user_id = ?`, user.ID).Error
+	if err != nil {
+		tx.Rollback()
+		goscope.Log.Errorf("UserPurge: Unable to remove user from loop history: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to remove user from loop history")
+		return
+	}
+	err = tx.Exec(`DELETE FROM user_oidc_identities WHERE user_id = ?`, user.ID).Error
+	if err != nil {
+		tx.Rollback()
+		goscope.Log.Errorf("UserPurge: Unable to remove oidc identities: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to remove linked login providers")
+		return
+	}
+	err = tx.Exec(`DELETE FROM user_email_changes WHERE user_id = ?`, user.ID).Error
+	if err != nil {
+		tx.Rollback()
+		goscope.Log.Errorf("UserPurge: Unable to remove email changes: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to remove email changes")
+		return
+	}
+	err = tx.Exec(`DELETE FROM user_sessions WHERE user_id = ?`, user.ID).Error
+	if err != nil {
+		tx.Rollback()
+		goscope.Log.Errorf("UserPurge: Unable to remove session connections: %v", err)
+		c.String(http.StatusInternalServerError, "Unable to remove session connections")
+		return
+	}
 	err = tx.Exec(`DELETE FROM user_onesignals WHERE user_id = ?`, user.ID).Error
 	if err != nil {
 		tx.Rollback()
@@ -414,6 +541,16 @@ UPDATE events SET user_id = (
 		c.String(http.StatusInternalServerError, "Unable to remove onesignal connections")
 		return
 	}
+	// the successors are invited by the system, the invitation must not point to the deleted user
+	for i := range chainsToOrphan {
+		chain := &chainsToOrphan[i]
+		if httperr := services.ChainOrphan(tx, chain, nil, successors[chain.ID]); httperr != nil {
+			tx.Rollback()
+			goscope.Log.Errorf("UserPurge: Unable to hand over loop: %v", httperr)
+			c.String(httperr.Status, "Unable to hand over hosted loop")
+			return
+		}
+	}
 	err = tx.Exec(`DELETE FROM users WHERE id = ?`, user.ID).Error
 	if err != nil {
 		tx.Rollback()
@@ -422,25 +559,9 @@ UPDATE events SET user_id = (
 		return
 	}
 
-	glog.Infof("Purging chains %v", chainIDsToDelete)
-	if len(chainIDsToDelete) > 0 {
-		err := tx.Exec(`DELETE FROM bags WHERE user_chain_id IN (
-			SELECT id FROM user_chains WHERE chain_id IN ?
-		)`, chainIDsToDelete).Error
-		if err != nil {
-			tx.Rollback()
-			goscope.Log.Errorf("UserPurge: %v", err)
-			c.String(http.StatusInternalServerError, "Unable to disconnect all loop bag connections")
-			return
-		}
-		err = tx.Exec(`DELETE FROM user_chains WHERE chain_id IN ?`, chainIDsToDelete).Error
-		if err != nil {
-			tx.Rollback()
-			goscope.Log.Errorf("UserPurge: Unable to remove hosted loop connections: %v", err)
-			c.String(http.StatusInternalServerError, "Unable to remove hosted loop connections")
-			return
-		}
-		err = tx.Exec(`DELETE FROM chains WHERE id IN ?`, chainIDsToDelete).Error
+	// empty loops are soft deleted, purgeDeletedChains removes them after the retention period
+	for _, chainID := range chainIDsToDelete {
+		err = (&models.Chain{ID: chainID}).SoftDelete(tx)
 		if err != nil {
 			tx.Rollback()
 			goscope.Log.Errorf("UserPurge: Unable to remove hosted loop: %v", err)
@@ -466,6 +587,9 @@ UPDATE events SET user_id = (
 	}
 
 	tx.Commit()
+
+	// loops closed at their member limit may have room again
+	services.ChainUpdateCapacityByIDs(db, chainIDs...)
 }
 
 func UserTransferChain(c *gin.Context) {
@@ -482,15 +606,12 @@ func UserTransferChain(c *gin.Context) {
 		return
 	}
 
-	ok, authUser, authChain := auth.Authenticate(c, db, auth.AuthState3AdminChainUser, body.FromChainUID)
-	if !ok {
-		return
-	}
+	authUser := auth.GetAuthUser(c)
+	authChain := auth.GetAuthChain(c)
 
 	if !authUser.IsRootAdmin {
-		_, isChainAdmin := authUser.IsPartOfChain(body.ToChainUID)
-		if !isChainAdmin {
-			c.String(http.StatusUnauthorized, "you must be a host of both loops")
+		if !authUser.HasChainPermission(body.ToChainUID, models.ChainPermissionManageMembers) {
+			c.String(http.StatusUnauthorized, "you must be allowed to manage the members of both loops")
 			return
 		}
 	}
@@ -544,6 +665,15 @@ LIMIT 1
 		return
 	}
 
+	// for the history of both loops
+	toChain := &models.Chain{}
+	tx.Raw(`SELECT * FROM chains WHERE id = ? LIMIT 1`, result.ToChainID).Scan(toChain)
+	oldRoles, err := models.UserChainGetRolesByChain(tx, result.ToChainID)
+	if err != nil {
+		handleError(tx, err)
+		return
+	}
+
 	// If the user already exists in the destination chain:
 	// - on copy instruction:     do nothing
 	// - on transfer instruction: remove from source chain
@@ -570,7 +700,12 @@ LIMIT 1
 		err = tx.Commit().Error
 		if err != nil {
 			handleError(tx, err)
+			return
 		}
+		if !body.IsCopy {
+			services.ChainAuditLogTransfer(db, authUser.ID, authChain, toChain, []uint{result.UserID}, oldRoles, false)
+		}
+		services.ChainUpdateCapacityByIDs(db, result.FromChainID)
 		return
 	} else if body.IsCopy {
 		// Copy from one chain to another
@@ -579,6 +714,7 @@ LIMIT 1
 			UserID:       result.UserID,
 			ChainID:      result.ToChainID,
 			IsChainAdmin: uc.IsChainAdmin,
+			Role:         uc.Role,
 			IsApproved:   uc.IsApproved,
 		}).Error
 		if err != nil {
@@ -602,6 +738,9 @@ LIMIT 1
 		handleError(tx, err)
 		return
 	}
+
+	services.ChainAuditLogTransfer(db, authUser.ID, authChain, toChain, []uint{result.UserID}, oldRoles, body.IsCopy)
+	services.ChainUpdateCapacityByIDs(db, result.FromChainID, result.ToChainID)
 }
 
 func UserCheckIfEmailExists(c *gin.Context) {
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gopkg.in/guregu/null.v3/zero"
+	"gorm.io/gorm"
+)
+
+var ErrApiKeyNotFound = errors.New("Api key not found")
+
+const (
+	// Read public loops
+	ApiKeyScopeChainsRead = "chains:read"
+	// Read events
+	ApiKeyScopeEventsRead = "events:read"
+	// Create, update and delete the events of the loop of the key
+	ApiKeyScopeEventsWrite = "events:write"
+)
+
+var ApiKeyScopes = []string{ApiKeyScopeChainsRead, ApiKeyScopeEventsRead, ApiKeyScopeEventsWrite}
+
+// Scopes that only apply to the loop the key is issued for
+var ApiKeyScopesOfChain = []string{ApiKeyScopeEventsWrite}
+
+// A credential for partner integrations issued by a root admin,
+// only the hash of the key is stored.
+type ApiKey struct {
+	ID      uint   `json:"-"`
+	UID     string `json:"uid" gorm:"uniqueIndex"`
+	Name    string `json:"name"`
+	KeyHash string `json:"-" gorm:"uniqueIndex;size:64"`
+	// The first characters of the key, to recognize it
+	KeyPrefix string   `json:"key_prefix"`
+	Scopes    []string `json:"scopes" gorm:"serializer:json"`
+	ChainID   zero.Int `json:"-"`
+	ChainUID  string   `json:"chain_uid" gorm:"-:migration;<-:false"`
+	// NULL when the root admin is purged and no other root admin is left
+	CreatedByUserID zero.Int  `json:"-"`
+	CreatedAt       time.Time `json:"created_at"`
+	LastUsedAt      zero.Time `json:"last_used_at"`
+	RevokedAt       zero.Time `json:"revoked_at"`
+}
+
+const apiKeyGetSql = `SELECT api_keys.*, IFNULL(chains.uid, '') AS chain_uid
+FROM api_keys
+LEFT JOIN chains ON chains.id = api_keys.chain_id
+`
+
+func ApiKeyGetAll(db *gorm.DB) ([]ApiKey, error) {
+	keys := []ApiKey{}
+	err := db.Raw(apiKeyGetSql + `ORDER BY api_keys.created_at DESC`).Scan(&keys).Error
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func ApiKeyGetByUID(db *gorm.DB, uid string) (*ApiKey, error) {
+	key := &ApiKey{}
+	err := db.Raw(apiKeyGetSql+`WHERE api_keys.uid = ? LIMIT 1`, uid).Scan(key).Error
+	if err != nil {
+		return nil, err
+	}
+	if key.ID == 0 {
+		return nil, ErrApiKeyNotFound
+	}
+	return key, nil
+}
+
+// Returns only keys that are not revoked
+func ApiKeyGetActiveByHash(db *gorm.DB, keyHash string) (*ApiKey, error) {
+	key := &ApiKey{}
+	err := db.Raw(apiKeyGetSql+`WHERE api_keys.key_hash = ? AND api_keys.revoked_at IS NULL LIMIT 1`, keyHash).Scan(key).Error
+	if err != nil {
+		return nil, err
+	}
+	if key.ID == 0 {
+		return nil, ErrApiKeyNotFound
+	}
+	return key, nil
+}
+
+func (k *ApiKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (k *ApiKey) Revoke(db *gorm.DB) error {
+	return db.Exec(`UPDATE api_keys SET revoked_at = NOW() WHERE id = ? AND revoked_at IS NULL`, k.ID).Error
+}
+
+func (k *ApiKey) UpdateLastUsed(db *gorm.DB) error {
+	return db.Exec(`UPDATE api_keys SET last_used_at = NOW() WHERE id = ?`, k.ID).Error
+}
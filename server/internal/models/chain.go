@@ -1,7 +1,6 @@
 package models
 
 import (
-	"database/sql"
 	"errors"
 	"time"
 
@@ -14,32 +13,51 @@ var validate = validator.New()
 
 var ErrChainNotFound = errors.New("Chain not found")
 
+// Amount of days a deleted loop can be restored before it is purged
+const ChainDeletedRetentionDays = 30
+
+// Amount of days participants can volunteer to host a loop without a host before it is archived
+const ChainOrphanedGraceDays = 30
+
 type Chain struct {
-	ID                            uint
-	UID                           string      `gorm:"uniqueIndex"`
-	FID                           zero.String `gorm:"column:fid"`
-	Name                          string
-	Description                   string
-	Address                       string
-	CountryCode                   string
-	Latitude                      float64
-	Longitude                     float64
-	Radius                        float32
-	Published                     bool
-	OpenToNewMembers              bool
-	RulesOverride                 string
-	HeadersOverride               string
-	Sizes                         []string `gorm:"serializer:json"`
-	Genders                       []string `gorm:"serializer:json"`
-	UserChains                    []UserChain
-	CreatedAt                     time.Time
-	UpdatedAt                     time.Time
-	DeletedAt                     zero.Time
-	Theme                         string
-	IsAppDisabled                 bool
-	RoutePrivacy                  int
-	LastAbandonedAt               sql.NullTime
-	LastAbandonedRecruitmentEmail sql.NullTime
+	ID                        uint
+	UID                       string      `gorm:"uniqueIndex"`
+	FID                       zero.String `gorm:"column:fid"`
+	Name                      string      `gorm:"index:idx_chain_search,class:FULLTEXT"`
+	Description               string      `gorm:"index:idx_chain_search,class:FULLTEXT"`
+	Address                   string      `gorm:"index:idx_chain_search,class:FULLTEXT"`
+	CountryCode               string
+	Latitude                  float64
+	Longitude                 float64
+	Radius                    float32
+	Published                 bool
+	OpenToNewMembers          bool
+	MaxMembers                zero.Int // NULL when the loop has no member limit
+	IsClosedAtCapacity        bool     // only loops closed at the member limit are reopened automatically
+	RulesOverride             string
+	HeadersOverride           string
+	Sizes                     []string `gorm:"serializer:json"`
+	Genders                   []string `gorm:"serializer:json"`
+	UserChains                []UserChain
+	CreatedAt                 time.Time
+	UpdatedAt                 time.Time
+	DeletedAt                 zero.Time
+	MergedIntoChainID         zero.Int // set when the loop is soft deleted by merging it into another loop
+	Theme                     string
+	IsAppDisabled             bool
+	RoutePrivacy              int
+	OrphanedAt                zero.Time // set when the last host left the loop
+	OrphanedRecruitmentEmail  zero.Time
+	LifecycleState            string    `gorm:"size:20;not null;default:'active'"`
+	InactiveWarningAt         zero.Time // archiving is done by soft deleting the loop
+	AbandonedAt               zero.Time
+	AbandonedRecruitmentEmail zero.Time // participants are asked to restart the loop as host a week after it is abandoned
+	AbandonedReminderAt       zero.Time
+	StillActiveAt             zero.Time // confirmed by a host
+	// values from before the loop was abandoned, restored when the loop is active again
+	PublishedBeforeAbandoned          zero.Bool
+	OpenToNewMembersBeforeAbandoned   zero.Bool
+	IsClosedAtCapacityBeforeAbandoned zero.Bool
 }
 
 type ChainResponse struct {
@@ -54,6 +72,7 @@ type ChainResponse struct {
 	Genders          []string `json:"genders" gorm:"chains.genders;serializer:json"`
 	Published        bool     `json:"published" gorm:"chains.published"`
 	OpenToNewMembers bool     `json:"open_to_new_members" gorm:"chains.open_to_new_members"`
+	MaxMembers       zero.Int `json:"max_members" gorm:"chains.max_members"`
 	TotalMembers     *int     `json:"total_members,omitempty" gorm:"total_members"`
 	TotalHosts       *int     `json:"total_hosts,omitempty" gorm:"total_hosts"`
 	RulesOverride    *string  `json:"rules_override,omitempty" gorm:"chains.rules_override"`
@@ -63,7 +82,7 @@ type ChainResponse struct {
 	RoutePrivacy     *int     `json:"route_privacy,omitempty" gorm:"chains.route_privacy"`
 }
 
-// Selects chain; id, uid, name, description, address, latitude, longitude, radius, sizes, genders, published, open_to_new_members
+// Selects chain; id, uid, name, description, address, latitude, longitude, radius, sizes, genders, published, open_to_new_members, max_members
 const ChainResponseSQLSelect = `SELECT chains.id,
 chains.uid,
 chains.name,
@@ -75,7 +94,8 @@ chains.radius,
 chains.sizes,
 chains.genders,
 chains.published,
-chains.open_to_new_members`
+chains.open_to_new_members,
+chains.max_members`
 
 func (c *Chain) SetRouteOrderByUserUIDs(db *gorm.DB, userUIDs []string) error {
 	tx := db.Begin()
@@ -162,6 +182,53 @@ func (c *Chain) ClearAllLastNotifiedIsUnapprovedAt(db *gorm.DB) error {
 	`, c.ID).Error
 }
 
+// Hides the loop, the memberships and bags are kept so that the loop can be restored
+// until it is purged after ChainDeletedRetentionDays.
+func (c *Chain) SoftDelete(db *gorm.DB) error {
+	return db.Exec(`UPDATE chains SET deleted_at = NOW() WHERE id = ?`, c.ID).Error
+}
+
+// A loop that was archived without a host gets a new grace period,
+// restoring a loop counts as activity of the host.
+func (c *Chain) Restore(db *gorm.DB) error {
+	return db.Exec(`
+UPDATE chains SET deleted_at = NULL,
+	orphaned_at = IF(orphaned_at IS NULL, NULL, NOW()),
+	orphaned_recruitment_email = NULL,
+	`+chainLifecycleResetSQL+`,
+	still_active_at = NOW()
+WHERE id = ?
+	`, c.ID).Error
+}
+
+// Closes the loop after the last host left, participants are invited to become host
+// until the loop is archived after ChainOrphanedGraceDays.
+func (c *Chain) Orphan(db *gorm.DB) error {
+	err := db.Exec(`
+UPDATE chains SET orphaned_at = NOW(), orphaned_recruitment_email = NULL,
+	open_to_new_members = FALSE, is_closed_at_capacity = FALSE
+WHERE id = ?
+	`, c.ID).Error
+	if err != nil {
+		return err
+	}
+	c.OrphanedAt = zero.TimeFrom(time.Now())
+	c.OrphanedRecruitmentEmail = zero.Time{}
+	c.OpenToNewMembers = false
+	c.IsClosedAtCapacity = false
+	return nil
+}
+
+func (c *Chain) ClearOrphaned(db *gorm.DB) error {
+	return db.Exec(`UPDATE chains SET orphaned_at = NULL, orphaned_recruitment_email = NULL WHERE id = ?`, c.ID).Error
+}
+
+// A merged loop has no members left and can not be restored
+func (c *Chain) IsRestorable() bool {
+	return c.DeletedAt.Valid && !c.MergedIntoChainID.Valid && c.DeletedAt.Time.After(time.Now().AddDate(0, 0, -ChainDeletedRetentionDays))
+}
+
+// Permanently deletes the loop with its memberships, bags and bulky items
 func (c *Chain) Delete(db *gorm.DB) error {
 	tx := db.Begin()
 	var err error
@@ -190,6 +257,41 @@ func (c *Chain) Delete(db *gorm.DB) error {
 		return err
 	}
 
+	err = tx.Exec(`DELETE FROM api_keys WHERE chain_id = ?`, c.ID).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Exec(`DELETE FROM chain_host_invitations WHERE chain_id = ?`, c.ID).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Exec(`DELETE FROM chain_waitlists WHERE chain_id = ?`, c.ID).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Exec(`DELETE FROM chain_audit_logs WHERE chain_id = ?`, c.ID).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Exec(`DELETE FROM chain_question_answers WHERE chain_id = ?`, c.ID).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Exec(`DELETE FROM chain_invites WHERE chain_id = ?`, c.ID).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Exec(`DELETE FROM chain_questions WHERE chain_id = ?`, c.ID).Error
+	if err != nil {
+		return err
+	}
+
 	err = tx.Exec(`DELETE FROM chains WHERE id = ?`, c.ID).Error
 	if err != nil {
 		return err
@@ -199,6 +301,120 @@ func (c *Chain) Delete(db *gorm.DB) error {
 	return nil
 }
 
+// Moves all members with their bags and bulky items to the target loop, members of both loops
+// keep the highest role. The route of this loop is appended to the route of the target loop
+// and this loop is soft deleted.
+func (c *Chain) MergeInto(db *gorm.DB, target *Chain) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		duplicates := []struct {
+			SourceID           uint
+			SourceIsChainAdmin bool
+			SourceRole         string
+			SourceIsApproved   bool
+			TargetID           uint
+			TargetIsChainAdmin bool
+			TargetRole         string
+		}{}
+		err := tx.Raw(`
+SELECT
+	s.id AS source_id, s.is_chain_admin AS source_is_chain_admin, s.role AS source_role, s.is_approved AS source_is_approved,
+	t.id AS target_id, t.is_chain_admin AS target_is_chain_admin, t.role AS target_role
+FROM user_chains AS s
+JOIN user_chains AS t ON t.user_id = s.user_id AND t.chain_id = ?
+WHERE s.chain_id = ?
+		`, target.ID, c.ID).Scan(&duplicates).Error
+		if err != nil {
+			return err
+		}
+		for _, d := range duplicates {
+			if err := tx.Exec(`UPDATE bags SET user_chain_id = ? WHERE user_chain_id = ?`, d.TargetID, d.SourceID).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec(`UPDATE bulky_items SET user_chain_id = ? WHERE user_chain_id = ?`, d.TargetID, d.SourceID).Error; err != nil {
+				return err
+			}
+			if d.SourceIsApproved {
+				if err := tx.Exec(`UPDATE user_chains SET is_approved = TRUE WHERE id = ?`, d.TargetID).Error; err != nil {
+					return err
+				}
+			}
+			if !d.TargetIsChainAdmin && (d.SourceIsChainAdmin || (d.TargetRole == "" && d.SourceRole != "")) {
+				role := d.SourceRole
+				if d.SourceIsChainAdmin {
+					role = UserChainRoleHost
+				}
+				if err := UserChainSetRole(tx, d.TargetID, role); err != nil {
+					return err
+				}
+			}
+			if err := tx.Exec(`DELETE FROM user_chains WHERE id = ?`, d.SourceID).Error; err != nil {
+				return err
+			}
+		}
+
+		maxRouteOrder := 0
+		tx.Raw(`SELECT COALESCE(MAX(route_order), 0) FROM user_chains WHERE chain_id = ?`, target.ID).Scan(&maxRouteOrder)
+		err = tx.Exec(`
+UPDATE user_chains SET chain_id = ?, route_order = IF(is_approved, route_order + ?, 0)
+WHERE chain_id = ?
+		`, target.ID, maxRouteOrder, c.ID).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Exec(`UPDATE events SET chain_id = ? WHERE chain_id = ?`, target.ID, c.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`DELETE FROM chain_host_invitations WHERE chain_id = ?`, c.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`DELETE FROM chain_invites WHERE chain_id = ?`, c.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`DELETE FROM chain_waitlists WHERE chain_id = ?`, c.ID).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`
+UPDATE chains SET deleted_at = NOW(), merged_into_chain_id = ?, published = FALSE, open_to_new_members = FALSE
+WHERE id = ?
+		`, target.ID, c.ID).Error
+	})
+}
+
+// Creates the new loop and moves the members with their bags and bulky items to it,
+// hostUserID becomes host of the new loop as well unless it is 0.
+func (c *Chain) SplitInto(db *gorm.DB, newChain *Chain, userIDs []uint, hostUserID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(newChain).Error; err != nil {
+			return err
+		}
+
+		err := tx.Exec(`
+UPDATE user_chains SET chain_id = ?, route_order = 0
+WHERE chain_id = ? AND user_id IN ?
+		`, newChain.ID, c.ID, userIDs).Error
+		if err != nil {
+			return err
+		}
+
+		if hostUserID == 0 {
+			return nil
+		}
+		userChainID := uint(0)
+		tx.Raw(`SELECT id FROM user_chains WHERE chain_id = ? AND user_id = ? LIMIT 1`, newChain.ID, hostUserID).Scan(&userChainID)
+		if userChainID != 0 {
+			return UserChainSetRole(tx, userChainID, UserChainRoleHost)
+		}
+		return tx.Create(&UserChain{
+			UserID:       hostUserID,
+			ChainID:      newChain.ID,
+			IsChainAdmin: true,
+			Role:         UserChainRoleHost,
+			IsApproved:   true,
+		}).Error
+	})
+}
+
 func ChainGetNamesByIDs(db *gorm.DB, chainIDs ...uint) ([]string, error) {
 
 	type aux struct {
@@ -264,6 +480,19 @@ WHERE uc1.chain_id = ? AND uc1.is_approved = TRUE
 	return result
 }
 
+// Is true if the loop has a member limit and the amount of approved members has reached it
+func (c *Chain) IsFull(db *gorm.DB) bool {
+	return c.FreeCapacity(db) == 0
+}
+
+// Returns the amount of members that can still join, -1 if the loop has no member limit
+func (c *Chain) FreeCapacity(db *gorm.DB) int {
+	if !c.MaxMembers.Valid || c.MaxMembers.Int64 <= 0 {
+		return -1
+	}
+	return max(0, int(c.MaxMembers.Int64)-c.GetTotals(db).TotalMembers)
+}
+
 func ChainCheckIfExist(db *gorm.DB, ChainUID string, checkIfIsOpenToNewMembers bool) (chainID uint, found bool, err error) {
 	var row struct {
 		ID uint `gorm:"id"`
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"reflect"
+	"time"
+
+	"gopkg.in/guregu/null.v3/zero"
+	"gorm.io/gorm"
+)
+
+// Actions recorded in the history of a loop
+const (
+	ChainAuditActionUpdate      = "chain_update"
+	ChainAuditActionUserApprove = "user_approve"
+	ChainAuditActionUserDeny    = "user_deny"
+	ChainAuditActionUserRemove  = "user_remove"
+	ChainAuditActionRoleChange  = "role_change"
+	ChainAuditActionRouteOrder  = "route_order"
+	// A member is moved or copied between loops, by a transfer, merge or split
+	ChainAuditActionUserTransfer = "user_transfer"
+)
+
+type ChainAuditChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// A change made to a loop, the actor is NULL for automated changes or a purged user
+type ChainAuditLog struct {
+	ID            uint                        `json:"-"`
+	ChainID       uint                        `json:"-" gorm:"index"`
+	ActorUserID   zero.Int                    `json:"-" gorm:"index"`
+	ActorUserUID  string                      `json:"actor_user_uid" gorm:"-:migration;<-:false"`
+	ActorName     string                      `json:"actor_name" gorm:"-:migration;<-:false"`
+	TargetUserID  zero.Int                    `json:"-" gorm:"index"`
+	TargetUserUID string                      `json:"target_user_uid" gorm:"-:migration;<-:false"`
+	TargetName    string                      `json:"target_name" gorm:"-:migration;<-:false"`
+	Action        string                      `json:"action" gorm:"size:30"`
+	Changes       map[string]ChainAuditChange `json:"changes" gorm:"serializer:json"`
+	CreatedAt     time.Time                   `json:"created_at"`
+}
+
+// Use 0 for actorUserID or targetUserID when there is none
+func ChainAuditLogCreate(db *gorm.DB, chainID, actorUserID, targetUserID uint, action string, changes map[string]ChainAuditChange) error {
+	return db.Create(&ChainAuditLog{
+		ChainID:      chainID,
+		ActorUserID:  zero.NewInt(int64(actorUserID), actorUserID != 0),
+		TargetUserID: zero.NewInt(int64(targetUserID), targetUserID != 0),
+		Action:       action,
+		Changes:      changes,
+	}).Error
+}
+
+// Lists the most recent changes of a loop
+func ChainAuditLogGetAll(db *gorm.DB, chainID uint, limit int) ([]ChainAuditLog, error) {
+	logs := []ChainAuditLog{}
+	err := db.Raw(`
+SELECT l.*,
+	IFNULL(a.uid, '') AS actor_user_uid, IFNULL(a.name, '') AS actor_name,
+	IFNULL(t.uid, '') AS target_user_uid, IFNULL(t.name, '') AS target_name
+FROM chain_audit_logs AS l
+LEFT JOIN users AS a ON a.id = l.actor_user_id
+LEFT JOIN users AS t ON t.id = l.target_user_id
+WHERE l.chain_id = ?
+ORDER BY l.id DESC
+LIMIT ?
+	`, chainID, limit).Scan(&logs).Error
+	if err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// The values of the columns that are recorded when a loop is updated
+func (c *Chain) AuditValues() map[string]any {
+	return map[string]any{
+		"name":                c.Name,
+		"description":         c.Description,
+		"address":             c.Address,
+		"country_code":        c.CountryCode,
+		"latitude":            c.Latitude,
+		"longitude":           c.Longitude,
+		"radius":              c.Radius,
+		"sizes":               c.Sizes,
+		"genders":             c.Genders,
+		"rules_override":      c.RulesOverride,
+		"headers_override":    c.HeadersOverride,
+		"published":           c.Published,
+		"open_to_new_members": c.OpenToNewMembers,
+		"max_members":         c.MaxMembers.ValueOrZero(),
+		"theme":               c.Theme,
+		"route_privacy":       c.RoutePrivacy,
+		"is_app_disabled":     c.IsAppDisabled,
+	}
+}
+
+// Returns only the values that changed
+func ChainAuditDiff(oldValues, newValues map[string]any) map[string]ChainAuditChange {
+	changes := map[string]ChainAuditChange{}
+	for key, newValue := range newValues {
+		oldValue := oldValues[key]
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes[key] = ChainAuditChange{Old: oldValue, New: newValue}
+		}
+	}
+	return changes
+}
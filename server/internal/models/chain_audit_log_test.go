@@ -0,0 +1,29 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/guregu/null.v3/zero"
+)
+
+func TestChainAuditDiff(t *testing.T) {
+	chain := &Chain{
+		Name:       "Loop",
+		Radius:     3,
+		Sizes:      []string{"1", "2"},
+		MaxMembers: zero.NewInt(0, false),
+	}
+	oldValues := chain.AuditValues()
+
+	assert.Empty(t, ChainAuditDiff(oldValues, chain.AuditValues()))
+
+	chain.Name = "Renamed loop"
+	chain.Sizes = []string{"1", "2", "3"}
+	chain.MaxMembers = zero.IntFrom(20)
+	changes := ChainAuditDiff(oldValues, chain.AuditValues())
+	assert.Len(t, changes, 3)
+	assert.Equal(t, ChainAuditChange{Old: "Loop", New: "Renamed loop"}, changes["name"])
+	assert.Equal(t, ChainAuditChange{Old: []string{"1", "2"}, New: []string{"1", "2", "3"}}, changes["sizes"])
+	assert.Equal(t, ChainAuditChange{Old: int64(0), New: int64(20)}, changes["max_members"])
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"gopkg.in/guregu/null.v3/zero"
+	"gorm.io/gorm"
+)
+
+var ErrChainHostInvitationNotFound = errors.New("Host invitation not found or expired")
+
+// how long an invitation to become host can be accepted
+const chainHostInvitationMaxAge = 14 * 24 * time.Hour
+
+// An invitation to become host of a loop, the invitee is only promoted after accepting.
+// The email can belong to someone that has not registered yet.
+type ChainHostInvitation struct {
+	ID              uint      `json:"-"`
+	UID             string    `json:"uid" gorm:"uniqueIndex;size:36"`
+	ChainID         uint      `json:"-" gorm:"index"`
+	ChainUID        string    `json:"chain_uid" gorm:"-:migration;<-:false"`
+	ChainName       string    `json:"chain_name" gorm:"-:migration;<-:false"`
+	InvitedByUserID uint      `json:"-"`
+	InvitedByName   string    `json:"invited_by_name" gorm:"-:migration;<-:false"`
+	UserID          zero.Int  `json:"-" gorm:"index"`
+	UserUID         string    `json:"user_uid" gorm:"-:migration;<-:false"`
+	Email           string    `json:"email" gorm:"index;size:255"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+const chainHostInvitationSelect = `
+SELECT chi.*, c.uid AS chain_uid, c.name AS chain_name, ib.name AS invited_by_name, u.uid AS user_uid
+FROM chain_host_invitations AS chi
+JOIN chains AS c ON c.id = chi.chain_id
+LEFT JOIN users AS ib ON ib.id = chi.invited_by_user_id
+LEFT JOIN users AS u ON u.id = chi.user_id
+`
+
+// Replaces any pending invitation of the same person to the loop
+func ChainHostInvitationCreate(db *gorm.DB, chainID, invitedByUserID uint, userID zero.Int, email string) (*ChainHostInvitation, error) {
+	inv := &ChainHostInvitation{
+		UID:             uuid.NewV4().String(),
+		ChainID:         chainID,
+		InvitedByUserID: invitedByUserID,
+		UserID:          userID,
+		Email:           email,
+		ExpiresAt:       time.Now().Add(chainHostInvitationMaxAge),
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+DELETE FROM chain_host_invitations
+WHERE chain_id = ? AND (email = ? OR (user_id IS NOT NULL AND user_id = ?))
+		`, chainID, email, userID).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(inv).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+func ChainHostInvitationGetAllByChain(db *gorm.DB, chainID uint) ([]ChainHostInvitation, error) {
+	invitations := []ChainHostInvitation{}
+	err := db.Raw(chainHostInvitationSelect+`
+WHERE chi.chain_id = ? AND chi.expires_at > NOW()
+ORDER BY chi.created_at DESC
+	`, chainID).Scan(&invitations).Error
+	if err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// Invitations sent to the email address of the user are only included once the address is verified
+func ChainHostInvitationGetAllByUser(db *gorm.DB, user *User) ([]ChainHostInvitation, error) {
+	invitations := []ChainHostInvitation{}
+	err := db.Raw(chainHostInvitationSelect+`
+WHERE (chi.user_id = ? OR (chi.user_id IS NULL AND chi.email = ? AND ? = TRUE)) AND chi.expires_at > NOW()
+ORDER BY chi.created_at DESC
+	`, user.ID, user.Email.String, user.Email.Valid && user.IsEmailVerified).Scan(&invitations).Error
+	if err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func ChainHostInvitationGetByUID(db *gorm.DB, uid string) (*ChainHostInvitation, error) {
+	inv := &ChainHostInvitation{}
+	err := db.Raw(chainHostInvitationSelect+`
+WHERE chi.uid = ? AND chi.expires_at > NOW()
+LIMIT 1
+	`, uid).Scan(inv).Error
+	if err != nil {
+		return nil, err
+	}
+	if inv.ID == 0 {
+		return nil, ErrChainHostInvitationNotFound
+	}
+	return inv, nil
+}
+
+// Is true if the invitation was sent to the user or to their verified email address
+func (inv *ChainHostInvitation) IsFor(user *User) bool {
+	if inv.UserID.Valid {
+		return inv.UserID.Int64 == int64(user.ID)
+	}
+	return user.IsEmailVerified && user.Email.Valid && strings.EqualFold(user.Email.String, inv.Email)
+}
+
+func (inv *ChainHostInvitation) Delete(db *gorm.DB) error {
+	return db.Exec(`DELETE FROM chain_host_invitations WHERE id = ?`, inv.ID).Error
+}
+
+func ChainHostInvitationDeleteOld(db *gorm.DB) {
+	db.Exec(`DELETE FROM chain_host_invitations WHERE expires_at < NOW()`)
+}
@@ -0,0 +1,168 @@
+package models
+
+import (
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"gopkg.in/guregu/null.v3/zero"
+	"gorm.io/gorm"
+)
+
+var ErrChainInviteNotFound = errors.New("Invite not found, expired or used up")
+
+// Characters used for invite codes, similar looking characters are left out
+const chainInviteCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const chainInviteCodeLength = 8
+
+// how many codes are tried before giving up, a code can already be in use
+const chainInviteCodeAttempts = 5
+
+// An invite link or code made by a host, it lets users join the loop even when it is closed to new members
+type ChainInvite struct {
+	ID              uint   `json:"-"`
+	UID             string `json:"uid" gorm:"uniqueIndex;size:36"`
+	Code            string `json:"code" gorm:"uniqueIndex;size:8"`
+	ChainID         uint   `json:"-" gorm:"index"`
+	ChainUID        string `json:"chain_uid" gorm:"-:migration;<-:false"`
+	ChainName       string `json:"chain_name" gorm:"-:migration;<-:false"`
+	CreatedByUserID uint   `json:"-" gorm:"index"`
+	CreatedByName   string `json:"created_by_name" gorm:"-:migration;<-:false"`
+	// NULL when the invite can be used an unlimited amount of times
+	MaxUses zero.Int `json:"max_uses"`
+	Uses    int      `json:"uses"`
+	// Users that join are approved without a host having to approve them
+	IsAutoApproved bool      `json:"is_auto_approved"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+const chainInviteSelect = `
+SELECT ci.*, c.uid AS chain_uid, c.name AS chain_name, IFNULL(cb.name, '') AS created_by_name
+FROM chain_invites AS ci
+JOIN chains AS c ON c.id = ci.chain_id
+LEFT JOIN users AS cb ON cb.id = ci.created_by_user_id
+`
+
+// Only invites that can still be used
+const chainInviteWhereUsable = `ci.expires_at > NOW() AND (ci.max_uses IS NULL OR ci.uses < ci.max_uses) AND c.deleted_at IS NULL`
+
+func chainInviteCode() (string, error) {
+	b := make([]byte, chainInviteCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = chainInviteCodeAlphabet[int(b[i])%len(chainInviteCodeAlphabet)]
+	}
+	return string(b), nil
+}
+
+// Returns true when the error is caused by a value that already exists in a unique index
+func isDuplicateKeyError(db *gorm.DB, err error) bool {
+	if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok {
+		err = translator.Translate(err)
+	}
+	return errors.Is(err, gorm.ErrDuplicatedKey)
+}
+
+// A new code is generated when the code is already used by another invite
+func ChainInviteCreate(db *gorm.DB, chainID, createdByUserID uint, expiresAt time.Time, maxUses zero.Int, isAutoApproved bool) (*ChainInvite, error) {
+	var err error
+	for i := 0; i < chainInviteCodeAttempts; i++ {
+		var code string
+		code, err = chainInviteCode()
+		if err != nil {
+			return nil, err
+		}
+		inv := &ChainInvite{
+			UID:             uuid.NewV4().String(),
+			Code:            code,
+			ChainID:         chainID,
+			CreatedByUserID: createdByUserID,
+			MaxUses:         maxUses,
+			IsAutoApproved:  isAutoApproved,
+			ExpiresAt:       expiresAt,
+		}
+		err = db.Create(inv).Error
+		if err == nil {
+			return inv, nil
+		}
+		if !isDuplicateKeyError(db, err) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+func ChainInviteGetAllByChain(db *gorm.DB, chainID uint) ([]ChainInvite, error) {
+	invites := []ChainInvite{}
+	err := db.Raw(chainInviteSelect+`
+WHERE ci.chain_id = ? AND `+chainInviteWhereUsable+`
+ORDER BY ci.created_at DESC
+	`, chainID).Scan(&invites).Error
+	if err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+// Finds a usable invite by the uid of the link or by the code, codes are case insensitive
+func ChainInviteGetUsable(db *gorm.DB, uidOrCode string) (*ChainInvite, error) {
+	inv := &ChainInvite{}
+	err := db.Raw(chainInviteSelect+`
+WHERE (ci.uid = ? OR ci.code = ?) AND `+chainInviteWhereUsable+`
+LIMIT 1
+	`, uidOrCode, strings.ToUpper(strings.TrimSpace(uidOrCode))).Scan(inv).Error
+	if err != nil {
+		return nil, err
+	}
+	if inv.ID == 0 {
+		return nil, ErrChainInviteNotFound
+	}
+	return inv, nil
+}
+
+func ChainInviteGetByUID(db *gorm.DB, chainID uint, uid string) (*ChainInvite, error) {
+	inv := &ChainInvite{}
+	err := db.Raw(chainInviteSelect+`
+WHERE ci.chain_id = ? AND ci.uid = ?
+LIMIT 1
+	`, chainID, uid).Scan(inv).Error
+	if err != nil {
+		return nil, err
+	}
+	if inv.ID == 0 {
+		return nil, ErrChainInviteNotFound
+	}
+	return inv, nil
+}
+
+// Counts a use of the invite, returns ErrChainInviteNotFound if it can no longer be used
+func (inv *ChainInvite) Use(db *gorm.DB) error {
+	res := db.Exec(`
+UPDATE chain_invites
+SET uses = uses + 1
+WHERE id = ? AND expires_at > NOW() AND (max_uses IS NULL OR uses < max_uses)
+	`, inv.ID)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrChainInviteNotFound
+	}
+	inv.Uses++
+	return nil
+}
+
+func (inv *ChainInvite) Delete(db *gorm.DB) error {
+	return db.Exec(`DELETE FROM chain_invites WHERE id = ?`, inv.ID).Error
+}
+
+// Also removes invites that are used up
+func ChainInviteDeleteOld(db *gorm.DB) {
+	db.Exec(`DELETE FROM chain_invites WHERE expires_at < NOW() OR (max_uses IS NOT NULL AND uses >= max_uses)`)
+}
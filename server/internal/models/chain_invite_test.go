@@ -0,0 +1,20 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainInviteCode(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code, err := chainInviteCode()
+		require.NoError(t, err)
+		assert.Len(t, code, chainInviteCodeLength)
+		for _, r := range code {
+			assert.True(t, strings.ContainsRune(chainInviteCodeAlphabet, r), code)
+		}
+	}
+}
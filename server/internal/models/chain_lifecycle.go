@@ -0,0 +1,169 @@
+package models
+
+import (
+	"time"
+
+	"gopkg.in/guregu/null.v3/zero"
+	"gorm.io/gorm"
+)
+
+// A loop becomes inactive when the hosts show no activity,
+// participants waiting too long to be approved also warn the hosts
+const (
+	ChainLifecycleActive          = "active"
+	ChainLifecycleInactiveWarning = "inactive_warning"
+	ChainLifecycleAbandoned       = "abandoned"
+	ChainLifecycleArchived        = "archived"
+)
+
+const (
+	chainLifecycleInactiveWarningAfter = 60 * 24 * time.Hour
+	chainLifecycleAbandonedAfter       = 150 * 24 * time.Hour
+	// hosts always get the inactive warning and some time to respond before the loop is abandoned
+	chainLifecycleAbandonedAfterWarning = 30 * 24 * time.Hour
+	// after being abandoned the hosts are reminded a week before the loop is archived
+	chainLifecycleReminderAfter = 23 * 24 * time.Hour
+	chainLifecycleArchivedAfter = 30 * 24 * time.Hour
+)
+
+type ChainLifecycle struct {
+	ChainID uint
+	State   string
+	// The latest host sign in, bag movement, approval or confirmation that the loop is still active
+	LastActiveAt time.Time
+	// The participant waiting the longest to be approved
+	OldestPendingAt     zero.Time
+	OldestPendingName   string
+	InactiveWarningAt   zero.Time
+	AbandonedAt         zero.Time
+	AbandonedReminderAt zero.Time
+}
+
+// Returns all loops that have a host,
+// loops without a host are handled by the host succession instead.
+func ChainLifecycleGetAll(db *gorm.DB) ([]ChainLifecycle, error) {
+	lifecycles := []ChainLifecycle{}
+	err := db.Raw(`
+SELECT
+	c.id AS chain_id,
+	c.lifecycle_state AS state,
+	GREATEST(
+		c.created_at,
+		COALESCE(c.still_active_at, c.created_at),
+		COALESCE((
+			SELECT MAX(u.last_signed_in_at) FROM user_chains AS uc
+			JOIN users AS u ON u.id = uc.user_id
+			WHERE uc.chain_id = c.id AND uc.is_chain_admin = TRUE
+		), c.created_at),
+		COALESCE((
+			SELECT MAX(b.updated_at) FROM bags AS b
+			JOIN user_chains AS uc ON uc.id = b.user_chain_id
+			WHERE uc.chain_id = c.id
+		), c.created_at),
+		COALESCE((
+			SELECT MAX(uc.created_at) FROM user_chains AS uc
+			WHERE uc.chain_id = c.id AND uc.is_approved = TRUE
+		), c.created_at)
+	) AS last_active_at,
+	(
+		SELECT MIN(uc.created_at) FROM user_chains AS uc
+		JOIN users AS u ON u.id = uc.user_id
+		WHERE uc.chain_id = c.id AND uc.is_approved = FALSE AND u.is_email_verified = TRUE
+	) AS oldest_pending_at,
+	(
+		SELECT u.name FROM user_chains AS uc
+		JOIN users AS u ON u.id = uc.user_id
+		WHERE uc.chain_id = c.id AND uc.is_approved = FALSE AND u.is_email_verified = TRUE
+		ORDER BY uc.created_at ASC
+		LIMIT 1
+	) AS oldest_pending_name,
+	c.inactive_warning_at AS inactive_warning_at,
+	c.abandoned_at AS abandoned_at,
+	c.abandoned_reminder_at AS abandoned_reminder_at
+FROM chains AS c
+WHERE c.deleted_at IS NULL AND c.orphaned_at IS NULL
+	`).Scan(&lifecycles).Error
+	return lifecycles, err
+}
+
+// Returns the state the loop should be in at the given time.
+// A loop is only abandoned after its hosts were warned at least chainLifecycleAbandonedAfterWarning ago.
+func (l *ChainLifecycle) NextState(now time.Time) string {
+	inactive := now.Sub(l.LastActiveAt)
+
+	switch {
+	case inactive < chainLifecycleInactiveWarningAfter:
+		// activity brings an abandoned loop back, only active loops are warned for a waiting participant
+		if l.IsParticipantWaitingTooLong(now) && (l.State == ChainLifecycleActive || l.State == ChainLifecycleInactiveWarning) {
+			return ChainLifecycleInactiveWarning
+		}
+		return ChainLifecycleActive
+	case inactive < chainLifecycleAbandonedAfter:
+		return ChainLifecycleInactiveWarning
+	}
+
+	switch l.State {
+	case ChainLifecycleInactiveWarning:
+		if l.InactiveWarningAt.Valid && now.Sub(l.InactiveWarningAt.Time) >= chainLifecycleAbandonedAfterWarning {
+			return ChainLifecycleAbandoned
+		}
+	case ChainLifecycleAbandoned:
+		if l.AbandonedAt.Valid && now.Sub(l.AbandonedAt.Time) >= chainLifecycleArchivedAfter {
+			return ChainLifecycleArchived
+		}
+		return ChainLifecycleAbandoned
+	}
+	return ChainLifecycleInactiveWarning
+}
+
+func (l *ChainLifecycle) IsParticipantWaitingTooLong(now time.Time) bool {
+	return l.OldestPendingAt.Valid && now.Sub(l.OldestPendingAt.Time) >= chainLifecycleInactiveWarningAfter
+}
+
+func (l *ChainLifecycle) IsReminderDue(now time.Time) bool {
+	return l.State == ChainLifecycleAbandoned && l.AbandonedAt.Valid && !l.AbandonedReminderAt.Valid &&
+		now.Sub(l.AbandonedAt.Time) >= chainLifecycleReminderAfter
+}
+
+// Puts back the published and open values the loop had before it was abandoned
+const chainLifecycleResetSQL = `lifecycle_state = 'active',
+	inactive_warning_at = NULL, abandoned_at = NULL, abandoned_reminder_at = NULL,
+	abandoned_recruitment_email = NULL,
+	published = COALESCE(published_before_abandoned, published),
+	open_to_new_members = COALESCE(open_to_new_members_before_abandoned, open_to_new_members),
+	is_closed_at_capacity = COALESCE(is_closed_at_capacity_before_abandoned, is_closed_at_capacity),
+	published_before_abandoned = NULL, open_to_new_members_before_abandoned = NULL,
+	is_closed_at_capacity_before_abandoned = NULL`
+
+// Records the state with the time of the transition, abandoned loops are closed and unpublished
+// until the loop is active again.
+func ChainSetLifecycleState(db *gorm.DB, chainID uint, state string) error {
+	if state == ChainLifecycleActive {
+		return db.Exec(`UPDATE chains SET `+chainLifecycleResetSQL+` WHERE id = ?`, chainID).Error
+	}
+
+	sql := `UPDATE chains SET lifecycle_state = ?`
+	switch state {
+	case ChainLifecycleInactiveWarning:
+		sql += `, inactive_warning_at = NOW()`
+	case ChainLifecycleAbandoned:
+		sql += `, abandoned_at = NOW(),
+	published_before_abandoned = COALESCE(published_before_abandoned, published),
+	open_to_new_members_before_abandoned = COALESCE(open_to_new_members_before_abandoned, open_to_new_members),
+	is_closed_at_capacity_before_abandoned = COALESCE(is_closed_at_capacity_before_abandoned, is_closed_at_capacity),
+	published = FALSE, open_to_new_members = FALSE, is_closed_at_capacity = FALSE`
+	}
+	return db.Exec(sql+` WHERE id = ?`, state, chainID).Error
+}
+
+func ChainSetLifecycleReminded(db *gorm.DB, chainID uint) error {
+	return db.Exec(`UPDATE chains SET abandoned_reminder_at = NOW() WHERE id = ?`, chainID).Error
+}
+
+// A host confirms from the email that the loop is still active
+func (c *Chain) ConfirmStillActive(db *gorm.DB) error {
+	if err := db.Exec(`UPDATE chains SET still_active_at = NOW() WHERE id = ?`, c.ID).Error; err != nil {
+		return err
+	}
+	return ChainSetLifecycleState(db, c.ID, ChainLifecycleActive)
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/guregu/null.v3/zero"
+)
+
+func TestChainLifecycleNextState(t *testing.T) {
+	now := time.Now()
+	daysAgo := func(days int) time.Time {
+		return now.Add(-time.Duration(days) * 24 * time.Hour)
+	}
+
+	t.Run("Without pending participants", func(t *testing.T) {
+		l := &ChainLifecycle{State: ChainLifecycleActive, LastActiveAt: daysAgo(30)}
+		assert.Equal(t, ChainLifecycleActive, l.NextState(now))
+
+		l.LastActiveAt = daysAgo(61)
+		assert.Equal(t, ChainLifecycleInactiveWarning, l.NextState(now))
+
+		l.State = ChainLifecycleInactiveWarning
+		l.LastActiveAt = daysAgo(365)
+		l.InactiveWarningAt = zero.TimeFrom(daysAgo(31))
+		assert.Equal(t, ChainLifecycleAbandoned, l.NextState(now))
+	})
+
+	t.Run("A participant waiting too long warns active hosts", func(t *testing.T) {
+		l := &ChainLifecycle{
+			State:           ChainLifecycleActive,
+			LastActiveAt:    daysAgo(1),
+			OldestPendingAt: zero.TimeFrom(daysAgo(30)),
+		}
+		assert.Equal(t, ChainLifecycleActive, l.NextState(now))
+
+		l.OldestPendingAt = zero.TimeFrom(daysAgo(61))
+		assert.Equal(t, ChainLifecycleInactiveWarning, l.NextState(now))
+
+		// only inactivity of the hosts abandons a loop
+		l.State = ChainLifecycleInactiveWarning
+		l.OldestPendingAt = zero.TimeFrom(daysAgo(200))
+		l.InactiveWarningAt = zero.TimeFrom(daysAgo(31))
+		assert.Equal(t, ChainLifecycleInactiveWarning, l.NextState(now))
+	})
+
+	t.Run("Long inactive loops are warned before being abandoned", func(t *testing.T) {
+		l := &ChainLifecycle{
+			State:           ChainLifecycleActive,
+			LastActiveAt:    daysAgo(200),
+			OldestPendingAt: zero.TimeFrom(daysAgo(200)),
+		}
+		assert.Equal(t, ChainLifecycleInactiveWarning, l.NextState(now))
+
+		l.State = ChainLifecycleInactiveWarning
+		l.InactiveWarningAt = zero.TimeFrom(daysAgo(1))
+		assert.Equal(t, ChainLifecycleInactiveWarning, l.NextState(now))
+
+		l.InactiveWarningAt = zero.TimeFrom(daysAgo(30))
+		assert.Equal(t, ChainLifecycleAbandoned, l.NextState(now))
+	})
+
+	t.Run("Activity resets the lifecycle", func(t *testing.T) {
+		l := &ChainLifecycle{
+			State:           ChainLifecycleAbandoned,
+			LastActiveAt:    daysAgo(1),
+			OldestPendingAt: zero.TimeFrom(daysAgo(200)),
+			AbandonedAt:     zero.TimeFrom(daysAgo(40)),
+		}
+		assert.Equal(t, ChainLifecycleActive, l.NextState(now))
+	})
+
+	t.Run("Abandoned loops are archived after a month", func(t *testing.T) {
+		l := &ChainLifecycle{
+			State:           ChainLifecycleAbandoned,
+			LastActiveAt:    daysAgo(200),
+			OldestPendingAt: zero.TimeFrom(daysAgo(200)),
+			AbandonedAt:     zero.TimeFrom(daysAgo(24)),
+		}
+		assert.Equal(t, ChainLifecycleAbandoned, l.NextState(now))
+		assert.True(t, l.IsReminderDue(now))
+
+		l.AbandonedReminderAt = zero.TimeFrom(daysAgo(1))
+		assert.False(t, l.IsReminderDue(now))
+
+		l.AbandonedAt = zero.TimeFrom(daysAgo(31))
+		assert.Equal(t, ChainLifecycleArchived, l.NextState(now))
+	})
+}
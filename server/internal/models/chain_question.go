@@ -0,0 +1,211 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/samber/lo"
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+)
+
+// Types of questions asked to users that want to join a loop
+const (
+	ChainQuestionTypeText         = "text"
+	ChainQuestionTypeSingleChoice = "single_choice"
+	ChainQuestionTypeYesNo        = "yes_no"
+)
+
+const (
+	ChainQuestionsMax          = 10
+	ChainQuestionMaxSize       = 300
+	ChainQuestionOptionsMax    = 20
+	ChainQuestionOptionMaxSize = 100
+	ChainQuestionAnswerMaxSize = 1000
+)
+
+var (
+	ErrChainQuestionInvalid       = errors.New("Invalid question")
+	ErrChainQuestionAnswerInvalid = errors.New("Invalid answer to a question of the loop")
+	ErrChainQuestionAnswerMissing = errors.New("A required question of the loop is not answered")
+)
+
+// A question a host asks users that want to join the loop
+type ChainQuestion struct {
+	ID       uint   `json:"-"`
+	UID      string `json:"uid" gorm:"uniqueIndex"`
+	ChainID  uint   `json:"-" gorm:"index"`
+	Type     string `json:"type" gorm:"size:20"`
+	Question string `json:"question"`
+	// Only used by single choice questions
+	Options    []string  `json:"options" gorm:"serializer:json"`
+	IsRequired bool      `json:"is_required"`
+	Position   int       `json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ChainQuestionAnswer struct {
+	ID              uint      `json:"-"`
+	ChainQuestionID uint      `json:"-" gorm:"index"`
+	QuestionUID     string    `json:"question_uid" gorm:"-:migration;<-:false"`
+	Question        string    `json:"question" gorm:"-:migration;<-:false"`
+	ChainID         uint      `json:"-" gorm:"index"`
+	UserID          uint      `json:"-" gorm:"index"`
+	Answer          string    `json:"answer"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// An answer to a question as sent by the user
+type ChainQuestionAnswerRequest struct {
+	QuestionUID string `json:"question_uid" binding:"required,uuid"`
+	Answer      string `json:"answer"`
+}
+
+func (q *ChainQuestion) Validate() bool {
+	if q.Question == "" || len(q.Question) > ChainQuestionMaxSize {
+		return false
+	}
+	switch q.Type {
+	case ChainQuestionTypeText, ChainQuestionTypeYesNo:
+		return len(q.Options) == 0
+	case ChainQuestionTypeSingleChoice:
+		if len(q.Options) < 2 || len(q.Options) > ChainQuestionOptionsMax || len(lo.Uniq(q.Options)) != len(q.Options) {
+			return false
+		}
+		return !lo.ContainsBy(q.Options, func(o string) bool {
+			return o == "" || len(o) > ChainQuestionOptionMaxSize
+		})
+	}
+	return false
+}
+
+func (q *ChainQuestion) ValidateAnswer(answer string) bool {
+	switch q.Type {
+	case ChainQuestionTypeText:
+		return len(answer) <= ChainQuestionAnswerMaxSize
+	case ChainQuestionTypeSingleChoice:
+		return lo.Contains(q.Options, answer)
+	case ChainQuestionTypeYesNo:
+		return answer == "yes" || answer == "no"
+	}
+	return false
+}
+
+func ChainQuestionGetAllByChain(db *gorm.DB, chainID uint) ([]ChainQuestion, error) {
+	questions := []ChainQuestion{}
+	err := db.Raw(`SELECT * FROM chain_questions WHERE chain_id = ? ORDER BY position ASC`, chainID).Scan(&questions).Error
+	if err != nil {
+		return nil, err
+	}
+	return questions, nil
+}
+
+// Replaces all questions of the loop, questions are matched by uid to keep their answers.
+// Answers of removed questions are deleted.
+func ChainQuestionSetAll(db *gorm.DB, chainID uint, questions []ChainQuestion) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		existing, err := ChainQuestionGetAllByChain(tx, chainID)
+		if err != nil {
+			return err
+		}
+		existingByUID := lo.KeyBy(existing, func(q ChainQuestion) string { return q.UID })
+
+		keptIDs := []uint{}
+		for i := range questions {
+			q := &questions[i]
+			q.ChainID = chainID
+			q.Position = i + 1
+			if e, ok := existingByUID[q.UID]; ok {
+				q.ID = e.ID
+				q.CreatedAt = e.CreatedAt
+				err = tx.Model(q).Select("type", "question", "options", "is_required", "position").Updates(q).Error
+			} else {
+				q.ID = 0
+				q.UID = uuid.NewV4().String()
+				err = tx.Create(q).Error
+			}
+			if err != nil {
+				return err
+			}
+			keptIDs = append(keptIDs, q.ID)
+		}
+
+		removedIDs, _ := lo.Difference(lo.Map(existing, func(q ChainQuestion, _ int) uint { return q.ID }), keptIDs)
+		if len(removedIDs) == 0 {
+			return nil
+		}
+		if err := tx.Exec(`DELETE FROM chain_question_answers WHERE chain_question_id IN ?`, removedIDs).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`DELETE FROM chain_questions WHERE id IN ?`, removedIDs).Error
+	})
+}
+
+// Checks the answers against the questions of the loop,
+// required questions are only checked if isRequiredChecked is true.
+func ChainQuestionValidateAnswers(questions []ChainQuestion, answers []ChainQuestionAnswerRequest, isRequiredChecked bool) error {
+	questionsByUID := lo.KeyBy(questions, func(q ChainQuestion) string { return q.UID })
+	answered := map[string]bool{}
+	for _, a := range answers {
+		q, ok := questionsByUID[a.QuestionUID]
+		_, isDuplicate := answered[a.QuestionUID]
+		// an empty answer leaves the question unanswered
+		if !ok || isDuplicate || (a.Answer != "" && !q.ValidateAnswer(a.Answer)) {
+			return ErrChainQuestionAnswerInvalid
+		}
+		answered[a.QuestionUID] = a.Answer != ""
+	}
+	if isRequiredChecked {
+		for _, q := range questions {
+			if q.IsRequired && !answered[q.UID] {
+				return ErrChainQuestionAnswerMissing
+			}
+		}
+	}
+	return nil
+}
+
+// Expects the answers to be validated, previous answers of the user to the loop are replaced
+func ChainQuestionAnswerSetAll(db *gorm.DB, chainID, userID uint, questions []ChainQuestion, answers []ChainQuestionAnswerRequest) error {
+	questionsByUID := lo.KeyBy(questions, func(q ChainQuestion) string { return q.UID })
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := ChainQuestionAnswerDeleteByUser(tx, chainID, userID); err != nil {
+			return err
+		}
+		for _, a := range answers {
+			if a.Answer == "" {
+				continue
+			}
+			err := tx.Create(&ChainQuestionAnswer{
+				ChainQuestionID: questionsByUID[a.QuestionUID].ID,
+				ChainID:         chainID,
+				UserID:          userID,
+				Answer:          a.Answer,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Returns the answers of the users of the loop by user id, in the order of the questions
+func ChainQuestionAnswerGetAllByUsers(db *gorm.DB, chainID uint, userIDs ...uint) (map[uint][]ChainQuestionAnswer, error) {
+	answers := []ChainQuestionAnswer{}
+	err := db.Raw(`
+SELECT a.*, q.uid AS question_uid, q.question AS question
+FROM chain_question_answers AS a
+JOIN chain_questions AS q ON q.id = a.chain_question_id
+WHERE a.chain_id = ? AND a.user_id IN ?
+ORDER BY q.position ASC
+	`, chainID, userIDs).Scan(&answers).Error
+	if err != nil {
+		return nil, err
+	}
+	return lo.GroupBy(answers, func(a ChainQuestionAnswer) uint { return a.UserID }), nil
+}
+
+func ChainQuestionAnswerDeleteByUser(db *gorm.DB, chainID, userID uint) error {
+	return db.Exec(`DELETE FROM chain_question_answers WHERE chain_id = ? AND user_id = ?`, chainID, userID).Error
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainQuestionValidate(t *testing.T) {
+	assert.True(t, (&ChainQuestion{Type: ChainQuestionTypeText, Question: "Why do you want to join?"}).Validate())
+	assert.False(t, (&ChainQuestion{Type: ChainQuestionTypeText, Question: ""}).Validate())
+	assert.False(t, (&ChainQuestion{Type: "multiple_choice", Question: "Which?"}).Validate())
+	assert.False(t, (&ChainQuestion{Type: ChainQuestionTypeYesNo, Question: "Do you live nearby?", Options: []string{"a"}}).Validate())
+	assert.True(t, (&ChainQuestion{Type: ChainQuestionTypeSingleChoice, Question: "Which?", Options: []string{"a", "b"}}).Validate())
+	assert.False(t, (&ChainQuestion{Type: ChainQuestionTypeSingleChoice, Question: "Which?", Options: []string{"a"}}).Validate())
+	assert.False(t, (&ChainQuestion{Type: ChainQuestionTypeSingleChoice, Question: "Which?", Options: []string{"a", "a"}}).Validate())
+	assert.False(t, (&ChainQuestion{Type: ChainQuestionTypeText, Question: strings.Repeat("a", ChainQuestionMaxSize+1)}).Validate())
+	assert.False(t, (&ChainQuestion{Type: ChainQuestionTypeSingleChoice, Question: "Which?", Options: []string{"a", strings.Repeat("b", ChainQuestionOptionMaxSize+1)}}).Validate())
+}
+
+func TestChainQuestionValidateAnswers(t *testing.T) {
+	questions := []ChainQuestion{
+		{UID: "text", Type: ChainQuestionTypeText, Question: "Why do you want to join?"},
+		{UID: "choice", Type: ChainQuestionTypeSingleChoice, Question: "Which?", Options: []string{"a", "b"}},
+		{UID: "yes_no", Type: ChainQuestionTypeYesNo, Question: "Do you live nearby?", IsRequired: true},
+	}
+
+	assert.NoError(t, ChainQuestionValidateAnswers(questions, []ChainQuestionAnswerRequest{
+		{QuestionUID: "text", Answer: "To share clothes"},
+		{QuestionUID: "choice", Answer: "b"},
+		{QuestionUID: "yes_no", Answer: "yes"},
+	}, true))
+	assert.ErrorIs(t, ChainQuestionValidateAnswers(questions, []ChainQuestionAnswerRequest{
+		{QuestionUID: "choice", Answer: "c"},
+	}, false), ErrChainQuestionAnswerInvalid)
+	assert.ErrorIs(t, ChainQuestionValidateAnswers(questions, []ChainQuestionAnswerRequest{
+		{QuestionUID: "yes_no", Answer: "maybe"},
+	}, true), ErrChainQuestionAnswerInvalid)
+	assert.ErrorIs(t, ChainQuestionValidateAnswers(questions, []ChainQuestionAnswerRequest{
+		{QuestionUID: "unknown", Answer: "yes"},
+	}, false), ErrChainQuestionAnswerInvalid)
+
+	t.Run("Required questions", func(t *testing.T) {
+		answers := []ChainQuestionAnswerRequest{{QuestionUID: "yes_no", Answer: ""}}
+		assert.ErrorIs(t, ChainQuestionValidateAnswers(questions, answers, true), ErrChainQuestionAnswerMissing)
+		assert.ErrorIs(t, ChainQuestionValidateAnswers(questions, nil, true), ErrChainQuestionAnswerMissing)
+		assert.NoError(t, ChainQuestionValidateAnswers(questions, nil, false))
+	})
+}
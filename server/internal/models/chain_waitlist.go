@@ -0,0 +1,129 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gopkg.in/guregu/null.v3/zero"
+	"gorm.io/gorm"
+)
+
+var ErrChainWaitlistNotFound = errors.New("User is not on the waitlist of this loop")
+
+// A user waiting for a loop that is closed to new members,
+// the entry is removed once the user joins any loop.
+type ChainWaitlist struct {
+	ID        uint   `json:"-"`
+	ChainID   uint   `json:"-" gorm:"uniqueIndex:uidx_chain_waitlist"`
+	UserID    uint   `json:"-" gorm:"uniqueIndex:uidx_chain_waitlist;index"`
+	UserUID   string `json:"user_uid" gorm:"-:migration;<-:false"`
+	UserName  string `json:"user_name" gorm:"-:migration;<-:false"`
+	UserEmail string `json:"user_email" gorm:"-:migration;<-:false"`
+	UserI18n  string `json:"-" gorm:"-:migration;<-:false"`
+	// Starts at 1, hosts can reorder the waitlist
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	// Set when the user was emailed that the loop reopened
+	InvitedAt zero.Time `json:"invited_at"`
+}
+
+// Adds the user to the end of the waitlist, returns the existing entry if already waiting
+func ChainWaitlistJoin(db *gorm.DB, chainID, userID uint) (*ChainWaitlist, error) {
+	w := &ChainWaitlist{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		tx.Raw(`SELECT * FROM chain_waitlists WHERE chain_id = ? AND user_id = ? LIMIT 1`, chainID, userID).Scan(w)
+		if w.ID != 0 {
+			return nil
+		}
+
+		position := 0
+		tx.Raw(`SELECT COALESCE(MAX(position), 0) FROM chain_waitlists WHERE chain_id = ?`, chainID).Scan(&position)
+		w = &ChainWaitlist{
+			ChainID:  chainID,
+			UserID:   userID,
+			Position: position + 1,
+		}
+		return tx.Create(w).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func ChainWaitlistGetAllByChain(db *gorm.DB, chainID uint) ([]ChainWaitlist, error) {
+	waitlist := []ChainWaitlist{}
+	err := db.Raw(`
+SELECT cw.*, u.uid AS user_uid, u.name AS user_name, u.email AS user_email, u.i18n AS user_i18n
+FROM chain_waitlists AS cw
+JOIN users AS u ON u.id = cw.user_id
+WHERE cw.chain_id = ?
+ORDER BY cw.position ASC
+	`, chainID).Scan(&waitlist).Error
+	if err != nil {
+		return nil, err
+	}
+	return waitlist, nil
+}
+
+// Positions of the remaining users are closed up
+func ChainWaitlistLeave(db *gorm.DB, chainID, userID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		w := &ChainWaitlist{}
+		tx.Raw(`SELECT * FROM chain_waitlists WHERE chain_id = ? AND user_id = ? LIMIT 1`, chainID, userID).Scan(w)
+		if w.ID == 0 {
+			return ErrChainWaitlistNotFound
+		}
+		if err := tx.Exec(`DELETE FROM chain_waitlists WHERE id = ?`, w.ID).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`
+UPDATE chain_waitlists SET position = position - 1
+WHERE chain_id = ? AND position > ?
+		`, chainID, w.Position).Error
+	})
+}
+
+// Expects all user uids on the waitlist in the new order
+func ChainWaitlistSetOrder(db *gorm.DB, chainID uint, userUIDs []string) error {
+	count := 0
+	db.Raw(`
+SELECT COUNT(*) FROM chain_waitlists AS cw
+JOIN users AS u ON u.id = cw.user_id
+WHERE cw.chain_id = ? AND u.uid IN ?
+	`, chainID, userUIDs).Scan(&count)
+	total := 0
+	db.Raw(`SELECT COUNT(*) FROM chain_waitlists WHERE chain_id = ?`, chainID).Scan(&total)
+	if count != len(userUIDs) || count != total {
+		return ErrChainWaitlistNotFound
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for i, userUID := range userUIDs {
+			err := tx.Exec(`
+UPDATE chain_waitlists SET position = ?
+WHERE chain_id = ? AND user_id = (SELECT id FROM users WHERE uid = ?)
+			`, i+1, chainID, userUID).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func ChainWaitlistSetInvited(db *gorm.DB, chainID uint, userIDs []uint) error {
+	return db.Exec(`UPDATE chain_waitlists SET invited_at = NOW() WHERE chain_id = ? AND user_id IN ?`, chainID, userIDs).Error
+}
+
+// Removes the user from all waitlists, the positions of the remaining users are closed up
+func ChainWaitlistRemoveUser(db *gorm.DB, userID uint) error {
+	chainIDs := []uint{}
+	db.Raw(`SELECT chain_id FROM chain_waitlists WHERE user_id = ?`, userID).Scan(&chainIDs)
+	for _, chainID := range chainIDs {
+		if err := ChainWaitlistLeave(db, chainID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
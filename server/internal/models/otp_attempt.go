@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"gopkg.in/guregu/null.v3/zero"
+	"gorm.io/gorm"
+)
+
+// Failed one time password attempts of an email address
+type OtpAttempt struct {
+	ID             uint
+	Email          string `gorm:"uniqueIndex;size:255"`
+	FailedAttempts int
+	LockedUntil    zero.Time
+	UpdatedAt      time.Time
+}
+
+func OtpAttemptGetByEmail(db *gorm.DB, email string) (*OtpAttempt, error) {
+	attempt := &OtpAttempt{}
+	err := db.Raw(`SELECT * FROM otp_attempts WHERE email = ? LIMIT 1`, email).Scan(attempt).Error
+	if err != nil {
+		return nil, err
+	}
+	return attempt, nil
+}
+
+func (a *OtpAttempt) IsLocked() bool {
+	return a.LockedUntil.Valid && a.LockedUntil.Time.After(time.Now())
+}
@@ -54,12 +54,13 @@ SELECT
 	user_chains.user_id        AS user_id,
 	users.uid                  AS user_uid,
 	user_chains.is_chain_admin AS is_chain_admin,
+	user_chains.role           AS role,
 	user_chains.created_at     AS created_at,
 	user_chains.is_approved    AS is_approved
 FROM user_chains
 LEFT JOIN chains ON user_chains.chain_id = chains.id
 LEFT JOIN users ON user_chains.user_id = users.id
-WHERE users.id = ?
+WHERE users.id = ? AND chains.deleted_at IS NULL
 	`, u.ID).Scan(&userChains).Error
 	if err != nil {
 		return err
@@ -111,6 +112,16 @@ func (u *User) IsPartOfChain(chainUID string) (ok, isChainAdmin bool) {
 	return ok, isChainAdmin
 }
 
+// This required user to have run AddUserChainsToObject before this
+func (u *User) HasChainPermission(chainUID, permission string) bool {
+	for _, c := range u.Chains {
+		if c.ChainUID == chainUID {
+			return c.HasPermission(permission)
+		}
+	}
+	return false
+}
+
 // This required user to have run AddUserChainsToObject before this
 func (u *User) IsAnyChainAdmin() (isAnyChainAdmin bool) {
 	for _, c := range u.Chains {
@@ -165,6 +176,7 @@ type UserContactData struct {
 	Name       string      `gorm:"name"`
 	Email      zero.String `gorm:"email"`
 	I18n       string      `gorm:"i18n"`
+	ChainID    uint        `gorm:"chain_id"`
 	ChainName  string      `gorm:"chain_name"`
 	IsApproved bool        `gorm:"is_approved"`
 }
@@ -205,6 +217,7 @@ SELECT
 	users.name AS name,
 	users.email AS email,
 	users.i18n AS i18n,
+	uc.chain_id AS chain_id,
 	chains.name AS chain_name
 FROM user_chains AS uc
 LEFT JOIN users ON uc.user_id = users.id
@@ -5,23 +5,105 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/samber/lo"
 	"gopkg.in/guregu/null.v3/zero"
 	"gorm.io/gorm"
 )
 
+// Roles of a member in a loop, a participant has no role
+const (
+	UserChainRoleHost         = "host"
+	UserChainRoleBagManager   = "bag_manager"
+	UserChainRoleRouteManager = "route_manager"
+	UserChainRoleModerator    = "moderator"
+)
+
+// Permissions in a loop, these are granted by the role of a member
+const (
+	// Edit and delete the loop, and change the roles of members
+	ChainPermissionManageLoop = "manage_loop"
+	// Approve, edit and remove members
+	ChainPermissionManageMembers = "manage_members"
+	ChainPermissionManageBags    = "manage_bags"
+	ChainPermissionManageRoute   = "manage_route"
+	ChainPermissionManageEvents  = "manage_events"
+)
+
+var UserChainRolePermissions = map[string][]string{
+	UserChainRoleHost: {
+		ChainPermissionManageLoop,
+		ChainPermissionManageMembers,
+		ChainPermissionManageBags,
+		ChainPermissionManageRoute,
+		ChainPermissionManageEvents,
+	},
+	UserChainRoleBagManager:   {ChainPermissionManageBags},
+	UserChainRoleRouteManager: {ChainPermissionManageRoute},
+	UserChainRoleModerator:    {ChainPermissionManageMembers, ChainPermissionManageEvents},
+}
+
+var ErrUserChainRoleInvalid = errors.New("Invalid role")
+
+// An empty role is a participant
+func ValidateUserChainRole(role string) bool {
+	if role == "" {
+		return true
+	}
+	_, ok := UserChainRolePermissions[role]
+	return ok
+}
+
 type UserChain struct {
-	ID                         uint        `json:"-"`
-	UserID                     uint        `json:"-" gorm:"index"`
-	UserUID                    string      `json:"user_uid" gorm:"-:migration;<-:false"`
-	ChainID                    uint        `json:"-"`
-	ChainUID                   string      `json:"chain_uid" gorm:"-:migration;<-:false"`
+	ID       uint   `json:"-"`
+	UserID   uint   `json:"-" gorm:"index"`
+	UserUID  string `json:"user_uid" gorm:"-:migration;<-:false"`
+	ChainID  uint   `json:"-"`
+	ChainUID string `json:"chain_uid" gorm:"-:migration;<-:false"`
+	// Is true for the host role
 	IsChainAdmin               bool        `json:"is_chain_admin"`
+	Role                       string      `json:"role" gorm:"size:20;not null;default:''"`
 	CreatedAt                  time.Time   `json:"created_at"`
 	IsApproved                 bool        `json:"is_approved"`
 	LastNotifiedIsUnapprovedAt zero.Time   `json:"-"`
 	RouteOrder                 int         `json:"-"`
 	Bags                       []Bag       `json:"-"`
 	Bulky                      []BulkyItem `json:"-"`
+	// Only set for users that are not yet approved
+	JoinAnswers []ChainQuestionAnswer `json:"join_answers,omitempty" gorm:"-"`
+}
+
+// Keeps is_chain_admin equal to having the host role
+func (uc *UserChain) BeforeSave(tx *gorm.DB) error {
+	if uc.IsChainAdmin {
+		uc.Role = UserChainRoleHost
+	} else if uc.Role == UserChainRoleHost {
+		uc.Role = ""
+	}
+	return nil
+}
+
+// A user that joins a loop no longer waits for any other loop
+func (uc *UserChain) AfterCreate(tx *gorm.DB) error {
+	return ChainWaitlistRemoveUser(tx, uc.UserID)
+}
+
+func (uc *UserChain) HasPermission(permission string) bool {
+	if uc.IsChainAdmin {
+		return true
+	}
+	return lo.Contains(UserChainRolePermissions[uc.Role], permission)
+}
+
+// Also sets is_chain_admin for the host role
+func UserChainSetRole(db *gorm.DB, userChainID uint, role string) error {
+	if !ValidateUserChainRole(role) {
+		return ErrUserChainRoleInvalid
+	}
+	return db.Exec(`
+UPDATE user_chains
+SET role = ?, is_chain_admin = ?
+WHERE id = ?
+	`, role, role == UserChainRoleHost, userChainID).Error
 }
 
 var ErrRouteInvalid = errors.New("Invalid route")
@@ -63,6 +145,12 @@ DELETE FROM bulky_items WHERE user_chain_id IN (
 		return fmt.Errorf("Unable to delete bags from user in loop: %v", err)
 	}
 
+	err = ChainQuestionAnswerDeleteByUser(tx, chainID, u.ID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Unable to delete answers from user in loop: %v", err)
+	}
+
 	return tx.Commit().Error
 }
 
@@ -115,6 +203,44 @@ DELETE FROM bulky_items WHERE user_chain_id IN (
 	return nil
 }
 
+// Loops that the user is the only host of, deleted loops are ignored
+func (u *User) GetLastHostChainIDs(db *gorm.DB) ([]uint, error) {
+	chainIDs := []uint{}
+	err := db.Raw(`
+SELECT uc.chain_id
+FROM user_chains AS uc
+JOIN chains AS c ON c.id = uc.chain_id AND c.deleted_at IS NULL
+WHERE uc.chain_id IN (
+	SELECT uc2.chain_id
+	FROM user_chains AS uc2
+	WHERE uc2.is_chain_admin = TRUE AND uc2.user_id = ?
+) AND uc.is_chain_admin = TRUE
+GROUP BY uc.chain_id
+HAVING COUNT(uc.id) = 1
+	`, u.ID).Scan(&chainIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	return chainIDs, nil
+}
+
+// The role of every member of the loop by user id
+func UserChainGetRolesByChain(db *gorm.DB, chainID uint) (map[uint]string, error) {
+	results := []struct {
+		UserID uint
+		Role   string
+	}{}
+	err := db.Raw(`SELECT user_id, role FROM user_chains WHERE chain_id = ?`, chainID).Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	roles := map[uint]string{}
+	for _, r := range results {
+		roles[r.UserID] = r.Role
+	}
+	return roles, nil
+}
+
 func UserChainGetIndirectByChain(db *gorm.DB, chainID uint) ([]UserChain, error) {
 	results := []UserChain{}
 
@@ -126,6 +252,7 @@ func UserChainGetIndirectByChain(db *gorm.DB, chainID uint) ([]UserChain, error)
 		user_chains.user_id        AS user_id,
 		users.uid                  AS user_uid,
 		user_chains.is_chain_admin AS is_chain_admin,
+		user_chains.role           AS role,
 		user_chains.created_at     AS created_at,
 		user_chains.is_approved    AS is_approved
 	FROM user_chains
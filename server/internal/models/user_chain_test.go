@@ -0,0 +1,46 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserChainHasPermission(t *testing.T) {
+	host := &UserChain{IsChainAdmin: true}
+	bagManager := &UserChain{Role: UserChainRoleBagManager}
+	moderator := &UserChain{Role: UserChainRoleModerator}
+	participant := &UserChain{}
+
+	assert.True(t, host.HasPermission(ChainPermissionManageLoop))
+	assert.True(t, host.HasPermission(ChainPermissionManageBags))
+
+	assert.True(t, bagManager.HasPermission(ChainPermissionManageBags))
+	assert.False(t, bagManager.HasPermission(ChainPermissionManageMembers))
+	assert.False(t, bagManager.HasPermission(ChainPermissionManageLoop))
+
+	assert.True(t, moderator.HasPermission(ChainPermissionManageMembers))
+	assert.False(t, moderator.HasPermission(ChainPermissionManageRoute))
+
+	assert.False(t, participant.HasPermission(ChainPermissionManageBags))
+}
+
+func TestUserChainBeforeSave(t *testing.T) {
+	uc := &UserChain{IsChainAdmin: true}
+	uc.BeforeSave(nil)
+	assert.Equal(t, UserChainRoleHost, uc.Role)
+
+	uc.IsChainAdmin = false
+	uc.BeforeSave(nil)
+	assert.Equal(t, "", uc.Role)
+
+	uc.Role = UserChainRoleRouteManager
+	uc.BeforeSave(nil)
+	assert.Equal(t, UserChainRoleRouteManager, uc.Role)
+}
+
+func TestValidateUserChainRole(t *testing.T) {
+	assert.True(t, ValidateUserChainRole(""))
+	assert.True(t, ValidateUserChainRole(UserChainRoleModerator))
+	assert.False(t, ValidateUserChainRole("owner"))
+}
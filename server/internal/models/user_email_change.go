@@ -0,0 +1,75 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrUserEmailChangeNotFound = errors.New("Email change request not found or expired")
+
+// how long the confirmation link sent to the new email address is valid
+const userEmailChangeMaxAge = 2 * 24 * time.Hour
+
+// A pending change of the email address of a user,
+// the email is only changed once the new address is confirmed.
+type UserEmailChange struct {
+	ID        uint
+	UserID    uint   `gorm:"index"`
+	NewEmail  string `gorm:"size:255"`
+	TokenHash string `gorm:"uniqueIndex;size:64"`
+	// The user or root admin that requested the change
+	RequestedByUserID uint
+	CreatedAt         time.Time
+	ExpiresAt         time.Time
+}
+
+// Replaces any pending email change of the user, returns the token for the confirmation link
+func UserEmailChangeCreate(db *gorm.DB, userID, requestedByUserID uint, newEmail string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`DELETE FROM user_email_changes WHERE user_id = ?`, userID).Error; err != nil {
+			return err
+		}
+		return tx.Create(&UserEmailChange{
+			UserID:            userID,
+			NewEmail:          newEmail,
+			TokenHash:         TokenHash(token),
+			RequestedByUserID: requestedByUserID,
+			ExpiresAt:         time.Now().Add(userEmailChangeMaxAge),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func UserEmailChangeGetByToken(db *gorm.DB, token string) (*UserEmailChange, error) {
+	ec := &UserEmailChange{}
+	err := db.Raw(`
+SELECT * FROM user_email_changes
+WHERE token_hash = ? AND expires_at > NOW()
+LIMIT 1
+	`, TokenHash(token)).Scan(ec).Error
+	if err != nil {
+		return nil, err
+	}
+	if ec.ID == 0 {
+		return nil, ErrUserEmailChangeNotFound
+	}
+	return ec, nil
+}
+
+func UserEmailChangeDeleteOld(db *gorm.DB) {
+	db.Exec(`DELETE FROM user_email_changes WHERE expires_at < NOW()`)
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"gopkg.in/guregu/null.v3/zero"
+	"gorm.io/gorm"
+)
+
+// A request made by a root admin while impersonating a user,
+// the admin or user is NULL after they are purged.
+type UserImpersonationLog struct {
+	ID           uint      `json:"-"`
+	AdminUserID  zero.Int  `json:"-" gorm:"index"`
+	AdminUserUID string    `json:"admin_user_uid" gorm:"-:migration;<-:false"`
+	UserID       zero.Int  `json:"-" gorm:"index"`
+	UserUID      string    `json:"user_uid" gorm:"-:migration;<-:false"`
+	SessionUID   string    `json:"session_uid"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	Status       int       `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Lists the most recent requests, of all impersonated users if userID is 0
+func UserImpersonationLogGetAll(db *gorm.DB, userID uint, limit int) ([]UserImpersonationLog, error) {
+	logs := []UserImpersonationLog{}
+	query := `
+SELECT l.*, IFNULL(a.uid, '') AS admin_user_uid, IFNULL(u.uid, '') AS user_uid
+FROM user_impersonation_logs AS l
+LEFT JOIN users AS a ON a.id = l.admin_user_id
+LEFT JOIN users AS u ON u.id = l.user_id
+`
+	args := []any{}
+	if userID != 0 {
+		query += `WHERE l.user_id = ?
+`
+		args = append(args, userID)
+	}
+	query += `ORDER BY l.id DESC
+LIMIT ?`
+	args = append(args, limit)
+
+	err := db.Raw(query, args...).Scan(&logs).Error
+	if err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Links the subject of an OpenID Connect provider to a user,
+// so that a user can still log in after changing the email at the provider.
+type UserOidcIdentity struct {
+	ID        uint
+	UserID    uint   `gorm:"index"`
+	Provider  string `gorm:"uniqueIndex:uidx_provider_subject;size:64"`
+	Subject   string `gorm:"uniqueIndex:uidx_provider_subject;size:255"`
+	CreatedAt time.Time
+}
+
+// Returns nil if the subject is not linked to a user
+func UserOidcIdentityGet(db *gorm.DB, provider, subject string) (*UserOidcIdentity, error) {
+	identity := &UserOidcIdentity{}
+	err := db.Raw(`
+SELECT * FROM user_oidc_identities
+WHERE provider = ? AND subject = ?
+LIMIT 1
+	`, provider, subject).Scan(identity).Error
+	if err != nil {
+		return nil, err
+	}
+	if identity.ID == 0 {
+		return nil, nil
+	}
+	return identity, nil
+}
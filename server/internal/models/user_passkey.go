@@ -0,0 +1,78 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gopkg.in/guregu/null.v3"
+	"gorm.io/gorm"
+)
+
+var ErrUserPasskeyNotFound = errors.New("Passkey not found")
+
+// A WebAuthn credential a user can log in with instead of a one time password
+type UserPasskey struct {
+	ID     uint   `json:"-"`
+	UID    string `json:"uid" gorm:"uniqueIndex"`
+	UserID uint   `json:"-" gorm:"index"`
+	Name   string `json:"name"`
+	// base64url encoded credential id chosen by the authenticator
+	CredentialID string `json:"-" gorm:"uniqueIndex;size:255"`
+	// COSE encoded public key
+	PublicKey []byte `json:"-"`
+	SignCount uint32 `json:"-"`
+	// a passkey that can be synced between devices, this does not change after registration
+	BackupEligible bool      `json:"-"`
+	CreatedAt      time.Time `json:"created_at"`
+	// NULL until the passkey is used to log in
+	LastUsedAt null.Time `json:"last_used_at"`
+}
+
+func UserPasskeyGetAllByUser(db *gorm.DB, userID uint) ([]UserPasskey, error) {
+	passkeys := []UserPasskey{}
+	err := db.Raw(`
+SELECT * FROM user_passkeys
+WHERE user_id = ?
+ORDER BY created_at DESC
+	`, userID).Scan(&passkeys).Error
+	if err != nil {
+		return nil, err
+	}
+	return passkeys, nil
+}
+
+func UserPasskeyGetByUID(db *gorm.DB, passkeyUID string) (*UserPasskey, error) {
+	passkey := &UserPasskey{}
+	err := db.Raw(`SELECT * FROM user_passkeys WHERE uid = ? LIMIT 1`, passkeyUID).Scan(passkey).Error
+	if err != nil {
+		return nil, err
+	}
+	if passkey.ID == 0 {
+		return nil, ErrUserPasskeyNotFound
+	}
+	return passkey, nil
+}
+
+func UserPasskeyGetByCredentialID(db *gorm.DB, credentialID string) (*UserPasskey, error) {
+	passkey := &UserPasskey{}
+	err := db.Raw(`SELECT * FROM user_passkeys WHERE credential_id = ? LIMIT 1`, credentialID).Scan(passkey).Error
+	if err != nil {
+		return nil, err
+	}
+	if passkey.ID == 0 {
+		return nil, ErrUserPasskeyNotFound
+	}
+	return passkey, nil
+}
+
+func (p *UserPasskey) UpdateSignCount(db *gorm.DB, signCount uint32) error {
+	return db.Exec(`
+UPDATE user_passkeys
+SET sign_count = ?, last_used_at = NOW()
+WHERE id = ?
+	`, signCount, p.ID).Error
+}
+
+func (p *UserPasskey) Delete(db *gorm.DB) error {
+	return db.Exec(`DELETE FROM user_passkeys WHERE id = ?`, p.ID).Error
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gopkg.in/guregu/null.v3/zero"
+	"gorm.io/gorm"
+)
+
+var ErrRefreshTokenNotFound = errors.New("Refresh token not found")
+
+// Single use token to receive a new access token,
+// all refresh tokens of a session are considered the same token family.
+type UserRefreshToken struct {
+	ID            uint
+	TokenHash     string `gorm:"uniqueIndex;size:64"`
+	UserID        uint   `gorm:"index"`
+	UserSessionID uint   `gorm:"index"`
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+	UsedAt        zero.Time
+}
+
+func UserRefreshTokenGetByHash(db *gorm.DB, tokenHash string) (*UserRefreshToken, error) {
+	rt := &UserRefreshToken{}
+	err := db.Raw(`SELECT * FROM user_refresh_tokens WHERE token_hash = ? LIMIT 1`, tokenHash).Scan(rt).Error
+	if err != nil {
+		return nil, err
+	}
+	if rt.ID == 0 {
+		return nil, ErrRefreshTokenNotFound
+	}
+	return rt, nil
+}
+
+func (rt *UserRefreshToken) IsUsed() bool {
+	return rt.UsedAt.Valid
+}
+
+// Returns false if the token was already used, this prevents two concurrent requests using the same token
+func (rt *UserRefreshToken) MarkUsed(db *gorm.DB) (bool, error) {
+	res := db.Exec(`UPDATE user_refresh_tokens SET used_at = NOW() WHERE id = ? AND used_at IS NULL`, rt.ID)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func UserRefreshTokenDeleteAllBySession(db *gorm.DB, userSessionID uint) error {
+	return db.Exec(`DELETE FROM user_refresh_tokens WHERE user_session_id = ?`, userSessionID).Error
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gopkg.in/guregu/null.v3/zero"
+	"gorm.io/gorm"
+)
+
+var ErrUserSessionNotFound = errors.New("Session not found")
+
+// A device that a user has logged in with, each issued jwt references one session
+type UserSession struct {
+	ID         uint      `json:"-"`
+	UID        string    `json:"uid" gorm:"uniqueIndex"`
+	UserID     uint      `json:"-" gorm:"index"`
+	DeviceName string    `json:"device_name"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	RevokedAt  zero.Time `json:"-"`
+	IsCurrent  bool      `json:"is_current" gorm:"-"`
+	// Set when a root admin impersonates the user, these sessions expire and can not be refreshed
+	ImpersonatedByUserID zero.Int  `json:"-"`
+	ExpiresAt            zero.Time `json:"-"`
+	// Hash of the token issued before sessions existed, so that only one session is created for it
+	LegacyTokenHash zero.String `json:"-" gorm:"index;size:64"`
+}
+
+func UserSessionGetByUID(db *gorm.DB, sessionUID string) (*UserSession, error) {
+	session := &UserSession{}
+	err := db.Raw(`SELECT * FROM user_sessions WHERE uid = ? LIMIT 1`, sessionUID).Scan(session).Error
+	if err != nil {
+		return nil, err
+	}
+	if session.ID == 0 {
+		return nil, ErrUserSessionNotFound
+	}
+	return session, nil
+}
+
+func UserSessionGetByLegacyTokenHash(db *gorm.DB, userID uint, tokenHash string) (*UserSession, error) {
+	session := &UserSession{}
+	err := db.Raw(`SELECT * FROM user_sessions WHERE user_id = ? AND legacy_token_hash = ? LIMIT 1`, userID, tokenHash).Scan(session).Error
+	if err != nil {
+		return nil, err
+	}
+	if session.ID == 0 {
+		return nil, ErrUserSessionNotFound
+	}
+	return session, nil
+}
+
+// Lists all sessions of a user that have not been revoked, most recently seen first
+func UserSessionGetAllActiveByUser(db *gorm.DB, userID uint) ([]UserSession, error) {
+	sessions := []UserSession{}
+	err := db.Raw(`
+SELECT * FROM user_sessions
+WHERE user_id = ? AND revoked_at IS NULL AND impersonated_by_user_id IS NULL
+ORDER BY last_seen_at DESC
+	`, userID).Scan(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (s *UserSession) IsRevoked() bool {
+	return s.RevokedAt.Valid
+}
+
+func (s *UserSession) IsImpersonated() bool {
+	return s.ImpersonatedByUserID.Valid
+}
+
+func (s *UserSession) IsExpired() bool {
+	return s.ExpiresAt.Valid && s.ExpiresAt.Time.Before(time.Now())
+}
+
+func (s *UserSession) Revoke(db *gorm.DB) error {
+	return db.Exec(`UPDATE user_sessions SET revoked_at = NOW() WHERE id = ? AND revoked_at IS NULL`, s.ID).Error
+}
+
+func (s *UserSession) UpdateLastSeen(db *gorm.DB, ipAddress string) error {
+	return db.Exec(`
+UPDATE user_sessions
+SET last_seen_at = NOW(), ip_address = ?
+WHERE id = ?
+	`, ipAddress, s.ID).Error
+}
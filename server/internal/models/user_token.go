@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
 
 type UserToken struct {
 	ID        uint
@@ -8,4 +12,16 @@ type UserToken struct {
 	Verified  bool
 	UserID    uint
 	CreatedAt time.Time
+	// The token is invalidated after too many failed attempts
+	FailedAttempts int
+	// Identifies the token from a signed magic link
+	LinkNonce string `gorm:"index;size:64"`
+	// The chain to join after logging in with the magic link
+	ChainUID string
+}
+
+// Only the hash of secret tokens is stored, like refresh tokens, api keys and email change tokens
+func TokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -10,8 +10,10 @@ import (
 	cron "github.com/go-co-op/gocron"
 	"github.com/golang/glog"
 	"github.com/the-clothing-loop/website/server/internal/app"
+	"github.com/the-clothing-loop/website/server/internal/app/auth"
 	"github.com/the-clothing-loop/website/server/internal/app/goscope"
 	"github.com/the-clothing-loop/website/server/internal/controllers"
+	"github.com/the-clothing-loop/website/server/internal/models"
 	"github.com/the-clothing-loop/website/server/pkg/throttle"
 )
 
@@ -21,6 +23,7 @@ func Routes() *gin.Engine {
 	// initialization
 	db := app.DatabaseInit()
 	app.MailInit()
+	auth.OidcInit()
 
 	if app.Config.ENV == app.EnvEnumProduction || (app.Config.SENDINBLUE_API_KEY != "" && app.Config.ENV == app.EnvEnumDevelopment) {
 		app.BrevoInit()
@@ -77,89 +80,149 @@ func Routes() *gin.Engine {
 	)
 	r.Use(controllers.MiddlewareSetDB(db))
 
+	RoutesV2(auth.NewRouterGroup(r.Group("/v2")))
+
+	return r
+}
+
+// Registers all routes with the minimum role required to access them
+func RoutesV2(v2 *auth.RouterGroup) {
 	thr := throttle.Policy(&throttle.Quota{
 		Limit:  20,
 		Within: 24 * time.Hour,
 	})
-
-	// router groups
-	v2 := r.Group("/v2")
+	thrLoginEmail := throttle.Policy(&throttle.Quota{
+		Limit:  5,
+		Within: time.Hour,
+	}, &throttle.Options{
+		KeyPrefix:              "login_email",
+		IdentificationFunction: controllers.LoginEmailIdentify,
+	})
 
 	// ping
-	v2.Any("/ping", func(c *gin.Context) {
+	v2.Any("/ping", auth.Guest(), func(c *gin.Context) {
 		c.String(200, "pong")
 	})
 
 	// info
-	v2.GET("/info", controllers.InfoGet)
+	v2.GET("/info", auth.Guest(), controllers.InfoGet)
 
 	// login
-	v2.POST("/register/basic-user", controllers.RegisterBasicUser)
-	v2.POST("/register/orphaned-user", controllers.RegisterBasicUser)
-	v2.POST("/register/chain-admin", controllers.RegisterChainAdmin)
-	v2.POST("/login/email", controllers.LoginEmail)
-	v2.GET("/login/validate", thr, controllers.LoginValidate)
-	v2.DELETE("/logout", controllers.Logout)
-	v2.POST("/refresh-token", controllers.RefreshToken)
+	v2.POST("/register/basic-user", auth.Guest(), controllers.RegisterBasicUser)
+	v2.POST("/register/orphaned-user", auth.Guest(), controllers.RegisterBasicUser)
+	v2.POST("/register/chain-admin", auth.Guest(), controllers.RegisterChainAdmin)
+	v2.POST("/login/email", auth.Guest(), thrLoginEmail, controllers.LoginEmail)
+	v2.GET("/login/validate", auth.Guest(), thr, controllers.LoginValidate)
+	v2.GET("/login/oidc", auth.Guest(), controllers.LoginOidcProviders)
+	v2.GET("/login/oidc/:provider", auth.Guest(), thr, controllers.LoginOidcStart)
+	v2.GET("/login/oidc/:provider/callback", auth.Guest(), controllers.LoginOidcCallback)
+	v2.POST("/login/passkey/begin", auth.Guest(), thr, controllers.LoginPasskeyBegin)
+	v2.POST("/login/passkey", auth.Guest(), thr, controllers.LoginPasskeyFinish)
+	v2.DELETE("/logout", auth.Guest(), controllers.Logout)
+	v2.POST("/refresh-token", auth.Guest(), controllers.RefreshToken)
 
 	// payments
-	v2.POST("/payment/initiate", controllers.PaymentsInitiate)
-	v2.POST("/payment/webhook", controllers.PaymentsWebhook)
+	v2.POST("/payment/initiate", auth.Guest(), controllers.PaymentsInitiate)
+	v2.POST("/payment/webhook", auth.Guest(), controllers.PaymentsWebhook)
 
 	// user
-	v2.GET("/user", controllers.UserGet)
-	v2.GET("/user/all-chain", controllers.UserGetAllOfChain)
-	v2.GET("/user/newsletter", controllers.UserHasNewsletter)
-	v2.PATCH("/user", controllers.UserUpdate)
-	v2.DELETE("/user/purge", controllers.UserPurge)
-	v2.POST("/user/transfer-chain", controllers.UserTransferChain)
-	v2.GET("/user/check-email", controllers.UserCheckIfEmailExists)
+	v2.GET("/user", auth.ChainPermission(models.ChainPermissionManageMembers, auth.ChainUIDFromQuery("chain_uid").Optional()), controllers.UserGet)
+	v2.GET("/user/all-chain", auth.UserOfChain(auth.ChainUIDFromQuery("chain_uid")), controllers.UserGetAllOfChain)
+	v2.GET("/user/newsletter", auth.AnyUser().WithChain(auth.ChainUIDFromQuery("chain_uid").Optional()), controllers.UserHasNewsletter)
+	v2.PATCH("/user", auth.AnyUser().WithChain(auth.ChainUIDFromJSON("chain_uid").Optional()), controllers.UserUpdate)
+	v2.DELETE("/user/purge", auth.AnyUser().WithoutImpersonation(), controllers.UserPurge)
+	v2.POST("/user/transfer-chain", auth.ChainPermission(models.ChainPermissionManageMembers, auth.ChainUIDFromJSON("from_chain_uid")), controllers.UserTransferChain)
+	v2.GET("/user/check-email", auth.Guest(), controllers.UserCheckIfEmailExists)
+	v2.GET("/user/sessions", auth.AnyUser(), controllers.UserSessionGetAll)
+	v2.DELETE("/user/sessions/:uid", auth.AnyUser().WithoutImpersonation(), controllers.UserSessionDelete)
+	v2.GET("/user/passkeys", auth.AnyUser(), controllers.UserPasskeyGetAll)
+	v2.POST("/user/passkeys/begin", auth.AnyUser().WithoutImpersonation(), controllers.UserPasskeyRegisterBegin)
+	v2.POST("/user/passkeys", auth.AnyUser().WithoutImpersonation(), controllers.UserPasskeyRegisterFinish)
+	v2.DELETE("/user/passkeys/:uid", auth.AnyUser().WithoutImpersonation(), controllers.UserPasskeyDelete)
+	v2.POST("/user/email-change", auth.AnyUser().WithoutImpersonation(), controllers.UserEmailChangeRequest)
+	v2.POST("/user/email-change/confirm", auth.Guest(), thr, controllers.UserEmailChangeConfirm)
+	v2.GET("/user/host-invitations", auth.AnyUser(), controllers.UserHostInvitationGetAll)
+	v2.POST("/user/host-invitations/:uid/accept", auth.AnyUser().WithoutImpersonation(), controllers.UserHostInvitationAccept)
+	v2.POST("/user/host-invitations/:uid/decline", auth.AnyUser().WithoutImpersonation(), controllers.UserHostInvitationDecline)
 
 	// chain
-	v2.GET("/chain", controllers.ChainGet)
-	v2.GET("/chain/all", controllers.ChainGetAll)
-	v2.PATCH("/chain", controllers.ChainUpdate)
-	v2.DELETE("/chain", controllers.ChainDelete)
-	v2.POST("/chain", controllers.ChainCreate)
-	v2.POST("/chain/add-user", controllers.ChainAddUser)
-	v2.POST("/chain/remove-user", controllers.ChainRemoveUser)
-	v2.PATCH("/chain/approve-user", controllers.ChainApproveUser)
-	v2.DELETE("/chain/unapproved-user", controllers.ChainDeleteUnapproved)
-	v2.POST("/chain/poke", controllers.Poke)
-	v2.GET("/chain/near", controllers.ChainGetNear)
+	v2.GET("/chain", auth.Guest().WithApiKeyScope(models.ApiKeyScopeChainsRead), controllers.ChainGet)
+	v2.GET("/chain/all", auth.Guest().WithApiKeyScope(models.ApiKeyScopeChainsRead), controllers.ChainGetAll)
+	v2.PATCH("/chain", auth.ChainPermission(models.ChainPermissionManageLoop, auth.ChainUIDFromJSON("uid")), controllers.ChainUpdate)
+	v2.DELETE("/chain", auth.ChainPermission(models.ChainPermissionManageLoop, auth.ChainUIDFromQuery("chain_uid")).WithoutImpersonation(), controllers.ChainDelete)
+	v2.POST("/chain", auth.AnyUser(), controllers.ChainCreate)
+	v2.POST("/chain/still-active", auth.ChainPermission(models.ChainPermissionManageLoop, auth.ChainUIDFromJSON("chain_uid")), controllers.ChainConfirmStillActive)
+	v2.GET("/chain/audit-log", auth.ChainPermission(models.ChainPermissionManageLoop, auth.ChainUIDFromQuery("chain_uid")), controllers.ChainGetAuditLog)
+	v2.GET("/chain/deleted", auth.AnyUser(), controllers.ChainGetAllDeleted)
+	v2.POST("/chain/restore", auth.AnyUser().WithoutImpersonation(), controllers.ChainRestore)
+	v2.GET("/chain/orphaned/all", auth.RootUser(), controllers.ChainGetAllOrphaned)
+	v2.POST("/chain/become-host", auth.UserOfChain(auth.ChainUIDFromJSON("chain_uid")).WithoutImpersonation(), controllers.ChainBecomeHost)
+	v2.POST("/chain/merge", auth.ChainPermission(models.ChainPermissionManageLoop, auth.ChainUIDFromJSON("target_chain_uid")).WithoutImpersonation(), controllers.ChainMerge)
+	v2.GET("/chain/split/preview", auth.ChainPermission(models.ChainPermissionManageLoop, auth.ChainUIDFromQuery("chain_uid")), controllers.ChainSplitPreview)
+	v2.POST("/chain/split", auth.ChainPermission(models.ChainPermissionManageLoop, auth.ChainUIDFromJSON("chain_uid")).WithoutImpersonation(), controllers.ChainSplit)
+	v2.POST("/chain/add-user", auth.AnyUser().WithChain(auth.ChainUIDFromJSON("chain_uid")), controllers.ChainAddUser)
+	v2.POST("/chain/remove-user", auth.AnyUser().WithChain(auth.ChainUIDFromJSON("chain_uid")), controllers.ChainRemoveUser)
+	v2.PATCH("/chain/approve-user", auth.ChainPermission(models.ChainPermissionManageMembers, auth.ChainUIDFromJSON("chain_uid")), controllers.ChainApproveUser)
+	v2.DELETE("/chain/unapproved-user", auth.ChainPermission(models.ChainPermissionManageMembers, auth.ChainUIDFromQuery("chain_uid")), controllers.ChainDeleteUnapproved)
+	v2.POST("/chain/poke", auth.AnyUser(), controllers.Poke)
+	v2.GET("/chain/near", auth.Guest().WithApiKeyScope(models.ApiKeyScopeChainsRead), controllers.ChainGetNear)
+	v2.GET("/chain/search", auth.Guest().WithApiKeyScope(models.ApiKeyScopeChainsRead), controllers.ChainSearch)
+	v2.GET("/chain/geojson", auth.Guest().WithApiKeyScope(models.ApiKeyScopeChainsRead), controllers.ChainGetGeoJSON)
+	v2.GET("/chain/questions", auth.Guest(), controllers.ChainQuestionGetAll)
+	v2.PUT("/chain/questions", auth.ChainPermission(models.ChainPermissionManageLoop, auth.ChainUIDFromJSON("chain_uid")), controllers.ChainQuestionSetAll)
+	v2.GET("/chain/waitlist/all", auth.ChainPermission(models.ChainPermissionManageMembers, auth.ChainUIDFromQuery("chain_uid")), controllers.ChainWaitlistGetAll)
+	v2.POST("/chain/waitlist", auth.AnyUser().WithChain(auth.ChainUIDFromJSON("chain_uid")), controllers.ChainWaitlistJoin)
+	v2.DELETE("/chain/waitlist", auth.AnyUser().WithChain(auth.ChainUIDFromQuery("chain_uid")), controllers.ChainWaitlistLeave)
+	v2.POST("/chain/waitlist/order", auth.ChainPermission(models.ChainPermissionManageMembers, auth.ChainUIDFromJSON("chain_uid")), controllers.ChainWaitlistOrder)
+	v2.GET("/chain/invite", auth.Guest(), thr, controllers.ChainInviteGet)
+	v2.GET("/chain/invite/all", auth.ChainPermission(models.ChainPermissionManageMembers, auth.ChainUIDFromQuery("chain_uid")), controllers.ChainInviteGetAll)
+	v2.POST("/chain/invite", auth.ChainPermission(models.ChainPermissionManageMembers, auth.ChainUIDFromJSON("chain_uid")), controllers.ChainInviteCreate)
+	v2.DELETE("/chain/invite/:uid", auth.ChainPermission(models.ChainPermissionManageMembers, auth.ChainUIDFromQuery("chain_uid")), controllers.ChainInviteRevoke)
+	v2.POST("/chain/invite/accept", auth.AnyUser().WithoutImpersonation(), thr, controllers.ChainInviteAccept)
+	v2.GET("/chain/host-invitation/all", auth.ChainPermission(models.ChainPermissionManageLoop, auth.ChainUIDFromQuery("chain_uid")), controllers.ChainHostInvitationGetAll)
+	v2.POST("/chain/host-invitation", auth.ChainPermission(models.ChainPermissionManageLoop, auth.ChainUIDFromJSON("chain_uid")), controllers.ChainHostInvitationCreate)
+	v2.DELETE("/chain/host-invitation/:uid", auth.ChainPermission(models.ChainPermissionManageLoop, auth.ChainUIDFromQuery("chain_uid")), controllers.ChainHostInvitationRevoke)
 
 	// bag
-	v2.GET("/bag/all", controllers.BagGetAll)
-	v2.PUT("/bag", controllers.BagPut)
-	v2.DELETE("/bag", controllers.BagRemove)
+	v2.GET("/bag/all", auth.UserOfChain(auth.ChainUIDFromQuery("chain_uid")), controllers.BagGetAll)
+	v2.PUT("/bag", auth.UserOfChain(auth.ChainUIDFromJSON("chain_uid")), controllers.BagPut)
+	v2.DELETE("/bag", auth.ChainPermission(models.ChainPermissionManageBags, auth.ChainUIDFromQuery("chain_uid")), controllers.BagRemove)
 
 	// bulky item
-	v2.GET("/bulky-item/all", controllers.BulkyGetAll)
-	v2.PUT("/bulky-item", controllers.BulkyPut)
-	v2.DELETE("/bulky-item", controllers.BulkyRemove)
+	v2.GET("/bulky-item/all", auth.UserOfChain(auth.ChainUIDFromQuery("chain_uid")), controllers.BulkyGetAll)
+	v2.PUT("/bulky-item", auth.UserOfChain(auth.ChainUIDFromJSON("chain_uid")), controllers.BulkyPut)
+	v2.DELETE("/bulky-item", auth.UserOfChain(auth.ChainUIDFromQuery("chain_uid")), controllers.BulkyRemove)
 
 	// imgbb
-	v2.POST("/image", controllers.ImageUpload)
-	v2.DELETE("/image", controllers.ImageDelete)
+	v2.POST("/image", auth.AnyUser(), controllers.ImageUpload)
+	v2.DELETE("/image", auth.AnyUser(), controllers.ImageDelete)
 
 	// route
-	v2.GET("/route/order", controllers.RouteOrderGet)
-	v2.POST("/route/order", controllers.RouteOrderSet)
-	v2.GET("/route/optimize", controllers.RouteOptimize)
-	v2.GET("/route/coordinates", controllers.GetRouteCoordinates)
+	v2.GET("/route/order", auth.UserOfChain(auth.ChainUIDFromQuery("chain_uid")), controllers.RouteOrderGet)
+	v2.POST("/route/order", auth.ChainPermission(models.ChainPermissionManageRoute, auth.ChainUIDFromJSON("chain_uid")), controllers.RouteOrderSet)
+	v2.GET("/route/optimize", auth.ChainPermission(models.ChainPermissionManageRoute, auth.ChainUIDFromQuery("chain_uid")), controllers.RouteOptimize)
+	v2.GET("/route/coordinates", auth.ChainPermission(models.ChainPermissionManageRoute, auth.ChainUIDFromQuery("chain_uid")), controllers.GetRouteCoordinates)
 
 	// contact
-	v2.POST("/contact/newsletter", controllers.ContactNewsletter)
-	v2.POST("/contact/email", controllers.ContactMail)
+	v2.POST("/contact/newsletter", auth.Guest(), controllers.ContactNewsletter)
+	v2.POST("/contact/email", auth.Guest(), controllers.ContactMail)
 
 	// event
-	v2.GET("/event/:uid/ical", controllers.EventICal)
-	v2.GET("/event/:uid", controllers.EventGet)
-	v2.GET("/event/all", controllers.EventGetAll)
-	v2.GET("/event/previous", controllers.EventGetPrevious)
-	v2.POST("/event", controllers.EventCreate)
-	v2.PATCH("/event", controllers.EventUpdate)
-	v2.DELETE("/event/:uid", controllers.EventDelete)
-
-	return r
+	v2.GET("/event/:uid/ical", auth.Guest().WithApiKeyScope(models.ApiKeyScopeEventsRead), controllers.EventICal)
+	v2.GET("/event/:uid", auth.Guest().WithApiKeyScope(models.ApiKeyScopeEventsRead), controllers.EventGet)
+	v2.GET("/event/all", auth.Guest().WithApiKeyScope(models.ApiKeyScopeEventsRead), controllers.EventGetAll)
+	v2.GET("/event/previous", auth.Guest().WithApiKeyScope(models.ApiKeyScopeEventsRead), controllers.EventGetPrevious)
+	v2.POST("/event", auth.ChainPermission(models.ChainPermissionManageEvents, auth.ChainUIDFromJSON("chain_uid").Optional()).WithApiKeyScope(models.ApiKeyScopeEventsWrite), controllers.EventCreate)
+	v2.PATCH("/event", auth.AnyUser().WithApiKeyScope(models.ApiKeyScopeEventsWrite), controllers.EventUpdate)
+	v2.DELETE("/event/:uid", auth.AnyUser().WithApiKeyScope(models.ApiKeyScopeEventsWrite), controllers.EventDelete)
+
+	// api key
+	v2.GET("/api-key/all", auth.RootUser(), controllers.ApiKeyGetAll)
+	v2.POST("/api-key", auth.RootUser(), controllers.ApiKeyCreate)
+	v2.DELETE("/api-key/:uid", auth.RootUser(), controllers.ApiKeyRevoke)
+
+	// impersonate
+	v2.POST("/impersonate", auth.RootUser(), controllers.ImpersonateStart)
+	v2.DELETE("/impersonate", auth.AnyUser(), controllers.ImpersonateStop)
+	v2.GET("/impersonate/log", auth.RootUser(), controllers.ImpersonateLogGetAll)
 }
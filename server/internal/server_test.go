@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/the-clothing-loop/website/server/internal/app/auth"
+)
+
+var updatePermissionMatrix = flag.Bool("update", false, "update testdata/permission_matrix.txt")
+
+// Any change to the minimum role of a route must be reviewed,
+// run `go test ./internal -run TestPermissionMatrix -update` to regenerate the matrix.
+func TestPermissionMatrix(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	v2 := auth.NewRouterGroup(r.Group("/v2"))
+	RoutesV2(v2)
+
+	hasPolicy := map[string]bool{}
+	lines := []string{}
+	for _, rp := range v2.Policies() {
+		hasPolicy[rp.Method+" "+rp.Path] = true
+		lines = append(lines, fmt.Sprintf("%-6s %-28s %s", rp.Method, rp.Path, rp.Policy))
+	}
+
+	for _, route := range r.Routes() {
+		if !(hasPolicy[route.Method+" "+route.Path] || hasPolicy["ANY "+route.Path]) {
+			t.Errorf("Route %s %s is registered without a policy", route.Method, route.Path)
+		}
+	}
+
+	matrix := strings.Join(lines, "\n") + "\n"
+	golden := filepath.Join("testdata", "permission_matrix.txt")
+	if *updatePermissionMatrix {
+		err := os.WriteFile(golden, []byte(matrix), 0644)
+		require.NoError(t, err)
+	}
+
+	expected, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), matrix)
+}
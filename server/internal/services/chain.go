@@ -1,14 +1,23 @@
 package services
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
+	"github.com/OneSignal/onesignal-go-api"
+	"github.com/samber/lo"
+	uuid "github.com/satori/go.uuid"
+	"github.com/the-clothing-loop/website/server/internal/app"
 	"github.com/the-clothing-loop/website/server/internal/app/goscope"
 	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/internal/views"
 	"github.com/the-clothing-loop/website/server/pkg/httperror"
+	"gopkg.in/guregu/null.v3/zero"
 	"gorm.io/gorm"
 )
 
+// Soft deletes the loop so that it can be restored until it is purged by the daily cron job
 func ChainDelete(db *gorm.DB, chain *models.Chain) *httperror.HttpError {
 	users, err := chain.GetUserContactData(db)
 	if err != nil {
@@ -16,7 +25,7 @@ func ChainDelete(db *gorm.DB, chain *models.Chain) *httperror.HttpError {
 		return httperror.New(http.StatusInternalServerError, "Unable to notify loop members, please contact us.")
 	}
 
-	err = chain.Delete(db)
+	err = chain.SoftDelete(db)
 	if err != nil {
 		goscope.Log.Errorf("Error deleting loop: %v", chain.UID)
 		return httperror.New(http.StatusInternalServerError, "Unable to delete loop, please contact us.")
@@ -25,3 +34,603 @@ func ChainDelete(db *gorm.DB, chain *models.Chain) *httperror.HttpError {
 	emailLoopHasBeenDeleted(db, users, chain.Name)
 	return nil
 }
+
+// Invites a member, a registered user that is not part of the loop yet or an unregistered email address
+// to become host of the loop, user is nil for an unregistered email address.
+func ChainHostInvite(db *gorm.DB, chain *models.Chain, invitedBy, user *models.User, email string) (*models.ChainHostInvitation, *httperror.HttpError) {
+	userID := zero.Int{}
+	if user != nil {
+		isHost := false
+		db.Raw(`SELECT COUNT(*) > 0 FROM user_chains WHERE user_id = ? AND chain_id = ? AND is_chain_admin = TRUE`, user.ID, chain.ID).Scan(&isHost)
+		if isHost {
+			return nil, httperror.New(http.StatusConflict, "User is already a host of this loop")
+		}
+		userID = zero.IntFrom(int64(user.ID))
+		email = user.Email.String
+	}
+	if email == "" {
+		return nil, httperror.New(http.StatusBadRequest, "User has no email address to send the invitation to")
+	}
+
+	// an invitation without inviter is sent by the system
+	invitedByID, invitedByName := uint(0), ""
+	if invitedBy != nil {
+		invitedByID, invitedByName = invitedBy.ID, invitedBy.Name
+	}
+	inv, err := models.ChainHostInvitationCreate(db, chain.ID, invitedByID, userID, email)
+	if err != nil {
+		goscope.Log.Errorf("Unable to create host invitation: %v", err)
+		return nil, httperror.New(http.StatusInternalServerError, "Unable to create host invitation")
+	}
+	inv.ChainUID = chain.UID
+	inv.ChainName = chain.Name
+	inv.InvitedByName = invitedByName
+
+	name, lng := "", ""
+	if user != nil {
+		name, lng = user.Name, user.I18n
+		inv.UserUID = user.UID
+	}
+	err = views.EmailHostInvitation(db, lng, name, email, chain.Name, invitedByName, inv.UID)
+	if err != nil {
+		goscope.Log.Errorf("Unable to send host invitation email: %v", err)
+	}
+	if user != nil {
+		app.OneSignalCreateNotification(db, []string{user.UID}, *views.Notifications["hostInvitationTitle"], onesignal.StringMap{
+			En: onesignal.PtrString(chain.Name),
+		})
+	}
+
+	return inv, nil
+}
+
+// Promotes the user to host, the user is added to the loop if not yet a member.
+// Accepting an invitation also accepts the Terms of the Hosts.
+// Returns true if the user was not yet a member of the loop.
+func ChainHostInvitationAccept(db *gorm.DB, user *models.User, inv *models.ChainHostInvitation) (bool, *httperror.HttpError) {
+	isNewMember := false
+	oldRole := ""
+	err := db.Transaction(func(tx *gorm.DB) error {
+		userChain := &models.UserChain{}
+		tx.Raw(`SELECT * FROM user_chains WHERE user_id = ? AND chain_id = ? LIMIT 1`, user.ID, inv.ChainID).Scan(userChain)
+		oldRole = userChain.Role
+		if userChain.ID == 0 {
+			isNewMember = true
+			err := tx.Create(&models.UserChain{
+				UserID:       user.ID,
+				ChainID:      inv.ChainID,
+				IsChainAdmin: true,
+				IsApproved:   true,
+			}).Error
+			if err != nil {
+				return err
+			}
+		} else {
+			if err := models.UserChainSetRole(tx, userChain.ID, models.UserChainRoleHost); err != nil {
+				return err
+			}
+			if err := tx.Exec(`UPDATE user_chains SET is_approved = TRUE WHERE id = ?`, userChain.ID).Error; err != nil {
+				return err
+			}
+		}
+		// the loop has a host again
+		if err := (&models.Chain{ID: inv.ChainID}).ClearOrphaned(tx); err != nil {
+			return err
+		}
+		return inv.Delete(tx)
+	})
+	if err != nil {
+		goscope.Log.Errorf("Unable to accept host invitation: %v", err)
+		return false, httperror.New(http.StatusInternalServerError, "Unable to accept host invitation")
+	}
+
+	if err := models.ChainAuditLogCreate(db, inv.ChainID, user.ID, user.ID, models.ChainAuditActionRoleChange, map[string]models.ChainAuditChange{
+		"role": {Old: oldRole, New: models.UserChainRoleHost},
+	}); err != nil {
+		goscope.Log.Errorf("Unable to add to loop history: %v", err)
+	}
+
+	if err := user.AcceptLegal(db); err != nil {
+		goscope.Log.Errorf("Unable to set toh to true, during host invitation: %v", err)
+	}
+	return isNewMember, nil
+}
+
+// The successor of the last host must be another approved member of the loop
+func ChainGetSuccessor(db *gorm.DB, chain *models.Chain, leavingUserID uint, successorUID string) (*models.User, *httperror.HttpError) {
+	successor, err := models.UserGetByUID(db, successorUID, false)
+	if err != nil || successor.ID == leavingUserID {
+		return nil, httperror.New(http.StatusBadRequest, "Successor must be another member of the loop")
+	}
+	_, found, _ := models.UserChainCheckIfRelationExist(db, chain.ID, successor.ID, true)
+	if !found {
+		return nil, httperror.New(http.StatusBadRequest, "Successor must be another member of the loop")
+	}
+	return successor, nil
+}
+
+// Is run after the last host left the loop, the successor is invited to become host.
+// Without a successor participants are invited to volunteer by the daily cron job.
+// Without a leaving user the invitation is sent by the system, used when the host deletes their account.
+func ChainOrphan(db *gorm.DB, chain *models.Chain, leavingUser, successor *models.User) *httperror.HttpError {
+	if err := chain.Orphan(db); err != nil {
+		goscope.Log.Errorf("Unable to set loop without host: %v", err)
+		return httperror.New(http.StatusInternalServerError, "Unable to set loop without host")
+	}
+	if successor != nil {
+		_, httperr := ChainHostInvite(db, chain, leavingUser, successor, "")
+		return httperr
+	}
+	return nil
+}
+
+// An approved member volunteers to become host of a loop without a host
+func ChainBecomeHost(db *gorm.DB, chain *models.Chain, user *models.User) *httperror.HttpError {
+	if !chain.OrphanedAt.Valid {
+		return httperror.New(http.StatusConflict, "This loop already has a host")
+	}
+	userChain := &models.UserChain{}
+	db.Raw(`SELECT * FROM user_chains WHERE user_id = ? AND chain_id = ? AND is_approved = TRUE LIMIT 1`, user.ID, chain.ID).Scan(userChain)
+	if userChain.ID == 0 {
+		return httperror.New(http.StatusUnauthorized, "Only members of the loop can become host")
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := models.UserChainSetRole(tx, userChain.ID, models.UserChainRoleHost); err != nil {
+			return err
+		}
+		return chain.ClearOrphaned(tx)
+	})
+	if err != nil {
+		goscope.Log.Errorf("Unable to become host: %v", err)
+		return httperror.New(http.StatusInternalServerError, "Unable to become host")
+	}
+
+	if err := models.ChainAuditLogCreate(db, chain.ID, user.ID, user.ID, models.ChainAuditActionRoleChange, map[string]models.ChainAuditChange{
+		"role": {Old: userChain.Role, New: models.UserChainRoleHost},
+	}); err != nil {
+		goscope.Log.Errorf("Unable to add to loop history: %v", err)
+	}
+
+	if err := user.AcceptLegal(db); err != nil {
+		goscope.Log.Errorf("Unable to set toh to true, while becoming host: %v", err)
+	}
+	return nil
+}
+
+// Emails the users at the top of the waitlist that the loop is open to new members again,
+// no more users are invited than there are free places. Users stay on the waitlist until they join a loop.
+func ChainWaitlistInvite(db *gorm.DB, chain *models.Chain) error {
+	waitlist, err := models.ChainWaitlistGetAllByChain(db, chain.ID)
+	if err != nil {
+		return err
+	}
+	if freeCapacity := chain.FreeCapacity(db); freeCapacity >= 0 && len(waitlist) > freeCapacity {
+		waitlist = waitlist[:freeCapacity]
+	}
+	if len(waitlist) == 0 {
+		return nil
+	}
+
+	userIDs := []uint{}
+	for _, w := range waitlist {
+		userIDs = append(userIDs, w.UserID)
+		if w.UserEmail == "" {
+			continue
+		}
+		err := views.EmailWaitlistLoopOpen(db, w.UserI18n, w.UserName, w.UserEmail, chain.Name, chain.UID)
+		if err != nil {
+			goscope.Log.Errorf("Unable to send waitlist email: %v", err)
+		}
+	}
+
+	return models.ChainWaitlistSetInvited(db, chain.ID, userIDs)
+}
+
+// Closes the loop once the member limit is reached and reopens it when members leave,
+// only loops that were closed because of the limit are reopened. Hosts are notified when this flips.
+func ChainUpdateCapacity(db *gorm.DB, chain *models.Chain) error {
+	isFull := chain.IsFull(db)
+	if chain.OpenToNewMembers && isFull {
+		err := db.Exec(`UPDATE chains SET open_to_new_members = FALSE, is_closed_at_capacity = TRUE WHERE id = ?`, chain.ID).Error
+		if err != nil {
+			return err
+		}
+		chain.OpenToNewMembers = false
+		chain.IsClosedAtCapacity = true
+
+		notifyChainHosts(db, chain, "loopClosedAtCapacityTitle")
+	} else if chain.IsClosedAtCapacity && !isFull && !chain.OrphanedAt.Valid {
+		err := db.Exec(`UPDATE chains SET open_to_new_members = TRUE, is_closed_at_capacity = FALSE WHERE id = ?`, chain.ID).Error
+		if err != nil {
+			return err
+		}
+		chain.OpenToNewMembers = true
+		chain.IsClosedAtCapacity = false
+
+		notifyChainHosts(db, chain, "loopReopenedBelowCapacityTitle")
+		return ChainWaitlistInvite(db, chain)
+	}
+	return nil
+}
+
+func ChainUpdateCapacityByIDs(db *gorm.DB, chainIDs ...uint) {
+	chains := []models.Chain{}
+	db.Raw(`SELECT * FROM chains WHERE id IN ?`, chainIDs).Scan(&chains)
+	for i := range chains {
+		if err := ChainUpdateCapacity(db, &chains[i]); err != nil {
+			goscope.Log.Errorf("Unable to update loop capacity: %v", err)
+		}
+	}
+}
+
+func notifyChainHosts(db *gorm.DB, chain *models.Chain, notificationKey string) {
+	userUIDs := []string{}
+	db.Raw(`
+SELECT u.uid FROM users AS u
+JOIN user_chains AS uc ON uc.user_id = u.id
+WHERE uc.chain_id = ? AND uc.is_chain_admin = TRUE
+	`, chain.ID).Scan(&userUIDs)
+	if len(userUIDs) == 0 {
+		return
+	}
+
+	err := app.OneSignalCreateNotification(db, userUIDs, *views.Notifications[notificationKey], onesignal.StringMap{
+		En: onesignal.PtrString(chain.Name),
+	})
+	if err != nil {
+		goscope.Log.Errorf("Unable to notify hosts: %v", err)
+	}
+}
+
+type ChainMergeResult struct {
+	SourceChainUID string `json:"source_chain_uid"`
+	TargetChainUID string `json:"target_chain_uid"`
+	// Members of only the source loop
+	MembersMoved int `json:"members_moved"`
+	// Members of both loops, they are merged into one membership
+	MembersDeduplicated int  `json:"members_deduplicated"`
+	BagsMoved           int  `json:"bags_moved"`
+	BulkyItemsMoved     int  `json:"bulky_items_moved"`
+	TotalMembers        int  `json:"total_members"`
+	DryRun              bool `json:"dry_run"`
+}
+
+// Merges the source loop into the target loop and emails all members,
+// with dryRun nothing is changed and only the result is reported.
+func ChainMerge(db *gorm.DB, source, target *models.Chain, authUser *models.User, dryRun bool) (*ChainMergeResult, *httperror.HttpError) {
+	if source.ID == target.ID {
+		return nil, httperror.New(http.StatusBadRequest, "A loop can not be merged with itself")
+	}
+
+	result := &ChainMergeResult{
+		SourceChainUID: source.UID,
+		TargetChainUID: target.UID,
+		DryRun:         dryRun,
+	}
+	db.Raw(`
+SELECT COUNT(*) FROM user_chains
+WHERE chain_id = ? AND user_id NOT IN (SELECT user_id FROM user_chains WHERE chain_id = ?)
+	`, source.ID, target.ID).Scan(&result.MembersMoved)
+	db.Raw(`
+SELECT COUNT(*) FROM user_chains
+WHERE chain_id = ? AND user_id IN (SELECT user_id FROM user_chains WHERE chain_id = ?)
+	`, source.ID, target.ID).Scan(&result.MembersDeduplicated)
+	db.Raw(`
+SELECT COUNT(*) FROM bags
+WHERE user_chain_id IN (SELECT id FROM user_chains WHERE chain_id = ?)
+	`, source.ID).Scan(&result.BagsMoved)
+	db.Raw(`
+SELECT COUNT(*) FROM bulky_items
+WHERE user_chain_id IN (SELECT id FROM user_chains WHERE chain_id = ?)
+	`, source.ID).Scan(&result.BulkyItemsMoved)
+	db.Raw(`SELECT COUNT(*) FROM user_chains WHERE chain_id = ?`, target.ID).Scan(&result.TotalMembers)
+	result.TotalMembers += result.MembersMoved
+
+	if dryRun {
+		return result, nil
+	}
+
+	sourceUserIDs := []uint{}
+	db.Raw(`SELECT user_id FROM user_chains WHERE chain_id = ?`, source.ID).Scan(&sourceUserIDs)
+	oldRoles, err := models.UserChainGetRolesByChain(db, target.ID)
+	if err != nil {
+		goscope.Log.Errorf("Unable to find roles: %v", err)
+		return nil, httperror.New(http.StatusInternalServerError, "Unable to merge loops")
+	}
+
+	if err := source.MergeInto(db, target); err != nil {
+		goscope.Log.Errorf("Unable to merge loops: %v", err)
+		return nil, httperror.New(http.StatusInternalServerError, "Unable to merge loops")
+	}
+
+	ChainAuditLogTransfer(db, authUser.ID, source, target, sourceUserIDs, oldRoles, false)
+
+	if err := ChainUpdateCapacity(db, target); err != nil {
+		goscope.Log.Errorf("Unable to update loop capacity: %v", err)
+	}
+
+	users, err := target.GetUserContactData(db)
+	if err != nil {
+		goscope.Log.Errorf("Unable to notify members of merged loop: %v", err)
+		return result, nil
+	}
+	for _, user := range users {
+		if !user.Email.Valid {
+			continue
+		}
+		views.EmailLoopMerged(db, user.I18n, user.Name, user.Email.String, source.Name, target.Name)
+	}
+
+	return result, nil
+}
+
+// Moves the given approved members to a new loop with the same settings,
+// the new loop is located at the center of the moved members.
+// At least one host must stay, a new loop without a host is orphaned.
+func ChainSplit(db *gorm.DB, chain *models.Chain, authUser *models.User, name string, userUIDs []string) (*models.Chain, *httperror.HttpError) {
+	users := []struct {
+		ID        uint
+		Latitude  float64
+		Longitude float64
+	}{}
+	db.Raw(`
+SELECT u.id, u.latitude, u.longitude FROM users AS u
+JOIN user_chains AS uc ON uc.user_id = u.id
+WHERE uc.chain_id = ? AND uc.is_approved = TRUE AND u.uid IN ?
+	`, chain.ID, userUIDs).Scan(&users)
+	if len(users) == 0 || len(users) != len(userUIDs) {
+		return nil, httperror.New(http.StatusBadRequest, "All users must be approved members of the loop")
+	}
+	if totals := chain.GetTotals(db); len(users) >= totals.TotalMembers {
+		return nil, httperror.New(http.StatusBadRequest, "At least one member must stay in the loop")
+	}
+
+	userIDs := []uint{}
+	latitude, longitude := 0.0, 0.0
+	for _, u := range users {
+		userIDs = append(userIDs, u.ID)
+		latitude += u.Latitude
+		longitude += u.Longitude
+	}
+
+	hosts := []uint{}
+	db.Raw(`SELECT user_id FROM user_chains WHERE chain_id = ? AND is_chain_admin = TRUE`, chain.ID).Scan(&hosts)
+	movedHosts := lo.Intersect(hosts, userIDs)
+	if len(movedHosts) == len(hosts) {
+		return nil, httperror.New(http.StatusBadRequest, "At least one host must stay in the loop")
+	}
+	// without a moved host the acting host also becomes host of the new loop,
+	// a root admin who is not a host of the loop is not added
+	hostUserID := uint(0)
+	if len(movedHosts) == 0 && lo.Contains(hosts, authUser.ID) {
+		hostUserID = authUser.ID
+	}
+
+	newChain := &models.Chain{
+		UID:                uuid.NewV4().String(),
+		Name:               name,
+		Description:        chain.Description,
+		Address:            chain.Address,
+		CountryCode:        chain.CountryCode,
+		Latitude:           latitude / float64(len(users)),
+		Longitude:          longitude / float64(len(users)),
+		Radius:             chain.Radius,
+		Published:          chain.Published,
+		OpenToNewMembers:   chain.OpenToNewMembers,
+		MaxMembers:         chain.MaxMembers,
+		IsClosedAtCapacity: chain.IsClosedAtCapacity,
+		RulesOverride:      chain.RulesOverride,
+		HeadersOverride:    chain.HeadersOverride,
+		Sizes:              chain.Sizes,
+		Genders:            chain.Genders,
+		Theme:              chain.Theme,
+		IsAppDisabled:      chain.IsAppDisabled,
+		RoutePrivacy:       chain.RoutePrivacy,
+	}
+	if err := chain.SplitInto(db, newChain, userIDs, hostUserID); err != nil {
+		goscope.Log.Errorf("Unable to split loop: %v", err)
+		return nil, httperror.New(http.StatusInternalServerError, "Unable to split loop")
+	}
+
+	ChainAuditLogTransfer(db, authUser.ID, chain, newChain, userIDs, map[uint]string{}, false)
+
+	if len(movedHosts) == 0 && hostUserID == 0 {
+		if httperr := ChainOrphan(db, newChain, authUser, nil); httperr != nil {
+			return nil, httperr
+		}
+	}
+
+	return newChain, nil
+}
+
+// Records the members moved between loops in the history of both loops, a copied member is only recorded in the destination.
+// The role changes in the destination are found by comparing to oldRoles, the roles before the move.
+func ChainAuditLogTransfer(db *gorm.DB, actorUserID uint, from, to *models.Chain, userIDs []uint, oldRoles map[uint]string, isCopy bool) {
+	newRoles, err := models.UserChainGetRolesByChain(db, to.ID)
+	if err != nil {
+		goscope.Log.Errorf("Unable to add to loop history: %v", err)
+		return
+	}
+
+	changes := map[string]models.ChainAuditChange{
+		"chain_uid": {Old: from.UID, New: to.UID},
+	}
+	for _, userID := range userIDs {
+		chainIDs := []uint{to.ID}
+		if !isCopy {
+			chainIDs = append(chainIDs, from.ID)
+		}
+		for _, chainID := range chainIDs {
+			if err := models.ChainAuditLogCreate(db, chainID, actorUserID, userID, models.ChainAuditActionUserTransfer, changes); err != nil {
+				goscope.Log.Errorf("Unable to add to loop history: %v", err)
+			}
+		}
+	}
+	for userID, role := range newRoles {
+		if oldRoles[userID] == role {
+			continue
+		}
+		if err := models.ChainAuditLogCreate(db, to.ID, actorUserID, userID, models.ChainAuditActionRoleChange, map[string]models.ChainAuditChange{
+			"role": {Old: oldRoles[userID], New: role},
+		}); err != nil {
+			goscope.Log.Errorf("Unable to add to loop history: %v", err)
+		}
+	}
+}
+
+// Emails the members of both loops which loop they are part of after the split
+func ChainSplitNotify(db *gorm.DB, chain, newChain *models.Chain) {
+	for _, c := range []*models.Chain{chain, newChain} {
+		users, err := c.GetUserContactData(db)
+		if err != nil {
+			goscope.Log.Errorf("Unable to notify members of split loop: %v", err)
+			continue
+		}
+		for _, user := range users {
+			if !user.Email.Valid {
+				continue
+			}
+			views.EmailLoopSplit(db, user.I18n, user.Name, user.Email.String, chain.Name, newChain.Name, c.Name)
+		}
+	}
+}
+
+// Moves the loop to the next state of its lifecycle and emails the hosts about it,
+// archived loops are soft deleted.
+func ChainLifecycleTransition(db *gorm.DB, l *models.ChainLifecycle, state string) error {
+	if err := models.ChainSetLifecycleState(db, l.ChainID, state); err != nil {
+		return err
+	}
+
+	chain := &models.Chain{}
+	if err := db.Raw(`SELECT * FROM chains WHERE id = ? LIMIT 1`, l.ChainID).Scan(chain).Error; err != nil {
+		return err
+	}
+	if state == models.ChainLifecycleArchived {
+		if httperr := ChainDelete(db, chain); httperr != nil {
+			return httperr
+		}
+		return nil
+	}
+
+	// the warning only names the participant that is waiting too long
+	participantName := ""
+	if l.IsParticipantWaitingTooLong(time.Now()) {
+		participantName = l.OldestPendingName
+	}
+	hosts, err := models.UserGetAdminsByChain(db, chain.ID)
+	if err != nil {
+		return err
+	}
+	for _, host := range hosts {
+		if !host.Email.Valid {
+			continue
+		}
+		switch state {
+		case models.ChainLifecycleInactiveWarning:
+			views.EmailIsYourLoopStillActive(db, host.I18n, host.Name, host.Email.String, chain.Name, chain.UID, participantName)
+		case models.ChainLifecycleAbandoned:
+			views.EmailYourLoopDeletedNextMonth(db, host.I18n, host.Name, host.Email.String, chain.Name, chain.UID)
+		}
+	}
+	return nil
+}
+
+// Reminds the hosts of an abandoned loop a week before it is archived
+func ChainLifecycleRemind(db *gorm.DB, l *models.ChainLifecycle) error {
+	if err := models.ChainSetLifecycleReminded(db, l.ChainID); err != nil {
+		return err
+	}
+
+	hosts, err := models.UserGetAdminsByChain(db, l.ChainID)
+	if err != nil {
+		return err
+	}
+	chainUID := ""
+	db.Raw(`SELECT uid FROM chains WHERE id = ? LIMIT 1`, l.ChainID).Scan(&chainUID)
+	for _, host := range hosts {
+		if !host.Email.Valid {
+			continue
+		}
+		views.EmailYourLoopDeletedNextWeek(db, host.I18n, host.Name, host.Email.String, host.ChainName, chainUID)
+	}
+	return nil
+}
+
+// Adds the user to the loop of the invite, also when the loop is closed to new members.
+// Returns true if the user is approved by the invite.
+func ChainInviteJoin(db *gorm.DB, inv *models.ChainInvite, user *models.User, answers []models.ChainQuestionAnswerRequest) (bool, *httperror.HttpError) {
+	chain := &models.Chain{}
+	db.Raw(`SELECT * FROM chains WHERE id = ? AND deleted_at IS NULL LIMIT 1`, inv.ChainID).Scan(chain)
+	if chain.ID == 0 {
+		return false, httperror.New(http.StatusNotFound, models.ErrChainNotFound.Error())
+	}
+	if _, found, _ := models.UserChainCheckIfRelationExist(db, chain.ID, user.ID, false); found {
+		return false, httperror.New(http.StatusConflict, "User is already part of this loop")
+	}
+	if chain.IsFull(db) {
+		return false, httperror.New(http.StatusConflict, "Loop has reached its member limit")
+	}
+
+	questions, err := models.ChainQuestionGetAllByChain(db, chain.ID)
+	if err != nil {
+		goscope.Log.Errorf("Unable to retrieve questions: %v", err)
+		return false, httperror.New(http.StatusInternalServerError, "Unable to retrieve questions")
+	}
+	if err := models.ChainQuestionValidateAnswers(questions, answers, true); err != nil {
+		return false, httperror.New(http.StatusBadRequest, err.Error())
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := inv.Use(tx); err != nil {
+			return err
+		}
+		// approved members are added to the end of the route
+		routeOrder := 0
+		if inv.IsAutoApproved {
+			tx.Raw(`SELECT COALESCE(MAX(route_order), 0) + 1 FROM user_chains WHERE chain_id = ?`, chain.ID).Scan(&routeOrder)
+		}
+		err := tx.Create(&models.UserChain{
+			UserID:     user.ID,
+			ChainID:    chain.ID,
+			IsApproved: inv.IsAutoApproved,
+			RouteOrder: routeOrder,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return models.ChainQuestionAnswerSetAll(tx, chain.ID, user.ID, questions, answers)
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrChainInviteNotFound) {
+			return false, httperror.New(http.StatusGone, err.Error())
+		}
+		goscope.Log.Errorf("Unable to join loop by invite: %v", err)
+		return false, httperror.New(http.StatusInternalServerError, "Unable to join loop by invite")
+	}
+
+	if inv.IsAutoApproved {
+		if err := models.ChainAuditLogCreate(db, chain.ID, inv.CreatedByUserID, user.ID, models.ChainAuditActionUserApprove, map[string]models.ChainAuditChange{
+			"invite": {New: inv.Code},
+		}); err != nil {
+			goscope.Log.Errorf("Unable to add to loop history: %v", err)
+		}
+	}
+	if err := ChainUpdateCapacity(db, chain); err != nil {
+		goscope.Log.Errorf("Unable to update loop capacity: %v", err)
+	}
+
+	// unverified users are emailed on their first login, see LoginValidate
+	if user.IsEmailVerified && user.Email.Valid {
+		if inv.IsAutoApproved {
+			views.EmailAnAdminApprovedYourJoinRequest(db, user.I18n, user.Name, user.Email.String, chain.Name)
+		} else {
+			if err := EmailLoopAdminsOnUserJoin(db, user, chain.ID); err != nil {
+				goscope.Log.Errorf("Unable to send email to associated loop admins: %v", err)
+			}
+			EmailYouSignedUpForLoop(db, user, chain.Name)
+		}
+	}
+	return inv.IsAutoApproved, nil
+}
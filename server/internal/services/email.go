@@ -26,6 +26,15 @@ func EmailLoopAdminsOnUserJoin(db *gorm.DB, user *models.User, chainIDs ...uint)
 		return fmt.Errorf("No admins exist for this loop")
 	}
 
+	answers := map[uint][]models.ChainQuestionAnswer{}
+	for _, chainID := range chainIDs {
+		answersByUser, err := models.ChainQuestionAnswerGetAllByUsers(db, chainID, user.ID)
+		if err != nil {
+			return err
+		}
+		answers[chainID] = answersByUser[user.ID]
+	}
+
 	for _, result := range results {
 		if !result.Email.Valid {
 			continue
@@ -39,6 +48,7 @@ func EmailLoopAdminsOnUserJoin(db *gorm.DB, user *models.User, chainIDs ...uint)
 			user.PhoneNumber,
 			user.Address,
 			user.Sizes,
+			answers[result.ChainID],
 		)
 	}
 
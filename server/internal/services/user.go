@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/the-clothing-loop/website/server/internal/app"
+	"github.com/the-clothing-loop/website/server/internal/app/goscope"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/pkg/httperror"
+	"gorm.io/gorm"
+)
+
+// Swaps the email of the user once the new address is confirmed,
+// the newsletter subscription and Brevo contact move along with it.
+func UserChangeEmail(ctx context.Context, db *gorm.DB, ec *models.UserEmailChange) (*models.User, *httperror.HttpError) {
+	user := &models.User{}
+	db.Raw(`SELECT * FROM users WHERE id = ? LIMIT 1`, ec.UserID).Scan(user)
+	if user.ID == 0 {
+		return nil, httperror.New(http.StatusNotFound, models.ErrUserNotFound.Error())
+	}
+
+	otherUserID, found, err := models.UserCheckEmail(db, ec.NewEmail)
+	if err != nil {
+		goscope.Log.Errorf("Unable to check email: %v", err)
+		return nil, httperror.New(http.StatusInternalServerError, "Unable to check email")
+	}
+	if found && otherUserID != user.ID {
+		return nil, httperror.New(http.StatusConflict, "Email is already in use")
+	}
+
+	oldEmail := user.Email.String
+	hasNewsletter := false
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`UPDATE users SET email = ?, is_email_verified = TRUE WHERE id = ?`, ec.NewEmail, user.ID).Error
+		if err != nil {
+			return err
+		}
+
+		count := 0
+		tx.Raw(`SELECT COUNT(*) FROM newsletters WHERE email = ?`, oldEmail).Scan(&count)
+		hasNewsletter = count > 0 && oldEmail != ""
+		if hasNewsletter {
+			if err := tx.Exec(`DELETE FROM newsletters WHERE email = ?`, ec.NewEmail).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec(`UPDATE newsletters SET email = ? WHERE email = ?`, ec.NewEmail, oldEmail).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Exec(`DELETE FROM user_email_changes WHERE user_id = ?`, user.ID).Error
+	})
+	if err != nil {
+		goscope.Log.Errorf("Unable to change email: %v", err)
+		return nil, httperror.New(http.StatusInternalServerError, "Unable to change email")
+	}
+
+	if hasNewsletter && app.Brevo != nil {
+		app.Brevo.DeleteContact(ctx, oldEmail)
+		app.Brevo.CreateContact(ctx, ec.NewEmail)
+	}
+
+	user.Email.SetValid(ec.NewEmail)
+	user.IsEmailVerified = true
+	return user, nil
+}
+
+// Without the accepted legal terms the user has no role in any loop,
+// the loops the user was the last host of are kept without a host.
+func UserDeclineLegal(db *gorm.DB, user *models.User) *httperror.HttpError {
+	lastHostChainIDs, err := user.GetLastHostChainIDs(db)
+	if err != nil {
+		goscope.Log.Errorf("Unable to find hosted loops: %v", err)
+		return httperror.New(http.StatusInternalServerError, "Unable to find hosted loops")
+	}
+
+	userChains := []models.UserChain{}
+	db.Raw(`SELECT * FROM user_chains WHERE user_id = ? AND role != ''`, user.ID).Scan(&userChains)
+
+	err = db.Exec(`UPDATE user_chains SET is_chain_admin = FALSE, role = '' WHERE user_id = ?`, user.ID).Error
+	if err != nil {
+		goscope.Log.Errorf("Unable to remove roles: %v", err)
+		return httperror.New(http.StatusInternalServerError, "Unable to remove roles")
+	}
+
+	for _, uc := range userChains {
+		if err := models.ChainAuditLogCreate(db, uc.ChainID, user.ID, user.ID, models.ChainAuditActionRoleChange, map[string]models.ChainAuditChange{
+			"role": {Old: uc.Role, New: ""},
+		}); err != nil {
+			goscope.Log.Errorf("Unable to add to loop history: %v", err)
+		}
+	}
+
+	if len(lastHostChainIDs) == 0 {
+		return nil
+	}
+	chains := []models.Chain{}
+	db.Raw(`SELECT * FROM chains WHERE id IN ?`, lastHostChainIDs).Scan(&chains)
+	for i := range chains {
+		if httperr := ChainOrphan(db, &chains[i], user, nil); httperr != nil {
+			return httperr
+		}
+	}
+	return nil
+}
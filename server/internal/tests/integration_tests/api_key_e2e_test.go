@@ -0,0 +1,127 @@
+//go:build !ci
+
+package integration_tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
+)
+
+func TestApiKey(t *testing.T) {
+	chain, rootUser, rootToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsRootAdmin: true,
+	})
+	otherChain, otherUser, _ := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+	otherEvent := mocks.MockEvent(t, db, otherUser.ID, otherChain.ID)
+
+	createKey := func(t *testing.T, body gin.H) mocks.MockGinContextResponse {
+		c, resultFunc := mocks.MockGinContext(db, http.MethodPost, "/v2/api-key", &body, rootToken)
+		router.HandleContext(c)
+		return resultFunc()
+	}
+	request := func(key, method, url string, body *gin.H) mocks.MockGinContextResponse {
+		c, resultFunc := mocks.MockGinContext(db, method, url, body, "")
+		c.Request.Header.Set("Authorization", "ApiKey "+key)
+		router.HandleContext(c)
+		return resultFunc()
+	}
+
+	t.Run("Create requires a chain for chain scopes", func(t *testing.T) {
+		result := createKey(t, gin.H{
+			"name":   "Partner",
+			"scopes": []string{models.ApiKeyScopeEventsWrite},
+		})
+		assert.Equal(t, http.StatusBadRequest, result.Response.StatusCode, result.Body)
+
+		result = createKey(t, gin.H{
+			"name":   "Partner",
+			"scopes": []string{"users:write"},
+		})
+		assert.Equal(t, http.StatusBadRequest, result.Response.StatusCode, result.Body)
+	})
+
+	result := createKey(t, gin.H{
+		"name":      "Partner",
+		"scopes":    []string{models.ApiKeyScopeEventsRead, models.ApiKeyScopeEventsWrite},
+		"chain_uid": chain.UID,
+	})
+	require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+	key := result.BodyJSON()["key"].(string)
+	keyUID := result.BodyJSON()["api_key"].(map[string]any)["uid"].(string)
+	assert.NotContains(t, result.BodyJSON()["api_key"], "key_hash")
+
+	var eventUID string
+	t.Run("Create event of own chain", func(t *testing.T) {
+		result := request(key, http.MethodPost, "/v2/event", &gin.H{
+			"name":      "Partner event",
+			"latitude":  52.37,
+			"longitude": 4.89,
+			"address":   "Amsterdam",
+			"date":      time.Now().Add(48 * time.Hour),
+			"genders":   []string{},
+			"chain_uid": chain.UID,
+			"image_url": "https://example.com/image.jpg",
+		})
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		events := []models.Event{}
+		db.Raw(`SELECT * FROM events WHERE chain_id = ? AND user_id = ?`, chain.ID, rootUser.ID).Scan(&events)
+		require.Len(t, events, 1)
+		eventUID = events[0].UID
+	})
+
+	t.Run("Refuse event of other chain", func(t *testing.T) {
+		result := request(key, http.MethodPost, "/v2/event", &gin.H{
+			"name":      "Partner event",
+			"latitude":  52.37,
+			"longitude": 4.89,
+			"address":   "Amsterdam",
+			"date":      time.Now().Add(48 * time.Hour),
+			"genders":   []string{},
+			"chain_uid": otherChain.UID,
+			"image_url": "https://example.com/image.jpg",
+		})
+		assert.Equal(t, http.StatusForbidden, result.Response.StatusCode, result.Body)
+
+		result = request(key, http.MethodDelete, "/v2/event/"+otherEvent.UID, nil)
+		assert.Equal(t, http.StatusUnauthorized, result.Response.StatusCode, result.Body)
+	})
+
+	t.Run("Refuse routes without scope", func(t *testing.T) {
+		result := request(key, http.MethodGet, "/v2/user?user_uid="+rootUser.UID, nil)
+		assert.Equal(t, http.StatusForbidden, result.Response.StatusCode, result.Body)
+
+		result = request(key, http.MethodGet, "/v2/chain?chain_uid="+chain.UID, nil)
+		assert.Equal(t, http.StatusForbidden, result.Response.StatusCode, result.Body)
+
+		// root admin privileges are not given to api keys
+		result = request(key, http.MethodGet, "/v2/api-key/all", nil)
+		assert.Equal(t, http.StatusForbidden, result.Response.StatusCode, result.Body)
+	})
+
+	t.Run("Read and delete event", func(t *testing.T) {
+		result := request(key, http.MethodGet, "/v2/event/"+eventUID, nil)
+		assert.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		result = request(key, http.MethodDelete, "/v2/event/"+eventUID, nil)
+		assert.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		c, resultFunc := mocks.MockGinContext(db, http.MethodDelete, fmt.Sprintf("/v2/api-key/%s", keyUID), nil, rootToken)
+		router.HandleContext(c)
+		result := resultFunc()
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		result = request(key, http.MethodGet, "/v2/event/all", nil)
+		assert.Equal(t, http.StatusUnauthorized, result.Response.StatusCode, result.Body)
+	})
+}
@@ -0,0 +1,46 @@
+//go:build !ci
+
+package integration_tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
+)
+
+func TestBulkyPutOtherChain(t *testing.T) {
+	chain, owner, _ := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+	otherChain, other, otherToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+
+	ucID := uint(0)
+	db.Raw(`SELECT id FROM user_chains WHERE chain_id = ? AND user_id = ?`, chain.ID, owner.ID).Scan(&ucID)
+	bulkyItem := &models.BulkyItem{
+		Title:       "Sofa",
+		UserChainID: ucID,
+	}
+	require.NoError(t, db.Create(bulkyItem).Error)
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM bulky_items WHERE id = ?`, bulkyItem.ID)
+	})
+
+	title := "Taken over"
+	c, resultFunc := mocks.MockGinContext(db, http.MethodPut, "/v2/bulky-item", &gin.H{
+		"id":        bulkyItem.ID,
+		"user_uid":  other.UID,
+		"chain_uid": otherChain.UID,
+		"title":     title,
+	}, otherToken)
+	router.HandleContext(c)
+	result := resultFunc()
+	assert.Equal(t, http.StatusNotFound, result.Response.StatusCode, result.Body)
+
+	updated := &models.BulkyItem{}
+	db.Raw(`SELECT * FROM bulky_items WHERE id = ?`, bulkyItem.ID).Scan(updated)
+	assert.Equal(t, "Sofa", updated.Title)
+	assert.Equal(t, ucID, updated.UserChainID)
+}
@@ -9,7 +9,6 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/the-clothing-loop/website/server/internal/app/auth"
-	"github.com/the-clothing-loop/website/server/internal/controllers"
 	"github.com/the-clothing-loop/website/server/internal/models"
 	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
 )
@@ -33,14 +32,14 @@ func TestAddUser(t *testing.T) {
 	}, token)
 
 	// run sut
-	controllers.ChainAddUser(c)
+	router.HandleContext(c)
 
 	// retrieve result
 	result := resultFunc()
 
 	// test
 	// assert.FailNowf(t, "testing", "status: %d, body: ~%s~, token: %s, header: %s", result.Response.StatusCode, result.Body, token, c.Request.Header.Get("Authorization"))
-	tokenReq, _ := auth.TokenReadFromRequest(c)
+	tokenReq, _, _ := auth.TokenReadFromRequest(c)
 	assert.Equal(t, token, tokenReq)
 	assert.Equalf(t, 200, result.Response.StatusCode, "body: %s auth header: %v", result.Body, c.Request.Header.Get("Authorization"))
 
@@ -0,0 +1,66 @@
+//go:build !ci
+
+package integration_tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
+)
+
+func TestChainAuditLog(t *testing.T) {
+	chain, host, hostToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsChainAdmin: true,
+	})
+	participant, participantToken := mocks.MockUser(t, db, chain.ID, mocks.MockChainAndUserOptions{
+		IsNotApproved: true,
+	})
+
+	request := func(method, url string, body *gin.H, token string) mocks.MockGinContextResponse {
+		c, resultFunc := mocks.MockGinContext(db, method, url, body, token)
+		router.HandleContext(c)
+		return resultFunc()
+	}
+
+	result := request(http.MethodPatch, "/v2/chain", &gin.H{
+		"uid":  chain.UID,
+		"name": "Renamed loop",
+	}, hostToken)
+	require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+	result = request(http.MethodPatch, "/v2/chain/approve-user", &gin.H{
+		"chain_uid": chain.UID,
+		"user_uid":  participant.UID,
+	}, hostToken)
+	require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+	t.Run("Only hosts can read the history", func(t *testing.T) {
+		result := request(http.MethodGet, fmt.Sprintf("/v2/chain/audit-log?chain_uid=%s", chain.UID), nil, participantToken)
+		assert.Equal(t, http.StatusUnauthorized, result.Response.StatusCode, result.Body)
+	})
+
+	t.Run("History", func(t *testing.T) {
+		result := request(http.MethodGet, fmt.Sprintf("/v2/chain/audit-log?chain_uid=%s", chain.UID), nil, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		logs := []models.ChainAuditLog{}
+		require.NoError(t, json.Unmarshal([]byte(result.Body), &logs))
+		require.Len(t, logs, 2)
+
+		assert.Equal(t, models.ChainAuditActionUserApprove, logs[0].Action)
+		assert.Equal(t, host.UID, logs[0].ActorUserUID)
+		assert.Equal(t, participant.UID, logs[0].TargetUserUID)
+
+		assert.Equal(t, models.ChainAuditActionUpdate, logs[1].Action)
+		require.Len(t, logs[1].Changes, 1)
+		assert.Equal(t, chain.Name, logs[1].Changes["name"].Old)
+		assert.Equal(t, "Renamed loop", logs[1].Changes["name"].New)
+	})
+}
@@ -0,0 +1,111 @@
+//go:build !ci
+
+package integration_tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
+)
+
+func TestChainCapacity(t *testing.T) {
+	chain, _, hostToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsChainAdmin:       true,
+		IsOpenToNewMembers: true,
+	})
+	participant, _ := mocks.MockUser(t, db, chain.ID, mocks.MockChainAndUserOptions{})
+	unapproved, _ := mocks.MockUser(t, db, chain.ID, mocks.MockChainAndUserOptions{
+		IsNotApproved: true,
+	})
+	_, userA, tokenA := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+	_, userB, tokenB := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+
+	request := func(method, url string, body *gin.H, token string) mocks.MockGinContextResponse {
+		c, resultFunc := mocks.MockGinContext(db, method, url, body, token)
+		router.HandleContext(c)
+		return resultFunc()
+	}
+	getChain := func() *models.Chain {
+		result := &models.Chain{}
+		db.Raw(`SELECT * FROM chains WHERE id = ? LIMIT 1`, chain.ID).Scan(result)
+		return result
+	}
+
+	t.Run("Reaching the member limit closes the loop", func(t *testing.T) {
+		result := request(http.MethodPatch, "/v2/chain", &gin.H{
+			"uid":         chain.UID,
+			"max_members": 2,
+		}, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		c := getChain()
+		assert.EqualValues(t, 2, c.MaxMembers.Int64)
+		assert.False(t, c.OpenToNewMembers)
+		assert.True(t, c.IsClosedAtCapacity)
+	})
+
+	t.Run("Approving is not possible in a full loop", func(t *testing.T) {
+		result := request(http.MethodPatch, "/v2/chain/approve-user", &gin.H{
+			"chain_uid": chain.UID,
+			"user_uid":  unapproved.UID,
+		}, hostToken)
+		assert.Equal(t, http.StatusConflict, result.Response.StatusCode, result.Body)
+	})
+
+	t.Run("Removing a member reopens the loop", func(t *testing.T) {
+		for _, token := range []string{tokenA, tokenB} {
+			result := request(http.MethodPost, "/v2/chain/waitlist", &gin.H{
+				"chain_uid": chain.UID,
+			}, token)
+			require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+		}
+
+		result := request(http.MethodPost, "/v2/chain/remove-user", &gin.H{
+			"chain_uid": chain.UID,
+			"user_uid":  participant.UID,
+		}, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		c := getChain()
+		assert.True(t, c.OpenToNewMembers)
+		assert.False(t, c.IsClosedAtCapacity)
+
+		// only one place is free
+		waitlist, err := models.ChainWaitlistGetAllByChain(db, chain.ID)
+		require.NoError(t, err)
+		require.Len(t, waitlist, 2)
+		assert.Equal(t, userA.UID, waitlist[0].UserUID)
+		assert.True(t, waitlist[0].InvitedAt.Valid)
+		assert.Equal(t, userB.UID, waitlist[1].UserUID)
+		assert.False(t, waitlist[1].InvitedAt.Valid)
+	})
+
+	t.Run("Approving the last member closes the loop", func(t *testing.T) {
+		result := request(http.MethodPatch, "/v2/chain/approve-user", &gin.H{
+			"chain_uid": chain.UID,
+			"user_uid":  unapproved.UID,
+		}, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		c := getChain()
+		assert.False(t, c.OpenToNewMembers)
+		assert.True(t, c.IsClosedAtCapacity)
+	})
+
+	t.Run("Removing the member limit reopens the loop", func(t *testing.T) {
+		result := request(http.MethodPatch, "/v2/chain", &gin.H{
+			"uid":         chain.UID,
+			"max_members": 0,
+		}, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		c := getChain()
+		assert.False(t, c.MaxMembers.Valid)
+		assert.True(t, c.OpenToNewMembers)
+	})
+}
@@ -7,7 +7,6 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
-	"github.com/the-clothing-loop/website/server/internal/controllers"
 	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
 )
 
@@ -25,10 +24,10 @@ func TestChainDeleteWithTwoHosts(t *testing.T) {
 
 	// create gin.Context mock
 	url := "/v2/chain?chain_uid=" + chain.UID
-	c, resultFunc := mocks.MockGinContext(db, http.MethodPost, url, nil, token)
+	c, resultFunc := mocks.MockGinContext(db, http.MethodDelete, url, nil, token)
 
 	// run sut
-	controllers.ChainDelete(c)
+	router.HandleContext(c)
 
 	// retrieve result
 	result := resultFunc()
@@ -59,10 +58,10 @@ func TestChainDelete(t *testing.T) {
 
 	// create gin.Context mock
 	url := "/v2/chain?chain_uid=" + chain.UID
-	c, resultFunc := mocks.MockGinContext(db, http.MethodPost, url, nil, token)
+	c, resultFunc := mocks.MockGinContext(db, http.MethodDelete, url, nil, token)
 
 	// run sut
-	controllers.ChainDelete(c)
+	router.HandleContext(c)
 
 	// retrieve result
 	result := resultFunc()
@@ -70,11 +69,12 @@ func TestChainDelete(t *testing.T) {
 	// test
 	assert.Equal(t, http.StatusOK, result.Response.StatusCode)
 
+	// memberships are kept so that the loop can be restored
 	count := -1
 	db.Raw(`SELECT COUNT(id) FROM user_chains WHERE chain_id = ? AND user_id IN ?`, chain.ID, []uint{host.ID, participant.ID}).Scan(&count)
-	assert.Equal(t, 0, count)
+	assert.Equal(t, 1, count)
 
 	count = -1
-	db.Raw(`SELECT COUNT(id) FROM chains WHERE id = ?`, chain.ID).Scan(&count)
-	assert.Equal(t, 0, count)
+	db.Raw(`SELECT COUNT(id) FROM chains WHERE id = ? AND deleted_at IS NOT NULL`, chain.ID).Scan(&count)
+	assert.Equal(t, 1, count)
 }
@@ -0,0 +1,91 @@
+//go:build !ci
+
+package integration_tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
+)
+
+func TestChainGetGeoJSON(t *testing.T) {
+	// the bounding box is in the middle of the ocean to only find the loops of this test
+	bbox := "-150.5,-60.5,-149.5,-59.5"
+	chain1, _, _ := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+	chain2, _, _ := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+	db.Exec(`UPDATE chains SET latitude = -60.01, longitude = -150.01 WHERE id = ?`, chain1.ID)
+	db.Exec(`UPDATE chains SET latitude = -60.03, longitude = -150.03 WHERE id = ?`, chain2.ID)
+	db.Exec(`UPDATE chains SET sizes = '["1","3"]' WHERE id = ?`, chain1.ID)
+	db.Exec(`UPDATE chains SET sizes = '["3"]' WHERE id = ?`, chain2.ID)
+
+	type featureCollection struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Geometry struct {
+				Coordinates [2]float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Properties map[string]any `json:"properties"`
+		} `json:"features"`
+	}
+	get := func(url, etag string) (mocks.MockGinContextResponse, *featureCollection) {
+		c, resultFunc := mocks.MockGinContext(db, http.MethodGet, url, nil, "")
+		if etag != "" {
+			c.Request.Header.Set("If-None-Match", etag)
+		}
+		router.HandleContext(c)
+		result := resultFunc()
+
+		fc := &featureCollection{}
+		if result.Response.StatusCode == http.StatusOK {
+			require.NoError(t, json.Unmarshal([]byte(result.Body), fc))
+		}
+		return result, fc
+	}
+
+	t.Run("Clusters at low zoom", func(t *testing.T) {
+		result, fc := get("/v2/chain/geojson?zoom=3&bbox="+bbox, "")
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+		assert.Equal(t, "FeatureCollection", fc.Type)
+		require.Len(t, fc.Features, 1)
+		assert.Equal(t, true, fc.Features[0].Properties["cluster"])
+		assert.EqualValues(t, 2, fc.Features[0].Properties["point_count"])
+		assert.InDelta(t, -150.02, fc.Features[0].Geometry.Coordinates[0], 0.0001)
+		assert.InDelta(t, -60.02, fc.Features[0].Geometry.Coordinates[1], 0.0001)
+	})
+
+	t.Run("Loops at high zoom", func(t *testing.T) {
+		result, fc := get("/v2/chain/geojson?zoom=14&bbox="+bbox, "")
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+		require.Len(t, fc.Features, 2)
+		assert.Equal(t, chain1.UID, fc.Features[0].Properties["uid"])
+		assert.Equal(t, chain2.UID, fc.Features[1].Properties["uid"])
+	})
+
+	t.Run("Filter by sizes", func(t *testing.T) {
+		result, fc := get("/v2/chain/geojson?zoom=14&filter_sizes=1,3&bbox="+bbox, "")
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+		require.Len(t, fc.Features, 1)
+		assert.Equal(t, chain1.UID, fc.Features[0].Properties["uid"])
+
+		result, _ = get("/v2/chain/geojson?zoom=14&filter_sizes=x&bbox="+bbox, "")
+		assert.Equal(t, http.StatusBadRequest, result.Response.StatusCode)
+	})
+
+	t.Run("Not modified with a matching ETag", func(t *testing.T) {
+		result, _ := get("/v2/chain/geojson?zoom=14&bbox="+bbox, "")
+		etag := result.Response.Header.Get("ETag")
+		require.NotEmpty(t, etag)
+
+		result, _ = get("/v2/chain/geojson?zoom=14&bbox="+bbox, etag)
+		assert.Equal(t, http.StatusNotModified, result.Response.StatusCode)
+	})
+
+	t.Run("Invalid bounding box", func(t *testing.T) {
+		result, _ := get("/v2/chain/geojson?zoom=3&bbox=1,2,3", "")
+		assert.Equal(t, http.StatusBadRequest, result.Response.StatusCode)
+	})
+}
@@ -0,0 +1,122 @@
+//go:build !ci
+
+package integration_tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
+)
+
+func TestChainHostInvitation(t *testing.T) {
+	chain, _, hostToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsChainAdmin: true,
+	})
+	participant, participantToken := mocks.MockUser(t, db, chain.ID, mocks.MockChainAndUserOptions{})
+	_, outsider, outsiderToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+
+	request := func(method, url string, body *gin.H, token string) mocks.MockGinContextResponse {
+		c, resultFunc := mocks.MockGinContext(db, method, url, body, token)
+		router.HandleContext(c)
+		return resultFunc()
+	}
+	invite := func(body gin.H) *models.ChainHostInvitation {
+		body["chain_uid"] = chain.UID
+		result := request(http.MethodPost, "/v2/chain/host-invitation", &body, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+		inv := &models.ChainHostInvitation{}
+		require.NoError(t, json.Unmarshal([]byte(result.Body), inv))
+		return inv
+	}
+	isHost := func(userID uint) bool {
+		isChainAdmin := false
+		db.Raw(`SELECT is_chain_admin FROM user_chains WHERE user_id = ? AND chain_id = ?`, userID, chain.ID).Scan(&isChainAdmin)
+		return isChainAdmin
+	}
+
+	t.Run("Participant can not invite", func(t *testing.T) {
+		result := request(http.MethodPost, "/v2/chain/host-invitation", &gin.H{
+			"chain_uid": chain.UID,
+			"user_uid":  outsider.UID,
+		}, participantToken)
+		assert.Equal(t, http.StatusUnauthorized, result.Response.StatusCode, result.Body)
+	})
+
+	t.Run("Decline invitation", func(t *testing.T) {
+		inv := invite(gin.H{"user_uid": participant.UID})
+
+		result := request(http.MethodPost, fmt.Sprintf("/v2/user/host-invitations/%s/decline", inv.UID), nil, outsiderToken)
+		assert.Equal(t, http.StatusNotFound, result.Response.StatusCode, result.Body)
+
+		result = request(http.MethodPost, fmt.Sprintf("/v2/user/host-invitations/%s/decline", inv.UID), nil, participantToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		result = request(http.MethodPost, fmt.Sprintf("/v2/user/host-invitations/%s/accept", inv.UID), &gin.H{
+			"allow_toh": true,
+		}, participantToken)
+		assert.Equal(t, http.StatusNotFound, result.Response.StatusCode, result.Body)
+		assert.False(t, isHost(participant.ID))
+	})
+
+	t.Run("Accept requires the Terms of the Hosts", func(t *testing.T) {
+		inv := invite(gin.H{"user_uid": participant.UID})
+
+		result := request(http.MethodPost, fmt.Sprintf("/v2/user/host-invitations/%s/accept", inv.UID), &gin.H{
+			"allow_toh": false,
+		}, participantToken)
+		assert.Equal(t, http.StatusBadRequest, result.Response.StatusCode, result.Body)
+		assert.False(t, isHost(participant.ID))
+
+		result = request(http.MethodPost, fmt.Sprintf("/v2/user/host-invitations/%s/accept", inv.UID), &gin.H{
+			"allow_toh": true,
+		}, participantToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+		assert.True(t, isHost(participant.ID))
+
+		acceptedTOH := false
+		db.Raw(`SELECT accepted_toh FROM users WHERE id = ?`, participant.ID).Scan(&acceptedTOH)
+		assert.True(t, acceptedTOH)
+	})
+
+	t.Run("Invite an email address that is not registered", func(t *testing.T) {
+		email := "invited_" + faker.Person().Contact().Email
+		inv := invite(gin.H{"email": email})
+		assert.Empty(t, inv.UserUID)
+
+		result := request(http.MethodGet, fmt.Sprintf("/v2/chain/host-invitation/all?chain_uid=%s", chain.UID), nil, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+		assert.Contains(t, result.Body, inv.UID)
+
+		// the invitee registers with the invited email address
+		_, invitee, inviteeToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+		db.Exec(`UPDATE users SET email = ? WHERE id = ?`, email, invitee.ID)
+
+		result = request(http.MethodGet, "/v2/user/host-invitations", nil, inviteeToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+		assert.Contains(t, result.Body, inv.UID)
+
+		result = request(http.MethodPost, fmt.Sprintf("/v2/user/host-invitations/%s/accept", inv.UID), &gin.H{
+			"allow_toh": true,
+		}, inviteeToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+		assert.True(t, isHost(invitee.ID))
+	})
+
+	t.Run("Revoke invitation", func(t *testing.T) {
+		inv := invite(gin.H{"user_uid": outsider.UID})
+
+		result := request(http.MethodDelete, fmt.Sprintf("/v2/chain/host-invitation/%s?chain_uid=%s", inv.UID, chain.UID), nil, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		result = request(http.MethodGet, "/v2/user/host-invitations", nil, outsiderToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+		assert.NotContains(t, result.Body, inv.UID)
+	})
+}
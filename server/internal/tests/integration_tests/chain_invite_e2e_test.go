@@ -0,0 +1,118 @@
+//go:build !ci
+
+package integration_tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
+)
+
+func TestChainInvite(t *testing.T) {
+	chain, _, hostToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsChainAdmin:       true,
+		IsOpenToNewMembers: false,
+	})
+	_, user1, user1Token := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+	_, _, user2Token := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+
+	request := func(method, url string, body *gin.H, token string) mocks.MockGinContextResponse {
+		c, resultFunc := mocks.MockGinContext(db, method, url, body, token)
+		router.HandleContext(c)
+		return resultFunc()
+	}
+
+	inv := &models.ChainInvite{}
+	t.Run("Host creates an invite", func(t *testing.T) {
+		result := request(http.MethodPost, "/v2/chain/invite", &gin.H{
+			"chain_uid":        chain.UID,
+			"max_uses":         1,
+			"is_auto_approved": true,
+		}, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+		require.NoError(t, json.Unmarshal([]byte(result.Body), inv))
+		assert.Len(t, inv.Code, 8)
+
+		result = request(http.MethodGet, fmt.Sprintf("/v2/chain/invite/all?chain_uid=%s", chain.UID), nil, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+		invites := []models.ChainInvite{}
+		require.NoError(t, json.Unmarshal([]byte(result.Body), &invites))
+		require.Len(t, invites, 1)
+		assert.Equal(t, inv.UID, invites[0].UID)
+	})
+
+	t.Run("Join a closed loop by code", func(t *testing.T) {
+		result := request(http.MethodGet, fmt.Sprintf("/v2/chain/invite?invite=%s", strings.ToLower(inv.Code)), nil, "")
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		result = request(http.MethodPost, "/v2/chain/invite/accept", &gin.H{
+			"invite": inv.Code,
+		}, user1Token)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		isApproved := false
+		db.Raw(`SELECT is_approved FROM user_chains WHERE user_id = ? AND chain_id = ?`, user1.ID, chain.ID).Scan(&isApproved)
+		assert.True(t, isApproved)
+	})
+
+	t.Run("Invite is used up", func(t *testing.T) {
+		result := request(http.MethodPost, "/v2/chain/invite/accept", &gin.H{
+			"invite": inv.UID,
+		}, user2Token)
+		assert.Equal(t, http.StatusNotFound, result.Response.StatusCode, result.Body)
+	})
+
+	t.Run("Register by invite to a full loop", func(t *testing.T) {
+		result := request(http.MethodPost, "/v2/chain/invite", &gin.H{
+			"chain_uid": chain.UID,
+		}, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+		fullInv := &models.ChainInvite{}
+		require.NoError(t, json.Unmarshal([]byte(result.Body), fullInv))
+
+		db.Exec(`UPDATE chains SET max_members = (SELECT COUNT(*) FROM user_chains WHERE chain_id = ?) WHERE id = ?`, chain.ID, chain.ID)
+		defer db.Exec(`UPDATE chains SET max_members = NULL WHERE id = ?`, chain.ID)
+
+		email := fmt.Sprintf("%s@%s", faker.UUID().V4(), faker.Internet().FreeEmailDomain())
+		result = request(http.MethodPost, "/v2/register/basic-user", &gin.H{
+			"invite": fullInv.Code,
+			"user": gin.H{
+				"name":    "Test " + faker.Person().Name(),
+				"email":   email,
+				"address": faker.Address().Address(),
+				"sizes":   mocks.MockSizes(false),
+			},
+		}, "")
+		assert.Equal(t, http.StatusConflict, result.Response.StatusCode, result.Body)
+
+		// the user is not created without joining the loop
+		userID := 0
+		db.Raw(`SELECT id FROM users WHERE email = ? LIMIT 1`, email).Scan(&userID)
+		assert.Zero(t, userID)
+	})
+
+	t.Run("Host revokes an invite", func(t *testing.T) {
+		result := request(http.MethodPost, "/v2/chain/invite", &gin.H{
+			"chain_uid": chain.UID,
+		}, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+		revoked := &models.ChainInvite{}
+		require.NoError(t, json.Unmarshal([]byte(result.Body), revoked))
+
+		result = request(http.MethodDelete, fmt.Sprintf("/v2/chain/invite/%s?chain_uid=%s", revoked.UID, chain.UID), nil, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		result = request(http.MethodPost, "/v2/chain/invite/accept", &gin.H{
+			"invite": revoked.UID,
+		}, user2Token)
+		assert.Equal(t, http.StatusNotFound, result.Response.StatusCode, result.Body)
+	})
+}
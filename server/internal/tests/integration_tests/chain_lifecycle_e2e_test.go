@@ -0,0 +1,90 @@
+//go:build !ci
+
+package integration_tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/the-clothing-loop/website/server/internal/controllers"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
+)
+
+func TestChainConfirmStillActive(t *testing.T) {
+	chain, _, hostToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsChainAdmin: true,
+	})
+	require.NoError(t, models.ChainSetLifecycleState(db, chain.ID, models.ChainLifecycleAbandoned))
+
+	abandoned := &models.Chain{}
+	db.Raw(`SELECT * FROM chains WHERE id = ?`, chain.ID).Scan(abandoned)
+	assert.True(t, abandoned.AbandonedAt.Valid)
+
+	c, resultFunc := mocks.MockGinContext(db, http.MethodPost, "/v2/chain/still-active", &gin.H{
+		"chain_uid": chain.UID,
+	}, hostToken)
+	router.HandleContext(c)
+	result := resultFunc()
+	require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+	updated := &models.Chain{}
+	db.Raw(`SELECT * FROM chains WHERE id = ?`, chain.ID).Scan(updated)
+	assert.Equal(t, models.ChainLifecycleActive, updated.LifecycleState)
+	assert.True(t, updated.StillActiveAt.Valid)
+	assert.False(t, updated.AbandonedAt.Valid)
+}
+
+func TestChainLifecycleRestoresPublished(t *testing.T) {
+	chain, _, _ := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsChainAdmin:       true,
+		IsOpenToNewMembers: true,
+	})
+
+	require.NoError(t, models.ChainSetLifecycleState(db, chain.ID, models.ChainLifecycleInactiveWarning))
+	warned := &models.Chain{}
+	db.Raw(`SELECT * FROM chains WHERE id = ?`, chain.ID).Scan(warned)
+	assert.True(t, warned.Published)
+	assert.True(t, warned.OpenToNewMembers)
+
+	require.NoError(t, models.ChainSetLifecycleState(db, chain.ID, models.ChainLifecycleAbandoned))
+	abandoned := &models.Chain{}
+	db.Raw(`SELECT * FROM chains WHERE id = ?`, chain.ID).Scan(abandoned)
+	assert.False(t, abandoned.Published)
+	assert.False(t, abandoned.OpenToNewMembers)
+
+	require.NoError(t, models.ChainSetLifecycleState(db, chain.ID, models.ChainLifecycleActive))
+	active := &models.Chain{}
+	db.Raw(`SELECT * FROM chains WHERE id = ?`, chain.ID).Scan(active)
+	assert.True(t, active.Published)
+	assert.True(t, active.OpenToNewMembers)
+	assert.False(t, active.PublishedBeforeAbandoned.Valid)
+}
+
+func TestChainLifecycleAbandonedRecruitment(t *testing.T) {
+	chain, host, _ := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsChainAdmin: true,
+	})
+	participant, _ := mocks.MockUser(t, db, chain.ID, mocks.MockChainAndUserOptions{})
+	db.Exec(`UPDATE chains SET created_at = (NOW() - INTERVAL 200 DAY) WHERE id = ?`, chain.ID)
+	db.Exec(`UPDATE user_chains SET created_at = (NOW() - INTERVAL 200 DAY) WHERE chain_id = ?`, chain.ID)
+	db.Exec(`UPDATE users SET last_signed_in_at = (NOW() - INTERVAL 200 DAY) WHERE id IN ?`, []uint{host.ID, participant.ID})
+	require.NoError(t, models.ChainSetLifecycleState(db, chain.ID, models.ChainLifecycleAbandoned))
+
+	// participants are only asked to restart the loop a week after it is abandoned
+	controllers.CronDaily(db)
+	updated := &models.Chain{}
+	db.Raw(`SELECT * FROM chains WHERE id = ?`, chain.ID).Scan(updated)
+	assert.Equal(t, models.ChainLifecycleAbandoned, updated.LifecycleState)
+	assert.False(t, updated.AbandonedRecruitmentEmail.Valid)
+
+	db.Exec(`UPDATE chains SET abandoned_at = (NOW() - INTERVAL 8 DAY) WHERE id = ?`, chain.ID)
+	controllers.CronDaily(db)
+	updated = &models.Chain{}
+	db.Raw(`SELECT * FROM chains WHERE id = ?`, chain.ID).Scan(updated)
+	assert.Equal(t, models.ChainLifecycleAbandoned, updated.LifecycleState)
+	assert.True(t, updated.AbandonedRecruitmentEmail.Valid)
+}
@@ -0,0 +1,112 @@
+//go:build !ci
+
+package integration_tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/internal/services"
+	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
+)
+
+func TestChainMerge(t *testing.T) {
+	target, host, hostToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsChainAdmin:    true,
+		RouteOrderIndex: 1,
+	})
+	source, sourceHost, sourceHostToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsChainAdmin:    true,
+		RouteOrderIndex: 1,
+	})
+	participant, _ := mocks.MockUser(t, db, source.ID, mocks.MockChainAndUserOptions{
+		RouteOrderIndex: 2,
+	})
+	mocks.MockBag(t, db, source.ID, participant.ID, mocks.MockBagOptions{})
+
+	// the host of the target loop is also a member of the source loop
+	require.NoError(t, db.Create(&models.UserChain{
+		UserID:       host.ID,
+		ChainID:      source.ID,
+		IsChainAdmin: true,
+		Role:         models.UserChainRoleHost,
+		IsApproved:   true,
+		RouteOrder:   3,
+	}).Error)
+
+	request := func(body gin.H, token string) mocks.MockGinContextResponse {
+		c, resultFunc := mocks.MockGinContext(db, http.MethodPost, "/v2/chain/merge", &body, token)
+		router.HandleContext(c)
+		return resultFunc()
+	}
+
+	t.Run("Only hosts of both loops can merge", func(t *testing.T) {
+		result := request(gin.H{
+			"source_chain_uid": target.UID,
+			"target_chain_uid": source.UID,
+		}, sourceHostToken)
+		assert.Equal(t, http.StatusUnauthorized, result.Response.StatusCode, result.Body)
+	})
+
+	t.Run("Dry run", func(t *testing.T) {
+		result := request(gin.H{
+			"source_chain_uid": source.UID,
+			"target_chain_uid": target.UID,
+			"dry_run":          true,
+		}, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		res := &services.ChainMergeResult{}
+		require.NoError(t, json.Unmarshal([]byte(result.Body), res))
+		assert.Equal(t, 2, res.MembersMoved)
+		assert.Equal(t, 1, res.MembersDeduplicated)
+		assert.Equal(t, 1, res.BagsMoved)
+		assert.Equal(t, 3, res.TotalMembers)
+
+		count := 0
+		db.Raw(`SELECT COUNT(*) FROM user_chains WHERE chain_id = ?`, source.ID).Scan(&count)
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("Merge", func(t *testing.T) {
+		result := request(gin.H{
+			"source_chain_uid": source.UID,
+			"target_chain_uid": target.UID,
+		}, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		isDeleted := false
+		db.Raw(`SELECT deleted_at IS NOT NULL FROM chains WHERE id = ?`, source.ID).Scan(&isDeleted)
+		assert.True(t, isDeleted)
+
+		count := 0
+		db.Raw(`SELECT COUNT(*) FROM user_chains WHERE chain_id = ?`, source.ID).Scan(&count)
+		assert.Equal(t, 0, count)
+
+		route, err := target.GetRouteOrderByUserUID(db)
+		require.NoError(t, err)
+		assert.Len(t, route, 3)
+		assert.Equal(t, host.UID, route[0])
+		assert.Equal(t, participant.UID, route[2])
+
+		bags := 0
+		db.Raw(`
+SELECT COUNT(*) FROM bags
+JOIN user_chains AS uc ON uc.id = bags.user_chain_id
+WHERE uc.chain_id = ? AND uc.user_id = ?
+		`, target.ID, participant.ID).Scan(&bags)
+		assert.Equal(t, 1, bags)
+
+		count = 0
+		db.Raw(`SELECT COUNT(*) FROM chain_audit_logs WHERE chain_id = ? AND target_user_id = ? AND action = ?`, target.ID, participant.ID, models.ChainAuditActionUserTransfer).Scan(&count)
+		assert.Equal(t, 1, count)
+		// the host of the source loop is also host of the target loop now
+		db.Raw(`SELECT COUNT(*) FROM chain_audit_logs WHERE chain_id = ? AND target_user_id = ? AND action = ?`, target.ID, sourceHost.ID, models.ChainAuditActionRoleChange).Scan(&count)
+		assert.Equal(t, 1, count)
+	})
+}
@@ -0,0 +1,194 @@
+//go:build !ci
+
+package integration_tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
+)
+
+func TestChainOrphaned(t *testing.T) {
+	chain, host, hostToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsChainAdmin:       true,
+		IsOpenToNewMembers: true,
+	})
+	participant, participantToken := mocks.MockUser(t, db, chain.ID, mocks.MockChainAndUserOptions{})
+	_, _, rootToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsRootAdmin: true,
+	})
+
+	request := func(method, url string, body *gin.H, token string) mocks.MockGinContextResponse {
+		c, resultFunc := mocks.MockGinContext(db, method, url, body, token)
+		router.HandleContext(c)
+		return resultFunc()
+	}
+
+	t.Run("Last host leaves", func(t *testing.T) {
+		result := request(http.MethodPost, "/v2/chain/remove-user", &gin.H{
+			"user_uid":  host.UID,
+			"chain_uid": chain.UID,
+		}, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		updated := &models.Chain{}
+		db.Raw(`SELECT * FROM chains WHERE id = ?`, chain.ID).Scan(updated)
+		assert.True(t, updated.OrphanedAt.Valid)
+		assert.False(t, updated.OpenToNewMembers)
+		assert.False(t, updated.DeletedAt.Valid)
+	})
+
+	t.Run("Root admin lists loops without host", func(t *testing.T) {
+		result := request(http.MethodGet, "/v2/chain/orphaned/all", nil, rootToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		chains := []models.ChainResponse{}
+		require.NoError(t, json.Unmarshal([]byte(result.Body), &chains))
+		uids := []string{}
+		for _, c := range chains {
+			uids = append(uids, c.UID)
+		}
+		assert.Contains(t, uids, chain.UID)
+	})
+
+	t.Run("Participant becomes host", func(t *testing.T) {
+		result := request(http.MethodPost, "/v2/chain/become-host", &gin.H{
+			"chain_uid": chain.UID,
+			"allow_toh": true,
+		}, participantToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		isChainAdmin := false
+		db.Raw(`SELECT is_chain_admin FROM user_chains WHERE chain_id = ? AND user_id = ?`, chain.ID, participant.ID).Scan(&isChainAdmin)
+		assert.True(t, isChainAdmin)
+
+		isOrphaned := true
+		db.Raw(`SELECT orphaned_at IS NOT NULL FROM chains WHERE id = ?`, chain.ID).Scan(&isOrphaned)
+		assert.False(t, isOrphaned)
+
+		result = request(http.MethodPost, "/v2/chain/become-host", &gin.H{
+			"chain_uid": chain.UID,
+			"allow_toh": true,
+		}, participantToken)
+		assert.Equal(t, http.StatusConflict, result.Response.StatusCode, result.Body)
+	})
+}
+
+func TestChainOrphanedWithSuccessor(t *testing.T) {
+	chain, host, hostToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsChainAdmin: true,
+	})
+	successor, _ := mocks.MockUser(t, db, chain.ID, mocks.MockChainAndUserOptions{})
+
+	c, resultFunc := mocks.MockGinContext(db, http.MethodPost, "/v2/chain/remove-user", &gin.H{
+		"user_uid":           host.UID,
+		"chain_uid":          chain.UID,
+		"successor_user_uid": successor.UID,
+	}, hostToken)
+	router.HandleContext(c)
+	result := resultFunc()
+	require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+	count := 0
+	db.Raw(`SELECT COUNT(*) FROM chain_host_invitations WHERE chain_id = ? AND user_id = ?`, chain.ID, successor.ID).Scan(&count)
+	assert.Equal(t, 1, count)
+}
+
+func TestChainOrphanedEmptyIsSoftDeleted(t *testing.T) {
+	chain, host, hostToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsChainAdmin: true,
+	})
+
+	c, resultFunc := mocks.MockGinContext(db, http.MethodPost, "/v2/chain/remove-user", &gin.H{
+		"user_uid":  host.UID,
+		"chain_uid": chain.UID,
+	}, hostToken)
+	router.HandleContext(c)
+	result := resultFunc()
+	require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+	deleted := &models.Chain{}
+	db.Raw(`SELECT * FROM chains WHERE id = ?`, chain.ID).Scan(deleted)
+	assert.True(t, deleted.DeletedAt.Valid)
+
+	// the host can restore the loop after leaving it by accident
+	c, resultFunc = mocks.MockGinContext(db, http.MethodPost, "/v2/chain/restore", &gin.H{
+		"chain_uid": chain.UID,
+	}, hostToken)
+	router.HandleContext(c)
+	result = resultFunc()
+	require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+}
+
+func TestChainOrphanedEmptyIsSoftDeletedOnPurge(t *testing.T) {
+	chain, host, hostToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsChainAdmin: true,
+	})
+
+	c, resultFunc := mocks.MockGinContext(db, http.MethodDelete, fmt.Sprintf("/v2/user/purge?user_uid=%s", host.UID), nil, hostToken)
+	router.HandleContext(c)
+	result := resultFunc()
+	require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+	deleted := &models.Chain{}
+	db.Raw(`SELECT * FROM chains WHERE id = ?`, chain.ID).Scan(deleted)
+	require.NotZero(t, deleted.ID)
+	assert.True(t, deleted.DeletedAt.Valid)
+}
+
+func TestChainOrphanedWithSuccessorOnPurge(t *testing.T) {
+	chain, host, hostToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsChainAdmin: true,
+	})
+	successor, _ := mocks.MockUser(t, db, chain.ID, mocks.MockChainAndUserOptions{})
+
+	c, resultFunc := mocks.MockGinContext(db, http.MethodDelete, fmt.Sprintf("/v2/user/purge?user_uid=%s&successors[%s]=%s", host.UID, chain.UID, successor.UID), nil, hostToken)
+	router.HandleContext(c)
+	result := resultFunc()
+	require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+	updated := &models.Chain{}
+	db.Raw(`SELECT * FROM chains WHERE id = ?`, chain.ID).Scan(updated)
+	assert.True(t, updated.OrphanedAt.Valid)
+
+	// the invitation is sent by the system, not by the deleted user
+	invitations := []models.ChainHostInvitation{}
+	db.Raw(`SELECT * FROM chain_host_invitations WHERE chain_id = ? AND user_id = ?`, chain.ID, successor.ID).Scan(&invitations)
+	require.Len(t, invitations, 1)
+	assert.Zero(t, invitations[0].InvitedByUserID)
+}
+
+func TestChainOrphanedOnDeclinedLegal(t *testing.T) {
+	chain, host, hostToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsChainAdmin: true,
+	})
+	mocks.MockUser(t, db, chain.ID, mocks.MockChainAndUserOptions{})
+
+	c, resultFunc := mocks.MockGinContext(db, http.MethodPatch, "/v2/user", &gin.H{
+		"user_uid":       host.UID,
+		"accepted_legal": false,
+	}, hostToken)
+	router.HandleContext(c)
+	result := resultFunc()
+	require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+	isChainAdmin := true
+	db.Raw(`SELECT is_chain_admin FROM user_chains WHERE chain_id = ? AND user_id = ?`, chain.ID, host.ID).Scan(&isChainAdmin)
+	assert.False(t, isChainAdmin)
+
+	updated := &models.Chain{}
+	db.Raw(`SELECT * FROM chains WHERE id = ?`, chain.ID).Scan(updated)
+	assert.True(t, updated.OrphanedAt.Valid)
+	assert.False(t, updated.OpenToNewMembers)
+
+	count := 0
+	db.Raw(`SELECT COUNT(*) FROM chain_audit_logs WHERE chain_id = ? AND target_user_id = ? AND action = ?`, chain.ID, host.ID, models.ChainAuditActionRoleChange).Scan(&count)
+	assert.Equal(t, 1, count)
+}
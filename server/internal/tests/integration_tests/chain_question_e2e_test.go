@@ -0,0 +1,139 @@
+//go:build !ci
+
+package integration_tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/the-clothing-loop/website/server/internal/app/auth"
+	"github.com/the-clothing-loop/website/server/internal/controllers"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
+)
+
+func TestChainQuestions(t *testing.T) {
+	chain, _, hostToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsChainAdmin:       true,
+		IsOpenToNewMembers: true,
+	})
+	_, participant, participantToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+
+	request := func(method, url string, body *gin.H, token string) mocks.MockGinContextResponse {
+		c, resultFunc := mocks.MockGinContext(db, method, url, body, token)
+		router.HandleContext(c)
+		return resultFunc()
+	}
+
+	questions := []models.ChainQuestion{}
+	t.Run("Host sets questions", func(t *testing.T) {
+		result := request(http.MethodPut, "/v2/chain/questions", &gin.H{
+			"chain_uid": chain.UID,
+			"questions": []gin.H{
+				{"type": models.ChainQuestionTypeText, "question": "Why do you want to join?"},
+				{"type": models.ChainQuestionTypeYesNo, "question": "Do you live nearby?", "is_required": true},
+			},
+		}, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		result = request(http.MethodGet, fmt.Sprintf("/v2/chain/questions?chain_uid=%s", chain.UID), nil, "")
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+		require.NoError(t, json.Unmarshal([]byte(result.Body), &questions))
+		require.Len(t, questions, 2)
+		assert.Equal(t, models.ChainQuestionTypeYesNo, questions[1].Type)
+	})
+
+	t.Run("Required questions must be answered", func(t *testing.T) {
+		result := request(http.MethodPost, "/v2/chain/add-user", &gin.H{
+			"user_uid":  participant.UID,
+			"chain_uid": chain.UID,
+			"answers": []gin.H{
+				{"question_uid": questions[0].UID, "answer": "To share clothes"},
+			},
+		}, participantToken)
+		assert.Equal(t, http.StatusBadRequest, result.Response.StatusCode, result.Body)
+	})
+
+	t.Run("Host sees the answers of pending members", func(t *testing.T) {
+		result := request(http.MethodPost, "/v2/chain/add-user", &gin.H{
+			"user_uid":  participant.UID,
+			"chain_uid": chain.UID,
+			"answers": []gin.H{
+				{"question_uid": questions[0].UID, "answer": "To share clothes"},
+				{"question_uid": questions[1].UID, "answer": "yes"},
+			},
+		}, participantToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		result = request(http.MethodGet, fmt.Sprintf("/v2/user/all-chain?chain_uid=%s", chain.UID), nil, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+		users := []models.User{}
+		require.NoError(t, json.Unmarshal([]byte(result.Body), &users))
+
+		var answers []models.ChainQuestionAnswer
+		for _, u := range users {
+			if u.UID != participant.UID {
+				continue
+			}
+			for _, uc := range u.Chains {
+				if uc.ChainUID == chain.UID {
+					answers = uc.JoinAnswers
+				}
+			}
+		}
+		require.Len(t, answers, 2)
+		assert.Equal(t, "Why do you want to join?", answers[0].Question)
+		assert.Equal(t, "yes", answers[1].Answer)
+	})
+
+	t.Run("Answers are deleted when the request is denied", func(t *testing.T) {
+		result := request(http.MethodDelete, fmt.Sprintf("/v2/chain/unapproved-user?chain_uid=%s&user_uid=%s&reason=%s",
+			chain.UID, participant.UID, controllers.UnapprovedReasonOther), nil, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		count := -1
+		db.Raw(`SELECT COUNT(*) FROM chain_question_answers WHERE chain_id = ? AND user_id = ?`, chain.ID, participant.ID).Scan(&count)
+		assert.Equal(t, 0, count)
+	})
+}
+
+func TestChainQuestionsLoginLink(t *testing.T) {
+	chain, _, hostToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsChainAdmin:       true,
+		IsOpenToNewMembers: true,
+	})
+	_, user, _ := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+
+	c, resultFunc := mocks.MockGinContext(db, http.MethodPut, "/v2/chain/questions", &gin.H{
+		"chain_uid": chain.UID,
+		"questions": []gin.H{
+			{"type": models.ChainQuestionTypeYesNo, "question": "Do you live nearby?", "is_required": true},
+		},
+	}, hostToken)
+	router.HandleContext(c)
+	result := resultFunc()
+	require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+	// the login link of the loop does not skip the required questions
+	link, err := auth.MagicLinkCreate(db, user.ID, chain.UID)
+	require.NoError(t, err)
+	c, resultFunc = mocks.MockGinContext(db, http.MethodGet, fmt.Sprintf("/v2/login/validate?t=%s", link.Token), nil, "")
+	router.HandleContext(c)
+	result = resultFunc()
+	require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+	body := struct {
+		JoinForm bool `json:"join_form"`
+	}{}
+	require.NoError(t, json.Unmarshal([]byte(result.Body), &body))
+	assert.True(t, body.JoinForm)
+
+	count := -1
+	db.Raw(`SELECT COUNT(*) FROM user_chains WHERE chain_id = ? AND user_id = ?`, chain.ID, user.ID).Scan(&count)
+	assert.Equal(t, 0, count)
+}
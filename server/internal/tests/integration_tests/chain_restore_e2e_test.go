@@ -0,0 +1,96 @@
+//go:build !ci
+
+package integration_tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
+)
+
+func TestChainRestore(t *testing.T) {
+	chain, _, hostToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsChainAdmin: true,
+	})
+	participant, participantToken := mocks.MockUser(t, db, chain.ID, mocks.MockChainAndUserOptions{})
+	mocks.MockBag(t, db, chain.ID, participant.ID, mocks.MockBagOptions{})
+
+	request := func(token string) mocks.MockGinContextResponse {
+		c, resultFunc := mocks.MockGinContext(db, http.MethodPost, "/v2/chain/restore", &gin.H{
+			"chain_uid": chain.UID,
+		}, token)
+		router.HandleContext(c)
+		return resultFunc()
+	}
+
+	require.NoError(t, chain.SoftDelete(db))
+
+	t.Run("Deleted loop is hidden", func(t *testing.T) {
+		c, resultFunc := mocks.MockGinContext(db, http.MethodGet, "/v2/chain?chain_uid="+chain.UID, nil, "")
+		router.HandleContext(c)
+		result := resultFunc()
+		assert.Equal(t, http.StatusBadRequest, result.Response.StatusCode, result.Body)
+	})
+
+	t.Run("Hosts list the deleted loop", func(t *testing.T) {
+		getDeleted := func(token string) []string {
+			c, resultFunc := mocks.MockGinContext(db, http.MethodGet, "/v2/chain/deleted", nil, token)
+			router.HandleContext(c)
+			result := resultFunc()
+			require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+			chains := []struct {
+				UID string `json:"uid"`
+			}{}
+			require.NoError(t, json.Unmarshal([]byte(result.Body), &chains))
+			uids := []string{}
+			for _, c := range chains {
+				uids = append(uids, c.UID)
+			}
+			return uids
+		}
+		assert.Equal(t, []string{chain.UID}, getDeleted(hostToken))
+		assert.Empty(t, getDeleted(participantToken))
+	})
+
+	t.Run("Participants can not restore", func(t *testing.T) {
+		result := request(participantToken)
+		assert.Equal(t, http.StatusUnauthorized, result.Response.StatusCode, result.Body)
+	})
+
+	t.Run("Retention period has passed", func(t *testing.T) {
+		db.Exec(`UPDATE chains SET deleted_at = (NOW() - INTERVAL ? DAY) WHERE id = ?`, models.ChainDeletedRetentionDays+1, chain.ID)
+		result := request(hostToken)
+		assert.Equal(t, http.StatusGone, result.Response.StatusCode, result.Body)
+		db.Exec(`UPDATE chains SET deleted_at = NOW() WHERE id = ?`, chain.ID)
+	})
+
+	t.Run("Merged loop can not be restored", func(t *testing.T) {
+		db.Exec(`UPDATE chains SET merged_into_chain_id = ? WHERE id = ?`, chain.ID, chain.ID)
+		result := request(hostToken)
+		assert.Equal(t, http.StatusGone, result.Response.StatusCode, result.Body)
+		db.Exec(`UPDATE chains SET merged_into_chain_id = NULL WHERE id = ?`, chain.ID)
+	})
+
+	t.Run("Host restores", func(t *testing.T) {
+		result := request(hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		isDeleted := true
+		db.Raw(`SELECT deleted_at IS NOT NULL FROM chains WHERE id = ?`, chain.ID).Scan(&isDeleted)
+		assert.False(t, isDeleted)
+
+		bags := 0
+		db.Raw(`
+SELECT COUNT(*) FROM bags
+JOIN user_chains AS uc ON uc.id = bags.user_chain_id
+WHERE uc.chain_id = ? AND uc.user_id = ?
+		`, chain.ID, participant.ID).Scan(&bags)
+		assert.Equal(t, 1, bags)
+	})
+}
@@ -0,0 +1,162 @@
+//go:build !ci
+
+package integration_tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
+)
+
+func TestChainRoles(t *testing.T) {
+	chain, host, hostToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsChainAdmin: true,
+	})
+	bagManager, bagManagerToken := mocks.MockUser(t, db, chain.ID, mocks.MockChainAndUserOptions{
+		Role: models.UserChainRoleBagManager,
+	})
+	participant, participantToken := mocks.MockUser(t, db, chain.ID, mocks.MockChainAndUserOptions{})
+	unapproved, _ := mocks.MockUser(t, db, chain.ID, mocks.MockChainAndUserOptions{
+		IsNotApproved: true,
+	})
+
+	request := func(method, url string, body *gin.H, token string) mocks.MockGinContextResponse {
+		c, resultFunc := mocks.MockGinContext(db, method, url, body, token)
+		router.HandleContext(c)
+		return resultFunc()
+	}
+	getRole := func(userID uint) (role string, isChainAdmin bool) {
+		row := struct {
+			Role         string
+			IsChainAdmin bool
+		}{}
+		db.Raw(`SELECT role, is_chain_admin FROM user_chains WHERE user_id = ? AND chain_id = ?`, userID, chain.ID).Scan(&row)
+		return row.Role, row.IsChainAdmin
+	}
+
+	t.Run("Bag manager can only manage bags", func(t *testing.T) {
+		bag := mocks.MockBag(t, db, chain.ID, participant.ID, mocks.MockBagOptions{})
+		result := request(http.MethodDelete, fmt.Sprintf("/v2/bag?chain_uid=%s&user_uid=%s&bag_id=%d", chain.UID, bagManager.UID, bag.ID), nil, bagManagerToken)
+		assert.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		result = request(http.MethodGet, fmt.Sprintf("/v2/route/optimize?chain_uid=%s", chain.UID), nil, bagManagerToken)
+		assert.Equal(t, http.StatusUnauthorized, result.Response.StatusCode, result.Body)
+
+		result = request(http.MethodPatch, "/v2/chain/approve-user", &gin.H{
+			"chain_uid": chain.UID,
+			"user_uid":  unapproved.UID,
+		}, bagManagerToken)
+		assert.Equal(t, http.StatusUnauthorized, result.Response.StatusCode, result.Body)
+	})
+
+	t.Run("Only hosts can change roles", func(t *testing.T) {
+		result := request(http.MethodPost, "/v2/chain/add-user", &gin.H{
+			"chain_uid": chain.UID,
+			"user_uid":  participant.UID,
+			"role":      models.UserChainRoleModerator,
+		}, bagManagerToken)
+		assert.Equal(t, http.StatusUnauthorized, result.Response.StatusCode, result.Body)
+
+		result = request(http.MethodPost, "/v2/chain/add-user", &gin.H{
+			"chain_uid": chain.UID,
+			"user_uid":  participant.UID,
+			"role":      "owner",
+		}, hostToken)
+		assert.Equal(t, http.StatusBadRequest, result.Response.StatusCode, result.Body)
+
+		result = request(http.MethodPost, "/v2/chain/add-user", &gin.H{
+			"chain_uid": chain.UID,
+			"user_uid":  participant.UID,
+			"role":      models.UserChainRoleModerator,
+		}, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		role, isChainAdmin := getRole(participant.ID)
+		assert.Equal(t, models.UserChainRoleModerator, role)
+		assert.False(t, isChainAdmin)
+	})
+
+	t.Run("A new member is added with the role", func(t *testing.T) {
+		require.NoError(t, db.Exec(`UPDATE chains SET open_to_new_members = TRUE WHERE id = ?`, chain.ID).Error)
+		t.Cleanup(func() {
+			db.Exec(`UPDATE chains SET open_to_new_members = FALSE WHERE id = ?`, chain.ID)
+		})
+		_, newcomer, _ := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+
+		result := request(http.MethodPost, "/v2/chain/add-user", &gin.H{
+			"chain_uid": chain.UID,
+			"user_uid":  newcomer.UID,
+			"role":      models.UserChainRoleRouteManager,
+		}, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		role, isChainAdmin := getRole(newcomer.ID)
+		assert.Equal(t, models.UserChainRoleRouteManager, role)
+		assert.False(t, isChainAdmin)
+
+		count := 0
+		db.Raw(`
+SELECT COUNT(*) FROM chain_audit_logs
+WHERE chain_id = ? AND target_user_id = ? AND action = ?
+		`, chain.ID, newcomer.ID, models.ChainAuditActionRoleChange).Scan(&count)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("Moderator can approve but not remove or edit a host", func(t *testing.T) {
+		result := request(http.MethodPatch, "/v2/chain/approve-user", &gin.H{
+			"chain_uid": chain.UID,
+			"user_uid":  unapproved.UID,
+		}, participantToken)
+		assert.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		result = request(http.MethodPost, "/v2/chain/remove-user", &gin.H{
+			"chain_uid": chain.UID,
+			"user_uid":  host.UID,
+		}, participantToken)
+		assert.Equal(t, http.StatusUnauthorized, result.Response.StatusCode, result.Body)
+
+		result = request(http.MethodPatch, "/v2/user", &gin.H{
+			"chain_uid": chain.UID,
+			"user_uid":  host.UID,
+			"name":      "Renamed by moderator",
+		}, participantToken)
+		assert.Equal(t, http.StatusUnauthorized, result.Response.StatusCode, result.Body)
+
+		result = request(http.MethodPatch, "/v2/chain", &gin.H{
+			"uid":  chain.UID,
+			"name": "Renamed by moderator",
+		}, participantToken)
+		assert.Equal(t, http.StatusUnauthorized, result.Response.StatusCode, result.Body)
+	})
+
+	t.Run("The host role is only set after accepting the invitation", func(t *testing.T) {
+		result := request(http.MethodPost, "/v2/chain/add-user", &gin.H{
+			"chain_uid":      chain.UID,
+			"user_uid":       bagManager.UID,
+			"is_chain_admin": true,
+		}, hostToken)
+		require.Equal(t, http.StatusAccepted, result.Response.StatusCode, result.Body)
+		inv := &models.ChainHostInvitation{}
+		require.NoError(t, json.Unmarshal([]byte(result.Body), inv))
+
+		role, isChainAdmin := getRole(bagManager.ID)
+		assert.Equal(t, models.UserChainRoleBagManager, role)
+		assert.False(t, isChainAdmin)
+
+		result = request(http.MethodPost, fmt.Sprintf("/v2/user/host-invitations/%s/accept", inv.UID), &gin.H{
+			"allow_toh": true,
+		}, bagManagerToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		role, isChainAdmin = getRole(bagManager.ID)
+		assert.Equal(t, models.UserChainRoleHost, role)
+		assert.True(t, isChainAdmin)
+	})
+}
@@ -0,0 +1,78 @@
+//go:build !ci
+
+package integration_tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
+)
+
+func TestChainSearch(t *testing.T) {
+	// a unique word to only find the loops of this test
+	word := "search" + uuid.NewV4().String()[:8]
+	chains := []*models.Chain{}
+	for i := 0; i < 3; i++ {
+		chain, _, _ := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+		db.Exec(`UPDATE chains SET name = ?, latitude = ?, longitude = ?, sizes = ?, country_code = 'NL' WHERE id = ?`,
+			fmt.Sprintf("%s %d", word, i), 52.0+float64(i), 4.0, `["1","2"]`, chain.ID)
+		chains = append(chains, chain)
+	}
+	db.Exec(`UPDATE chains SET sizes = ? WHERE id = ?`, `["1","2","3"]`, chains[2].ID)
+
+	type searchResponse struct {
+		Chains     []models.ChainResponse `json:"chains"`
+		Total      int                    `json:"total"`
+		NextCursor *string                `json:"next_cursor"`
+	}
+	search := func(query string) *searchResponse {
+		c, resultFunc := mocks.MockGinContext(db, http.MethodGet, "/v2/chain/search?"+query, nil, "")
+		router.HandleContext(c)
+		result := resultFunc()
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		res := &searchResponse{}
+		require.NoError(t, json.Unmarshal([]byte(result.Body), res))
+		return res
+	}
+
+	t.Run("Paginate by name", func(t *testing.T) {
+		res := search(fmt.Sprintf("q=%s&limit=2", word))
+		assert.Equal(t, 3, res.Total)
+		require.Len(t, res.Chains, 2)
+		assert.Equal(t, chains[0].UID, res.Chains[0].UID)
+		require.NotNil(t, res.NextCursor)
+
+		res = search(fmt.Sprintf("q=%s&limit=2&cursor=%s", word, *res.NextCursor))
+		require.Len(t, res.Chains, 1)
+		assert.Equal(t, chains[2].UID, res.Chains[0].UID)
+		assert.Nil(t, res.NextCursor)
+	})
+
+	t.Run("Sort by distance", func(t *testing.T) {
+		res := search(fmt.Sprintf("q=%s&latitude=54.1&longitude=4.0", word))
+		require.Len(t, res.Chains, 3)
+		assert.Equal(t, chains[2].UID, res.Chains[0].UID)
+		assert.Equal(t, chains[0].UID, res.Chains[2].UID)
+	})
+
+	t.Run("Loops must contain all sizes", func(t *testing.T) {
+		res := search(fmt.Sprintf("q=%s&filter_sizes=1&filter_sizes=3", word))
+		assert.Equal(t, 1, res.Total)
+		require.Len(t, res.Chains, 1)
+		assert.Equal(t, chains[2].UID, res.Chains[0].UID)
+	})
+
+	t.Run("Filter by country", func(t *testing.T) {
+		res := search(fmt.Sprintf("q=%s&country_code=BE", word))
+		assert.Equal(t, 0, res.Total)
+		assert.Empty(t, res.Chains)
+	})
+}
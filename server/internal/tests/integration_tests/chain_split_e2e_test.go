@@ -0,0 +1,156 @@
+//go:build !ci
+
+package integration_tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
+)
+
+func TestChainSplit(t *testing.T) {
+	location := func(latitude, longitude float64) mocks.MockChainAndUserOptions {
+		return mocks.MockChainAndUserOptions{
+			OverrideLatitude:  &latitude,
+			OverrideLongitude: &longitude,
+		}
+	}
+	hostOptions := location(52.37, 4.89)
+	hostOptions.IsChainAdmin = true
+	chain, host, hostToken := mocks.MockChainAndUser(t, db, hostOptions)
+	amsterdam, _ := mocks.MockUser(t, db, chain.ID, location(52.38, 4.90))
+	utrecht1, _ := mocks.MockUser(t, db, chain.ID, location(52.09, 5.12))
+	utrecht2, _ := mocks.MockUser(t, db, chain.ID, location(52.08, 5.11))
+	mocks.MockBag(t, db, chain.ID, utrecht1.ID, mocks.MockBagOptions{})
+
+	request := func(method, url string, body *gin.H) mocks.MockGinContextResponse {
+		c, resultFunc := mocks.MockGinContext(db, method, url, body, hostToken)
+		router.HandleContext(c)
+		return resultFunc()
+	}
+
+	t.Run("Preview groups members by location", func(t *testing.T) {
+		result := request(http.MethodGet, fmt.Sprintf("/v2/chain/split/preview?chain_uid=%s", chain.UID), nil)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		groups := []struct {
+			UserUIDs []string `json:"user_uids"`
+		}{}
+		require.NoError(t, json.Unmarshal([]byte(result.Body), &groups))
+		require.Len(t, groups, 2)
+		assert.ElementsMatch(t, []string{host.UID, amsterdam.UID}, groups[0].UserUIDs)
+		assert.ElementsMatch(t, []string{utrecht1.UID, utrecht2.UID}, groups[1].UserUIDs)
+	})
+
+	t.Run("All members can not be moved", func(t *testing.T) {
+		result := request(http.MethodPost, "/v2/chain/split", &gin.H{
+			"chain_uid": chain.UID,
+			"name":      "Fake split loop",
+			"user_uids": []string{host.UID, amsterdam.UID, utrecht1.UID, utrecht2.UID},
+		})
+		assert.Equal(t, http.StatusBadRequest, result.Response.StatusCode, result.Body)
+	})
+
+	t.Run("All hosts can not be moved", func(t *testing.T) {
+		result := request(http.MethodPost, "/v2/chain/split", &gin.H{
+			"chain_uid": chain.UID,
+			"name":      "Fake split loop",
+			"user_uids": []string{host.UID, amsterdam.UID},
+		})
+		assert.Equal(t, http.StatusBadRequest, result.Response.StatusCode, result.Body)
+	})
+
+	t.Run("Split", func(t *testing.T) {
+		result := request(http.MethodPost, "/v2/chain/split", &gin.H{
+			"chain_uid": chain.UID,
+			"name":      "Fake split loop",
+			"user_uids": []string{utrecht1.UID, utrecht2.UID},
+		})
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		res := struct {
+			ChainUID string `json:"chain_uid"`
+		}{}
+		require.NoError(t, json.Unmarshal([]byte(result.Body), &res))
+		newChain := &models.Chain{}
+		db.Raw(`SELECT * FROM chains WHERE uid = ? LIMIT 1`, res.ChainUID).Scan(newChain)
+		require.NotZero(t, newChain.ID)
+		t.Cleanup(func() {
+			db.Exec(`DELETE FROM bags WHERE user_chain_id IN (SELECT id FROM user_chains WHERE chain_id = ?)`, newChain.ID)
+			db.Exec(`DELETE FROM user_chains WHERE chain_id = ?`, newChain.ID)
+			db.Exec(`DELETE FROM chain_audit_logs WHERE chain_id = ?`, newChain.ID)
+			db.Exec(`DELETE FROM chains WHERE id = ?`, newChain.ID)
+		})
+		assert.Equal(t, chain.Sizes, newChain.Sizes)
+		assert.Equal(t, chain.RoutePrivacy, newChain.RoutePrivacy)
+
+		route, err := newChain.GetRouteOrderByUserUID(db)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{host.UID, utrecht1.UID, utrecht2.UID}, route)
+
+		route, err = chain.GetRouteOrderByUserUID(db)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{host.UID, amsterdam.UID}, route)
+
+		bags := 0
+		db.Raw(`
+SELECT COUNT(*) FROM bags
+JOIN user_chains AS uc ON uc.id = bags.user_chain_id
+WHERE uc.chain_id = ? AND uc.user_id = ?
+		`, newChain.ID, utrecht1.ID).Scan(&bags)
+		assert.Equal(t, 1, bags)
+
+		// the moved members are in the history of both loops, the host is added to the new loop
+		count := 0
+		db.Raw(`SELECT COUNT(*) FROM chain_audit_logs WHERE chain_id IN ? AND action = ?`, []uint{chain.ID, newChain.ID}, models.ChainAuditActionUserTransfer).Scan(&count)
+		assert.Equal(t, 4, count)
+		db.Raw(`SELECT COUNT(*) FROM chain_audit_logs WHERE chain_id = ? AND target_user_id = ? AND action = ?`, newChain.ID, host.ID, models.ChainAuditActionRoleChange).Scan(&count)
+		assert.Equal(t, 1, count)
+	})
+}
+
+func TestChainSplitClosedAtCapacity(t *testing.T) {
+	chain, _, hostToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsChainAdmin: true,
+	})
+	member, _ := mocks.MockUser(t, db, chain.ID, mocks.MockChainAndUserOptions{})
+	mocks.MockUser(t, db, chain.ID, mocks.MockChainAndUserOptions{})
+	require.NoError(t, db.Exec(`
+UPDATE chains SET max_members = 3, open_to_new_members = FALSE, is_closed_at_capacity = TRUE WHERE id = ?
+	`, chain.ID).Error)
+
+	c, resultFunc := mocks.MockGinContext(db, http.MethodPost, "/v2/chain/split", &gin.H{
+		"chain_uid": chain.UID,
+		"name":      "Fake split loop",
+		"user_uids": []string{member.UID},
+	}, hostToken)
+	router.HandleContext(c)
+	result := resultFunc()
+	require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+	res := struct {
+		ChainUID string `json:"chain_uid"`
+	}{}
+	require.NoError(t, json.Unmarshal([]byte(result.Body), &res))
+	newChain := &models.Chain{}
+	db.Raw(`SELECT * FROM chains WHERE uid = ? LIMIT 1`, res.ChainUID).Scan(newChain)
+	require.NotZero(t, newChain.ID)
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM user_chains WHERE chain_id = ?`, newChain.ID)
+		db.Exec(`DELETE FROM chains WHERE id = ?`, newChain.ID)
+	})
+
+	// both loops have room again after the split
+	assert.True(t, newChain.OpenToNewMembers)
+	assert.False(t, newChain.IsClosedAtCapacity)
+	updated := &models.Chain{}
+	db.Raw(`SELECT * FROM chains WHERE id = ?`, chain.ID).Scan(updated)
+	assert.True(t, updated.OpenToNewMembers)
+}
@@ -0,0 +1,157 @@
+//go:build !ci
+
+package integration_tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/the-clothing-loop/website/server/internal/app/auth"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
+)
+
+func TestChainWaitlist(t *testing.T) {
+	chain, _, hostToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsChainAdmin: true,
+	})
+	_, participantToken := mocks.MockUser(t, db, chain.ID, mocks.MockChainAndUserOptions{})
+	_, userA, tokenA := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+	_, userB, tokenB := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+
+	request := func(method, url string, body *gin.H, token string) mocks.MockGinContextResponse {
+		c, resultFunc := mocks.MockGinContext(db, method, url, body, token)
+		router.HandleContext(c)
+		return resultFunc()
+	}
+	getWaitlist := func() []models.ChainWaitlist {
+		result := request(http.MethodGet, fmt.Sprintf("/v2/chain/waitlist/all?chain_uid=%s", chain.UID), nil, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+		waitlist := []models.ChainWaitlist{}
+		require.NoError(t, json.Unmarshal([]byte(result.Body), &waitlist))
+		return waitlist
+	}
+
+	t.Run("Join a closed loop", func(t *testing.T) {
+		result := request(http.MethodPost, "/v2/chain/add-user", &gin.H{
+			"chain_uid": chain.UID,
+			"user_uid":  userA.UID,
+		}, tokenA)
+		require.Equal(t, http.StatusAccepted, result.Response.StatusCode, result.Body)
+
+		result = request(http.MethodPost, "/v2/chain/waitlist", &gin.H{
+			"chain_uid": chain.UID,
+		}, tokenB)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		waitlist := getWaitlist()
+		require.Len(t, waitlist, 2)
+		assert.Equal(t, userA.UID, waitlist[0].UserUID)
+		assert.Equal(t, userB.UID, waitlist[1].UserUID)
+		assert.Equal(t, 2, waitlist[1].Position)
+	})
+
+	t.Run("Participant can not view the waitlist", func(t *testing.T) {
+		result := request(http.MethodGet, fmt.Sprintf("/v2/chain/waitlist/all?chain_uid=%s", chain.UID), nil, participantToken)
+		assert.Equal(t, http.StatusUnauthorized, result.Response.StatusCode, result.Body)
+	})
+
+	t.Run("Host reorders the waitlist", func(t *testing.T) {
+		result := request(http.MethodPost, "/v2/chain/waitlist/order", &gin.H{
+			"chain_uid": chain.UID,
+			"user_uids": []string{userB.UID},
+		}, hostToken)
+		assert.Equal(t, http.StatusBadRequest, result.Response.StatusCode, result.Body)
+
+		result = request(http.MethodPost, "/v2/chain/waitlist/order", &gin.H{
+			"chain_uid": chain.UID,
+			"user_uids": []string{userB.UID, userA.UID},
+		}, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		waitlist := getWaitlist()
+		require.Len(t, waitlist, 2)
+		assert.Equal(t, userB.UID, waitlist[0].UserUID)
+	})
+
+	t.Run("Leave the waitlist", func(t *testing.T) {
+		result := request(http.MethodDelete, fmt.Sprintf("/v2/chain/waitlist?chain_uid=%s&user_uid=%s", chain.UID, userA.UID), nil, tokenB)
+		assert.Equal(t, http.StatusUnauthorized, result.Response.StatusCode, result.Body)
+
+		result = request(http.MethodDelete, fmt.Sprintf("/v2/chain/waitlist?chain_uid=%s", chain.UID), nil, tokenB)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		waitlist := getWaitlist()
+		require.Len(t, waitlist, 1)
+		assert.Equal(t, userA.UID, waitlist[0].UserUID)
+		assert.Equal(t, 1, waitlist[0].Position)
+	})
+
+	t.Run("Reopening the loop invites the waitlist", func(t *testing.T) {
+		result := request(http.MethodPatch, "/v2/chain", &gin.H{
+			"uid":                 chain.UID,
+			"open_to_new_members": true,
+		}, hostToken)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		waitlist := getWaitlist()
+		require.Len(t, waitlist, 1)
+		assert.True(t, waitlist[0].InvitedAt.Valid)
+	})
+
+	t.Run("Joining a loop removes the user from the waitlist", func(t *testing.T) {
+		result := request(http.MethodPost, "/v2/chain/add-user", &gin.H{
+			"chain_uid": chain.UID,
+			"user_uid":  userA.UID,
+		}, tokenA)
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		assert.Empty(t, getWaitlist())
+	})
+}
+
+func TestChainWaitlistFull(t *testing.T) {
+	chain, _, _ := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsChainAdmin:       true,
+		IsOpenToNewMembers: true,
+	})
+	_, user, token := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+	require.NoError(t, db.Exec(`UPDATE chains SET max_members = 1 WHERE id = ?`, chain.ID).Error)
+
+	c, resultFunc := mocks.MockGinContext(db, http.MethodPost, "/v2/chain/waitlist", &gin.H{
+		"chain_uid": chain.UID,
+	}, token)
+	router.HandleContext(c)
+	result := resultFunc()
+	require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+	count := 0
+	db.Raw(`SELECT COUNT(*) FROM chain_waitlists WHERE chain_id = ? AND user_id = ?`, chain.ID, user.ID).Scan(&count)
+	assert.Equal(t, 1, count)
+}
+
+func TestChainWaitlistLoginLink(t *testing.T) {
+	chain, _, _ := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsChainAdmin: true,
+	})
+	_, user, _ := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+
+	// an existing user following the signup link of a closed loop logs in
+	link, err := auth.MagicLinkCreate(db, user.ID, chain.UID)
+	require.NoError(t, err)
+	c, resultFunc := mocks.MockGinContext(db, http.MethodGet, fmt.Sprintf("/v2/login/validate?t=%s", link.Token), nil, "")
+	router.HandleContext(c)
+	result := resultFunc()
+	require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+	count := 0
+	db.Raw(`SELECT COUNT(*) FROM chain_waitlists WHERE chain_id = ? AND user_id = ?`, chain.ID, user.ID).Scan(&count)
+	assert.Equal(t, 1, count)
+	db.Raw(`SELECT COUNT(*) FROM user_chains WHERE chain_id = ? AND user_id = ?`, chain.ID, user.ID).Scan(&count)
+	assert.Equal(t, 0, count)
+}
@@ -0,0 +1,101 @@
+//go:build !ci
+
+package integration_tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
+)
+
+func TestImpersonate(t *testing.T) {
+	_, admin, adminToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsRootAdmin: true,
+	})
+	_, otherAdmin, _ := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
+		IsRootAdmin: true,
+	})
+	_, user, userToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+
+	impersonate := func(token, userUID string) mocks.MockGinContextResponse {
+		c, resultFunc := mocks.MockGinContext(db, http.MethodPost, "/v2/impersonate", &gin.H{
+			"user_uid": userUID,
+		}, token)
+		router.HandleContext(c)
+		return resultFunc()
+	}
+
+	t.Run("Only root admins can impersonate", func(t *testing.T) {
+		result := impersonate(userToken, admin.UID)
+		assert.Equal(t, http.StatusUnauthorized, result.Response.StatusCode, result.Body)
+	})
+
+	t.Run("Root admins can not be impersonated", func(t *testing.T) {
+		result := impersonate(adminToken, otherAdmin.UID)
+		assert.Equal(t, http.StatusForbidden, result.Response.StatusCode, result.Body)
+	})
+
+	result := impersonate(adminToken, user.UID)
+	require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+	token := result.BodyJSON()["token"].(string)
+
+	t.Run("Act as user", func(t *testing.T) {
+		c, resultFunc := mocks.MockGinContext(db, http.MethodGet, fmt.Sprintf("/v2/user?user_uid=%s", user.UID), nil, token)
+		router.HandleContext(c)
+		result := resultFunc()
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+		assert.Equal(t, user.Email.String, result.BodyJSON()["email"])
+		assert.Equal(t, admin.UID, result.Response.Header.Get("X-Impersonated-By"))
+
+		// the session is not shown to the user
+		c, resultFunc = mocks.MockGinContext(db, http.MethodGet, "/v2/user/sessions", nil, userToken)
+		router.HandleContext(c)
+		result = resultFunc()
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+		sessions := []models.UserSession{}
+		require.NoError(t, json.Unmarshal([]byte(result.Body), &sessions))
+		for _, s := range sessions {
+			assert.NotContains(t, s.DeviceName, "Impersonated")
+		}
+	})
+
+	t.Run("Purge is blocked", func(t *testing.T) {
+		c, resultFunc := mocks.MockGinContext(db, http.MethodDelete, fmt.Sprintf("/v2/user/purge?user_uid=%s", user.UID), nil, token)
+		router.HandleContext(c)
+		result := resultFunc()
+		assert.Equal(t, http.StatusForbidden, result.Response.StatusCode, result.Body)
+
+		count := -1
+		db.Raw(`SELECT COUNT(*) FROM users WHERE id = ?`, user.ID).Scan(&count)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("Requests are logged", func(t *testing.T) {
+		logs, err := models.UserImpersonationLogGetAll(db, user.ID, 10)
+		require.NoError(t, err)
+		require.Len(t, logs, 2)
+		assert.Equal(t, admin.UID, logs[0].AdminUserUID)
+		assert.Equal(t, http.MethodDelete, logs[0].Method)
+		assert.Equal(t, http.StatusForbidden, logs[0].Status)
+		assert.Equal(t, http.MethodGet, logs[1].Method)
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		c, resultFunc := mocks.MockGinContext(db, http.MethodDelete, "/v2/impersonate", nil, token)
+		router.HandleContext(c)
+		result := resultFunc()
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		c, resultFunc = mocks.MockGinContext(db, http.MethodGet, fmt.Sprintf("/v2/user?user_uid=%s", user.UID), nil, token)
+		router.HandleContext(c)
+		result = resultFunc()
+		assert.Equal(t, http.StatusUnauthorized, result.Response.StatusCode, result.Body)
+	})
+}
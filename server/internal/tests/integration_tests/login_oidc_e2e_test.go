@@ -0,0 +1,126 @@
+//go:build !ci
+
+package integration_tests
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/the-clothing-loop/website/server/internal/app"
+	"github.com/the-clothing-loop/website/server/internal/app/auth"
+	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
+)
+
+func TestLoginOidc(t *testing.T) {
+	iss := mocks.MockOidcIssuerStart()
+	defer iss.Close()
+
+	oldProviders := app.Config.OIDC_PROVIDERS
+	app.Config.OIDC_PROVIDERS = []app.ConfigOidcProvider{{
+		ID:           "test",
+		Name:         "Test",
+		Issuer:       iss.URL(),
+		ClientID:     iss.ClientID,
+		ClientSecret: iss.ClientSecret,
+	}}
+	auth.OidcInit()
+	defer func() {
+		app.Config.OIDC_PROVIDERS = oldProviders
+		auth.OidcInit()
+	}()
+
+	// follows the login flow up to the redirect to the website
+	login := func(t *testing.T, user mocks.MockOidcUser) (*url.URL, *http.Response) {
+		iss.SetUser(user)
+
+		c, resultFunc := mocks.MockGinContext(db, http.MethodGet, "/v2/login/oidc/test", nil, "")
+		router.HandleContext(c)
+		result := resultFunc()
+		require.Equal(t, http.StatusFound, result.Response.StatusCode, result.Body)
+
+		callback, err := iss.Authorize(result.Response.Header.Get("Location"))
+		require.NoError(t, err)
+
+		c, resultFunc = mocks.MockGinContext(db, http.MethodGet, "/v2/login/oidc/test/callback?"+callback.RawQuery, nil, "")
+		router.HandleContext(c)
+		result = resultFunc()
+		require.Equal(t, http.StatusFound, result.Response.StatusCode, result.Body)
+
+		location, err := url.Parse(result.Response.Header.Get("Location"))
+		require.NoError(t, err)
+		return location, result.Response
+	}
+
+	cookie := func(res *http.Response, name string) string {
+		for _, c := range res.Cookies() {
+			if c.Name == name {
+				return c.Value
+			}
+		}
+		return ""
+	}
+
+	t.Run("List providers", func(t *testing.T) {
+		c, resultFunc := mocks.MockGinContext(db, http.MethodGet, "/v2/login/oidc", nil, "")
+		router.HandleContext(c)
+		result := resultFunc()
+		assert.Equal(t, http.StatusOK, result.Response.StatusCode)
+		assert.JSONEq(t, `[{"id":"test","name":"Test"}]`, result.Body)
+	})
+
+	t.Run("Unknown provider", func(t *testing.T) {
+		c, resultFunc := mocks.MockGinContext(db, http.MethodGet, "/v2/login/oidc/unknown", nil, "")
+		router.HandleContext(c)
+		result := resultFunc()
+		assert.Equal(t, http.StatusNotFound, result.Response.StatusCode)
+	})
+
+	_, user, _ := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+	subject := faker.UUID().V4()
+
+	t.Run("Link by verified email", func(t *testing.T) {
+		location, res := login(t, mocks.MockOidcUser{Subject: subject, Email: user.Email.String, EmailVerified: true})
+		assert.Equal(t, "/users/login/validate", location.Path)
+		assert.Equal(t, user.UID, location.Query().Get("oidc"))
+		assert.NotEmpty(t, cookie(res, "refresh_token"))
+
+		// the session is created by the callback
+		token := cookie(res, "token")
+		require.NotEmpty(t, token)
+		c, resultFunc := mocks.MockGinContext(db, http.MethodGet, "/v2/user?user_uid="+user.UID, nil, token)
+		router.HandleContext(c)
+		result := resultFunc()
+		assert.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+		assert.Equal(t, user.UID, result.BodyJSON()["uid"])
+	})
+
+	t.Run("Login by linked subject", func(t *testing.T) {
+		location, res := login(t, mocks.MockOidcUser{Subject: subject, Email: "changed_" + user.Email.String, EmailVerified: true})
+		assert.Equal(t, "/users/login/validate", location.Path)
+		assert.Equal(t, user.UID, location.Query().Get("oidc"))
+		assert.NotEmpty(t, cookie(res, "token"))
+	})
+
+	t.Run("Unverified email", func(t *testing.T) {
+		location, res := login(t, mocks.MockOidcUser{Subject: faker.UUID().V4(), Email: user.Email.String, EmailVerified: false})
+		assert.Equal(t, "/users/login", location.Path)
+		assert.Equal(t, "email_not_verified", location.Query().Get("oidc_error"))
+		assert.Empty(t, cookie(res, "token"))
+	})
+
+	t.Run("Not registered", func(t *testing.T) {
+		location, _ := login(t, mocks.MockOidcUser{Subject: faker.UUID().V4(), Email: "not_registered_" + faker.Internet().Email(), EmailVerified: true})
+		assert.Equal(t, "not_registered", location.Query().Get("oidc_error"))
+	})
+
+	t.Run("Invalid state", func(t *testing.T) {
+		c, resultFunc := mocks.MockGinContext(db, http.MethodGet, "/v2/login/oidc/test/callback?code=abc&state=invalid", nil, "")
+		router.HandleContext(c)
+		result := resultFunc()
+		location, _ := url.Parse(result.Response.Header.Get("Location"))
+		assert.Equal(t, "invalid", location.Query().Get("oidc_error"))
+	})
+}
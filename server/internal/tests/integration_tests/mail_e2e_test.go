@@ -157,6 +157,8 @@ func TestEmailDoYouWantToBeHost(t *testing.T) {
 			lng+" "+faker.Person().Name(),
 			faker.Person().Contact().Email,
 			faker.Company().Name(),
+			faker.UUID().V4(),
+			true,
 		)
 		assert.Nil(t, err)
 	})
@@ -168,6 +170,7 @@ func TestEmailIsYourLoopStillActive(t *testing.T) {
 			lng+" "+faker.Person().Name(),
 			faker.Person().Contact().Email,
 			faker.Company().Name(),
+			faker.UUID().V4(),
 			faker.Person().Name(),
 		)
 		assert.Nil(t, err)
@@ -179,8 +182,9 @@ func TestEmailLoginVerificationWebsite(t *testing.T) {
 		err := views.EmailLoginVerification(c, db,
 			lng+" "+faker.Person().Name(),
 			faker.Person().Contact().Email,
-			faker.UUID().V4(),
-			false, faker.UUID().V4())
+			fmt.Sprintf("%08d", faker.RandomNumber(8)),
+			faker.Lorem().Word(),
+			false)
 		assert.Nil(t, err)
 	})
 }
@@ -190,7 +194,8 @@ func TestEmailLoginVerificationApp(t *testing.T) {
 			lng+" "+faker.Person().Name(),
 			faker.Person().Contact().Email,
 			fmt.Sprintf("%08d", faker.RandomNumber(8)),
-			true, "")
+			faker.Lorem().Word(),
+			true)
 		assert.Nil(t, err)
 	})
 }
@@ -224,8 +229,7 @@ func TestEmailRegisterVerification(t *testing.T) {
 		err := views.EmailRegisterVerification(c, db,
 			lng+" "+faker.Person().Name(),
 			faker.Person().Contact().Email,
-			faker.UUID().V4(),
-			faker.UUID().V4(),
+			faker.Lorem().Word(),
 		)
 		assert.Nil(t, err)
 	})
@@ -242,6 +246,7 @@ func TestEmailSomeoneIsInterestedInJoiningYourLoop(t *testing.T) {
 			faker.Person().Contact().Phone,
 			faker.Address().Address(),
 			[]string{models.SizeEnumWomenMedium, models.SizeEnumWomenLarge, models.SizeEnumMenSmall, models.SizeEnumBaby},
+			[]models.ChainQuestionAnswer{{Question: faker.Lorem().Sentence(5), Answer: faker.Lorem().Sentence(8)}},
 		)
 		assert.Nil(t, err)
 	})
@@ -14,52 +14,6 @@ import (
 	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
 )
 
-func TestOldPendingParticipantsCloseOldChain(t *testing.T) {
-	t.Skip("Skipping as closeChainsWithOldPendingParticipants is commented out")
-	chain, host, _ := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
-		IsChainAdmin:       true,
-		IsOpenToNewMembers: true,
-		IsNotPublished:     false,
-	})
-	participant, _ := mocks.MockUser(t, db, chain.ID, mocks.MockChainAndUserOptions{
-		IsNotApproved: true,
-	})
-
-	db.Exec(`
-UPDATE user_chains SET created_at = (NOW() - INTERVAL 62 DAY)
-WHERE chain_id = ?
-	`, chain.ID)
-
-	controllers.CronMonthly(db)
-
-	resultChain := &models.Chain{}
-	err := db.Raw(`SELECT * FROM chains WHERE id = ?`, chain.ID).Scan(resultChain).Error
-	assert.Nil(t, err)
-	assert.True(t, resultChain.Published)
-
-	resultUserChain := &models.UserChain{}
-	err = db.Raw(`SELECT * FROM user_chains WHERE chain_id = ? AND user_id = ?`, chain.ID, participant.ID).Scan(resultUserChain).Error
-	assert.Nil(t, err)
-	assert.True(t, resultUserChain.LastNotifiedIsUnapprovedAt.Valid, resultUserChain.LastNotifiedIsUnapprovedAt.Time)
-
-	db.Exec(`
-UPDATE user_chains SET last_notified_is_unapproved_at = (NOW() - INTERVAL 32 DAY)
-WHERE chain_id = ?
-	`, chain.ID)
-	db.Exec(`
-UPDATE users SET last_signed_in_at = (NOW() - INTERVAL 95 DAY)
-WHERE id IN ?
-	`, []uint{host.ID, participant.ID})
-
-	controllers.CronMonthly(db)
-
-	resultChain = &models.Chain{}
-	err = db.Raw(`SELECT * FROM chains WHERE id = ?`, chain.ID).Scan(resultChain).Error
-	assert.Nil(t, err)
-	assert.False(t, resultChain.Published, chain)
-	assert.False(t, resultChain.OpenToNewMembers, chain)
-}
-
 func TestOldPendingParticipantsStopAfterEmailApproved(t *testing.T) {
 	chain, _, hostToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{
 		IsChainAdmin:       true,
@@ -101,7 +55,7 @@ WHERE chain_id = ?
 		"user_uid":  participant.UID,
 	}, hostToken)
 
-	controllers.ChainApproveUser(c)
+	router.HandleContext(c)
 
 	// run cron again
 	controllers.CronMonthly(db)
@@ -156,7 +110,7 @@ WHERE chain_id = ?
 	result := resultFunc()
 	assert.Equalf(t, 200, result.Response.StatusCode, "body: %s auth header: %v", result.Body, c.Request.Header.Get("Authorization"))
 
-	controllers.ChainDeleteUnapproved(c)
+	router.HandleContext(c)
 
 	resultUserChain = &models.UserChain{}
 	err = db.Raw(`SELECT * FROM user_chains WHERE chain_id = ? AND user_id = ?`, chain.ID, participant.ID).Scan(resultUserChain).Error
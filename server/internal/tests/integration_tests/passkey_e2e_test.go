@@ -0,0 +1,112 @@
+//go:build !ci
+
+package integration_tests
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/the-clothing-loop/website/server/internal/app"
+	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
+)
+
+func TestPasskey(t *testing.T) {
+	_, user, token := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+
+	siteURL, _ := url.Parse(app.Config.SITE_BASE_URL_FE)
+	authenticator := mocks.MockPasskeyAuthenticatorNew(siteURL.Hostname(), siteURL.Scheme+"://"+siteURL.Host)
+
+	begin := func(t *testing.T, path, token string) gin.H {
+		c, resultFunc := mocks.MockGinContext(db, http.MethodPost, path, nil, token)
+		router.HandleContext(c)
+		result := resultFunc()
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+		return result.BodyJSON()
+	}
+
+	var passkeyUID string
+	t.Run("Register", func(t *testing.T) {
+		options := begin(t, "/v2/user/passkeys/begin", token)
+		challenge := options["challenge"].(string)
+		assert.Equal(t, siteURL.Hostname(), options["rp"].(map[string]any)["id"])
+
+		userHandle, err := base64.RawURLEncoding.DecodeString(options["user"].(map[string]any)["id"].(string))
+		require.NoError(t, err)
+		assert.Equal(t, user.UID, string(userHandle))
+
+		credential := authenticator.Create(challenge, userHandle)
+		c, resultFunc := mocks.MockGinContext(db, http.MethodPost, "/v2/user/passkeys", &gin.H{
+			"name":       "Test device",
+			"credential": credential,
+		}, token)
+		router.HandleContext(c)
+		result := resultFunc()
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+		passkeyUID = result.BodyJSON()["uid"].(string)
+
+		// the challenge is used up
+		c, resultFunc = mocks.MockGinContext(db, http.MethodPost, "/v2/user/passkeys", &gin.H{
+			"name":       "Test device",
+			"credential": credential,
+		}, token)
+		router.HandleContext(c)
+		result = resultFunc()
+		assert.Equal(t, http.StatusBadRequest, result.Response.StatusCode, result.Body)
+	})
+
+	login := func(challenge string) mocks.MockGinContextResponse {
+		credential := authenticator.Get(challenge)
+		c, resultFunc := mocks.MockGinContext(db, http.MethodPost, "/v2/login/passkey", &credential, "")
+		router.HandleContext(c)
+		return resultFunc()
+	}
+
+	t.Run("Login", func(t *testing.T) {
+		options := begin(t, "/v2/login/passkey/begin", "")
+		result := login(options["challenge"].(string))
+		require.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		body := result.BodyJSON()
+		assert.Equal(t, user.UID, body["user"].(map[string]any)["uid"])
+		assert.NotEmpty(t, body["token"])
+		assert.NotEmpty(t, body["refresh_token"])
+	})
+
+	t.Run("Login with unknown challenge", func(t *testing.T) {
+		result := login("unknown")
+		assert.Equal(t, http.StatusUnauthorized, result.Response.StatusCode, result.Body)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		c, resultFunc := mocks.MockGinContext(db, http.MethodGet, "/v2/user/passkeys", nil, token)
+		router.HandleContext(c)
+		result := resultFunc()
+		assert.Equal(t, http.StatusOK, result.Response.StatusCode)
+		assert.Contains(t, result.Body, passkeyUID)
+		assert.Contains(t, result.Body, "Test device")
+		assert.NotContains(t, result.Body, "public_key")
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		_, _, otherToken := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+		c, resultFunc := mocks.MockGinContext(db, http.MethodDelete, fmt.Sprintf("/v2/user/passkeys/%s", passkeyUID), nil, otherToken)
+		router.HandleContext(c)
+		result := resultFunc()
+		assert.Equal(t, http.StatusNotFound, result.Response.StatusCode)
+
+		c, resultFunc = mocks.MockGinContext(db, http.MethodDelete, fmt.Sprintf("/v2/user/passkeys/%s", passkeyUID), nil, token)
+		router.HandleContext(c)
+		result = resultFunc()
+		assert.Equal(t, http.StatusOK, result.Response.StatusCode)
+
+		options := begin(t, "/v2/login/passkey/begin", "")
+		result = login(options["challenge"].(string))
+		assert.Equal(t, http.StatusUnauthorized, result.Response.StatusCode)
+	})
+}
@@ -5,14 +5,25 @@ package integration_tests
 import (
 	"testing"
 
+	"github.com/gin-gonic/gin"
 	Faker "github.com/jaswdr/faker"
+	"github.com/the-clothing-loop/website/server/internal"
 	"github.com/the-clothing-loop/website/server/internal/app"
+	"github.com/the-clothing-loop/website/server/internal/app/auth"
 	"gorm.io/gorm"
 )
 
 var db *gorm.DB
 var faker = Faker.New()
 
+// routes requests through the route policies, use router.HandleContext(c)
+var router = gin.New()
+
 func TestMain(m *testing.M) {
+	router.Use(func(c *gin.Context) {
+		c.Set("DB", db)
+	})
+	internal.RoutesV2(auth.NewRouterGroup(router.Group("/v2")))
+
 	app.RunTestMain(m, &db, "../../..")
 }
@@ -0,0 +1,85 @@
+//go:build !ci
+
+package integration_tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/the-clothing-loop/website/server/internal/models"
+	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
+)
+
+func TestUserEmailChange(t *testing.T) {
+	_, user, token := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+	_, otherUser, _ := mocks.MockChainAndUser(t, db, mocks.MockChainAndUserOptions{})
+	newEmail := "new_" + faker.Person().Contact().Email
+
+	t.Run("Request change of another user", func(t *testing.T) {
+		c, resultFunc := mocks.MockGinContext(db, http.MethodPost, "/v2/user/email-change", &gin.H{
+			"user_uid": otherUser.UID,
+			"email":    newEmail,
+		}, token)
+		router.HandleContext(c)
+		result := resultFunc()
+		assert.Equal(t, http.StatusUnauthorized, result.Response.StatusCode, result.Body)
+	})
+
+	t.Run("Request change to an email in use", func(t *testing.T) {
+		c, resultFunc := mocks.MockGinContext(db, http.MethodPost, "/v2/user/email-change", &gin.H{
+			"user_uid": user.UID,
+			"email":    otherUser.Email.String,
+		}, token)
+		router.HandleContext(c)
+		result := resultFunc()
+		assert.Equal(t, http.StatusConflict, result.Response.StatusCode, result.Body)
+	})
+
+	t.Run("Request change", func(t *testing.T) {
+		c, resultFunc := mocks.MockGinContext(db, http.MethodPost, "/v2/user/email-change", &gin.H{
+			"user_uid": user.UID,
+			"email":    newEmail,
+		}, token)
+		router.HandleContext(c)
+		result := resultFunc()
+		assert.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+	})
+
+	// the token is only sent by email, so a new change is created to retrieve it
+	changeToken, err := models.UserEmailChangeCreate(db, user.ID, user.ID, newEmail)
+	assert.NoError(t, err)
+
+	t.Run("Confirm with invalid token", func(t *testing.T) {
+		c, resultFunc := mocks.MockGinContext(db, http.MethodPost, "/v2/user/email-change/confirm", &gin.H{
+			"token": "invalid",
+		}, "")
+		router.HandleContext(c)
+		result := resultFunc()
+		assert.Equal(t, http.StatusNotFound, result.Response.StatusCode, result.Body)
+	})
+
+	t.Run("Confirm", func(t *testing.T) {
+		c, resultFunc := mocks.MockGinContext(db, http.MethodPost, "/v2/user/email-change/confirm", &gin.H{
+			"token": changeToken,
+		}, "")
+		router.HandleContext(c)
+		result := resultFunc()
+		assert.Equal(t, http.StatusOK, result.Response.StatusCode, result.Body)
+
+		updatedUser, err := models.UserGetByUID(db, user.UID, false)
+		assert.NoError(t, err)
+		assert.Equal(t, newEmail, updatedUser.Email.String)
+		assert.True(t, updatedUser.IsEmailVerified)
+	})
+
+	t.Run("Token is single use", func(t *testing.T) {
+		c, resultFunc := mocks.MockGinContext(db, http.MethodPost, "/v2/user/email-change/confirm", &gin.H{
+			"token": changeToken,
+		}, "")
+		router.HandleContext(c)
+		result := resultFunc()
+		assert.Equal(t, http.StatusNotFound, result.Response.StatusCode, result.Body)
+	})
+}
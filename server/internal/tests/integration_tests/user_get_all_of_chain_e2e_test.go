@@ -9,7 +9,6 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
-	"github.com/the-clothing-loop/website/server/internal/controllers"
 	"github.com/the-clothing-loop/website/server/internal/models"
 	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
 )
@@ -34,7 +33,7 @@ func TestUserGetAllOfChainRoutePrivacyHost(t *testing.T) {
 		url := fmt.Sprintf("/v2/user/all-chain?&chain_uid=%s", chain.UID)
 		c, resultFunc := mocks.MockGinContext(db, http.MethodGet, url, nil, token4)
 
-		controllers.UserGetAllOfChain(c)
+		router.HandleContext(c)
 		result := resultFunc()
 		actualUsers := &[]*models.User{}
 		json.Unmarshal([]byte(result.Body), actualUsers)
@@ -67,7 +66,7 @@ func TestUserGetAllOfChainRoutePrivacyHost(t *testing.T) {
 		url := fmt.Sprintf("/v2/user/all-chain?&chain_uid=%s", chain.UID)
 		c, resultFunc := mocks.MockGinContext(db, http.MethodGet, url, nil, token1)
 
-		controllers.UserGetAllOfChain(c)
+		router.HandleContext(c)
 		result := resultFunc()
 		actualUsers := &[]*models.User{}
 		json.Unmarshal([]byte(result.Body), actualUsers)
@@ -101,7 +100,7 @@ func TestUserGetAllOfChainRoutePrivacyOverflowCase(t *testing.T) {
 	url := fmt.Sprintf("/v2/user/all-chain?&chain_uid=%s", chain.UID)
 	c, resultFunc := mocks.MockGinContext(db, http.MethodGet, url, nil, token)
 
-	controllers.UserGetAllOfChain(c)
+	router.HandleContext(c)
 	result := resultFunc()
 	actualUsers := &[]*models.User{}
 	json.Unmarshal([]byte(result.Body), actualUsers)
@@ -137,7 +136,7 @@ func TestUserGetAllOfChainRoutePrivacySmallChain(t *testing.T) {
 		url := fmt.Sprintf("/v2/user/all-chain?&chain_uid=%s", chain.UID)
 		c, resultFunc := mocks.MockGinContext(db, http.MethodGet, url, nil, token)
 
-		controllers.UserGetAllOfChain(c)
+		router.HandleContext(c)
 		result := resultFunc()
 		actualUsers := &[]*models.User{}
 		json.Unmarshal([]byte(result.Body), actualUsers)
@@ -158,7 +157,7 @@ func TestUserGetAllOfChainRoutePrivacySmallChain(t *testing.T) {
 		url := fmt.Sprintf("/v2/user/all-chain?&chain_uid=%s", chain.UID)
 		c, resultFunc := mocks.MockGinContext(db, http.MethodGet, url, nil, token)
 
-		controllers.UserGetAllOfChain(c)
+		router.HandleContext(c)
 		result := resultFunc()
 		actualUsers := &[]*models.User{}
 		json.Unmarshal([]byte(result.Body), actualUsers)
@@ -192,7 +191,7 @@ func TestUserGetAllOfChainRoutePrivacyPausedUsers(t *testing.T) {
 	url := fmt.Sprintf("/v2/user/all-chain?&chain_uid=%s", chain.UID)
 	c, resultFunc := mocks.MockGinContext(db, http.MethodGet, url, nil, token)
 
-	controllers.UserGetAllOfChain(c)
+	router.HandleContext(c)
 	result := resultFunc()
 	actualUsers := &[]*models.User{}
 	json.Unmarshal([]byte(result.Body), actualUsers)
@@ -230,7 +229,7 @@ func TestUserGetAllOfChainRoutePrivacyEqual0(t *testing.T) {
 	url := fmt.Sprintf("/v2/user/all-chain?&chain_uid=%s", chain.UID)
 	c, resultFunc := mocks.MockGinContext(db, http.MethodGet, url, nil, token)
 
-	controllers.UserGetAllOfChain(c)
+	router.HandleContext(c)
 	result := resultFunc()
 	actualUsers := &[]*models.User{}
 	json.Unmarshal([]byte(result.Body), actualUsers)
@@ -271,7 +270,7 @@ func TestUserGetAllOfChainRoutePrivacyEqualMinusOne(t *testing.T) {
 	url := fmt.Sprintf("/v2/user/all-chain?&chain_uid=%s", chain.UID)
 	c, resultFunc := mocks.MockGinContext(db, http.MethodGet, url, nil, token)
 
-	controllers.UserGetAllOfChain(c)
+	router.HandleContext(c)
 	result := resultFunc()
 	actualUsers := &[]*models.User{}
 	json.Unmarshal([]byte(result.Body), actualUsers)
@@ -9,7 +9,6 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/the-clothing-loop/website/server/internal/app/auth"
-	"github.com/the-clothing-loop/website/server/internal/controllers"
 	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
 )
 
@@ -24,14 +23,14 @@ func TestUserGetUID(t *testing.T) {
 	c, resultFunc := mocks.MockGinContext(db, http.MethodGet, url, nil, token)
 
 	// run sut
-	controllers.UserGet(c)
+	router.HandleContext(c)
 
 	// retrieve result
 	result := resultFunc()
 	bodyJSON := result.BodyJSON()
 
 	// test
-	tokenReq, _ := auth.TokenReadFromRequest(c)
+	tokenReq, _, _ := auth.TokenReadFromRequest(c)
 	assert.Equal(t, token, tokenReq)
 
 	// assert.FailNowf(t, "testing", "status: %d, body: ~%s~, token: %s, header: %s", result.Response.StatusCode, result.Body, token, c.Request.Header.Get("Authorization"))
@@ -9,7 +9,6 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
-	"github.com/the-clothing-loop/website/server/internal/controllers"
 	"github.com/the-clothing-loop/website/server/internal/models"
 	"github.com/the-clothing-loop/website/server/internal/tests/mocks"
 )
@@ -45,7 +44,7 @@ func TestUserTransferChain(t *testing.T) {
 			}, token)
 
 			// run sut
-			controllers.UserTransferChain(c)
+			router.HandleContext(c)
 
 			// retrieve result
 			result := resultFunc()
@@ -70,6 +69,13 @@ SELECT (
 
 			assert.Equal(t, lo.Ternary(isCopy, 1, 0), valuesExist.ExistInChain1)
 			assert.Equal(t, 1, valuesExist.ExistInChain2)
+
+			// a copy does not change the history of the source loop
+			count := -1
+			db.Raw(`SELECT COUNT(*) FROM chain_audit_logs WHERE chain_id = ? AND target_user_id = ? AND action = ?`, chain1.ID, participant.ID, models.ChainAuditActionUserTransfer).Scan(&count)
+			assert.Equal(t, lo.Ternary(isCopy, 0, 1), count)
+			db.Raw(`SELECT COUNT(*) FROM chain_audit_logs WHERE chain_id = ? AND target_user_id = ? AND action = ?`, chain2.ID, participant.ID, models.ChainAuditActionUserTransfer).Scan(&count)
+			assert.Equal(t, 1, count)
 		})
 	}
 }
@@ -11,19 +11,19 @@ import (
 	"gorm.io/gorm"
 )
 
-type mockGinContextResponse struct {
+type MockGinContextResponse struct {
 	Response *http.Response
 	Body     string
 }
 
-func (r mockGinContextResponse) BodyJSON() gin.H {
+func (r MockGinContextResponse) BodyJSON() gin.H {
 	body := gin.H{}
 	json.Unmarshal([]byte(r.Body), &body)
 
 	return body
 }
 
-func MockGinContext(db *gorm.DB, method string, url string, bodyJSON *gin.H, token string) (*gin.Context, func() mockGinContextResponse) {
+func MockGinContext(db *gorm.DB, method string, url string, bodyJSON *gin.H, token string) (*gin.Context, func() MockGinContextResponse) {
 	body := bytes.NewBuffer([]byte{})
 	if bodyJSON != nil {
 		json_data, _ := json.Marshal(bodyJSON)
@@ -42,10 +42,10 @@ func MockGinContext(db *gorm.DB, method string, url string, bodyJSON *gin.H, tok
 	}
 
 	// ro.ServeHTTP(rr, c.Request)
-	resultFunc := func() mockGinContextResponse {
+	resultFunc := func() MockGinContextResponse {
 		body := rr.Body.String()
 		res := rr.Result()
-		return mockGinContextResponse{
+		return MockGinContextResponse{
 			Response: res,
 			Body:     body,
 		}
@@ -0,0 +1,221 @@
+package mocks
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const mockOidcKeyID = "mock-oidc"
+
+type MockOidcUser struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// A local OpenID Connect issuer for tests.
+// The authorization endpoint does not show a login page,
+// it immediately redirects back with a code for the configured user.
+type MockOidcIssuer struct {
+	Server       *httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	mu sync.Mutex
+	// The user that is logged in by the authorization endpoint
+	user  MockOidcUser
+	key   *rsa.PrivateKey
+	codes map[string]mockOidcAuthRequest
+}
+
+type mockOidcAuthRequest struct {
+	user          MockOidcUser
+	clientID      string
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+}
+
+// Starts a new issuer, call Close when done
+func MockOidcIssuerStart() *MockOidcIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	iss := &MockOidcIssuer{
+		ClientID:     "mock-oidc-client",
+		ClientSecret: "mock-oidc-secret",
+		key:          key,
+		codes:        map[string]mockOidcAuthRequest{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", iss.handleDiscovery)
+	mux.HandleFunc("/authorize", iss.handleAuthorize)
+	mux.HandleFunc("/token", iss.handleToken)
+	mux.HandleFunc("/jwks", iss.handleJwks)
+	iss.Server = httptest.NewServer(mux)
+
+	return iss
+}
+
+func (iss *MockOidcIssuer) Close() {
+	iss.Server.Close()
+}
+
+func (iss *MockOidcIssuer) URL() string {
+	return iss.Server.URL
+}
+
+// Sets the user that is logged in by the following authorization requests
+func (iss *MockOidcIssuer) SetUser(user MockOidcUser) {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+	iss.user = user
+}
+
+// Follows the authorization url like a browser would and returns the url
+// the user is redirected back to, containing the code and state.
+func (iss *MockOidcIssuer) Authorize(authCodeURL string) (*url.URL, error) {
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	res, err := client.Get(authCodeURL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	return res.Location()
+}
+
+func (iss *MockOidcIssuer) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	mockOidcWriteJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                iss.URL(),
+		"authorization_endpoint":                iss.URL() + "/authorize",
+		"token_endpoint":                        iss.URL() + "/token",
+		"jwks_uri":                              iss.URL() + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (iss *MockOidcIssuer) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != iss.ClientID || q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	b := make([]byte, 32)
+	rand.Read(b)
+	code := base64.RawURLEncoding.EncodeToString(b)
+	iss.mu.Lock()
+	iss.codes[code] = mockOidcAuthRequest{
+		user:          iss.user,
+		clientID:      q.Get("client_id"),
+		redirectURI:   q.Get("redirect_uri"),
+		nonce:         q.Get("nonce"),
+		codeChallenge: q.Get("code_challenge"),
+	}
+	iss.mu.Unlock()
+
+	rq := redirectURI.Query()
+	rq.Set("code", code)
+	rq.Set("state", q.Get("state"))
+	redirectURI.RawQuery = rq.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (iss *MockOidcIssuer) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		mockOidcWriteJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != iss.ClientID || clientSecret != iss.ClientSecret {
+		mockOidcWriteJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	// codes can only be used once
+	iss.mu.Lock()
+	req, ok := iss.codes[r.PostForm.Get("code")]
+	delete(iss.codes, r.PostForm.Get("code"))
+	iss.mu.Unlock()
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" ||
+		req.redirectURI != r.PostForm.Get("redirect_uri") ||
+		req.codeChallenge != mockOidcCodeChallenge(r.PostForm.Get("code_verifier")) {
+		mockOidcWriteJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            iss.URL(),
+		"sub":            req.user.Subject,
+		"aud":            req.clientID,
+		"exp":            time.Now().Add(5 * time.Minute).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          req.nonce,
+		"email":          req.user.Email,
+		"email_verified": req.user.EmailVerified,
+		"name":           req.user.Name,
+	})
+	token.Header["kid"] = mockOidcKeyID
+	idToken, err := token.SignedString(iss.key)
+	if err != nil {
+		mockOidcWriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	mockOidcWriteJSON(w, http.StatusOK, map[string]any{
+		"access_token": "oidctest-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (iss *MockOidcIssuer) handleJwks(w http.ResponseWriter, r *http.Request) {
+	pub := iss.key.PublicKey
+	mockOidcWriteJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kid": mockOidcKeyID,
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func mockOidcCodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func mockOidcWriteJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,136 @@
+package mocks
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+)
+
+// A software ES256 authenticator holding a single passkey, it always verifies the user.
+// Create and Get return the PublicKeyCredential json the frontend sends to the server.
+type MockPasskeyAuthenticator struct {
+	RPID   string
+	Origin string
+	// Set to the user handle passed to Create
+	UserHandle   []byte
+	CredentialID []byte
+	SignCount    uint32
+
+	key *ecdsa.PrivateKey
+}
+
+func MockPasskeyAuthenticatorNew(rpID, origin string) *MockPasskeyAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	return &MockPasskeyAuthenticator{
+		RPID:         rpID,
+		Origin:       origin,
+		CredentialID: id,
+		key:          key,
+	}
+}
+
+// Like navigator.credentials.create()
+func (a *MockPasskeyAuthenticator) Create(challenge string, userHandle []byte) gin.H {
+	a.UserHandle = userHandle
+
+	coseKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// user present, user verified and attested credential data included
+	authData := a.authData(0x01 | 0x04 | 0x40)
+	authData = append(authData, make([]byte, 16)...) // aaguid
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.CredentialID)))
+	authData = append(authData, a.CredentialID...)
+	authData = append(authData, coseKey...)
+
+	attestationObject, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": authData,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return a.credential(gin.H{
+		"clientDataJSON":    mockPasskeyEncode(a.clientData("webauthn.create", challenge)),
+		"attestationObject": mockPasskeyEncode(attestationObject),
+	})
+}
+
+// Like navigator.credentials.get()
+func (a *MockPasskeyAuthenticator) Get(challenge string) gin.H {
+	a.SignCount++
+	clientDataJSON := a.clientData("webauthn.get", challenge)
+	// user present and user verified
+	authData := a.authData(0x01 | 0x04)
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	hash := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, hash[:])
+	if err != nil {
+		panic(err)
+	}
+
+	return a.credential(gin.H{
+		"clientDataJSON":    mockPasskeyEncode(clientDataJSON),
+		"authenticatorData": mockPasskeyEncode(authData),
+		"signature":         mockPasskeyEncode(signature),
+		"userHandle":        mockPasskeyEncode(a.UserHandle),
+	})
+}
+
+func (a *MockPasskeyAuthenticator) credential(response gin.H) gin.H {
+	id := mockPasskeyEncode(a.CredentialID)
+	return gin.H{
+		"id":       id,
+		"rawId":    id,
+		"type":     "public-key",
+		"response": response,
+	}
+}
+
+func (a *MockPasskeyAuthenticator) clientData(ceremony, challenge string) []byte {
+	b, _ := json.Marshal(map[string]any{
+		"type":      ceremony,
+		"challenge": challenge,
+		"origin":    a.Origin,
+	})
+	return b
+}
+
+func (a *MockPasskeyAuthenticator) authData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+	b := append([]byte{}, rpIDHash[:]...)
+	b = append(b, flags)
+	return binary.BigEndian.AppendUint32(b, a.SignCount)
+}
+
+// Binary fields are base64url encoded like the frontend does
+func mockPasskeyEncode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
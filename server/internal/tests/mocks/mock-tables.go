@@ -31,6 +31,8 @@ type MockChainAndUserOptions struct {
 	IsNotApproved      bool
 	IsRootAdmin        bool
 	IsChainAdmin       bool
+	// Ignored when IsChainAdmin is set, which is the host role
+	Role               string
 	IsNotPublished     bool
 	IsOpenToNewMembers bool
 	RoutePrivacy       *int
@@ -79,6 +81,7 @@ func MockUser(t *testing.T, db *gorm.DB, chainID uint, o MockChainAndUserOptions
 		chains = append(chains, models.UserChain{
 			ChainID:      chainID,
 			IsChainAdmin: o.IsChainAdmin,
+			Role:         o.Role,
 			IsApproved:   !o.IsNotApproved,
 			RouteOrder:   o.RouteOrderIndex,
 		})
@@ -117,11 +120,11 @@ func MockUser(t *testing.T, db *gorm.DB, chainID uint, o MockChainAndUserOptions
 	}
 
 	if !o.IsNotTokenVerified {
-		var err error
-		token, err = auth.JwtGenerate(user)
+		tokens, err := auth.SessionLogin(db, user, auth.SessionInfo{DeviceName: "mock"})
 		if err != nil {
 			glog.Fatalf("Unable to generate token: %v", err)
 		}
+		token = tokens.Access
 	}
 
 	t.Cleanup(func() {
@@ -131,6 +134,18 @@ func MockUser(t *testing.T, db *gorm.DB, chainID uint, o MockChainAndUserOptions
 		)`, chainID, user.ID)
 		tx.Exec(`DELETE FROM user_chains WHERE user_id = ? OR chain_id = ?`, user.ID, chainID)
 		tx.Exec(`DELETE FROM user_tokens WHERE user_id = ?`, user.ID)
+		tx.Exec(`DELETE FROM user_refresh_tokens WHERE user_id = ?`, user.ID)
+		tx.Exec(`DELETE FROM user_sessions WHERE user_id = ?`, user.ID)
+		tx.Exec(`DELETE FROM user_email_changes WHERE user_id = ?`, user.ID)
+		tx.Exec(`DELETE FROM user_oidc_identities WHERE user_id = ?`, user.ID)
+		tx.Exec(`DELETE FROM user_passkeys WHERE user_id = ?`, user.ID)
+		tx.Exec(`DELETE FROM api_keys WHERE created_by_user_id = ?`, user.ID)
+		tx.Exec(`DELETE FROM chain_host_invitations WHERE user_id = ? OR invited_by_user_id = ?`, user.ID, user.ID)
+		tx.Exec(`DELETE FROM chain_waitlists WHERE user_id = ?`, user.ID)
+		tx.Exec(`DELETE FROM chain_audit_logs WHERE actor_user_id = ? OR target_user_id = ?`, user.ID, user.ID)
+		tx.Exec(`DELETE FROM chain_question_answers WHERE user_id = ?`, user.ID)
+		tx.Exec(`DELETE FROM chain_invites WHERE created_by_user_id = ?`, user.ID)
+		tx.Exec(`DELETE FROM user_impersonation_logs WHERE user_id = ? OR admin_user_id = ?`, user.ID, user.ID)
 		tx.Exec(`DELETE FROM users WHERE id = ?`, user.ID)
 		tx.Commit()
 	})
@@ -176,6 +191,13 @@ func MockChainAndUser(t *testing.T, db *gorm.DB, o MockChainAndUserOptions) (cha
 	// Cleanup runs FiLo
 	// So Cleanup must happen before MockUser
 	t.Cleanup(func() {
+		db.Exec(`DELETE FROM api_keys WHERE chain_id = ?`, chain.ID)
+		db.Exec(`DELETE FROM chain_host_invitations WHERE chain_id = ?`, chain.ID)
+		db.Exec(`DELETE FROM chain_waitlists WHERE chain_id = ?`, chain.ID)
+		db.Exec(`DELETE FROM chain_audit_logs WHERE chain_id = ?`, chain.ID)
+		db.Exec(`DELETE FROM chain_question_answers WHERE chain_id = ?`, chain.ID)
+		db.Exec(`DELETE FROM chain_questions WHERE chain_id = ?`, chain.ID)
+		db.Exec(`DELETE FROM chain_invites WHERE chain_id = ?`, chain.ID)
 		db.Exec(`DELETE FROM chains WHERE id = ?`, chain.ID)
 	})
 
@@ -3,7 +3,6 @@ package views
 import (
 	"bytes"
 	"embed"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"html/template"
@@ -229,6 +228,48 @@ func EmailApproveReminder(db *gorm.DB, lng,
 	return app.MailSend(db, m)
 }
 
+func EmailChangeEmailConfirmation(c *gin.Context, db *gorm.DB,
+	name,
+	newEmail,
+	token string,
+) error {
+	i18n := getI18nGin(c)
+	m := app.MailCreate()
+	m.ToName = name
+	m.ToAddress = newEmail
+	err := emailGenerateMessage(m, i18n, "change_email_confirmation", gin.H{
+		"Name":    name,
+		"BaseURL": app.Config.SITE_BASE_URL_FE,
+		"Token":   token,
+	})
+	if err != nil {
+		return err
+	}
+
+	return app.MailSend(db, m)
+}
+
+func EmailChangeEmailNotice(db *gorm.DB, lng,
+	name,
+	email,
+	newEmail string,
+) error {
+	lng = getI18n(lng)
+	m := app.MailCreate()
+	m.MaxRetryAttempts = models.MAIL_RETRY_TWO_DAYS
+	m.ToName = name
+	m.ToAddress = email
+	err := emailGenerateMessage(m, lng, "change_email_notice", gin.H{
+		"Name":     name,
+		"NewEmail": newEmail,
+	})
+	if err != nil {
+		return err
+	}
+
+	return app.MailSend(db, m)
+}
+
 func EmailContactConfirmation(c *gin.Context, db *gorm.DB,
 	name,
 	email,
@@ -269,10 +310,13 @@ func EmailContactReceived(db *gorm.DB,
 	return app.MailSend(db, m)
 }
 
+// An orphaned loop is a loop of which the last host left, participants can volunteer to become host
 func EmailDoYouWantToBeHost(db *gorm.DB, lng,
 	name,
 	email,
-	chainName string,
+	chainName,
+	chainUID string,
+	isOrphaned bool,
 ) error {
 	lng = getI18n(lng)
 	m := app.MailCreate()
@@ -282,6 +326,10 @@ func EmailDoYouWantToBeHost(db *gorm.DB, lng,
 	err := emailGenerateMessage(m, lng, "do_you_want_to_be_host", gin.H{
 		"Name":        name,
 		"ChainName":   chainName,
+		"ChainUID":    chainUID,
+		"IsOrphaned":  isOrphaned,
+		"GraceDays":   models.ChainOrphanedGraceDays,
+		"BaseURL":     app.Config.SITE_BASE_URL_FE,
 		"ToolkitLink": "https://drive.google.com/drive/folders/1iMJzIcBxgApKx89hcaHhhuP5YAs_Yb27",
 	})
 	if err != nil {
@@ -291,10 +339,37 @@ func EmailDoYouWantToBeHost(db *gorm.DB, lng,
 	return app.MailSend(db, m)
 }
 
+func EmailHostInvitation(db *gorm.DB, lng,
+	name,
+	email,
+	chainName,
+	invitedByName,
+	invitationUID string,
+) error {
+	lng = getI18n(lng)
+	m := app.MailCreate()
+	m.MaxRetryAttempts = models.MAIL_RETRY_TWO_DAYS
+	m.ToName = name
+	m.ToAddress = email
+	err := emailGenerateMessage(m, lng, "host_invitation", gin.H{
+		"Name":          name,
+		"ChainName":     chainName,
+		"InvitedByName": invitedByName,
+		"BaseURL":       app.Config.SITE_BASE_URL_FE,
+		"InvitationUID": invitationUID,
+	}, chainName)
+	if err != nil {
+		return err
+	}
+
+	return app.MailSend(db, m)
+}
+
 func EmailIsYourLoopStillActive(db *gorm.DB, lng,
 	name,
 	email,
 	chainName,
+	chainUID,
 	participantName string,
 ) error {
 	lng = getI18n(lng)
@@ -306,6 +381,28 @@ func EmailIsYourLoopStillActive(db *gorm.DB, lng,
 		"Name":            name,
 		"ParticipantName": participantName,
 		"ChainName":       chainName,
+		"ChainUID":        chainUID,
+		"BaseURL":         app.Config.SITE_BASE_URL_FE,
+	})
+	if err != nil {
+		return err
+	}
+
+	return app.MailSend(db, m)
+}
+
+func EmailLoginLocked(db *gorm.DB, lng,
+	name,
+	email,
+	lockedUntil string,
+) error {
+	lng = getI18n(lng)
+	m := app.MailCreate()
+	m.ToName = name
+	m.ToAddress = email
+	err := emailGenerateMessage(m, lng, "login_locked", gin.H{
+		"Name":        name,
+		"LockedUntil": lockedUntil,
 	})
 	if err != nil {
 		return err
@@ -314,31 +411,28 @@ func EmailIsYourLoopStillActive(db *gorm.DB, lng,
 	return app.MailSend(db, m)
 }
 
+// The app shows a field to enter the one time password,
+// the website uses the link token instead.
 func EmailLoginVerification(c *gin.Context, db *gorm.DB,
 	name,
 	email,
-	token string,
+	otp,
+	linkToken string,
 	isApp bool,
-	chainUID string,
 ) error {
 	i18n := getI18nGin(c)
 	m := app.MailCreate()
 	m.ToName = name
 	m.ToAddress = email
 
-	// This is a hack to add the chain param to the url
-	// Changing this in the template would be more work in combination with Crowdin
-	if !isApp {
-		emailBase64 := base64.StdEncoding.EncodeToString([]byte(email))
-		token += "&u=" + emailBase64
-		if chainUID != "" {
-			token += "&c=" + chainUID
-		}
+	token := linkToken
+	if isApp {
+		token = otp
 	}
 	err := emailGenerateMessage(m, i18n, "login_verification", gin.H{
 		"Name":    name,
 		"BaseURL": app.Config.SITE_BASE_URL_FE,
-		"Token":   template.URL(token),
+		"Token":   token,
 		"IsApp":   isApp,
 	})
 	if err != nil {
@@ -360,9 +454,10 @@ func EmailLoopIsDeleted(db *gorm.DB, lng,
 	m.ToName = name
 	m.ToAddress = email
 	err := emailGenerateMessage(m, lng, "loop_is_deleted", gin.H{
-		"Name":      name,
-		"ChainName": chainName,
-		"IsPending": isPending,
+		"Name":        name,
+		"ChainName":   chainName,
+		"IsPending":   isPending,
+		"RestoreDays": models.ChainDeletedRetentionDays,
 	})
 	if err != nil {
 		return err
@@ -397,8 +492,7 @@ func EmailPoke(db *gorm.DB, lng,
 func EmailRegisterVerification(c *gin.Context, db *gorm.DB,
 	name,
 	email,
-	token,
-	chainUID string,
+	linkToken string,
 ) error {
 	i18n := getI18nGin(c)
 	m := app.MailCreate()
@@ -406,16 +500,10 @@ func EmailRegisterVerification(c *gin.Context, db *gorm.DB,
 	m.ToName = name
 	m.ToAddress = email
 
-	emailBase64 := base64.StdEncoding.EncodeToString([]byte(email))
-	token += "&u=" + emailBase64
-	if chainUID != "" {
-		token += "&c=" + chainUID
-	}
-
 	err := emailGenerateMessage(m, i18n, "register_verification", gin.H{
 		"Name":    name,
 		"BaseURL": app.Config.SITE_BASE_URL_FE,
-		"Token":   template.URL(token),
+		"Token":   linkToken,
 	})
 	if err != nil {
 		return err
@@ -432,6 +520,7 @@ func EmailSomeoneIsInterestedInJoiningYourLoop(db *gorm.DB, lng,
 	participantPhoneNumber,
 	participantAddress string,
 	participantSizeEnums []string,
+	participantAnswers []models.ChainQuestionAnswer,
 ) error {
 	lng = getI18n(lng)
 
@@ -478,6 +567,7 @@ func EmailSomeoneIsInterestedInJoiningYourLoop(db *gorm.DB, lng,
 			"Phone":   participantPhoneNumber,
 			"Address": participantAddress,
 			"Sizes":   template.HTML(sizesHtml),
+			"Answers": participantAnswers,
 		},
 	})
 	if err != nil {
@@ -547,6 +637,78 @@ func EmailSubscribeToNewsletter(c *gin.Context, db *gorm.DB,
 	return app.MailSend(db, m)
 }
 
+func EmailLoopMerged(db *gorm.DB, lng,
+	name,
+	email,
+	sourceChainName,
+	targetChainName string,
+) error {
+	lng = getI18n(lng)
+	m := app.MailCreate()
+	m.MaxRetryAttempts = models.MAIL_RETRY_TWO_DAYS
+	m.ToName = name
+	m.ToAddress = email
+	err := emailGenerateMessage(m, lng, "loop_merged", gin.H{
+		"Name":            name,
+		"SourceChainName": sourceChainName,
+		"TargetChainName": targetChainName,
+	}, sourceChainName, targetChainName)
+	if err != nil {
+		return err
+	}
+
+	return app.MailSend(db, m)
+}
+
+func EmailLoopSplit(db *gorm.DB, lng,
+	name,
+	email,
+	originalChainName,
+	newChainName,
+	chainName string,
+) error {
+	lng = getI18n(lng)
+	m := app.MailCreate()
+	m.MaxRetryAttempts = models.MAIL_RETRY_TWO_DAYS
+	m.ToName = name
+	m.ToAddress = email
+	err := emailGenerateMessage(m, lng, "loop_split", gin.H{
+		"Name":              name,
+		"OriginalChainName": originalChainName,
+		"NewChainName":      newChainName,
+		"ChainName":         chainName,
+	}, originalChainName)
+	if err != nil {
+		return err
+	}
+
+	return app.MailSend(db, m)
+}
+
+func EmailWaitlistLoopOpen(db *gorm.DB, lng,
+	name,
+	email,
+	chainName,
+	chainUID string,
+) error {
+	lng = getI18n(lng)
+	m := app.MailCreate()
+	m.MaxRetryAttempts = models.MAIL_RETRY_TWO_DAYS
+	m.ToName = name
+	m.ToAddress = email
+	err := emailGenerateMessage(m, lng, "waitlist_loop_open", gin.H{
+		"Name":      name,
+		"ChainName": chainName,
+		"BaseURL":   app.Config.SITE_BASE_URL_FE,
+		"ChainUID":  chainUID,
+	}, chainName)
+	if err != nil {
+		return err
+	}
+
+	return app.MailSend(db, m)
+}
+
 func EmailYouSignedUpForLoop(db *gorm.DB, lng,
 	name,
 	email,
@@ -95,6 +95,25 @@ func TestEmailFormattingByLanguage(t *testing.T) {
 			DataExpected: []string{"Name", "BaseURL", "Approvals[0].Name", "Approvals[0].ChainName"},
 			Args:         []any{},
 		},
+		{
+			Name: "change_email_confirmation",
+			Data: map[string]any{
+				"Name":    faker.Person().Name(),
+				"BaseURL": faker.Internet().URL(),
+				"Token":   faker.UUID().V4(),
+			},
+			DataExpected: []string{"Name", "BaseURL", "Token"},
+			Args:         []any{},
+		},
+		{
+			Name: "change_email_notice",
+			Data: map[string]any{
+				"Name":     faker.Person().Name(),
+				"NewEmail": faker.Internet().Email(),
+			},
+			DataExpected: []string{"Name", "NewEmail"},
+			Args:         []any{},
+		},
 		{
 			Name: "contact_confirmation",
 			Data: map[string]any{
@@ -124,14 +143,42 @@ func TestEmailFormattingByLanguage(t *testing.T) {
 			DataExpected: []string{"Name", "ChainName"},
 			Args:         []any{},
 		},
+		{
+			Name: "do_you_want_to_be_host",
+			Data: map[string]any{
+				"Name":        faker.Person().Name(),
+				"ChainName":   faker.Company().Name(),
+				"ChainUID":    faker.UUID().V4(),
+				"IsOrphaned":  true,
+				"GraceDays":   30,
+				"BaseURL":     "https://example.com",
+				"ToolkitLink": "http://" + faker.YouTube().GenerateFullURL(),
+			},
+			DataExpected: []string{"Name", "ChainName", "ChainUID", "GraceDays"},
+			Args:         []any{},
+		},
+		{
+			Name: "host_invitation",
+			Data: map[string]any{
+				"Name":          faker.Person().Name(),
+				"ChainName":     faker.Company().Name(),
+				"InvitedByName": faker.Person().Name(),
+				"BaseURL":       faker.Internet().URL(),
+				"InvitationUID": faker.UUID().V4(),
+			},
+			DataExpected: []string{"Name", "ChainName", "InvitedByName", "BaseURL", "InvitationUID"},
+			Args:         []any{faker.Company().Name()},
+		},
 		{
 			Name: "is_your_loop_still_active",
 			Data: map[string]any{
 				"Name":            faker.Person().Name(),
 				"ParticipantName": faker.Person().Name(),
 				"ChainName":       faker.Company().Name(),
+				"ChainUID":        faker.UUID().V4(),
+				"BaseURL":         "https://example.com",
 			},
-			DataExpected: []string{"Name", "ParticipantName", "ChainName"},
+			DataExpected: []string{"Name", "ParticipantName", "ChainName", "ChainUID"},
 			Args:         []any{},
 		},
 		{
@@ -155,15 +202,46 @@ func TestEmailFormattingByLanguage(t *testing.T) {
 			DataExpected: []string{"Name", "Token", "BaseURL"},
 			Args:         []any{},
 		},
+		{
+			Name: "login_locked",
+			Data: map[string]any{
+				"Name":        faker.Person().Name(),
+				"LockedUntil": "2024-01-01 12:00 UTC",
+			},
+			DataExpected: []string{"Name", "LockedUntil"},
+			Args:         []any{},
+		},
 		{
 			Name: "loop_is_deleted",
 			Data: map[string]any{
-				"Name":      faker.Person().Name(),
-				"ChainName": faker.Company().Name(),
+				"Name":        faker.Person().Name(),
+				"ChainName":   faker.Company().Name(),
+				"RestoreDays": 30,
 			},
-			DataExpected: []string{"Name", "ChainName"},
+			DataExpected: []string{"Name", "ChainName", "RestoreDays"},
 			Args:         []any{},
 		},
+		{
+			Name: "loop_merged",
+			Data: map[string]any{
+				"Name":            faker.Person().Name(),
+				"SourceChainName": faker.Company().Name(),
+				"TargetChainName": faker.Company().Name(),
+			},
+			DataExpected: []string{"Name", "SourceChainName", "TargetChainName"},
+			Args:         []any{faker.Company().Name(), faker.Company().Name()},
+		},
+		{
+			Name: "loop_split",
+			Data: map[string]any{
+				"Name":              faker.Person().Name(),
+				"OriginalChainName": faker.Company().Name(),
+				"NewChainName":      faker.Company().Name(),
+				"ChainName":         faker.Company().Name(),
+			},
+			DataExpected: []string{"Name", "OriginalChainName", "NewChainName", "ChainName"},
+			Args:         []any{faker.Company().Name()},
+		},
 		{
 			Name: "poke",
 			Data: map[string]any{
@@ -194,9 +272,13 @@ func TestEmailFormattingByLanguage(t *testing.T) {
 					"Phone":   faker.Person().Contact().Phone,
 					"Address": faker.Address().Address(),
 					"Sizes":   faker.UUID().V4(),
+					"Answers": []any{map[string]any{
+						"Question": faker.Lorem().Sentence(5),
+						"Answer":   faker.Lorem().Sentence(8),
+					}},
 				},
 			},
-			DataExpected: []string{"Name", "ChainName", "Participant.Name", "Participant.Email", "Participant.Address", "Participant.Sizes"},
+			DataExpected: []string{"Name", "ChainName", "Participant.Name", "Participant.Email", "Participant.Address", "Participant.Sizes", "Participant.Answers[0].Question", "Participant.Answers[0].Answer"},
 			Args:         []any{},
 		},
 		{
@@ -227,6 +309,17 @@ func TestEmailFormattingByLanguage(t *testing.T) {
 			DataExpected: []string{"Name"},
 			Args:         []any{},
 		},
+		{
+			Name: "waitlist_loop_open",
+			Data: map[string]any{
+				"Name":      faker.Person().Name(),
+				"ChainName": faker.Company().Name(),
+				"BaseURL":   faker.Internet().URL(),
+				"ChainUID":  faker.UUID().V4(),
+			},
+			DataExpected: []string{"Name", "ChainName", "BaseURL", "ChainUID"},
+			Args:         []any{faker.Company().Name()},
+		},
 		{
 			Name: "you_signed_up_for_loop",
 			Data: map[string]any{
@@ -20,4 +20,19 @@ var Notifications map[string]*onesignal.StringMap = map[string]*onesignal.String
 		En: onesignal.PtrString("A bag has been assigned to you"),
 		// Nl: "",
 	},
+
+	"hostInvitationTitle": {
+		En: onesignal.PtrString("You have been invited to become a host"),
+		// Nl: "",
+	},
+
+	"loopClosedAtCapacityTitle": {
+		En: onesignal.PtrString("Your loop is full and has been closed to new members"),
+		// Nl: "",
+	},
+
+	"loopReopenedBelowCapacityTitle": {
+		En: onesignal.PtrString("Your loop has room again and is open to new members"),
+		// Nl: "",
+	},
 }
@@ -0,0 +1,145 @@
+package geocluster
+
+import (
+	"math"
+	"sort"
+)
+
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+type Cluster struct {
+	// Centroid of the points in the cluster
+	Latitude  float64
+	Longitude float64
+	// Indexes of the points in the given slice
+	Indexes []int
+}
+
+type cellKey struct {
+	x, y int
+}
+
+// Groups points that fall into the same grid cell, a map tile at the given zoom level
+// is divided into cellsPerTile cells on each axis.
+// Clusters are returned in a stable order.
+func Grid(points []Point, zoom int, cellsPerTile int) []Cluster {
+	cellSize := 360 / math.Pow(2, float64(zoom)) / float64(cellsPerTile)
+
+	keys := []cellKey{}
+	cells := map[cellKey]*Cluster{}
+	for i, p := range points {
+		key := cellKey{
+			x: int(math.Floor(p.Longitude / cellSize)),
+			y: int(math.Floor(p.Latitude / cellSize)),
+		}
+		cell, ok := cells[key]
+		if !ok {
+			cell = &Cluster{}
+			cells[key] = cell
+			keys = append(keys, key)
+		}
+		cell.Latitude += p.Latitude
+		cell.Longitude += p.Longitude
+		cell.Indexes = append(cell.Indexes, i)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].y != keys[j].y {
+			return keys[i].y < keys[j].y
+		}
+		return keys[i].x < keys[j].x
+	})
+
+	clusters := make([]Cluster, 0, len(keys))
+	for _, key := range keys {
+		cell := cells[key]
+		count := float64(len(cell.Indexes))
+		cell.Latitude /= count
+		cell.Longitude /= count
+		clusters = append(clusters, *cell)
+	}
+	return clusters
+}
+
+const kMeansMaxIterations = 50
+
+// Divides the points into k groups of points that are close to each other,
+// returns the group index of each point. The result is deterministic,
+// the initial centroids are picked by repeatedly taking the point furthest from the previous ones.
+func KMeans(points []Point, k int) []int {
+	labels := make([]int, len(points))
+	if len(points) == 0 || k <= 1 {
+		return labels
+	}
+	if k > len(points) {
+		k = len(points)
+	}
+
+	centroids := []Point{points[0]}
+	for len(centroids) < k {
+		furthest, furthestDistance := 0, -1.0
+		for i, p := range points {
+			d := distanceToNearest(p, centroids)
+			if d > furthestDistance {
+				furthest, furthestDistance = i, d
+			}
+		}
+		centroids = append(centroids, points[furthest])
+	}
+
+	for iteration := 0; iteration < kMeansMaxIterations; iteration++ {
+		changed := false
+		for i, p := range points {
+			nearest := 0
+			for j := range centroids {
+				if squaredDistance(p, centroids[j]) < squaredDistance(p, centroids[nearest]) {
+					nearest = j
+				}
+			}
+			if labels[i] != nearest {
+				labels[i] = nearest
+				changed = true
+			}
+		}
+		if !changed && iteration > 0 {
+			break
+		}
+
+		sums := make([]Point, k)
+		counts := make([]int, k)
+		for i, p := range points {
+			sums[labels[i]].Latitude += p.Latitude
+			sums[labels[i]].Longitude += p.Longitude
+			counts[labels[i]]++
+		}
+		for j := range centroids {
+			if counts[j] == 0 {
+				continue
+			}
+			centroids[j] = Point{
+				Latitude:  sums[j].Latitude / float64(counts[j]),
+				Longitude: sums[j].Longitude / float64(counts[j]),
+			}
+		}
+	}
+
+	return labels
+}
+
+func distanceToNearest(p Point, centroids []Point) float64 {
+	nearest := math.Inf(1)
+	for _, c := range centroids {
+		nearest = math.Min(nearest, squaredDistance(p, c))
+	}
+	return nearest
+}
+
+// Good enough to compare distances between points that are close to each other
+func squaredDistance(a, b Point) float64 {
+	dLat := a.Latitude - b.Latitude
+	dLong := a.Longitude - b.Longitude
+	return dLat*dLat + dLong*dLong
+}
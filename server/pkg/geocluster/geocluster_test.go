@@ -0,0 +1,57 @@
+package geocluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGrid(t *testing.T) {
+	points := []Point{
+		{Latitude: 52.1, Longitude: 4.1},
+		{Latitude: -33.9, Longitude: 18.4},
+		{Latitude: 52.3, Longitude: 4.3},
+	}
+
+	clusters := Grid(points, 2, 4)
+
+	assert.Len(t, clusters, 2)
+	assert.Equal(t, []int{1}, clusters[0].Indexes)
+	assert.Equal(t, []int{0, 2}, clusters[1].Indexes)
+	assert.InDelta(t, 52.2, clusters[1].Latitude, 0.0001)
+	assert.InDelta(t, 4.2, clusters[1].Longitude, 0.0001)
+}
+
+func TestGridHighZoomSeparatesPoints(t *testing.T) {
+	points := []Point{
+		{Latitude: 52.1, Longitude: 4.1},
+		{Latitude: 52.3, Longitude: 4.3},
+	}
+
+	clusters := Grid(points, 12, 4)
+
+	assert.Len(t, clusters, 2)
+}
+
+func TestGridEmpty(t *testing.T) {
+	assert.Empty(t, Grid([]Point{}, 0, 4))
+}
+
+func TestKMeans(t *testing.T) {
+	points := []Point{
+		{Latitude: 52.37, Longitude: 4.89},
+		{Latitude: 52.09, Longitude: 5.12},
+		{Latitude: 52.38, Longitude: 4.90},
+		{Latitude: 52.08, Longitude: 5.11},
+		{Latitude: 52.36, Longitude: 4.88},
+	}
+
+	labels := KMeans(points, 2)
+
+	assert.Equal(t, []int{0, 1, 0, 1, 0}, labels)
+}
+
+func TestKMeansFewerPointsThanGroups(t *testing.T) {
+	assert.Equal(t, []int{0}, KMeans([]Point{{Latitude: 1, Longitude: 1}}, 2))
+	assert.Empty(t, KMeans([]Point{}, 2))
+}